@@ -7,6 +7,14 @@ import (
 	"github.com/jim-barber-he/go/util"
 )
 
+// NewAssumeRoleError creates a new error for failing to assume a role.
+func NewAssumeRoleError(role string) error {
+	return &util.Error{
+		Msg:   "failed to assume role: ",
+		Param: role,
+	}
+}
+
 // NewCreateDirError creates a new error for directory creation failure.
 func NewCreateDirError(directory string) error {
 	return &util.Error{
@@ -15,6 +23,30 @@ func NewCreateDirError(directory string) error {
 	}
 }
 
+// NewKeyNotEnabledError creates a new error for when a KMS key exists but can't be used, such as when it is disabled.
+func NewKeyNotEnabledError(keyID, state string) error {
+	return &util.Error{
+		Msg:   "KMS key is not enabled: ",
+		Param: fmt.Sprintf("%s (%s)", keyID, state),
+	}
+}
+
+// NewKeyNotFoundError creates a new error for when a KMS key ID or alias doesn't exist.
+func NewKeyNotFoundError(keyID string) error {
+	return &util.Error{
+		Msg:   "KMS key or alias not found in this account and region: ",
+		Param: keyID,
+	}
+}
+
+// NewLoadSourceProfileError creates a new error for failing to load the source profile of an AWS Profile.
+func NewLoadSourceProfileError(profile string) error {
+	return &util.Error{
+		Msg:   "failed to load source profile: ",
+		Param: profile,
+	}
+}
+
 // NewOneParameterError creates a new error for invalid parameter count.
 func NewOneParameterError(numParameters int) error {
 	return &util.Error{
@@ -47,6 +79,22 @@ func NewParameterGetError(parameter string) error {
 	}
 }
 
+// NewParameterLabelError creates a new error for parameter labelling failure.
+func NewParameterLabelError(parameter string) error {
+	return &util.Error{
+		Msg:   "failed to label parameter: ",
+		Param: parameter,
+	}
+}
+
+// NewParameterListTagsError creates a new error for parameter tag listing failure.
+func NewParameterListTagsError(parameter string) error {
+	return &util.Error{
+		Msg:   "failed to list tags of parameter: ",
+		Param: parameter,
+	}
+}
+
 // NewParameterPutError creates a new error for parameter storage failure.
 func NewParameterPutError(parameter string) error {
 	return &util.Error{
@@ -55,6 +103,62 @@ func NewParameterPutError(parameter string) error {
 	}
 }
 
+// NewParameterTagError creates a new error for parameter tagging failure.
+func NewParameterTagError(parameter string) error {
+	return &util.Error{
+		Msg:   "failed to tag parameter: ",
+		Param: parameter,
+	}
+}
+
+// NewParameterUntagError creates a new error for parameter tag removal failure.
+func NewParameterUntagError(parameter string) error {
+	return &util.Error{
+		Msg:   "failed to remove tags from parameter: ",
+		Param: parameter,
+	}
+}
+
+// NewSecretBinaryError creates a new error for when a secret holds binary data rather than a string.
+func NewSecretBinaryError(secret string) error {
+	return &util.Error{
+		Msg:   "secret holds binary data rather than a string: ",
+		Param: secret,
+	}
+}
+
+// NewSecretDeleteError creates a new error for secret deletion failure.
+func NewSecretDeleteError(secret string) error {
+	return &util.Error{
+		Msg:   "failed to delete secret: ",
+		Param: secret,
+	}
+}
+
+// NewSecretDescribeError creates a new error for secret description failure.
+func NewSecretDescribeError(secret string) error {
+	return &util.Error{
+		Msg:   "failed to describe secret: ",
+		Param: secret,
+	}
+}
+
+// NewSecretGetError creates a new error for secret retrieval failure.
+func NewSecretGetError(secret string) error {
+	return &util.Error{
+		Msg:   "failed to get secret: ",
+		Param: secret,
+	}
+}
+
+// NewSecretPutError creates a new error for secret storage failure.
+func NewSecretPutError(secret string) error {
+	return &util.Error{
+		Msg:   "failed to put secret: ",
+		Param: secret,
+	}
+}
+
 // NewWriteCacheFileError creates a new error for failure to write to the cache file.
 func NewWriteCacheFileError(file string) error {
 	return &util.Error{
@@ -64,14 +168,25 @@ func NewWriteCacheFileError(file string) error {
 }
 
 var (
+	errCallerIdentity     = errors.New("failed to get the caller identity")
 	errGetCachePath       = errors.New("failed to get cache file path")
 	errGetClientName      = errors.New("failed to get client name")
 	errGetToken           = errors.New("failed to get token")
+	errKMSDescribeKey     = errors.New("failed to describe KMS key")
+	errKMSListAliases     = errors.New("failed to list KMS aliases")
+	errLoadConfig         = errors.New("failed to load AWS config")
 	errMarshalJSON        = errors.New("failed to marshal cache data to JSON")
+	errNoRefreshToken     = errors.New("the AWS SSO session has no refresh token, so it can't be kept alive")
+	errNotSSOProfile      = errors.New("current AWS Profile does not support AWS SSO")
 	errOpenBrowser        = errors.New("failed to open browser for authentication")
 	errOSUserNotFound     = errors.New("failed to find OS user")
 	errParameterGetByPath = errors.New("failed to get parameters by path")
+	errParametersDelete   = errors.New("failed to delete parameters")
+	errParametersDescribe = errors.New("failed to describe parameters")
+	errRateLimit          = errors.New("failed waiting for the API rate limit")
+	errReadCacheFile      = errors.New("failed to read cache file")
 	errRegisterClient     = errors.New("failed to register client")
+	errSecretsList        = errors.New("failed to list secrets")
 	errSSOTimeout         = errors.New("SSO login attempt timed out")
 	errStartDeviceAuth    = errors.New("failed to start device authorisation")
 	errWriteCacheFile     = errors.New("failed to write cache file")
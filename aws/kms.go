@@ -0,0 +1,87 @@
+/*
+Package aws implements functions to interact with Amazon Web Services.
+This part handles working with KMS keys.
+*/
+package aws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	"github.com/aws/aws-sdk-go-v2/service/kms/types"
+)
+
+// KMSAPI is the part of the KMS client API used by the KMS* functions.
+// It is satisfied by the *kms.Client returned by KMSClient, and lets callers pass in mocks, or wrappers that add
+// things like tracing.
+type KMSAPI interface {
+	DescribeKey(
+		ctx context.Context, params *kms.DescribeKeyInput, optFns ...func(*kms.Options),
+	) (*kms.DescribeKeyOutput, error)
+	ListAliases(
+		ctx context.Context, params *kms.ListAliasesInput, optFns ...func(*kms.Options),
+	) (*kms.ListAliasesOutput, error)
+}
+
+// Make sure that the KMS client satisfies the KMSAPI interface.
+var _ KMSAPI = (*kms.Client)(nil)
+
+// KMSAlias is an alias of a KMS key.
+// The TargetKeyID is empty for aliases that aren't attached to a key.
+type KMSAlias struct {
+	ARN         string `json:"arn"`
+	Name        string `json:"name"`
+	TargetKeyID string `json:"targetKeyId,omitempty"`
+}
+
+// KMSClient returns the authenticated KMS client that can be passed to the various KMS* functions.
+func KMSClient(cfg aws.Config) *kms.Client {
+	return kms.NewFromConfig(cfg)
+}
+
+// KMSListAliases returns the aliases of the KMS keys in the account and region of the client, including the aliases
+// of the AWS managed keys such as `alias/aws/ssm`.
+func KMSListAliases(ctx context.Context, kmsClient KMSAPI) ([]KMSAlias, error) {
+	paginator := kms.NewListAliasesPaginator(kmsClient, &kms.ListAliasesInput{})
+	var aliases []KMSAlias
+	for paginator.HasMorePages() {
+		output, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", errKMSListAliases, err)
+		}
+		for _, a := range output.Aliases {
+			aliases = append(aliases, KMSAlias{
+				ARN:         aws.ToString(a.AliasArn),
+				Name:        aws.ToString(a.AliasName),
+				TargetKeyID: aws.ToString(a.TargetKeyId),
+			})
+		}
+	}
+
+	return aliases, nil
+}
+
+// KMSResolveKey returns the ARN of the KMS key that a key ID, key ARN, alias name such as `alias/my_key`, or alias
+// ARN refers to.
+// It returns an error made with NewKeyNotFoundError if there is no such key or alias in the account and region of
+// the client, and one made with NewKeyNotEnabledError if the key can't be used to encrypt values, so that callers
+// can check a key before trying to store anything with it.
+func KMSResolveKey(ctx context.Context, kmsClient KMSAPI, keyID string) (string, error) {
+	output, err := kmsClient.DescribeKey(ctx, &kms.DescribeKeyInput{KeyId: aws.String(keyID)})
+	if err != nil {
+		var notFound *types.NotFoundException
+		if errors.As(err, &notFound) {
+			return "", fmt.Errorf("%w: %w", NewKeyNotFoundError(keyID), err)
+		}
+		return "", fmt.Errorf("%w: %w", errKMSDescribeKey, err)
+	}
+
+	if state := output.KeyMetadata.KeyState; state != types.KeyStateEnabled {
+		return "", NewKeyNotEnabledError(keyID, string(state))
+	}
+
+	return aws.ToString(output.KeyMetadata.Arn), nil
+}
@@ -0,0 +1,221 @@
+/*
+Package aws implements functions to interact with Amazon Web Services.
+This part handles working with AWS Secrets Manager.
+*/
+package aws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager/types"
+)
+
+// SecretsManagerAPI is the part of the Secrets Manager client API used by the SecretsManager* functions.
+// It is satisfied by the *secretsmanager.Client returned by SecretsManagerClient, and lets callers pass in mocks, or
+// wrappers that add things like tracing.
+type SecretsManagerAPI interface {
+	CreateSecret(
+		ctx context.Context, params *secretsmanager.CreateSecretInput, optFns ...func(*secretsmanager.Options),
+	) (*secretsmanager.CreateSecretOutput, error)
+	DeleteSecret(
+		ctx context.Context, params *secretsmanager.DeleteSecretInput, optFns ...func(*secretsmanager.Options),
+	) (*secretsmanager.DeleteSecretOutput, error)
+	DescribeSecret(
+		ctx context.Context, params *secretsmanager.DescribeSecretInput, optFns ...func(*secretsmanager.Options),
+	) (*secretsmanager.DescribeSecretOutput, error)
+	GetSecretValue(
+		ctx context.Context, params *secretsmanager.GetSecretValueInput, optFns ...func(*secretsmanager.Options),
+	) (*secretsmanager.GetSecretValueOutput, error)
+	ListSecrets(
+		ctx context.Context, params *secretsmanager.ListSecretsInput, optFns ...func(*secretsmanager.Options),
+	) (*secretsmanager.ListSecretsOutput, error)
+	PutSecretValue(
+		ctx context.Context, params *secretsmanager.PutSecretValueInput, optFns ...func(*secretsmanager.Options),
+	) (*secretsmanager.PutSecretValueOutput, error)
+}
+
+// Make sure that the Secrets Manager client satisfies the SecretsManagerAPI interface.
+var _ SecretsManagerAPI = (*secretsmanager.Client)(nil)
+
+// SecretsManagerSecret represents some of the fields that makes up a secret in AWS Secrets Manager.
+// Only secrets holding a string are supported, since that is all that SSM parameters can hold.
+// The Value and VersionID aren't set for secrets returned by SecretsManagerList.
+type SecretsManagerSecret struct {
+	ARN             string            `json:"arn"`
+	Description     string            `json:"description,omitempty"`
+	KeyID           string            `json:"keyId,omitempty"`
+	LastChangedDate time.Time         `json:"lastChangedDate"`
+	Name            string            `json:"name"`
+	Tags            map[string]string `json:"tags,omitempty"`
+	Value           string            `json:"value,omitempty"`
+	VersionID       string            `json:"versionId,omitempty"`
+}
+
+// Print displays the SecretsManagerSecret to the screen.
+func (s *SecretsManagerSecret) Print() {
+	s.Fprint(os.Stdout)
+}
+
+// Fprint writes the fields of the SecretsManagerSecret to w, one per line.
+func (s *SecretsManagerSecret) Fprint(w io.Writer) {
+	fmt.Fprintf(w, "ARN: %s\n", s.ARN)
+	if s.Description != "" {
+		fmt.Fprintf(w, "Description: %s\n", s.Description)
+	}
+	if s.KeyID != "" {
+		fmt.Fprintf(w, "KeyID: %s\n", s.KeyID)
+	}
+	fmt.Fprintf(w, "LastChangedDate: %s\n", s.LastChangedDate)
+	fmt.Fprintf(w, "Name: %s\n", s.Name)
+	if len(s.Tags) > 0 {
+		fmt.Fprintln(w, "Tags:")
+		for _, key := range slices.Sorted(maps.Keys(s.Tags)) {
+			fmt.Fprintf(w, "  %s: %s\n", key, s.Tags[key])
+		}
+	}
+	fmt.Fprintf(w, "Value: %s\n", s.Value)
+	if s.VersionID != "" {
+		fmt.Fprintf(w, "VersionID: %s\n", s.VersionID)
+	}
+}
+
+// SecretsManagerClient returns the authenticated Secrets Manager client that can be passed to the various
+// SecretsManager* functions.
+// API calls that are throttled are retried with an exponential backoff, the same way as the SSM client does.
+func SecretsManagerClient(cfg aws.Config) *secretsmanager.Client {
+	return secretsmanager.NewFromConfig(cfg, func(o *secretsmanager.Options) {
+		o.Retryer = retry.NewStandard(ssmRetryOptions(ssmMaxAttempts))
+	})
+}
+
+// SecretsManagerDelete deletes a secret from Secrets Manager.
+// Unless force is true, the secret is only scheduled for deletion, and can be restored during the default recovery
+// window of 30 days. While it is scheduled for deletion, a secret with the same name can't be created.
+func SecretsManagerDelete(ctx context.Context, smClient SecretsManagerAPI, name string, force bool) error {
+	_, err := smClient.DeleteSecret(ctx, &secretsmanager.DeleteSecretInput{
+		ForceDeleteWithoutRecovery: aws.Bool(force),
+		SecretId:                   aws.String(name),
+	})
+	if err != nil {
+		return fmt.Errorf("%w: %w", NewSecretDeleteError(name), err)
+	}
+	return nil
+}
+
+// SecretsManagerGet returns a populated SecretsManagerSecret structure with the current value and details of a secret.
+func SecretsManagerGet(ctx context.Context, smClient SecretsManagerAPI, name string) (SecretsManagerSecret, error) {
+	value, err := smClient.GetSecretValue(ctx, &secretsmanager.GetSecretValueInput{SecretId: aws.String(name)})
+	if err != nil {
+		return SecretsManagerSecret{}, fmt.Errorf("%w: %w", NewSecretGetError(name), err)
+	}
+	if value.SecretString == nil && value.SecretBinary != nil {
+		return SecretsManagerSecret{}, NewSecretBinaryError(name)
+	}
+
+	meta, err := smClient.DescribeSecret(ctx, &secretsmanager.DescribeSecretInput{SecretId: aws.String(name)})
+	if err != nil {
+		return SecretsManagerSecret{}, fmt.Errorf("%w: %w", NewSecretDescribeError(name), err)
+	}
+
+	return SecretsManagerSecret{
+		ARN:             aws.ToString(meta.ARN),
+		Description:     aws.ToString(meta.Description),
+		KeyID:           aws.ToString(meta.KmsKeyId),
+		LastChangedDate: aws.ToTime(meta.LastChangedDate),
+		Name:            aws.ToString(meta.Name),
+		Tags:            secretTags(meta.Tags),
+		Value:           aws.ToString(value.SecretString),
+		VersionID:       aws.ToString(value.VersionId),
+	}, nil
+}
+
+// SecretsManagerList returns the details of the secrets whose names start with the prefix, without their values.
+// An empty prefix returns all of the secrets.
+func SecretsManagerList(
+	ctx context.Context, smClient SecretsManagerAPI, prefix string,
+) ([]SecretsManagerSecret, error) {
+	input := &secretsmanager.ListSecretsInput{}
+	if prefix != "" {
+		input.Filters = []types.Filter{{Key: types.FilterNameStringTypeName, Values: []string{prefix}}}
+	}
+
+	var secrets []SecretsManagerSecret
+	paginator := secretsmanager.NewListSecretsPaginator(smClient, input)
+	for paginator.HasMorePages() {
+		output, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", errSecretsList, err)
+		}
+		for _, s := range output.SecretList {
+			secrets = append(secrets, SecretsManagerSecret{
+				ARN:             aws.ToString(s.ARN),
+				Description:     aws.ToString(s.Description),
+				KeyID:           aws.ToString(s.KmsKeyId),
+				LastChangedDate: aws.ToTime(s.LastChangedDate),
+				Name:            aws.ToString(s.Name),
+				Tags:            secretTags(s.Tags),
+			})
+		}
+	}
+
+	return secrets, nil
+}
+
+// SecretsManagerPut stores the value of a secret, creating the secret if it doesn't exist yet.
+// The description, encryption key, and tags are only used when creating the secret; an existing secret just gets a
+// new version holding the value.
+// The ID of the version holding the value is returned.
+func SecretsManagerPut(ctx context.Context, smClient SecretsManagerAPI, secret *SecretsManagerSecret) (string, error) {
+	output, err := smClient.PutSecretValue(ctx, &secretsmanager.PutSecretValueInput{
+		SecretId:     aws.String(secret.Name),
+		SecretString: aws.String(secret.Value),
+	})
+	if err == nil {
+		return aws.ToString(output.VersionId), nil
+	}
+	var notFound *types.ResourceNotFoundException
+	if !errors.As(err, &notFound) {
+		return "", fmt.Errorf("%w: %w", NewSecretPutError(secret.Name), err)
+	}
+
+	input := &secretsmanager.CreateSecretInput{
+		Name:         aws.String(secret.Name),
+		SecretString: aws.String(secret.Value),
+	}
+	if secret.Description != "" {
+		input.Description = aws.String(secret.Description)
+	}
+	if secret.KeyID != "" {
+		input.KmsKeyId = aws.String(secret.KeyID)
+	}
+	for _, key := range slices.Sorted(maps.Keys(secret.Tags)) {
+		input.Tags = append(input.Tags, types.Tag{Key: aws.String(key), Value: aws.String(secret.Tags[key])})
+	}
+	created, err := smClient.CreateSecret(ctx, input)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", NewSecretPutError(secret.Name), err)
+	}
+	return aws.ToString(created.VersionId), nil
+}
+
+// secretTags converts the tags of a secret into a map.
+func secretTags(tags []types.Tag) map[string]string {
+	if len(tags) == 0 {
+		return nil
+	}
+	m := make(map[string]string, len(tags))
+	for _, tag := range tags {
+		m[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+	}
+	return m
+}
@@ -6,57 +6,287 @@ package aws
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/ratelimit"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
 	"github.com/aws/aws-sdk-go-v2/service/ssm"
 	"github.com/aws/aws-sdk-go-v2/service/ssm/types"
+	"github.com/aws/smithy-go/middleware"
+	"golang.org/x/time/rate"
 )
 
-const parameterTypeSecureString string = "SecureString"
+const (
+	parameterTypeSecureString string = "SecureString"
+	parameterTypeStringList   string = "StringList"
+
+	// ssmDeleteBatchSize is the maximum number of parameters that the DeleteParameters API accepts at once.
+	ssmDeleteBatchSize = 10
+	// ssmGetBatchSize is the maximum number of parameters that the GetParameters API accepts at once.
+	ssmGetBatchSize = 10
+	// ssmMaxAttempts is how many times an SSM API call is attempted when it is throttled or fails with a transient
+	// error. It is higher than the SDK default of 3 so that large listings back off and complete instead of failing.
+	ssmMaxAttempts = 10
+)
+
+// SSMAPI is the part of the SSM client API used by the SSM* functions.
+// It is satisfied by the *ssm.Client returned by SSMClient, and lets callers pass in mocks, or wrappers that add
+// things like tracing.
+type SSMAPI interface {
+	AddTagsToResource(
+		ctx context.Context, params *ssm.AddTagsToResourceInput, optFns ...func(*ssm.Options),
+	) (*ssm.AddTagsToResourceOutput, error)
+	DeleteParameter(
+		ctx context.Context, params *ssm.DeleteParameterInput, optFns ...func(*ssm.Options),
+	) (*ssm.DeleteParameterOutput, error)
+	DeleteParameters(
+		ctx context.Context, params *ssm.DeleteParametersInput, optFns ...func(*ssm.Options),
+	) (*ssm.DeleteParametersOutput, error)
+	DescribeParameters(
+		ctx context.Context, params *ssm.DescribeParametersInput, optFns ...func(*ssm.Options),
+	) (*ssm.DescribeParametersOutput, error)
+	GetParameter(
+		ctx context.Context, params *ssm.GetParameterInput, optFns ...func(*ssm.Options),
+	) (*ssm.GetParameterOutput, error)
+	GetParameters(
+		ctx context.Context, params *ssm.GetParametersInput, optFns ...func(*ssm.Options),
+	) (*ssm.GetParametersOutput, error)
+	GetParametersByPath(
+		ctx context.Context, params *ssm.GetParametersByPathInput, optFns ...func(*ssm.Options),
+	) (*ssm.GetParametersByPathOutput, error)
+	LabelParameterVersion(
+		ctx context.Context, params *ssm.LabelParameterVersionInput, optFns ...func(*ssm.Options),
+	) (*ssm.LabelParameterVersionOutput, error)
+	ListTagsForResource(
+		ctx context.Context, params *ssm.ListTagsForResourceInput, optFns ...func(*ssm.Options),
+	) (*ssm.ListTagsForResourceOutput, error)
+	PutParameter(
+		ctx context.Context, params *ssm.PutParameterInput, optFns ...func(*ssm.Options),
+	) (*ssm.PutParameterOutput, error)
+	RemoveTagsFromResource(
+		ctx context.Context, params *ssm.RemoveTagsFromResourceInput, optFns ...func(*ssm.Options),
+	) (*ssm.RemoveTagsFromResourceOutput, error)
+}
+
+// Make sure that the SSM client satisfies the SSMAPI interface.
+var _ SSMAPI = (*ssm.Client)(nil)
+
+// SSMClientOption configures the SSM client returned by SSMClient.
+type SSMClientOption func(*ssm.Options)
 
 // SSMParameter represents some of the fields that makes up a parameter in the AWS SSM Parameter Store.
+// The Region isn't set by the functions in this package, but can be set by callers working with multiple regions.
 type SSMParameter struct {
-	ARN              string    `json:"arn"`
-	DataType         string    `json:"dataType"`
-	Error            string    `json:"error,omitempty"`
-	KeyID            string    `json:"keyId,omitempty"`
-	LastModifiedDate time.Time `json:"lastModifiedDate"`
-	LastModifiedUser string    `json:"lastModifiedUser,omitempty"`
-	Name             string    `json:"name"`
-	Type             string    `json:"type"`
-	Value            string    `json:"value"`
-	Version          int64     `json:"version"`
+	ARN              string            `json:"arn"`
+	DataType         string            `json:"dataType"`
+	Description      string            `json:"description,omitempty"`
+	Error            string            `json:"error,omitempty"`
+	KeyID            string            `json:"keyId,omitempty"`
+	LastModifiedDate time.Time         `json:"lastModifiedDate"`
+	LastModifiedUser string            `json:"lastModifiedUser,omitempty"`
+	Name             string            `json:"name"`
+	Policies         []SSMPolicy       `json:"policies,omitempty"`
+	Region           string            `json:"region,omitempty"`
+	Selector         string            `json:"selector,omitempty"`
+	Tags             map[string]string `json:"tags,omitempty"`
+	Tier             string            `json:"tier,omitempty"`
+	Type             string            `json:"type"`
+	Value            string            `json:"value"`
+	Version          int64             `json:"version"`
+}
+
+// SSMPolicy is a policy attached to a parameter, such as when it expires.
+// The Text is the JSON of the policy, and the Status is one of Pending, Finished, Failed, or InProgress.
+type SSMPolicy struct {
+	Status string `json:"status,omitempty"`
+	Text   string `json:"text"`
+	Type   string `json:"type"`
 }
 
 // Print displays the SSMParameter to the screen.
 func (p *SSMParameter) Print() {
-	fmt.Printf("ARN: %s\n", p.ARN)
-	fmt.Printf("DataType: %s\n", p.DataType)
+	p.Fprint(os.Stdout)
+}
+
+// Fprint writes the fields of the SSMParameter to w, one per line.
+func (p *SSMParameter) Fprint(w io.Writer) {
+	fmt.Fprintf(w, "ARN: %s\n", p.ARN)
+	fmt.Fprintf(w, "DataType: %s\n", p.DataType)
+	if p.Description != "" {
+		fmt.Fprintf(w, "Description: %s\n", p.Description)
+	}
 	if p.Error != "" {
-		fmt.Printf("Error: %s\n", p.Error)
+		fmt.Fprintf(w, "Error: %s\n", p.Error)
 	}
 	if p.KeyID != "" {
-		fmt.Printf("KeyID: %s\n", p.KeyID)
+		fmt.Fprintf(w, "KeyID: %s\n", p.KeyID)
 	}
-	fmt.Printf("LastModifiedDate: %s\n", p.LastModifiedDate)
+	fmt.Fprintf(w, "LastModifiedDate: %s\n", p.LastModifiedDate)
 	if p.LastModifiedUser != "" {
-		fmt.Printf("LastModifiedUser: %s\n", p.LastModifiedUser)
+		fmt.Fprintf(w, "LastModifiedUser: %s\n", p.LastModifiedUser)
 	}
-	fmt.Printf("Name: %s\n", p.Name)
-	fmt.Printf("Type: %s\n", p.Type)
-	fmt.Printf("Value: %s\n", p.Value)
-	fmt.Printf("Version: %d\n", p.Version)
+	fmt.Fprintf(w, "Name: %s\n", p.Name)
+	if len(p.Policies) > 0 {
+		fmt.Fprintln(w, "Policies:")
+		for _, policy := range p.Policies {
+			fmt.Fprintf(w, "  - %s (%s): %s\n", policy.Type, policy.Status, policy.Text)
+		}
+	}
+	if p.Region != "" {
+		fmt.Fprintf(w, "Region: %s\n", p.Region)
+	}
+	if p.Selector != "" {
+		fmt.Fprintf(w, "Selector: %s\n", p.Selector)
+	}
+	if len(p.Tags) > 0 {
+		fmt.Fprintln(w, "Tags:")
+		for _, key := range slices.Sorted(maps.Keys(p.Tags)) {
+			fmt.Fprintf(w, "  %s: %s\n", key, p.Tags[key])
+		}
+	}
+	if p.Tier != "" {
+		fmt.Fprintf(w, "Tier: %s\n", p.Tier)
+	}
+	fmt.Fprintf(w, "Type: %s\n", p.Type)
+	fmt.Fprintf(w, "Value: %s\n", p.Value)
+	// Also show the items of a StringList one per line since they can be hard to pick out of the value.
+	if p.Type == parameterTypeStringList && p.Value != "" {
+		for _, item := range strings.Split(p.Value, ",") {
+			fmt.Fprintf(w, "  - %s\n", item)
+		}
+	}
+	fmt.Fprintf(w, "Version: %d\n", p.Version)
 }
 
 // SSMClient returns the authenticated SSM client that can be passed to the various SSM* Functions.
-func SSMClient(cfg aws.Config) *ssm.Client {
-	return ssm.NewFromConfig(cfg)
+// API calls that are throttled, such as by a ThrottlingException, are retried with an exponential backoff.
+// The maximum attempts and retry mode from the AWS config, such as those set by the AWS_MAX_ATTEMPTS and
+// AWS_RETRY_MODE environment variables, are used if they are set, and can be overridden by WithMaxAttempts and
+// WithAdaptiveRetry.
+func SSMClient(cfg aws.Config, optFns ...SSMClientOption) *ssm.Client {
+	opts := []func(*ssm.Options){
+		func(o *ssm.Options) {
+			o.Retryer = retry.NewStandard(ssmRetryOptions(ssmMaxAttempts))
+		},
+	}
+	// Replacing the retryer above loses the retry mode, but the SDK still applies the maximum attempts.
+	if cfg.RetryMode == aws.RetryModeAdaptive {
+		opts = append(opts, WithAdaptiveRetry())
+	}
+	for _, fn := range optFns {
+		opts = append(opts, fn)
+	}
+	return ssm.NewFromConfig(cfg, opts...)
+}
+
+// ssmRetryOptions returns the settings of the standard retryer used by the SSM clients.
+func ssmRetryOptions(maxAttempts int) func(*retry.StandardOptions) {
+	return func(so *retry.StandardOptions) {
+		so.MaxAttempts = maxAttempts
+		// The default retry quota gives up retrying once many calls have failed, which is exactly what happens while
+		// being throttled, so only the backoff is relied on to slow down.
+		so.RateLimiter = ratelimit.None
+	}
+}
+
+// WithAdaptiveRetry makes the SSM client use the adaptive retry mode, which also slows down the rate of all API calls
+// made by the client while they are being throttled, rather than only backing off the calls that were throttled.
+// This suits heavy runs such as listing with full details, where many calls are made in quick succession.
+func WithAdaptiveRetry() SSMClientOption {
+	return func(o *ssm.Options) {
+		o.Retryer = retry.NewAdaptiveMode(func(ao *retry.AdaptiveModeOptions) {
+			ao.StandardOptions = append(ao.StandardOptions, ssmRetryOptions(ssmMaxAttempts))
+		})
+	}
+}
+
+// WithMaxAttempts sets how many times the SSM client attempts an API call that is throttled or fails with a
+// transient error, including the first attempt.
+// A value less than 1 leaves the maximum attempts unchanged.
+func WithMaxAttempts(maxAttempts int) SSMClientOption {
+	return func(o *ssm.Options) {
+		if maxAttempts > 0 {
+			// The SDK applies this to the retryer once all of the options have been applied.
+			o.RetryMaxAttempts = maxAttempts
+		}
+	}
+}
+
+// WithMaxRPS limits the SSM client to making at most `rps` API calls per second, including retries.
+// This keeps large runs below the SSM API rate limits rather than relying on being throttled.
+// A rate of zero or less leaves the calls unlimited.
+func WithMaxRPS(rps float64) SSMClientOption {
+	return func(o *ssm.Options) {
+		if rps <= 0 {
+			return
+		}
+		limiter := rate.NewLimiter(rate.Limit(rps), 1)
+		o.APIOptions = append(o.APIOptions, func(stack *middleware.Stack) error {
+			// Added after the retry middleware so that every attempt waits its turn.
+			return stack.Finalize.Insert(middleware.FinalizeMiddlewareFunc(
+				"SSMRateLimit",
+				func(
+					ctx context.Context, in middleware.FinalizeInput, next middleware.FinalizeHandler,
+				) (middleware.FinalizeOutput, middleware.Metadata, error) {
+					if err := limiter.Wait(ctx); err != nil {
+						return middleware.FinalizeOutput{}, middleware.Metadata{}, fmt.Errorf("%w: %w", errRateLimit, err)
+					}
+					return next.HandleFinalize(ctx, in)
+				},
+			), "Retry", middleware.After)
+		})
+	}
+}
+
+// SSMAddTags adds tags to a parameter, replacing the values of any tags it already has with the same keys.
+func SSMAddTags(ctx context.Context, ssmClient SSMAPI, name string, tags map[string]string) error {
+	if len(tags) == 0 {
+		return nil
+	}
+
+	input := &ssm.AddTagsToResourceInput{
+		ResourceId:   aws.String(name),
+		ResourceType: types.ResourceTypeForTaggingParameter,
+	}
+	for _, key := range slices.Sorted(maps.Keys(tags)) {
+		input.Tags = append(input.Tags, types.Tag{Key: aws.String(key), Value: aws.String(tags[key])})
+	}
+	if _, err := ssmClient.AddTagsToResource(ctx, input); err != nil {
+		return fmt.Errorf("%w: %w", NewParameterTagError(name), err)
+	}
+
+	return nil
+}
+
+// SSMCheckPutAccess checks whether the credentials are allowed to put the named parameter, without changing it.
+// The put is sent with an AllowedPattern that its value doesn't match, so AWS rejects it once the permissions have
+// been checked. A nil error means that the put is allowed.
+func SSMCheckPutAccess(ctx context.Context, ssmClient SSMAPI, name string) error {
+	_, err := ssmClient.PutParameter(ctx, &ssm.PutParameterInput{
+		AllowedPattern: aws.String("^$"),
+		Name:           aws.String(name),
+		Overwrite:      aws.Bool(false),
+		Type:           types.ParameterTypeString,
+		Value:          aws.String("permission check"),
+	})
+	var mismatch *types.ParameterPatternMismatchException
+	var exists *types.ParameterAlreadyExists
+	if err == nil || errors.As(err, &mismatch) || errors.As(err, &exists) {
+		return nil
+	}
+	return fmt.Errorf("%w: %w", NewParameterPutError(name), err)
 }
 
 // SSMDelete deletes a parameter by name from the SSM parameter store.
-func SSMDelete(ctx context.Context, ssmClient *ssm.Client, name string) error {
+func SSMDelete(ctx context.Context, ssmClient SSMAPI, name string) error {
 	_, err := ssmClient.DeleteParameter(ctx, &ssm.DeleteParameterInput{Name: aws.String(name)})
 	if err != nil {
 		return fmt.Errorf("%w: %w", NewParameterDeleteError(name), err)
@@ -64,9 +294,34 @@ func SSMDelete(ctx context.Context, ssmClient *ssm.Client, name string) error {
 	return nil
 }
 
+// SSMDeleteParameters deletes parameters by name from the SSM parameter store, in batches of up to 10 at a time.
+// It returns the names of any parameters that weren't deleted because they don't exist.
+func SSMDeleteParameters(ctx context.Context, ssmClient SSMAPI, names []string) ([]string, error) {
+	var invalid []string
+	for batch := range slices.Chunk(names, ssmDeleteBatchSize) {
+		output, err := ssmClient.DeleteParameters(ctx, &ssm.DeleteParametersInput{Names: batch})
+		if err != nil {
+			return invalid, fmt.Errorf("%w: %w", errParametersDelete, err)
+		}
+		invalid = append(invalid, output.InvalidParameters...)
+	}
+
+	return invalid, nil
+}
+
 // SSMDescribeParameter returns the ID of the encryption key and the last user who set/modified an SSM parameter.
 // If there is no encryption key because the parameter is a String, then the key ID will be an empty string.
-func SSMDescribeParameter(ctx context.Context, ssmClient *ssm.Client, name string) (string, string, error) {
+func SSMDescribeParameter(ctx context.Context, ssmClient SSMAPI, name string) (string, string, error) {
+	param, err := ssmDescribe(ctx, ssmClient, name)
+	if err != nil {
+		return "", "", err
+	}
+
+	return metadataKeyID(&param), aws.ToString(param.LastModifiedUser), nil
+}
+
+// ssmDescribe returns the metadata of an SSM parameter.
+func ssmDescribe(ctx context.Context, ssmClient SSMAPI, name string) (types.ParameterMetadata, error) {
 	output, err := ssmClient.DescribeParameters(ctx, &ssm.DescribeParametersInput{
 		ParameterFilters: []types.ParameterStringFilter{
 			{
@@ -77,25 +332,17 @@ func SSMDescribeParameter(ctx context.Context, ssmClient *ssm.Client, name strin
 		},
 	})
 	if err != nil {
-		return "", "", fmt.Errorf("%w: %w", NewParameterDescribeError(name), err)
+		return types.ParameterMetadata{}, fmt.Errorf("%w: %w", NewParameterDescribeError(name), err)
 	}
 
 	if len(output.Parameters) != 1 {
-		return "", "", NewOneParameterError(len(output.Parameters))
+		return types.ParameterMetadata{}, NewOneParameterError(len(output.Parameters))
 	}
 
-	param := output.Parameters[0]
-	keyID := ""
-	if param.Type == types.ParameterTypeSecureString {
-		keyID = aws.ToString(param.KeyId)
-	}
-	lastModifiedUser := aws.ToString(param.LastModifiedUser)
 	/*
 		Also output.Parameters has available...
 		- AllowedPattern
-		- Description
 		- Policies ([]types.ParameterInlinePolicy{}
-		- Tier
 		Along with these that GetParameter also returns...
 		- ARN
 		- DataType
@@ -104,50 +351,187 @@ func SSMDescribeParameter(ctx context.Context, ssmClient *ssm.Client, name strin
 		- Version
 	*/
 
-	return keyID, lastModifiedUser, nil
+	return output.Parameters[0], nil
+}
+
+// metadataKeyID returns the ID of the encryption key of a SecureString parameter, or an empty string for other types.
+func metadataKeyID(param *types.ParameterMetadata) string {
+	if param.Type != types.ParameterTypeSecureString {
+		return ""
+	}
+
+	return aws.ToString(param.KeyId)
+}
+
+// metadataPolicies returns the policies attached to a parameter.
+func metadataPolicies(param *types.ParameterMetadata) []SSMPolicy {
+	var policies []SSMPolicy
+	for _, p := range param.Policies {
+		policies = append(policies, SSMPolicy{
+			Status: aws.ToString(p.PolicyStatus),
+			Text:   aws.ToString(p.PolicyText),
+			Type:   aws.ToString(p.PolicyType),
+		})
+	}
+	return policies
 }
 
 // SSMGet returns a populated SSMParameter structure populated with details of a named SSM parameter.
-func SSMGet(ctx context.Context, ssmClient *ssm.Client, name string) (SSMParameter, error) {
-	var p SSMParameter
+// The name can end with a selector for a version or label of the parameter, such as `:3` or `:current`.
+// The tags of the parameter aren't fetched since they need another API call; use SSMGetFull when they are wanted.
+func SSMGet(ctx context.Context, ssmClient SSMAPI, name string) (SSMParameter, error) {
+	p, err := ssmGetParameter(ctx, ssmClient, name, true)
+	if err != nil {
+		return SSMParameter{}, err
+	}
+
+	if meta, err := ssmDescribe(ctx, ssmClient, p.Name); err == nil {
+		p.Description = aws.ToString(meta.Description)
+		p.KeyID = metadataKeyID(&meta)
+		p.LastModifiedUser = aws.ToString(meta.LastModifiedUser)
+		p.Policies = metadataPolicies(&meta)
+		p.Tier = string(meta.Tier)
+	}
+
+	return p, nil
+}
+
+// SSMGetFull returns all the details of a named SSM parameter like SSMGet does, along with its tags.
+func SSMGetFull(ctx context.Context, ssmClient SSMAPI, name string) (SSMParameter, error) {
+	p, err := SSMGet(ctx, ssmClient, name)
+	if err != nil {
+		return SSMParameter{}, err
+	}
+
+	if p.Tags, err = SSMListTags(ctx, ssmClient, p.Name); err != nil {
+		return SSMParameter{}, err
+	}
+
+	return p, nil
+}
+
+// ssmGetParameter returns the value of a named SSM parameter along with the details that GetParameter provides.
+// If decrypt is true and the value can't be decrypted, then the error is recorded against the parameter and it is
+// fetched again without decryption so that its other details are still available.
+func ssmGetParameter(ctx context.Context, ssmClient SSMAPI, name string, decrypt bool) (SSMParameter, error) {
+	var decryptErr string
 
 	output, err := ssmClient.GetParameter(ctx, &ssm.GetParameterInput{
 		Name:           aws.String(name),
-		WithDecryption: aws.Bool(true),
+		WithDecryption: aws.Bool(decrypt),
 	})
-	if err != nil {
-		p.Error = fmt.Sprint(err)
+	if err != nil && decrypt {
+		decryptErr = fmt.Sprint(err)
 		output, err = ssmClient.GetParameter(ctx, &ssm.GetParameterInput{Name: aws.String(name)})
-		if err != nil {
-			return SSMParameter{}, fmt.Errorf("%w: %w", NewParameterGetError(name), err)
+		if err == nil {
+			// Clear the value since it failed to decrypt.
+			output.Parameter.Value = aws.String("")
 		}
-		// Clear the value since it failed to decrypt.
-		output.Parameter.Value = aws.String("")
 	}
+	if err != nil {
+		return SSMParameter{}, fmt.Errorf("%w: %w", NewParameterGetError(name), err)
+	}
+
+	p := newSSMParameter(output.Parameter)
+	p.Error = decryptErr
+	return p, nil
+}
 
-	p.ARN = aws.ToString(output.Parameter.ARN)
+// newSSMParameter returns an SSMParameter holding the details of a parameter returned by the SSM API.
+func newSSMParameter(param *types.Parameter) SSMParameter {
+	p := SSMParameter{
+		ARN:              aws.ToString(param.ARN),
+		DataType:         aws.ToString(param.DataType),
+		LastModifiedDate: aws.ToTime(param.LastModifiedDate),
+		Name:             aws.ToString(param.Name),
+		Selector:         aws.ToString(param.Selector),
+		Type:             string(param.Type),
+		Value:            aws.ToString(param.Value),
+		Version:          param.Version,
+	}
 	// For some reason some SSM parameters had no data type set... These seem to show in the GUI as text.
-	if output.Parameter.DataType == nil {
+	if param.DataType == nil {
 		p.DataType = "text"
-	} else {
-		p.DataType = aws.ToString(output.Parameter.DataType)
 	}
-	p.LastModifiedDate = aws.ToTime(output.Parameter.LastModifiedDate)
-	p.Name = aws.ToString(output.Parameter.Name)
-	p.Type = string(output.Parameter.Type)
-	p.Value = aws.ToString(output.Parameter.Value)
-	p.Version = output.Parameter.Version
+	return p
+}
 
-	p.KeyID, p.LastModifiedUser, _ = SSMDescribeParameter(ctx, ssmClient, name)
+// SSMGetParameters returns the named parameters from the SSM parameter store, fetching them in batches of up to 10.
+// The parameters are returned in the same order as the names, and the names of any that don't exist are returned
+// separately.
+// Like SSMGet, the names can end with a selector for a version or label of the parameter.
+// If decrypt is true then the values of SecureString parameters are decrypted, otherwise they are left encrypted.
+// Only the details that the GetParameters API provides are populated; use SSMDescribeParameter and SSMListTags for
+// the others.
+// If a batch fails, such as when one of the parameters can't be decrypted, then the parameters in that batch are
+// fetched individually so that the decryption error is recorded against the parameter that caused it.
+func SSMGetParameters(
+	ctx context.Context, ssmClient SSMAPI, names []string, decrypt bool,
+) ([]SSMParameter, []string, error) {
+	found := make(map[string]SSMParameter, len(names))
+	var invalid []string
+	for batch := range slices.Chunk(names, ssmGetBatchSize) {
+		output, err := ssmClient.GetParameters(ctx, &ssm.GetParametersInput{
+			Names:          batch,
+			WithDecryption: aws.Bool(decrypt),
+		})
+		if err != nil {
+			for _, name := range batch {
+				p, err := ssmGetParameter(ctx, ssmClient, name, decrypt)
+				if err != nil {
+					var notFound *types.ParameterNotFound
+					if errors.As(err, &notFound) {
+						invalid = append(invalid, name)
+						continue
+					}
+					return nil, nil, err
+				}
+				found[name] = p
+			}
+			continue
+		}
+		invalid = append(invalid, output.InvalidParameters...)
+		for i := range output.Parameters {
+			param := newSSMParameter(&output.Parameters[i])
+			found[param.Name+param.Selector] = param
+		}
+	}
 
-	return p, nil
+	params := make([]SSMParameter, 0, len(found))
+	for _, name := range names {
+		if p, ok := found[name]; ok {
+			params = append(params, p)
+		}
+	}
+
+	return params, invalid, nil
+}
+
+// SSMLabel attaches labels to a version of a parameter, moving them from any other version that has them.
+// If the version is 0 then the labels are attached to the latest version.
+// It returns the version that was labelled, and any labels that were rejected for not meeting the requirements.
+func SSMLabel(
+	ctx context.Context, ssmClient SSMAPI, name string, version int64, labels []string,
+) (int64, []string, error) {
+	input := &ssm.LabelParameterVersionInput{
+		Labels: labels,
+		Name:   aws.String(name),
+	}
+	if version > 0 {
+		input.ParameterVersion = aws.Int64(version)
+	}
+	output, err := ssmClient.LabelParameterVersion(ctx, input)
+	if err != nil {
+		return 0, nil, fmt.Errorf("%w: %w", NewParameterLabelError(name), err)
+	}
+	return output.ParameterVersion, output.InvalidLabels, nil
 }
 
 // SSMList returns a list of parameters below a path in the SSM parameter store.
 // It can optionally recurse through the paths below the supplied path.
 // If the `full` parameter (for full details) is true, it'll fetch the encryption key ID and Last modified user,
 // at the expense of performing an AWS API lookup per parameter found, so doesn't scale well.
-func SSMList(ctx context.Context, ssmClient *ssm.Client, path string, recursive, full bool) ([]SSMParameter, error) {
+func SSMList(ctx context.Context, ssmClient SSMAPI, path string, recursive, full bool) ([]SSMParameter, error) {
 	paginator := ssm.NewGetParametersByPathPaginator(ssmClient, &ssm.GetParametersByPathInput{
 		Path:           aws.String(path),
 		Recursive:      aws.Bool(recursive),
@@ -181,6 +565,73 @@ func SSMList(ctx context.Context, ssmClient *ssm.Client, path string, recursive,
 	return params, nil
 }
 
+// SSMListMetadata returns the metadata of the parameters below a path in the SSM parameter store.
+// It can optionally recurse through the paths below the supplied path.
+// The values of the parameters are not returned, but the last user to modify each parameter is, without needing an
+// AWS API lookup per parameter.
+func SSMListMetadata(ctx context.Context, ssmClient SSMAPI, path string, recursive bool) ([]SSMParameter, error) {
+	option := "OneLevel"
+	if recursive {
+		option = "Recursive"
+	}
+	paginator := ssm.NewDescribeParametersPaginator(ssmClient, &ssm.DescribeParametersInput{
+		ParameterFilters: []types.ParameterStringFilter{
+			{
+				Key:    aws.String("Path"),
+				Option: aws.String(option),
+				Values: []string{path},
+			},
+		},
+	})
+	var params []SSMParameter
+	for paginator.HasMorePages() {
+		output, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", errParametersDescribe, err)
+		}
+		for _, p := range output.Parameters {
+			params = append(params, SSMParameter{
+				ARN:              aws.ToString(p.ARN),
+				DataType:         aws.ToString(p.DataType),
+				Description:      aws.ToString(p.Description),
+				KeyID:            metadataKeyID(&p),
+				LastModifiedDate: aws.ToTime(p.LastModifiedDate),
+				LastModifiedUser: aws.ToString(p.LastModifiedUser),
+				Name:             aws.ToString(p.Name),
+				Policies:         metadataPolicies(&p),
+				Tier:             string(p.Tier),
+				Type:             string(p.Type),
+				Version:          p.Version,
+			})
+		}
+	}
+
+	return params, nil
+}
+
+// SSMListNames returns the names of the parameters below a path in the SSM parameter store.
+// It can optionally recurse through the paths below the supplied path.
+// Unlike SSMList, the values aren't decrypted, so it works even when the encryption keys of the parameters are
+// inaccessible or deleted.
+func SSMListNames(ctx context.Context, ssmClient SSMAPI, path string, recursive bool) ([]string, error) {
+	paginator := ssm.NewGetParametersByPathPaginator(ssmClient, &ssm.GetParametersByPathInput{
+		Path:      aws.String(path),
+		Recursive: aws.Bool(recursive),
+	})
+	var names []string
+	for paginator.HasMorePages() {
+		output, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("%w : %w", errParameterGetByPath, err)
+		}
+		for _, p := range output.Parameters {
+			names = append(names, aws.ToString(p.Name))
+		}
+	}
+
+	return names, nil
+}
+
 // SSMListSafeDecrypt returns a list of parameters below a path in the SSM parameter store.
 // It can optionally recurse through the paths below the supplied path.
 // If the `full` parameter (for full details) is true, it'll fetch the encryption key ID and Last modified user,
@@ -188,7 +639,7 @@ func SSMList(ctx context.Context, ssmClient *ssm.Client, path string, recursive,
 // It differs from SSMList in that it retrieves parameters unencrypted then tries to decrypt them as they are
 // encountered. This allows it to handle decryption errors like when the decryption key has been deleted.
 func SSMListSafeDecrypt(
-	ctx context.Context, ssmClient *ssm.Client, path string, recursive, full bool,
+	ctx context.Context, ssmClient SSMAPI, path string, recursive, full bool,
 ) ([]SSMParameter, error) {
 	paginator := ssm.NewGetParametersByPathPaginator(ssmClient, &ssm.GetParametersByPathInput{
 		Path:      aws.String(path),
@@ -233,10 +684,30 @@ func SSMListSafeDecrypt(
 	return params, nil
 }
 
+// SSMListTags returns the tags of a parameter.
+func SSMListTags(ctx context.Context, ssmClient SSMAPI, name string) (map[string]string, error) {
+	output, err := ssmClient.ListTagsForResource(ctx, &ssm.ListTagsForResourceInput{
+		ResourceId:   aws.String(name),
+		ResourceType: types.ResourceTypeForTaggingParameter,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", NewParameterListTagsError(name), err)
+	}
+
+	tags := make(map[string]string, len(output.TagList))
+	for _, tag := range output.TagList {
+		tags[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+	}
+
+	return tags, nil
+}
+
 // SSMPut creates or updates a parameter in the SSM Parameter store.
 // The name and value comes from a populated SSMParameter struct that is passed to it.
 // If the Type is `SecureString` then it is expected that there is a encryption key ID being passed as well.
-func SSMPut(ctx context.Context, ssmClient *ssm.Client, param *SSMParameter) (int64, error) {
+// The DataType, Description, and Tier are only set if they are not empty.
+// The Policies replace the existing policies of the parameter if they are not nil, while a nil value keeps them.
+func SSMPut(ctx context.Context, ssmClient SSMAPI, param *SSMParameter) (int64, error) {
 	input := &ssm.PutParameterInput{
 		Name:      aws.String(param.Name),
 		Overwrite: aws.Bool(true),
@@ -246,9 +717,50 @@ func SSMPut(ctx context.Context, ssmClient *ssm.Client, param *SSMParameter) (in
 	if param.Type == parameterTypeSecureString {
 		input.KeyId = aws.String(param.KeyID)
 	}
+	if param.DataType != "" {
+		input.DataType = aws.String(param.DataType)
+	}
+	if param.Description != "" {
+		input.Description = aws.String(param.Description)
+	}
+	if param.Tier != "" {
+		input.Tier = types.ParameterTier(param.Tier)
+	}
+	// Existing policies are kept unless others are sent, so they are only sent when set. An empty list removes them.
+	if param.Policies != nil {
+		texts := make([]string, 0, len(param.Policies))
+		for _, p := range param.Policies {
+			texts = append(texts, p.Text)
+		}
+		input.Policies = aws.String("[" + strings.Join(texts, ",") + "]")
+	}
 	output, err := ssmClient.PutParameter(ctx, input)
 	if err != nil {
+		// Make it clear when the problem is the encryption key rather than the parameter.
+		var invalidKey *types.InvalidKeyId
+		if errors.As(err, &invalidKey) {
+			return -1, fmt.Errorf("%w: %w: %w", NewParameterPutError(param.Name), NewKeyNotFoundError(param.KeyID), err)
+		}
 		return -1, fmt.Errorf("%w: %w", NewParameterPutError(param.Name), err)
 	}
 	return output.Version, nil
 }
+
+// SSMRemoveTags removes the tags with the given keys from a parameter.
+// Keys that the parameter doesn't have are ignored.
+func SSMRemoveTags(ctx context.Context, ssmClient SSMAPI, name string, keys []string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	_, err := ssmClient.RemoveTagsFromResource(ctx, &ssm.RemoveTagsFromResourceInput{
+		ResourceId:   aws.String(name),
+		ResourceType: types.ResourceTypeForTaggingParameter,
+		TagKeys:      keys,
+	})
+	if err != nil {
+		return fmt.Errorf("%w: %w", NewParameterUntagError(name), err)
+	}
+
+	return nil
+}
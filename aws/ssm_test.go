@@ -0,0 +1,412 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/aws/aws-sdk-go-v2/service/ssm/types"
+)
+
+var errFakeDecrypt = errors.New("fake decryption failure")
+
+// fakeSSM is an in-memory SSMAPI that records the calls made to it.
+// Parameters listed in undecryptable fail whenever they are fetched with decryption, and cause the whole batch to
+// fail when fetched via GetParameters, the same as the real API does.
+// Embedding the interface means that calling any of the methods that aren't implemented here panics.
+type fakeSSM struct {
+	SSMAPI
+
+	deleteBatches  [][]string
+	getBatches     [][]string
+	getDecrypt     []bool
+	params         map[string]string
+	putErr         error
+	putInputs      []*ssm.PutParameterInput
+	singleDecrypts []bool
+	singleGets     []string
+	undecryptable  map[string]bool
+}
+
+func newFakeSSM(names ...string) *fakeSSM {
+	f := &fakeSSM{params: map[string]string{}, undecryptable: map[string]bool{}}
+	for _, name := range names {
+		f.params[name] = "value of " + name
+	}
+	return f
+}
+
+func (f *fakeSSM) DeleteParameters(
+	_ context.Context, params *ssm.DeleteParametersInput, _ ...func(*ssm.Options),
+) (*ssm.DeleteParametersOutput, error) {
+	f.deleteBatches = append(f.deleteBatches, params.Names)
+	output := &ssm.DeleteParametersOutput{}
+	for _, name := range params.Names {
+		if _, ok := f.params[name]; !ok {
+			output.InvalidParameters = append(output.InvalidParameters, name)
+			continue
+		}
+		delete(f.params, name)
+		output.DeletedParameters = append(output.DeletedParameters, name)
+	}
+	return output, nil
+}
+
+func (f *fakeSSM) GetParameter(
+	_ context.Context, params *ssm.GetParameterInput, _ ...func(*ssm.Options),
+) (*ssm.GetParameterOutput, error) {
+	name := aws.ToString(params.Name)
+	decrypt := aws.ToBool(params.WithDecryption)
+	f.singleGets = append(f.singleGets, name)
+	f.singleDecrypts = append(f.singleDecrypts, decrypt)
+	param, err := f.parameter(name, decrypt)
+	if err != nil {
+		return nil, err
+	}
+	return &ssm.GetParameterOutput{Parameter: param}, nil
+}
+
+func (f *fakeSSM) GetParameters(
+	_ context.Context, params *ssm.GetParametersInput, _ ...func(*ssm.Options),
+) (*ssm.GetParametersOutput, error) {
+	decrypt := aws.ToBool(params.WithDecryption)
+	f.getBatches = append(f.getBatches, params.Names)
+	f.getDecrypt = append(f.getDecrypt, decrypt)
+	output := &ssm.GetParametersOutput{}
+	for _, name := range params.Names {
+		param, err := f.parameter(name, decrypt)
+		var notFound *types.ParameterNotFound
+		switch {
+		case errors.As(err, &notFound):
+			output.InvalidParameters = append(output.InvalidParameters, name)
+		case err != nil:
+			return nil, err
+		default:
+			// Return the parameters in reverse order, since the API doesn't promise to keep the order of the names.
+			output.Parameters = slices.Insert(output.Parameters, 0, *param)
+		}
+	}
+	return output, nil
+}
+
+func (f *fakeSSM) PutParameter(
+	_ context.Context, params *ssm.PutParameterInput, _ ...func(*ssm.Options),
+) (*ssm.PutParameterOutput, error) {
+	f.putInputs = append(f.putInputs, params)
+	if f.putErr != nil {
+		return nil, f.putErr
+	}
+	return &ssm.PutParameterOutput{Version: 1}, nil
+}
+
+// parameter returns the named parameter the way the API would, or the error that the API would return.
+func (f *fakeSSM) parameter(name string, decrypt bool) (*types.Parameter, error) {
+	value, ok := f.params[name]
+	if !ok {
+		return nil, &types.ParameterNotFound{Message: aws.String(name)}
+	}
+	paramType := types.ParameterTypeString
+	if f.undecryptable[name] {
+		if decrypt {
+			return nil, fmt.Errorf("%w: %s", errFakeDecrypt, name)
+		}
+		paramType = types.ParameterTypeSecureString
+		value = "encrypted"
+	}
+	return &types.Parameter{Name: aws.String(name), Type: paramType, Value: aws.String(value)}, nil
+}
+
+// paramNames returns the names of n parameters below /test.
+func paramNames(n int) []string {
+	names := make([]string, 0, n)
+	for i := range n {
+		names = append(names, fmt.Sprintf("/test/param%02d", i))
+	}
+	return names
+}
+
+func TestSSMDeleteParameters(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name            string
+		existing        int
+		requested       int
+		expectedBatches []int
+		expectedInvalid int
+	}{
+		{name: "none", existing: 0, requested: 0, expectedBatches: nil, expectedInvalid: 0},
+		{name: "one batch", existing: 10, requested: 10, expectedBatches: []int{10}, expectedInvalid: 0},
+		{name: "partial batch", existing: 11, requested: 11, expectedBatches: []int{10, 1}, expectedInvalid: 0},
+		{name: "several batches", existing: 25, requested: 25, expectedBatches: []int{10, 10, 5}, expectedInvalid: 0},
+		{name: "missing", existing: 5, requested: 12, expectedBatches: []int{10, 2}, expectedInvalid: 7},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			client := newFakeSSM(paramNames(tt.existing)...)
+			invalid, err := SSMDeleteParameters(context.Background(), client, paramNames(tt.requested))
+			if err != nil {
+				t.Fatalf("SSMDeleteParameters() returned an unexpected error: %v", err)
+			}
+
+			var batches []int
+			for _, batch := range client.deleteBatches {
+				batches = append(batches, len(batch))
+			}
+			if !slices.Equal(batches, tt.expectedBatches) {
+				t.Errorf("SSMDeleteParameters() failed, expected batches %v, got %v", tt.expectedBatches, batches)
+			}
+			if len(invalid) != tt.expectedInvalid {
+				t.Errorf("SSMDeleteParameters() failed, expected %d invalid, got %v", tt.expectedInvalid, invalid)
+			}
+			if len(client.params) != 0 {
+				t.Errorf("SSMDeleteParameters() failed, expected no parameters left, got %d", len(client.params))
+			}
+		})
+	}
+}
+
+func TestSSMGetParameters(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name            string
+		existing        []string
+		requested       []string
+		decrypt         bool
+		expectedBatches []int
+		expectedInvalid []string
+	}{
+		{
+			name:            "several batches",
+			existing:        paramNames(25),
+			requested:       paramNames(25),
+			decrypt:         true,
+			expectedBatches: []int{10, 10, 5},
+		},
+		{
+			name:            "order of names",
+			existing:        paramNames(3),
+			requested:       []string{"/test/param02", "/test/param00", "/test/param01"},
+			decrypt:         true,
+			expectedBatches: []int{3},
+		},
+		{
+			name:            "not found",
+			existing:        paramNames(3),
+			requested:       []string{"/test/param00", "/test/missing", "/test/param02"},
+			decrypt:         true,
+			expectedBatches: []int{3},
+			expectedInvalid: []string{"/test/missing"},
+		},
+		{
+			name:            "without decryption",
+			existing:        paramNames(12),
+			requested:       paramNames(12),
+			decrypt:         false,
+			expectedBatches: []int{10, 2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			client := newFakeSSM(tt.existing...)
+			params, invalid, err := SSMGetParameters(context.Background(), client, tt.requested, tt.decrypt)
+			if err != nil {
+				t.Fatalf("SSMGetParameters() returned an unexpected error: %v", err)
+			}
+
+			var batches []int
+			for _, batch := range client.getBatches {
+				batches = append(batches, len(batch))
+			}
+			if !slices.Equal(batches, tt.expectedBatches) {
+				t.Errorf("SSMGetParameters() failed, expected batches %v, got %v", tt.expectedBatches, batches)
+			}
+			for _, decrypt := range client.getDecrypt {
+				if decrypt != tt.decrypt {
+					t.Errorf("SSMGetParameters() failed, expected WithDecryption %v, got %v", tt.decrypt, decrypt)
+				}
+			}
+			if len(client.singleGets) != 0 {
+				t.Errorf("SSMGetParameters() failed, expected no GetParameter calls, got %v", client.singleGets)
+			}
+
+			var expected, got []string
+			for _, name := range tt.requested {
+				if !slices.Contains(tt.expectedInvalid, name) {
+					expected = append(expected, name)
+				}
+			}
+			for _, p := range params {
+				got = append(got, p.Name)
+				if p.Value != "value of "+p.Name {
+					t.Errorf("SSMGetParameters() failed, expected value of %s, got %s", p.Name, p.Value)
+				}
+			}
+			if !slices.Equal(got, expected) {
+				t.Errorf("SSMGetParameters() failed, expected %v, got %v", expected, got)
+			}
+			if !slices.Equal(invalid, tt.expectedInvalid) {
+				t.Errorf("SSMGetParameters() failed, expected invalid %v, got %v", tt.expectedInvalid, invalid)
+			}
+		})
+	}
+}
+
+func TestSSMGetParametersDecryptFallback(t *testing.T) {
+	t.Parallel()
+
+	names := paramNames(12)
+	client := newFakeSSM(names...)
+	client.undecryptable["/test/param11"] = true
+	requested := append(slices.Clone(names), "/test/missing")
+
+	params, invalid, err := SSMGetParameters(context.Background(), client, requested, true)
+	if err != nil {
+		t.Fatalf("SSMGetParameters() returned an unexpected error: %v", err)
+	}
+
+	// The first batch succeeds, while the second fails and is fetched one parameter at a time, retrying without
+	// decryption when a parameter can't be fetched with it.
+	expectedSingle := []string{"/test/param10", "/test/param11", "/test/param11", "/test/missing", "/test/missing"}
+	if !slices.Equal(client.singleGets, expectedSingle) {
+		t.Errorf("SSMGetParameters() failed, expected GetParameter calls %v, got %v", expectedSingle, client.singleGets)
+	}
+	expectedDecrypts := []bool{true, true, false, true, false}
+	if !slices.Equal(client.singleDecrypts, expectedDecrypts) {
+		t.Errorf("SSMGetParameters() failed, expected decrypts %v, got %v", expectedDecrypts, client.singleDecrypts)
+	}
+	if !slices.Equal(invalid, []string{"/test/missing"}) {
+		t.Errorf("SSMGetParameters() failed, expected invalid [/test/missing], got %v", invalid)
+	}
+	if len(params) != len(names) {
+		t.Fatalf("SSMGetParameters() failed, expected %d parameters, got %d", len(names), len(params))
+	}
+
+	for i, p := range params {
+		if p.Name != names[i] {
+			t.Errorf("SSMGetParameters() failed, expected %s, got %s", names[i], p.Name)
+		}
+		if p.Name == "/test/param11" {
+			if p.Value != "" {
+				t.Errorf("SSMGetParameters() failed, expected no value for %s, got %s", p.Name, p.Value)
+			}
+			if !strings.Contains(p.Error, errFakeDecrypt.Error()) {
+				t.Errorf("SSMGetParameters() failed, expected error %q for %s, got %q", errFakeDecrypt, p.Name, p.Error)
+			}
+			if p.Type != string(types.ParameterTypeSecureString) {
+				t.Errorf("SSMGetParameters() failed, expected type SecureString for %s, got %s", p.Name, p.Type)
+			}
+			continue
+		}
+		if p.Error != "" {
+			t.Errorf("SSMGetParameters() failed, expected no error for %s, got %s", p.Name, p.Error)
+		}
+	}
+}
+
+func TestSSMPutPolicies(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		policies []SSMPolicy
+		expected *string
+	}{
+		{name: "nil keeps existing", policies: nil, expected: nil},
+		{name: "empty removes existing", policies: []SSMPolicy{}, expected: aws.String("[]")},
+		{
+			name: "replaces existing",
+			policies: []SSMPolicy{
+				{Text: `{"Type":"Expiration"}`},
+				{Text: `{"Type":"NoChangeNotification"}`},
+			},
+			expected: aws.String(`[{"Type":"Expiration"},{"Type":"NoChangeNotification"}]`),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			client := newFakeSSM()
+			param := &SSMParameter{Name: "/test/param", Policies: tt.policies, Type: "String", Value: "value"}
+			if _, err := SSMPut(context.Background(), client, param); err != nil {
+				t.Fatalf("SSMPut() returned an unexpected error: %v", err)
+			}
+			if len(client.putInputs) != 1 {
+				t.Fatalf("SSMPut() failed, expected 1 PutParameter call, got %d", len(client.putInputs))
+			}
+
+			got := client.putInputs[0].Policies
+			switch {
+			case tt.expected == nil && got != nil:
+				t.Errorf("SSMPut() failed, expected no policies, got %s", *got)
+			case tt.expected != nil && got == nil:
+				t.Errorf("SSMPut() failed, expected policies %s, got none", *tt.expected)
+			case tt.expected != nil && *got != *tt.expected:
+				t.Errorf("SSMPut() failed, expected policies %s, got %s", *tt.expected, *got)
+			}
+		})
+	}
+}
+
+func TestSSMPutInvalidKeyID(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name         string
+		putErr       error
+		expectKeyErr bool
+	}{
+		{name: "invalid key", putErr: &types.InvalidKeyId{Message: aws.String("bad key")}, expectKeyErr: true},
+		{name: "other error", putErr: &types.InternalServerError{Message: aws.String("oops")}, expectKeyErr: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			client := newFakeSSM()
+			client.putErr = tt.putErr
+			param := &SSMParameter{
+				KeyID: "alias/missing",
+				Name:  "/test/param",
+				Type:  parameterTypeSecureString,
+				Value: "secret",
+			}
+			version, err := SSMPut(context.Background(), client, param)
+			if err == nil {
+				t.Fatal("SSMPut() failed, expected an error, got nil")
+			}
+			if version != -1 {
+				t.Errorf("SSMPut() failed, expected version -1, got %d", version)
+			}
+			if !errors.Is(err, tt.putErr) {
+				t.Errorf("SSMPut() failed, expected the error to wrap %v, got %v", tt.putErr, err)
+			}
+			if keyID := aws.ToString(client.putInputs[0].KeyId); keyID != param.KeyID {
+				t.Errorf("SSMPut() failed, expected key ID %s, got %s", param.KeyID, keyID)
+			}
+
+			keyErr := NewKeyNotFoundError(param.KeyID).Error()
+			if strings.Contains(err.Error(), keyErr) != tt.expectKeyErr {
+				t.Errorf("SSMPut() failed, expected %q in the error to be %v, got %v", keyErr, tt.expectKeyErr, err)
+			}
+			if !strings.HasPrefix(err.Error(), NewParameterPutError(param.Name).Error()) {
+				t.Errorf("SSMPut() failed, expected the error to start with the parameter, got %v", err)
+			}
+		})
+	}
+}
@@ -7,26 +7,46 @@ package aws
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"os"
 	"os/user"
 	"path"
-	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/credentials/ssocreds"
 	"github.com/aws/aws-sdk-go-v2/service/ssooidc"
+	"github.com/aws/aws-sdk-go-v2/service/ssooidc/types"
 	"github.com/aws/aws-sdk-go-v2/service/sts"
 	"github.com/pkg/browser"
 )
 
+const (
+	// grantTypeDeviceCode is the OAuth grant type for exchanging the device code of an AWS SSO login for a token.
+	grantTypeDeviceCode = "urn:ietf:params:oauth:grant-type:device_code"
+	// grantTypeRefreshToken is the OAuth grant type for exchanging a refresh token for a new token.
+	grantTypeRefreshToken = "refresh_token"
+	// defaultTokenPollInterval is how often to check whether an AWS SSO login has been completed, unless the device
+	// authorisation says otherwise.
+	defaultTokenPollInterval = 2 * time.Second
+	// slowDownTokenPollInterval is added to the polling interval each time the service asks for it to slow down.
+	slowDownTokenPollInterval = 5 * time.Second
+	// keepAliveInterval is how often KeepSessionAlive checks whether the AWS SSO token needs refreshing.
+	keepAliveInterval = time.Minute
+	// keepAliveRefreshWindow is how long before the AWS SSO token expires that KeepSessionAlive refreshes it.
+	keepAliveRefreshWindow = 10 * time.Minute
+)
+
 // LoginSessionDetails is for passing AWS Profile and Region options to the Login function.
+// NoBrowser stops Login from opening a web browser for the AWS SSO login, for hosts without a display such as build
+// agents. The URL and code to complete the login with on another device are shown instead.
 type LoginSessionDetails struct {
-	Profile string
-	Region  string
+	NoBrowser bool
+	Profile   string
+	Region    string
 }
 
 type ssoCacheData struct {
@@ -50,9 +70,57 @@ func withSharedConfigProfileAndRegion(profile, region string) config.LoadOptions
 	}
 }
 
-// Login gets a session to AWS, optionally specifying an AWS Profile & Region to use via the LoginSessionDetails option.
-// If the session in the on-disk cache files are invalid, then perform the AWS SSO workflow to have the user login.
-func Login(ctx context.Context, details *LoginSessionDetails) aws.Config {
+// CallerIdentity returns the ARN of the identity that the credentials in the AWS configuration belong to.
+// Like LoadConfig, it never prompts the user to login, so it fails if the session in the on-disk cache files is
+// invalid.
+func CallerIdentity(ctx context.Context, cfg aws.Config) (string, error) {
+	output, err := sts.NewFromConfig(cfg).GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", errCallerIdentity, err)
+	}
+	return aws.ToString(output.Arn), nil
+}
+
+// KeepSessionAlive starts a goroutine that refreshes the AWS SSO token used by the configuration before it expires, so
+// that long running tools keep working past the lifetime of a single token. It stops when the context is done.
+// The token is refreshed using the refresh token that Login obtains. It never asks the user to login again, since a
+// background goroutine can't safely take over the terminal or open a browser, so if the session has no refresh token
+// it logs why and stops. Other failures are logged and tried again at the next check.
+// It does nothing for profiles that don't use AWS SSO, since their credentials aren't kept in the SSO token cache.
+func KeepSessionAlive(ctx context.Context, cfg aws.Config) {
+	// Recurse from assumed roles to the parent role until we find the configuration containing the SSO login details.
+	sharedConfig, err := findSSOSharedConfig(ctx, getSharedConfig(&cfg))
+	if err != nil {
+		if !errors.Is(err, errNotSSOProfile) {
+			log.Printf("not keeping the AWS SSO session alive: %v", err)
+		}
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(keepAliveInterval)
+		defer ticker.Stop()
+		for {
+			if err := refreshSSOSession(ctx, cfg, sharedConfig); err != nil {
+				log.Printf("failed to refresh the AWS SSO session: %v", err)
+				if errors.Is(err, errNoRefreshToken) {
+					return
+				}
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// LoadConfig loads the AWS configuration, optionally specifying an AWS Profile & Region to use via the
+// LoginSessionDetails option.
+// Unlike Login, it never prompts the user to login, so any AWS API calls made with the configuration will fail if the
+// session in the on-disk cache files is invalid. This suits non-interactive uses like shell completion.
+func LoadConfig(ctx context.Context, details *LoginSessionDetails) (aws.Config, error) {
 	var cfg aws.Config
 	var err error
 
@@ -69,7 +137,18 @@ func Login(ctx context.Context, details *LoginSessionDetails) aws.Config {
 		cfg, err = config.LoadDefaultConfig(ctx)
 	}
 	if err != nil {
-		log.Panicf("failed to load AWS config: %v", err)
+		return aws.Config{}, fmt.Errorf("%w: %w", errLoadConfig, err)
+	}
+
+	return cfg, nil
+}
+
+// Login gets a session to AWS, optionally specifying an AWS Profile & Region to use via the LoginSessionDetails option.
+// If the session in the on-disk cache files are invalid, then perform the AWS SSO workflow to have the user login.
+func Login(ctx context.Context, details *LoginSessionDetails) aws.Config {
+	cfg, err := LoadConfig(ctx, details)
+	if err != nil {
+		log.Panic(err)
 	}
 
 	// Check if the AWS SSO session is valid.
@@ -79,7 +158,7 @@ func Login(ctx context.Context, details *LoginSessionDetails) aws.Config {
 	}
 
 	// Session is not valid, so need to perform an AWS SSO login.
-	if err := ssoLogin(ctx, cfg); err != nil {
+	if err := ssoLogin(ctx, cfg, details.NoBrowser); err != nil {
 		log.Panicf("failed to perform AWS SSO login: %v", err)
 	}
 
@@ -100,30 +179,77 @@ func Login(ctx context.Context, details *LoginSessionDetails) aws.Config {
 	return cfg
 }
 
+// refreshSSOSession refreshes the cached AWS SSO token if it expires within the refresh window.
+// It returns errNoRefreshToken if the token can't be refreshed without the user logging in again.
+func refreshSSOSession(ctx context.Context, cfg aws.Config, sharedConfig config.SharedConfig) error {
+	cacheFilePath, err := getCacheFilePath(sharedConfig.SSOSessionName, sharedConfig.SSOSession.SSOStartURL)
+	if err != nil {
+		return err
+	}
+
+	cacheData, err := readCacheFile(cacheFilePath)
+	if err != nil {
+		return err
+	}
+	if time.Until(cacheData.ExpiresAt) > keepAliveRefreshWindow {
+		return nil
+	}
+	// Without a refresh token the only way to get a new token is to have the user login again.
+	if cacheData.RefreshToken == "" {
+		return errNoRefreshToken
+	}
+
+	token, err := newSSOOIDCClient(&cfg, cacheData.Region).CreateToken(ctx, &ssooidc.CreateTokenInput{
+		ClientId:     aws.String(cacheData.ClientID),
+		ClientSecret: aws.String(cacheData.ClientSecret),
+		GrantType:    aws.String(grantTypeRefreshToken),
+		RefreshToken: aws.String(cacheData.RefreshToken),
+	})
+	if err != nil {
+		return fmt.Errorf("%w: %w", errGetToken, err)
+	}
+
+	cacheData.AccessToken = aws.ToString(token.AccessToken)
+	cacheData.ExpiresAt = time.Unix(time.Now().Unix()+int64(token.ExpiresIn), 0).UTC()
+	if token.RefreshToken != nil {
+		cacheData.RefreshToken = *token.RefreshToken
+	}
+
+	if err := writeCacheFile(cacheFilePath, &cacheData); err != nil {
+		return fmt.Errorf("%w: %w", errWriteCacheFile, err)
+	}
+
+	return nil
+}
+
 // ssoLogin performs the workflow required for an AWS SSO login.
-// It will open a web browser for the AWS SSO with the appropriate client code.
+// It will open a web browser for the AWS SSO with the appropriate client code, unless noBrowser is set, in which case
+// the URL and code are shown for the user to open themselves, and it waits until the code expires.
 // Once the user has performed the AWS SSO login, the details of the session are written to the same on-disk cache
 // that the AWS CLI would write to. The AWS SDK uses this file automatically.
-func ssoLogin(ctx context.Context, cfg aws.Config) error {
+func ssoLogin(ctx context.Context, cfg aws.Config, noBrowser bool) error {
 	// Recurse from assumed roles to the parent role until we find the configuration containing the SSO login details.
 	sharedConfig := checkSharedConfig(ctx, getSharedConfig(&cfg))
 
-	// Possibly these could be of use later?
+	// Possibly this could be of use later?
 	// ssoAccountId = sharedConfig.SSOAccountID
-	// ssoRegion = sharedConfig.SSOSession.SSORegion
 
 	ssoStartURL := sharedConfig.SSOSession.SSOStartURL
-	ssooidcClient := ssooidc.NewFromConfig(cfg)
+	ssoRegion := getSSORegion(&cfg, sharedConfig)
+	ssooidcClient := newSSOOIDCClient(&cfg, ssoRegion)
 
 	clientName, err := ssoGetClientName(sharedConfig)
 	if err != nil {
 		return fmt.Errorf("%w: %w", errGetClientName, err)
 	}
 
+	// Registering for the refresh_token grant with the sso:account:access scope gets a refresh token issued along with
+	// the access token, which is what lets KeepSessionAlive refresh the session without the user logging in again.
 	registerClient, err := ssooidcClient.RegisterClient(ctx, &ssooidc.RegisterClientInput{
 		ClientName: aws.String(clientName),
 		ClientType: aws.String("public"),
-		Scopes:     []string{"sso-portal:*"},
+		GrantTypes: []string{grantTypeDeviceCode, grantTypeRefreshToken},
+		Scopes:     []string{"sso:account:access"},
 	})
 	if err != nil {
 		return fmt.Errorf("%w: %w", errRegisterClient, err)
@@ -139,13 +265,24 @@ func ssoLogin(ctx context.Context, cfg aws.Config) error {
 	}
 
 	authURL := aws.ToString(deviceAuth.VerificationUriComplete)
-	fmt.Fprintf(os.Stderr, "If your browser doesn't open, then open the following URL:\n%s\n\n", authURL)
-	if err := browser.OpenURL(authURL); err != nil {
-		return fmt.Errorf("%w: %w", errOpenBrowser, err)
+	timeout := time.Minute
+	if noBrowser {
+		fmt.Fprintf(
+			os.Stderr,
+			"To login, open the following URL on any device:\n%s\n\nOr open %s and enter the code: %s\n\n",
+			authURL, aws.ToString(deviceAuth.VerificationUri), aws.ToString(deviceAuth.UserCode),
+		)
+		// There is no browser already open at the login page, so allow as long as the code is valid for.
+		timeout = time.Duration(deviceAuth.ExpiresIn) * time.Second
+	} else {
+		fmt.Fprintf(os.Stderr, "If your browser doesn't open, then open the following URL:\n%s\n\n", authURL)
+		if err := browser.OpenURL(authURL); err != nil {
+			return fmt.Errorf("%w: %w", errOpenBrowser, err)
+		}
 	}
 
-	// Check every 2 seconds up to 1 minute for the browser login to be completed.
-	token, err := ssoTokenWait(ctx, ssooidcClient, registerClient, deviceAuth)
+	// Poll until the timeout for the login to be completed.
+	token, err := ssoTokenWait(ctx, ssooidcClient, registerClient, deviceAuth, timeout)
 	if err != nil {
 		return fmt.Errorf("%w: %w", errGetToken, err)
 	}
@@ -157,7 +294,7 @@ func ssoLogin(ctx context.Context, cfg aws.Config) error {
 
 	cacheData := ssoCacheData{
 		StartURL:              ssoStartURL,
-		Region:                sharedConfig.Region,
+		Region:                ssoRegion,
 		AccessToken:           *token.AccessToken,
 		ExpiresAt:             time.Unix(time.Now().Unix()+int64(token.ExpiresIn), 0).UTC(),
 		ClientID:              *registerClient.ClientId,
@@ -191,41 +328,77 @@ func ssoGetClientName(sharedConfig config.SharedConfig) (string, error) {
 	return fmt.Sprintf("%s-%s-%s", osUser, sharedConfig.Profile, sharedConfig.SSORoleName), nil
 }
 
+// ssoTokenWait polls for the token of an AWS SSO login until the user has completed the login or the timeout passes.
+// It waits between attempts for the interval that the device authorisation asks for, which grows each time the
+// service asks for polling to slow down. Errors that trying again can't fix, such as the login being denied or the
+// device code expiring, end the wait straight away.
 func ssoTokenWait(
 	ctx context.Context,
 	ssooidcClient *ssooidc.Client,
 	registerClient *ssooidc.RegisterClientOutput,
 	deviceAuth *ssooidc.StartDeviceAuthorizationOutput,
+	timeout time.Duration,
 ) (*ssooidc.CreateTokenOutput, error) {
-	var createTokenErr error
-	timeout := time.Minute
-	sleepTime := 2 * time.Second
-	startTime := time.Now()
+	interval := defaultTokenPollInterval
+	if deviceAuth.Interval > 0 {
+		interval = time.Duration(deviceAuth.Interval) * time.Second
+	}
+	deadline := time.Now().Add(timeout)
 
-	token := new(ssooidc.CreateTokenOutput)
-	for time.Since(startTime) < timeout {
-		token, createTokenErr = ssooidcClient.CreateToken(
+	for {
+		token, err := ssooidcClient.CreateToken(
 			ctx, &ssooidc.CreateTokenInput{
 				ClientId:     registerClient.ClientId,
 				ClientSecret: registerClient.ClientSecret,
 				DeviceCode:   deviceAuth.DeviceCode,
-				GrantType:    aws.String("urn:ietf:params:oauth:grant-type:device_code"),
-				// TODO: Work out how to use the following instead of DeviceCode and the above GrantType?
-				// Is this the key to getting refreshToken in my SSO cached credentials?
-				// GrantType:    aws.String("refresh_token"),
+				GrantType:    aws.String(grantTypeDeviceCode),
 			},
 		)
-		if createTokenErr == nil {
+		if err == nil {
 			return token, nil
 		}
-		if strings.Contains(createTokenErr.Error(), "AuthorizationPendingException") {
-			time.Sleep(sleepTime)
+		if ctx.Err() != nil || isTerminalTokenError(err) {
+			return nil, err
+		}
+
+		var slowDown *types.SlowDownException
+		if errors.As(err, &slowDown) {
+			interval += slowDownTokenPollInterval
+		}
+
+		if time.Now().Add(interval).After(deadline) {
+			return nil, errSSOTimeout
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(interval):
 		}
 	}
-	if createTokenErr != nil {
-		return nil, errSSOTimeout
-	}
-	return token, nil
+}
+
+// isTerminalTokenError returns true if the error from creating a token means that the login can't succeed by trying
+// again, as opposed to the login still being pending or a temporary failure.
+func isTerminalTokenError(err error) bool {
+	var (
+		accessDenied         *types.AccessDeniedException
+		expiredToken         *types.ExpiredTokenException
+		invalidClient        *types.InvalidClientException
+		invalidGrant         *types.InvalidGrantException
+		invalidRequest       *types.InvalidRequestException
+		invalidScope         *types.InvalidScopeException
+		unauthorizedClient   *types.UnauthorizedClientException
+		unsupportedGrantType *types.UnsupportedGrantTypeException
+	)
+
+	return errors.As(err, &accessDenied) ||
+		errors.As(err, &expiredToken) ||
+		errors.As(err, &invalidClient) ||
+		errors.As(err, &invalidGrant) ||
+		errors.As(err, &invalidRequest) ||
+		errors.As(err, &invalidScope) ||
+		errors.As(err, &unauthorizedClient) ||
+		errors.As(err, &unsupportedGrantType)
 }
 
 // checkSharedConfig checks for a valid shared config from the user's AWS Profile to see if it has valid SSO session
@@ -233,21 +406,32 @@ func ssoTokenWait(
 // check that, and so on. Eventually you'll hit a valid profile, or you'll get to the top-level where there is no
 // valid SSO session details at which point it has to give up.
 func checkSharedConfig(ctx context.Context, sharedConfig config.SharedConfig) config.SharedConfig {
+	sharedConfig, err := findSSOSharedConfig(ctx, sharedConfig)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	return sharedConfig
+}
+
+// findSSOSharedConfig does the work of checkSharedConfig, returning an error instead of panicking when no profile with
+// SSO session details is found. The error is errNotSSOProfile if the profile doesn't use AWS SSO.
+func findSSOSharedConfig(ctx context.Context, sharedConfig config.SharedConfig) (config.SharedConfig, error) {
 	if sharedConfig.SSOSession != nil {
-		return sharedConfig
+		return sharedConfig, nil
 	}
 
 	if sharedConfig.SourceProfileName == "" {
-		log.Panic("Current AWS Profile does not support AWS SSO")
+		return config.SharedConfig{}, errNotSSOProfile
 	}
 
 	// Check the source profile.
 	cfg, err := config.LoadDefaultConfig(ctx, config.WithSharedConfigProfile(sharedConfig.SourceProfileName))
 	if err != nil {
-		log.Panicf("failed to load source profile %s: %v", sharedConfig.SourceProfileName, err)
+		return config.SharedConfig{}, fmt.Errorf("%w: %w", NewLoadSourceProfileError(sharedConfig.SourceProfileName), err)
 	}
 
-	return checkSharedConfig(ctx, getSharedConfig(&cfg))
+	return findSSOSharedConfig(ctx, getSharedConfig(&cfg))
 }
 
 // getSharedConfig extracts the shared config from the slice of interfaces contained in the aws.Config struct.
@@ -262,6 +446,22 @@ func getSharedConfig(cfg *aws.Config) config.SharedConfig {
 	return config.SharedConfig{}
 }
 
+// getSSORegion returns the region that the AWS SSO instance is hosted in, which isn't necessarily the region being
+// used for everything else. It falls back to the configuration's region if the SSO session doesn't set one.
+func getSSORegion(cfg *aws.Config, sharedConfig config.SharedConfig) string {
+	if sharedConfig.SSOSession.SSORegion != "" {
+		return sharedConfig.SSOSession.SSORegion
+	}
+	return cfg.Region
+}
+
+// newSSOOIDCClient returns an SSO OIDC client for the region that the AWS SSO instance is hosted in.
+func newSSOOIDCClient(cfg *aws.Config, ssoRegion string) *ssooidc.Client {
+	return ssooidc.NewFromConfig(*cfg, func(o *ssooidc.Options) {
+		o.Region = ssoRegion
+	})
+}
+
 // getCacheFilePath returns the on-disk path of the cache file containing the AWS SSO session credentials.
 func getCacheFilePath(ssoSessionName, ssoStartURL string) (string, error) {
 	var cacheFilePath string
@@ -280,6 +480,21 @@ func getCacheFilePath(ssoSessionName, ssoStartURL string) (string, error) {
 	return cacheFilePath, nil
 }
 
+// readCacheFile reads the details of the AWS SSO session from its cache file.
+func readCacheFile(cacheFilePath string) (ssoCacheData, error) {
+	var cacheData ssoCacheData
+
+	data, err := os.ReadFile(cacheFilePath)
+	if err != nil {
+		return cacheData, fmt.Errorf("%w: %w", errReadCacheFile, err)
+	}
+	if err := json.Unmarshal(data, &cacheData); err != nil {
+		return cacheData, fmt.Errorf("%w: %w", errReadCacheFile, err)
+	}
+
+	return cacheData, nil
+}
+
 // writeCacheFile writes the contents of the valid credentials received after an AWS SSO login to a file.
 // It is expected that the correct cache file path is passed in as retrieved via the getCacheFilePath() function.
 func writeCacheFile(cacheFilePath string, cacheFileData *ssoCacheData) error {
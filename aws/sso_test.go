@@ -0,0 +1,40 @@
+package aws
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/ssooidc/types"
+)
+
+var errFakeNetwork = errors.New("fake network failure")
+
+func TestIsTerminalTokenError(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		err      error
+		expected bool
+	}{
+		{name: "authorization pending", err: &types.AuthorizationPendingException{}, expected: false},
+		{name: "slow down", err: &types.SlowDownException{}, expected: false},
+		{name: "internal server", err: &types.InternalServerException{}, expected: false},
+		{name: "other", err: errFakeNetwork, expected: false},
+		{name: "access denied", err: &types.AccessDeniedException{}, expected: true},
+		{name: "expired token", err: &types.ExpiredTokenException{}, expected: true},
+		{name: "invalid grant", err: &types.InvalidGrantException{}, expected: true},
+		{name: "wrapped", err: fmt.Errorf("operation error: %w", &types.ExpiredTokenException{}), expected: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := isTerminalTokenError(tt.err); got != tt.expected {
+				t.Errorf("isTerminalTokenError() failed, expected %t, got %t", tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+/*
+Package aws implements functions to interact with Amazon Web Services.
+This part handles assuming IAM roles via STS.
+*/
+package aws
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+)
+
+// AssumeRoleOptions holds the optional settings for AssumeRole.
+// A zero Duration uses the AWS SDK default of 15 minutes, and an empty ExternalID isn't sent.
+type AssumeRoleOptions struct {
+	Duration   time.Duration
+	ExternalID string
+}
+
+// AssumeRole returns a copy of the AWS configuration that uses the credentials of the role, such as one in a member
+// account, so that tools can switch to it after an AWS SSO login rather than needing an AWS profile per role.
+// The credentials of the role are refreshed automatically, using the credentials of the original configuration.
+// The role is assumed straight away so that problems such as missing permissions are returned here.
+func AssumeRole(
+	ctx context.Context, cfg aws.Config, roleARN, sessionName string, opts *AssumeRoleOptions,
+) (aws.Config, error) {
+	provider := stscreds.NewAssumeRoleProvider(sts.NewFromConfig(cfg), roleARN, func(o *stscreds.AssumeRoleOptions) {
+		o.RoleSessionName = sessionName
+		if opts == nil {
+			return
+		}
+		if opts.Duration > 0 {
+			o.Duration = opts.Duration
+		}
+		if opts.ExternalID != "" {
+			o.ExternalID = aws.String(opts.ExternalID)
+		}
+	})
+
+	roleCfg := cfg.Copy()
+	roleCfg.Credentials = aws.NewCredentialsCache(provider)
+	if _, err := roleCfg.Credentials.Retrieve(ctx); err != nil {
+		return aws.Config{}, fmt.Errorf("%w: %w", NewAssumeRoleError(roleARN), err)
+	}
+
+	return roleCfg, nil
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/jim-barber-he/go/util"
+	redis "github.com/redis/go-redis/v9"
+)
+
+const (
+	// durationsKeySuffix is appended to the lock key to get the key holding the durations of the recent successful runs.
+	durationsKeySuffix string = ".durations"
+	// durationsSamples is how many durations are kept to work out the adaptive release time from.
+	durationsSamples int = 100
+	// durationsMinSamples is how many durations are needed before the adaptive release time is used.
+	durationsMinSamples int = 5
+	// durationsPercentile is the percentile of the durations that the adaptive release time is based on.
+	durationsPercentile float64 = 0.99
+)
+
+// adaptiveRelease returns how many seconds the lock stored at redisKey should be held for at most.
+// When CRONLOCK_ADAPTIVE_RELEASE is set, this is the 99th percentile of the durations of the recent successful runs
+// multiplied by its value. It is never less than lockGrace or more than lockRelease, and lockRelease is returned
+// until enough runs have been recorded.
+func adaptiveRelease(ctx context.Context, rdb *redis.Client, redisKey string, lockRelease, lockGrace int) int {
+	factor := util.GetEnvFloat(envLockAdaptiveRelease, defLockAdaptiveRelease)
+	if factor <= 0 {
+		return lockRelease
+	}
+
+	values, err := rdb.LRange(ctx, redisKey+durationsKeySuffix, 0, -1).Result()
+	if err != nil {
+		slog.Warn(fmt.Sprintf("Failed to get run durations: %v", err))
+
+		return lockRelease
+	}
+
+	durations := make([]float64, 0, len(values))
+	for _, value := range values {
+		if duration, err := strconv.ParseFloat(value, 64); err == nil {
+			durations = append(durations, duration)
+		}
+	}
+
+	return releaseFromDurations(durations, factor, lockRelease, lockGrace)
+}
+
+// releaseFromDurations returns how many seconds the lock should be held for at most, given the durations in seconds
+// of the recent successful runs, as described for adaptiveRelease().
+func releaseFromDurations(durations []float64, factor float64, lockRelease, lockGrace int) int {
+	if len(durations) < durationsMinSamples {
+		slog.Debug(fmt.Sprintf("Only %d run durations recorded; using a release time of %ds", len(durations), lockRelease))
+
+		return lockRelease
+	}
+
+	durations = slices.Sorted(slices.Values(durations))
+	percentile := durations[int(math.Ceil(durationsPercentile*float64(len(durations))))-1]
+	release := min(max(int(math.Ceil(percentile*factor)), lockGrace, 1), lockRelease)
+	slog.Debug(fmt.Sprintf("p99 run duration is %.3fs; using a release time of %ds", percentile, release))
+
+	return release
+}
+
+// recordDuration records the duration of a successful run of the command for the lock stored at redisKey, keeping
+// the most recent ones for adaptiveRelease() to use.
+func recordDuration(ctx context.Context, rdb *redis.Client, redisKey string, duration time.Duration) error {
+	durationsKey := redisKey + durationsKeySuffix
+	slog.Debug(fmt.Sprintf("Recording run duration in %s", durationsKey))
+
+	_, err := rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.LPush(ctx, durationsKey, strconv.FormatFloat(duration.Seconds(), 'f', 3, 64))
+		pipe.LTrim(ctx, durationsKey, 0, int64(durationsSamples-1))
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record run duration: %w", err)
+	}
+
+	return nil
+}
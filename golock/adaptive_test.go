@@ -0,0 +1,76 @@
+package main
+
+import "testing"
+
+func TestReleaseFromDurations(t *testing.T) {
+	t.Parallel()
+
+	const (
+		lockGrace   = 40
+		lockRelease = 86400
+	)
+
+	tests := []struct {
+		name      string
+		durations []float64
+		factor    float64
+		expected  int
+	}{
+		{
+			name:      "too few samples",
+			durations: []float64{100, 100, 100, 100},
+			factor:    2,
+			expected:  lockRelease,
+		},
+		{
+			name:      "p99 of a few samples is the largest",
+			durations: []float64{300, 100, 200, 150, 120},
+			factor:    2,
+			expected:  600,
+		},
+		{
+			name:      "p99 of 100 samples ignores the largest",
+			durations: append(repeat(100, 99), 5000),
+			factor:    1.5,
+			expected:  150,
+		},
+		{
+			name:      "rounded up to a whole second",
+			durations: []float64{60.2, 60.1, 60, 60, 60},
+			factor:    1,
+			expected:  61,
+		},
+		{
+			name:      "never less than the grace period",
+			durations: []float64{1, 2, 3, 4, 5},
+			factor:    2,
+			expected:  lockGrace,
+		},
+		{
+			name:      "never more than the release time",
+			durations: []float64{80000, 80000, 80000, 80000, 80000},
+			factor:    2,
+			expected:  lockRelease,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := releaseFromDurations(tt.durations, tt.factor, lockRelease, lockGrace); got != tt.expected {
+				t.Errorf("releaseFromDurations() failed, expected %d, got %d", tt.expected, got)
+			}
+		})
+	}
+}
+
+// repeat returns a slice holding count copies of duration.
+func repeat(duration float64, count int) []float64 {
+	durations := make([]float64, count)
+	for i := range durations {
+		durations[i] = duration
+	}
+
+	return durations
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/jim-barber-he/go/util"
+)
+
+// Lock backends.
+const (
+	backendConsul string = "consul"
+	backendEtcd   string = "etcd"
+	backendRedis  string = "redis"
+)
+
+// locker is implemented by the lock backends other than Redis.
+// These backends support the basic locking features of golock, while the rest of the features need Redis.
+type locker interface {
+	// acquire tries to acquire the lock on key for at most release seconds.
+	// It returns false without an error if another process holds the lock.
+	acquire(ctx context.Context, key string, release int) (bool, error)
+	// release keeps the lock on key for the grace period of grace seconds, after which it is released.
+	release(ctx context.Context, key string, grace int) error
+	// reset removes the lock on key.
+	reset(ctx context.Context, key string) error
+	// fencingToken returns a token that increases each time a lock is acquired.
+	fencingToken() int64
+	// close closes the connection to the backend.
+	close()
+}
+
+// maxErrorBody is the most of the body of an error response from a lock backend that is included in the error.
+const maxErrorBody = 1024
+
+var (
+	// errBackendResponse is returned when a lock backend responds with an error status.
+	errBackendResponse = errors.New("unexpected response from lock backend")
+	// errRedisOnly is returned when an option that only works with the Redis backend is used with another backend.
+	errRedisOnly = errors.New("option is only supported by the redis backend")
+)
+
+// newBackendHTTPClient returns an HTTP client for the lock backends that have an HTTP API.
+// It uses CRONLOCK_REDIS_TIMEOUT for its timeout, and the CRONLOCK_TLS* options for HTTPS endpoints.
+func newBackendHTTPClient() (*http.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	if tlsEnabled := util.GetEnvBool(envLockTLS, defLockTLS); tlsEnabled {
+		tlsConfig, err := getTLSConfig()
+		if err != nil {
+			return nil, err
+		}
+		transport.TLSClientConfig = tlsConfig
+	}
+
+	return &http.Client{
+		Timeout:   getEnvDuration(envLockRedisTimeout, defLockRedisTimeout),
+		Transport: transport,
+	}, nil
+}
+
+// newLocker returns a locker connected to the named backend.
+func newLocker(ctx context.Context, backend string) (locker, error) {
+	switch backend {
+	case backendConsul:
+		return newConsulLocker(ctx)
+	case backendEtcd:
+		return newEtcdLocker(ctx)
+	default:
+		return nil, NewUnknownBackendError(backend)
+	}
+}
+
+// runWithBackend runs the command while holding a lock from one of the backends other than Redis.
+func runWithBackend(
+	ctx context.Context, backend string, opts options, command string, args []string, key string,
+	metrics *metricsRecorder, wd *watchdog,
+) int {
+	// Check for options that need Redis.
+	for name, used := range map[string]bool{
+		"--dry-run / " + envLockDryRun: opts.dryRun || os.Getenv(envLockDryRun) == "yes",
+		"--history / --last-output":    opts.history || opts.lastOutput,
+		envLockAdaptiveRelease:         util.GetEnvFloat(envLockAdaptiveRelease, defLockAdaptiveRelease) > 0,
+		envLockHistory:                 util.GetEnvInt(envLockHistory, defLockHistory) > 0,
+		envLockMinInterval:             getEnvSeconds(envLockMinInterval, defLockMinInterval) > 0,
+		envLockSlots:                   util.GetEnvInt(envLockSlots, defLockSlots) > 1,
+	} {
+		if used {
+			slog.Error(fmt.Sprintf("%s: %v", name, errRedisOnly))
+
+			return exitFailure
+		}
+	}
+
+	isReset := util.GetEnv(envLockReset, defLockReset) == "yes"
+
+	l, err := newLocker(ctx, backend)
+	if err != nil {
+		slog.Error(err.Error())
+
+		// The fallback policy only applies to actually running the command.
+		if isReset {
+			return exitFailure
+		}
+
+		return runWithFallback(command, args, key, metrics, wd)
+	}
+	defer l.close()
+
+	if isReset {
+		slog.Debug(fmt.Sprintf("Removing %s key", key))
+		if err := l.reset(ctx, key); err != nil {
+			slog.Error(err.Error())
+
+			return exitFailure
+		}
+
+		return exitSuccess
+	}
+
+	// Catch SIGINT and SIGTERM until the lock has been released, the same way as for the Redis backend.
+	signalled, stopSignals := signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM)
+	defer stopSignals()
+
+	sleepSplay(signalled)
+	if signalled.Err() != nil {
+		slog.Warn(errInterrupted.Error())
+
+		return exitFailure
+	}
+
+	lockGrace := getEnvSeconds(envLockGrace, defLockGrace)
+	lockRelease := getEnvSeconds(envLockRelease, defLockRelease)
+
+	notifySystemd("STATUS=Acquiring lock " + key)
+	acquired, err := l.acquire(ctx, key, lockRelease)
+	if err != nil {
+		slog.Error(err.Error())
+
+		return exitFailure
+	}
+	if !acquired {
+		metrics.outcome(outcomeSkipped)
+		notifySystemd(fmt.Sprintf("STATUS=Lock %s is held by another process", key))
+
+		return exitSuccess
+	}
+	metrics.outcome(outcomeAcquired)
+
+	if util.GetEnvBool(envLockFencing, defLockFencing) {
+		if err := exportFencingToken(key, l.fencingToken()); err != nil {
+			slog.Error(err.Error())
+			if err := l.release(ctx, key, lockGrace); err != nil {
+				slog.Error(err.Error())
+			}
+
+			return exitFailure
+		}
+	}
+
+	// Don't start the command if golock was signalled while acquiring the lock or getting ready to run it.
+	if signalled.Err() != nil {
+		slog.Warn(errInterrupted.Error())
+		if err := l.release(ctx, key, lockGrace); err != nil {
+			slog.Error(err.Error())
+		}
+
+		return exitFailure
+	}
+
+	result := runCommand(command, args, key, metrics, wd)
+
+	// Command is complete. Keep the lock for the minimum grace period before it is released.
+	if err := l.release(ctx, key, lockGrace); err != nil {
+		slog.Error(err.Error())
+	}
+
+	return result.exitCode
+}
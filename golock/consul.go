@@ -0,0 +1,258 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/jim-barber-he/go/util"
+)
+
+// The shortest and longest TTLs that Consul accepts for a session.
+const (
+	consulMinSessionTTL = 10
+	consulMaxSessionTTL = 86400
+)
+
+// errConsulNoLeader is returned when the Consul cluster doesn't have a leader to handle the locks.
+var errConsulNoLeader = errors.New("cluster has no leader")
+
+// consulLocker implements the locker interface using the Consul KV store and sessions.
+// A lock is a key acquired by a session with a TTL that deletes the key when the session is invalidated, so that
+// expired locks are removed by Consul itself.
+type consulLocker struct {
+	addr        string
+	client      *http.Client
+	holder      string
+	modifyIndex int64
+	session     string
+	token       string
+}
+
+// consulKV is the part of a key returned by the Consul KV API that is used by golock.
+type consulKV struct {
+	ModifyIndex int64
+	Session     string
+}
+
+// newConsulLocker returns a consulLocker for the Consul agent in the environment variables.
+func newConsulLocker(ctx context.Context) (*consulLocker, error) {
+	client, err := newBackendHTTPClient()
+	if err != nil {
+		return nil, err
+	}
+
+	l := &consulLocker{
+		addr:   strings.TrimSuffix(util.GetEnv(envLockConsulAddr, defLockConsulAddr), "/"),
+		client: client,
+		holder: holderID(),
+		token:  os.Getenv(envLockAuth),
+	}
+
+	slog.Debug("Connecting to Consul at " + l.addr)
+
+	// Check that the agent is responding and that the cluster has a leader.
+	var leader string
+	if err := l.do(ctx, http.MethodGet, "status/leader", nil, nil, &leader); err != nil {
+		return nil, fmt.Errorf("could not connect to consul: %w", err)
+	}
+	if leader == "" {
+		return nil, fmt.Errorf("could not connect to consul: %w", errConsulNoLeader)
+	}
+
+	return l, nil
+}
+
+// do sends a request to the Consul HTTP API and decodes the JSON response into result if it isn't nil.
+func (l *consulLocker) do(ctx context.Context, method, path string, query url.Values, body, result any) error {
+	u := l.addr + "/v1/" + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	var reqBody io.Reader
+	switch b := body.(type) {
+	case nil:
+	case []byte:
+		reqBody = bytes.NewReader(b)
+	default:
+		data, err := json.Marshal(b)
+		if err != nil {
+			return err
+		}
+		reqBody = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u, reqBody)
+	if err != nil {
+		return err
+	}
+	if l.token != "" {
+		req.Header.Set("X-Consul-Token", l.token)
+	}
+
+	resp, err := l.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// Consul responds with a 404 when reading a key that doesn't exist.
+	if resp.StatusCode == http.StatusNotFound && method == http.MethodGet {
+		return errLockNotFound
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+		return fmt.Errorf("%w: %s: %s", errBackendResponse, resp.Status, bytes.TrimSpace(msg))
+	}
+
+	if result == nil {
+		return nil
+	}
+
+	return json.NewDecoder(resp.Body).Decode(result)
+}
+
+// createSession creates a session that expires after ttl seconds, deleting the keys it holds when it does.
+func (l *consulLocker) createSession(ctx context.Context, ttl int) (string, error) {
+	var session struct{ ID string }
+	err := l.do(ctx, http.MethodPut, "session/create", nil, map[string]string{
+		"Name":      "golock " + l.holder,
+		"TTL":       strconv.Itoa(min(max(ttl, consulMinSessionTTL), consulMaxSessionTTL)) + "s",
+		"Behavior":  "delete",
+		"LockDelay": "0s",
+	}, &session)
+	if err != nil {
+		return "", fmt.Errorf("failed to create session: %w", err)
+	}
+
+	return session.ID, nil
+}
+
+// destroySession invalidates a session, deleting the keys that it holds.
+func (l *consulLocker) destroySession(ctx context.Context, session string) error {
+	if err := l.do(ctx, http.MethodPut, "session/destroy/"+session, nil, nil, nil); err != nil {
+		return fmt.Errorf("failed to destroy session: %w", err)
+	}
+
+	return nil
+}
+
+// acquire implements the locker interface.
+func (l *consulLocker) acquire(ctx context.Context, key string, release int) (bool, error) {
+	slog.Debug(fmt.Sprintf("Acquiring lock on %s key", key))
+
+	session, err := l.createSession(ctx, release)
+	if err != nil {
+		return false, err
+	}
+
+	acquired, err := l.lock(ctx, key, session)
+	if err != nil || !acquired {
+		if err := l.destroySession(ctx, session); err != nil {
+			slog.Warn(err.Error())
+		}
+		if err != nil {
+			return false, err
+		}
+		slog.Debug(fmt.Sprintf("Lock %s acquired by another process", key))
+
+		return false, nil
+	}
+
+	l.session = session
+	slog.Debug(fmt.Sprintf("Lock %s acquired", key))
+
+	return true, nil
+}
+
+// lock acquires the key with the session, and records the modify index of the key for the fencing token.
+func (l *consulLocker) lock(ctx context.Context, key, session string) (bool, error) {
+	var acquired bool
+	query := url.Values{"acquire": {session}}
+	if err := l.do(ctx, http.MethodPut, kvPath(key), query, []byte(l.holder), &acquired); err != nil {
+		return false, fmt.Errorf("failed to acquire lock: %w", err)
+	}
+	if !acquired {
+		return false, nil
+	}
+
+	// The modify index is raised by every change to the key, so it always increases when the lock is acquired.
+	var kvs []consulKV
+	if err := l.do(ctx, http.MethodGet, kvPath(key), nil, nil, &kvs); err != nil {
+		if errors.Is(err, errLockNotFound) {
+			return false, nil
+		}
+
+		return false, fmt.Errorf("failed to read lock: %w", err)
+	}
+	if len(kvs) == 0 || kvs[0].Session != session {
+		return false, nil
+	}
+	l.modifyIndex = kvs[0].ModifyIndex
+
+	return true, nil
+}
+
+// release implements the locker interface.
+// The lock is handed over to a new session that expires after the grace period, before the original session is
+// destroyed. Consul doesn't accept session TTLs below 10 seconds, so shorter grace periods are rounded up.
+func (l *consulLocker) release(ctx context.Context, key string, grace int) error {
+	if grace > 0 {
+		session, err := l.createSession(ctx, grace)
+		if err != nil {
+			return err
+		}
+
+		slog.Debug(fmt.Sprintf("Lock %s set to expire in: %ds", key, max(grace, consulMinSessionTTL)))
+		// Unlock and lock in a single transaction so that another process can't acquire the lock in between.
+		value := base64.StdEncoding.EncodeToString([]byte(l.holder))
+		ops := []map[string]map[string]string{
+			{"KV": {"Verb": "unlock", "Key": key, "Value": value, "Session": l.session}},
+			{"KV": {"Verb": "lock", "Key": key, "Value": value, "Session": session}},
+		}
+		if err := l.do(ctx, http.MethodPut, "txn", nil, ops, nil); err != nil {
+			if err := l.destroySession(ctx, session); err != nil {
+				slog.Warn(err.Error())
+			}
+
+			return fmt.Errorf("failed to set grace period on lock: %w", err)
+		}
+	}
+
+	return l.destroySession(ctx, l.session)
+}
+
+// reset implements the locker interface.
+func (l *consulLocker) reset(ctx context.Context, key string) error {
+	if err := l.do(ctx, http.MethodDelete, kvPath(key), nil, nil, nil); err != nil {
+		return fmt.Errorf("failed to remove key %s: %w", key, err)
+	}
+
+	return nil
+}
+
+// fencingToken implements the locker interface.
+func (l *consulLocker) fencingToken() int64 {
+	return l.modifyIndex
+}
+
+// close implements the locker interface.
+func (l *consulLocker) close() {
+	l.client.CloseIdleConnections()
+}
+
+// kvPath returns the path of key in the Consul KV API.
+func kvPath(key string) string {
+	return "kv/" + url.PathEscape(key)
+}
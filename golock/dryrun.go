@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	redis "github.com/redis/go-redis/v9"
+)
+
+// dryRun works out whether the lock stored at redisKey would be acquired and logs what would happen, without running
+// the command or modifying any keys in Redis.
+func dryRun(
+	ctx context.Context, rdb *redis.Client, redisKey, command string, lockSlots, lockRelease, lockGrace, minInterval int,
+) int {
+	slog.Info(fmt.Sprintf(
+		"Dry run: lock key %s, held for at most %ds, and at least %ds after [%s] completes",
+		redisKey, lockRelease, lockGrace, command,
+	))
+
+	var acquirable bool
+	var err error
+	if lockSlots > 1 {
+		acquirable, err = slotAvailable(ctx, rdb, redisKey, lockSlots)
+	} else {
+		acquirable, err = lockAvailable(ctx, rdb, redisKey)
+	}
+	if err != nil {
+		slog.Error(err.Error())
+
+		return exitFailure
+	}
+	if !acquirable {
+		slog.Info("Dry run: lock would not be acquired and the command would not be run")
+
+		return exitSuccess
+	}
+
+	if minInterval > 0 {
+		tooRecent, err := ranTooRecently(ctx, rdb, redisKey)
+		if err != nil {
+			slog.Error(err.Error())
+
+			return exitFailure
+		}
+		if tooRecent {
+			slog.Info(fmt.Sprintf(
+				"Dry run: lock would be acquired, but the command would not be run since it last ran within %ds",
+				minInterval,
+			))
+
+			return exitSuccess
+		}
+	}
+
+	slog.Info(fmt.Sprintf("Dry run: lock would be acquired and [%s] would be run", command))
+
+	return exitSuccess
+}
+
+// lockAvailable returns true if the exclusive lock stored at redisKey is free or has expired.
+func lockAvailable(ctx context.Context, rdb *redis.Client, redisKey string) (bool, error) {
+	value, err := rdb.Get(ctx, redisKey).Result()
+	if errors.Is(err, redis.Nil) {
+		slog.Info(fmt.Sprintf("Dry run: lock %s is free", redisKey))
+		return true, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to get expiration time: %w", err)
+	}
+
+	held := parseLockValue(value)
+	if expiresIn := held.expiresIn(); expiresIn > 0 {
+		slog.Info(fmt.Sprintf(
+			"Dry run: lock %s is held by another process %s (expires in %ds)", redisKey, held.holder(), expiresIn,
+		))
+		return false, nil
+	}
+
+	slog.Info(fmt.Sprintf("Dry run: lock %s is held by another process %s but has expired", redisKey, held.holder()))
+
+	return true, nil
+}
+
+// slotAvailable returns true if fewer than the number of slots of the semaphore stored at redisKey are held.
+func slotAvailable(ctx context.Context, rdb *redis.Client, redisKey string, slots int) (bool, error) {
+	now := strconv.FormatInt(time.Now().UTC().Unix(), 10)
+	held, err := rdb.ZCount(ctx, redisKey, "("+now, "+inf").Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to count holders: %w", err)
+	}
+
+	slog.Info(fmt.Sprintf("Dry run: %d of %d slots of %s are held", held, slots, redisKey))
+
+	return held < int64(slots), nil
+}
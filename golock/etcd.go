@@ -0,0 +1,253 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/jim-barber-he/go/util"
+)
+
+// etcdLocker implements the locker interface using the JSON gateway of an etcd v3 cluster.
+// A lock is a key that is attached to a lease, and is created in a transaction that only succeeds if the key doesn't
+// exist yet. This means that expired locks are removed by etcd itself when their lease expires.
+type etcdLocker struct {
+	client   *http.Client
+	endpoint string
+	holder   string
+	leaseID  int64
+	revision int64
+	token    string
+}
+
+// etcdHeader is the header returned in the etcd responses.
+type etcdHeader struct {
+	Revision int64 `json:"revision,string"`
+}
+
+// etcdKeyValue is a key returned by etcd.
+type etcdKeyValue struct {
+	Value []byte `json:"value"`
+}
+
+// etcdTxnResponse is the response to an etcd transaction.
+type etcdTxnResponse struct {
+	Header    etcdHeader `json:"header"`
+	Succeeded bool       `json:"succeeded"`
+	Responses []struct {
+		ResponseRange struct {
+			Kvs []etcdKeyValue `json:"kvs"`
+		} `json:"response_range"`
+	} `json:"responses"`
+}
+
+// newEtcdLocker returns an etcdLocker connected to the first of the etcd endpoints in the environment variables that
+// responds.
+func newEtcdLocker(ctx context.Context) (*etcdLocker, error) {
+	client, err := newBackendHTTPClient()
+	if err != nil {
+		return nil, err
+	}
+
+	l := &etcdLocker{client: client, holder: holderID()}
+
+	for _, endpoint := range strings.Split(util.GetEnv(envLockEtcdEndpoints, defLockEtcdEndpoints), ",") {
+		l.endpoint = strings.TrimSuffix(endpoint, "/")
+		slog.Debug("Connecting to etcd at " + l.endpoint)
+		if err = l.do(ctx, "maintenance/status", struct{}{}, nil); err == nil {
+			break
+		}
+		slog.Debug(err.Error())
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not connect to etcd: %w", err)
+	}
+
+	if username := os.Getenv(envLockUsername); username != "" {
+		var auth struct {
+			Token string `json:"token"`
+		}
+		body := map[string]string{"name": username, "password": os.Getenv(envLockAuth)}
+		if err := l.do(ctx, "auth/authenticate", body, &auth); err != nil {
+			return nil, fmt.Errorf("could not authenticate to etcd: %w", err)
+		}
+		l.token = auth.Token
+	}
+
+	return l, nil
+}
+
+// do sends a request to the etcd JSON gateway and decodes the JSON response into result if it isn't nil.
+func (l *etcdLocker) do(ctx context.Context, path string, body, result any) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, l.endpoint+"/v3/"+path, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if l.token != "" {
+		req.Header.Set("Authorization", l.token)
+	}
+
+	resp, err := l.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+		return fmt.Errorf("%w: %s: %s", errBackendResponse, resp.Status, bytes.TrimSpace(msg))
+	}
+
+	if result == nil {
+		return nil
+	}
+
+	return json.NewDecoder(resp.Body).Decode(result)
+}
+
+// grantLease creates a lease that expires after ttl seconds.
+func (l *etcdLocker) grantLease(ctx context.Context, ttl int) (int64, error) {
+	var lease struct {
+		ID int64 `json:"ID,string"`
+	}
+	if err := l.do(ctx, "lease/grant", map[string]string{"TTL": fmt.Sprint(ttl)}, &lease); err != nil {
+		return 0, fmt.Errorf("failed to grant lease: %w", err)
+	}
+
+	return lease.ID, nil
+}
+
+// revokeLease revokes a lease, which deletes the keys attached to it.
+func (l *etcdLocker) revokeLease(ctx context.Context, id int64) error {
+	if err := l.do(ctx, "lease/revoke", map[string]string{"ID": fmt.Sprint(id)}, nil); err != nil {
+		return fmt.Errorf("failed to revoke lease: %w", err)
+	}
+
+	return nil
+}
+
+// putIfCreated puts the key attached to the lease if the key was created at the revision.
+// A revision of 0 means that the key must not exist.
+func (l *etcdLocker) putIfCreated(ctx context.Context, key string, revision, leaseID int64) (*etcdTxnResponse, error) {
+	txn := map[string]any{
+		"compare": []map[string]string{{
+			"key":             base64.StdEncoding.EncodeToString([]byte(key)),
+			"target":          "CREATE",
+			"result":          "EQUAL",
+			"create_revision": fmt.Sprint(revision),
+		}},
+		"success": []map[string]any{{
+			"request_put": map[string]string{
+				"key":   base64.StdEncoding.EncodeToString([]byte(key)),
+				"value": base64.StdEncoding.EncodeToString([]byte(l.holder)),
+				"lease": fmt.Sprint(leaseID),
+			},
+		}},
+		"failure": []map[string]any{{
+			"request_range": map[string]string{"key": base64.StdEncoding.EncodeToString([]byte(key))},
+		}},
+	}
+
+	var resp etcdTxnResponse
+	if err := l.do(ctx, "kv/txn", txn, &resp); err != nil {
+		return nil, err
+	}
+
+	return &resp, nil
+}
+
+// acquire implements the locker interface.
+func (l *etcdLocker) acquire(ctx context.Context, key string, release int) (bool, error) {
+	slog.Debug(fmt.Sprintf("Acquiring lock on %s key", key))
+
+	leaseID, err := l.grantLease(ctx, release)
+	if err != nil {
+		return false, err
+	}
+
+	resp, err := l.putIfCreated(ctx, key, 0, leaseID)
+	if err != nil || !resp.Succeeded {
+		if err := l.revokeLease(ctx, leaseID); err != nil {
+			slog.Warn(err.Error())
+		}
+		if err != nil {
+			return false, fmt.Errorf("failed to acquire lock: %w", err)
+		}
+
+		holder := "unknown"
+		if len(resp.Responses) > 0 && len(resp.Responses[0].ResponseRange.Kvs) > 0 {
+			holder = string(resp.Responses[0].ResponseRange.Kvs[0].Value)
+		}
+		slog.Debug(fmt.Sprintf("Lock %s acquired by another process (%s)", key, holder))
+
+		return false, nil
+	}
+
+	l.leaseID = leaseID
+	l.revision = resp.Header.Revision
+	slog.Debug(fmt.Sprintf("Lock %s acquired", key))
+
+	return true, nil
+}
+
+// release implements the locker interface.
+// The key is moved to a new lease that expires after the grace period, before the original lease is revoked.
+func (l *etcdLocker) release(ctx context.Context, key string, grace int) error {
+	if grace > 0 {
+		leaseID, err := l.grantLease(ctx, grace)
+		if err != nil {
+			return err
+		}
+
+		// Only move the key if it is still the one that we created.
+		resp, err := l.putIfCreated(ctx, key, l.revision, leaseID)
+		if err != nil || !resp.Succeeded {
+			// The new lease isn't attached to the key, so revoke it rather than leaving it until it expires.
+			if err := l.revokeLease(ctx, leaseID); err != nil {
+				slog.Warn(err.Error())
+			}
+			if err != nil {
+				return fmt.Errorf("failed to set grace period on lock: %w", err)
+			}
+			slog.Warn(fmt.Sprintf("Lock %s was no longer held, so no grace period was set", key))
+		} else {
+			slog.Debug(fmt.Sprintf("Lock %s set to expire in: %ds", key, grace))
+		}
+	}
+
+	return l.revokeLease(ctx, l.leaseID)
+}
+
+// reset implements the locker interface.
+func (l *etcdLocker) reset(ctx context.Context, key string) error {
+	body := map[string]string{"key": base64.StdEncoding.EncodeToString([]byte(key))}
+	if err := l.do(ctx, "kv/deleterange", body, nil); err != nil {
+		return fmt.Errorf("failed to remove key %s: %w", key, err)
+	}
+
+	return nil
+}
+
+// fencingToken implements the locker interface.
+// The etcd revision that created the lock is used since it increases with every change made to the cluster.
+func (l *etcdLocker) fencingToken() int64 {
+	return l.revision
+}
+
+// close implements the locker interface.
+func (l *etcdLocker) close() {
+	l.client.CloseIdleConnections()
+}
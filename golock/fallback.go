@@ -0,0 +1,86 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"github.com/jim-barber-he/go/util"
+)
+
+// Policies for when Redis is unreachable.
+const (
+	fallbackFail  string = "fail"  // Exit with a failure without running the command.
+	fallbackLocal string = "local" // Run the command while holding a lock local to this host.
+	fallbackRun   string = "run"   // Run the command without a lock (fail-open).
+	fallbackSkip  string = "skip"  // Don't run the command as if another process held the lock (fail-closed).
+)
+
+// runWithFallback handles running the command according to the CRONLOCK_FALLBACK policy when Redis is unreachable.
+func runWithFallback(command string, args []string, redisKey string, metrics *metricsRecorder, wd *watchdog) int {
+	switch policy := util.GetEnv(envLockFallback, defLockFallback); policy {
+	case fallbackLocal:
+		slog.Warn(fmt.Sprintf("Redis is unreachable, falling back to a local lock for [%s]", command))
+
+		return runWithLocalLock(command, args, redisKey, metrics, wd)
+	case fallbackRun:
+		slog.Warn(fmt.Sprintf("Redis is unreachable, running [%s] without a lock", command))
+		metrics.outcome(outcomeAcquired)
+
+		return runCommand(command, args, redisKey, metrics, wd).exitCode
+	case fallbackSkip:
+		slog.Warn(fmt.Sprintf("Redis is unreachable, skipping [%s]", command))
+		metrics.outcome(outcomeSkipped)
+
+		return exitSuccess
+	case fallbackFail:
+		return exitFailure
+	default:
+		slog.Error(fmt.Sprintf("Unknown %s policy: %s", envLockFallback, policy))
+
+		return exitFailure
+	}
+}
+
+// runWithLocalLock runs the command while holding an exclusive flock on a file named after the lock key, so that the
+// command can at least not overlap with itself on this host.
+// The lock is released by the operating system when the file is closed, even if golock is killed.
+func runWithLocalLock(command string, args []string, redisKey string, metrics *metricsRecorder, wd *watchdog) int {
+	dir := util.GetEnv(envLockLocalDir, os.TempDir())
+	lockFile := filepath.Join(dir, keyFileName(redisKey)+".lock")
+
+	fp, err := os.OpenFile(lockFile, os.O_CREATE|os.O_RDWR, 0o600)
+	if err != nil {
+		slog.Error(fmt.Sprintf("Failed to open local lock file %s: %v", lockFile, err))
+
+		return exitFailure
+	}
+	defer fp.Close()
+
+	slog.Debug("Acquiring local lock on " + lockFile)
+	if err := syscall.Flock(int(fp.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		if errors.Is(err, syscall.EWOULDBLOCK) {
+			slog.Debug(fmt.Sprintf("Local lock %s acquired by another process", lockFile))
+			metrics.outcome(outcomeSkipped)
+
+			return exitSuccess
+		}
+		slog.Error(fmt.Sprintf("Failed to lock local lock file %s: %v", lockFile, err))
+
+		return exitFailure
+	}
+	slog.Debug(fmt.Sprintf("Local lock %s acquired", lockFile))
+	metrics.outcome(outcomeAcquired)
+
+	return runCommand(command, args, redisKey, metrics, wd).exitCode
+}
+
+// keyFileName returns the lock key in a form that can be used as the name of a file, since CRONLOCK_KEY may contain
+// path separators that would otherwise refer to directories that don't exist, or to somewhere outside the directory.
+func keyFileName(redisKey string) string {
+	return strings.ReplaceAll(redisKey, string(filepath.Separator), "_")
+}
@@ -0,0 +1,26 @@
+package main
+
+import "testing"
+
+func TestKeyFileName(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		key      string
+		expected string
+	}{
+		{key: "cronlock.backup", expected: "cronlock.backup"},
+		{key: "cronlock.jobs/nightly", expected: "cronlock.jobs_nightly"},
+		{key: "cronlock.../../etc/passwd", expected: "cronlock..._.._etc_passwd"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			t.Parallel()
+
+			if got := keyFileName(tt.key); got != tt.expected {
+				t.Errorf("keyFileName() failed, expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
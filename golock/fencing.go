@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+
+	redis "github.com/redis/go-redis/v9"
+)
+
+// fencingKeySuffix is appended to the lock key to get the key of the counter used to generate fencing tokens.
+// The counter never expires so that the tokens keep increasing for the life of the Redis server.
+const fencingKeySuffix string = ".fencing"
+
+// envFencingToken is the environment variable used to pass the fencing token to the command.
+const envFencingToken = "GOLOCK_FENCING_TOKEN"
+
+// setFencingToken generates the next fencing token for the lock stored at redisKey and exposes it to the command via
+// the GOLOCK_FENCING_TOKEN environment variable.
+// Downstream services can reject requests from a holder of the lock that passes a token lower than one it has seen.
+func setFencingToken(ctx context.Context, rdb *redis.Client, redisKey string) error {
+	token, err := rdb.Incr(ctx, redisKey+fencingKeySuffix).Result()
+	if err != nil {
+		return fmt.Errorf("failed to generate fencing token: %w", err)
+	}
+
+	return exportFencingToken(redisKey, token)
+}
+
+// exportFencingToken exposes the fencing token for the lock on key to the command via the GOLOCK_FENCING_TOKEN
+// environment variable.
+func exportFencingToken(key string, token int64) error {
+	slog.Debug(fmt.Sprintf("Lock %s fencing token is %d", key, token))
+
+	if err := os.Setenv(envFencingToken, strconv.FormatInt(token, 10)); err != nil {
+		return fmt.Errorf("failed to set %s: %w", envFencingToken, err)
+	}
+
+	return nil
+}
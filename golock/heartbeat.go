@@ -0,0 +1,181 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// heartbeatStatus is the JSON status reported by the heartbeat while the command is running.
+type heartbeatStatus struct {
+	Key     string    `json:"key"`
+	Command string    `json:"command"`
+	Host    string    `json:"host"`
+	Since   time.Time `json:"since"`
+	PID     int       `json:"pid"`
+	Alive   bool      `json:"alive"`
+}
+
+// heartbeat reports the status of the command run while holding the lock, either via an HTTP listener, by
+// periodically writing it to a file, or both.
+type heartbeat struct {
+	mu     sync.Mutex
+	status heartbeatStatus
+
+	file   string
+	server *http.Server
+	stopC  chan struct{}
+	wg     sync.WaitGroup
+}
+
+// startHeartbeat starts the heartbeat for the command holding the lock at key.
+// It returns nil if neither CRONLOCK_HEALTH_ADDR nor CRONLOCK_HEARTBEAT_FILE are set.
+func startHeartbeat(key, command string) *heartbeat {
+	addr := os.Getenv(envLockHealthAddr)
+	file := os.Getenv(envLockHeartbeatFile)
+	if addr == "" && file == "" {
+		return nil
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+
+	h := &heartbeat{
+		status: heartbeatStatus{Key: key, Command: command, Host: hostname, Since: time.Now()},
+		file:   file,
+		stopC:  make(chan struct{}),
+	}
+
+	if addr != "" {
+		listener, err := net.Listen("tcp", addr)
+		if err != nil {
+			// Failing to report the status is not a reason to not run the command.
+			slog.Warn(fmt.Sprintf("Failed to start health listener: %v", err))
+		} else {
+			slog.Debug("Health listener started on " + listener.Addr().String())
+			h.server = &http.Server{Handler: h, ReadHeaderTimeout: notifyTimeout}
+			h.wg.Add(1)
+			go func() {
+				defer h.wg.Done()
+				if err := h.server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+					slog.Warn(fmt.Sprintf("Health listener failed: %v", err))
+				}
+			}()
+		}
+	}
+
+	if file != "" {
+		interval := getEnvSeconds(envLockHeartbeatInterval, defLockHeartbeatInterval)
+		if interval <= 0 {
+			interval = defLockHeartbeatInterval
+		}
+		h.wg.Add(1)
+		go func() {
+			defer h.wg.Done()
+			h.touch(time.Duration(interval) * time.Second)
+		}()
+	}
+
+	return h
+}
+
+// current returns the current status, checking whether the command's process is still alive.
+func (h *heartbeat) current() heartbeatStatus {
+	h.mu.Lock()
+	status := h.status
+	h.mu.Unlock()
+
+	// Signal 0 only checks whether the process exists.
+	status.Alive = status.PID > 0 && syscall.Kill(status.PID, 0) == nil
+
+	return status
+}
+
+// ServeHTTP implements http.Handler by responding with the status as JSON.
+// The response code is 503 if the command isn't running so that it can be used as a health check.
+func (h *heartbeat) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	status := h.current()
+
+	w.Header().Set("Content-Type", "application/json")
+	if !status.Alive {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	if err := json.NewEncoder(w).Encode(status); err != nil {
+		slog.Debug(fmt.Sprintf("Failed to write health response: %v", err))
+	}
+}
+
+// touch writes the status to the heartbeat file every interval until the heartbeat is stopped.
+func (h *heartbeat) touch(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-h.stopC:
+			return
+		case <-ticker.C:
+			h.writeFile()
+		}
+	}
+}
+
+// writeFile writes the status to the heartbeat file.
+func (h *heartbeat) writeFile() {
+	data, err := json.Marshal(h.current())
+	if err == nil {
+		err = os.WriteFile(h.file, append(data, '\n'), 0o644)
+	}
+	if err != nil {
+		slog.Warn(fmt.Sprintf("Failed to write heartbeat file: %v", err))
+	}
+}
+
+// setPID records the PID of the command once it has started.
+func (h *heartbeat) setPID(pid int) {
+	if h == nil {
+		return
+	}
+
+	h.mu.Lock()
+	h.status.PID = pid
+	h.mu.Unlock()
+
+	// Write the heartbeat file straight away rather than waiting for the first interval to pass.
+	if h.file != "" {
+		h.writeFile()
+	}
+}
+
+// stop stops the heartbeat and removes the heartbeat file.
+func (h *heartbeat) stop() {
+	if h == nil {
+		return
+	}
+
+	close(h.stopC)
+	if h.server != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), notifyTimeout)
+		defer cancel()
+		if err := h.server.Shutdown(ctx); err != nil {
+			slog.Debug(fmt.Sprintf("Failed to stop health listener: %v", err))
+		}
+	}
+	h.wg.Wait()
+
+	if h.file != "" {
+		if err := os.Remove(h.file); err != nil && !errors.Is(err, os.ErrNotExist) {
+			slog.Warn(fmt.Sprintf("Failed to remove heartbeat file: %v", err))
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/jim-barber-he/go/texttable"
+	redis "github.com/redis/go-redis/v9"
+)
+
+// historyKeySuffix is appended to the lock key to get the key of the list holding the run history of the lock.
+const historyKeySuffix string = ".history"
+
+// runRecord is a record of a single run of a command that was stored in the run history.
+type runRecord struct {
+	Start    time.Time `json:"start"`
+	End      time.Time `json:"end"`
+	Hostname string    `json:"hostname"`
+	ExitCode int       `json:"exitCode"`
+	Duration float64   `json:"duration"`
+	Output   string    `json:"output,omitempty"`
+}
+
+// historyRow represents a row in the table output by showHistory.
+type historyRow struct {
+	Start    string `title:"START"`
+	End      string `title:"END"`
+	Hostname string `title:"HOSTNAME"`
+	ExitCode string `title:"EXIT-CODE"`
+	Duration string `title:"DURATION"`
+}
+
+// TabTitleRow implements the texttab.TableFormatter interface.
+func (hr *historyRow) TabTitleRow() string {
+	return texttable.ReflectedTitleRow(hr)
+}
+
+// TabValues implements the texttab.TableFormatter interface.
+func (hr *historyRow) TabValues() string {
+	return texttable.ReflectedTabValues(hr)
+}
+
+// newRunRecord returns a runRecord for a run of the command on this host.
+func newRunRecord(start time.Time, duration time.Duration, exitCode int) runRecord {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+
+	return runRecord{
+		Start:    start.UTC(),
+		End:      start.Add(duration).UTC(),
+		Hostname: hostname,
+		ExitCode: exitCode,
+		Duration: duration.Seconds(),
+	}
+}
+
+// recordHistory adds the record of a run to the front of the run history for the lock stored at redisKey.
+// The run history is capped at the size passed in, with the oldest records being dropped.
+func recordHistory(ctx context.Context, rdb *redis.Client, redisKey string, record runRecord, size int) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to marshal run record: %w", err)
+	}
+
+	historyKey := redisKey + historyKeySuffix
+	slog.Debug(fmt.Sprintf("Recording run in %s", historyKey))
+
+	_, err = rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.LPush(ctx, historyKey, data)
+		pipe.LTrim(ctx, historyKey, 0, int64(size-1))
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record run history: %w", err)
+	}
+
+	return nil
+}
+
+// showHistory displays the run history for the lock stored at redisKey, most recent run first.
+// If lastOutput is true, then just the captured output of the most recent run is displayed instead.
+func showHistory(ctx context.Context, rdb *redis.Client, redisKey string, lastOutput bool) error {
+	records, err := rdb.LRange(ctx, redisKey+historyKeySuffix, 0, -1).Result()
+	if err != nil {
+		return fmt.Errorf("failed to get run history: %w", err)
+	}
+	if len(records) == 0 {
+		fmt.Printf("No run history for %s\n", redisKey)
+		return nil
+	}
+
+	if lastOutput {
+		var record runRecord
+		if err := json.Unmarshal([]byte(records[0]), &record); err != nil {
+			return fmt.Errorf("failed to unmarshal run record: %w", err)
+		}
+		fmt.Print(record.Output)
+		return nil
+	}
+
+	var tbl texttable.Table[*historyRow]
+	for _, data := range records {
+		var record runRecord
+		if err := json.Unmarshal([]byte(data), &record); err != nil {
+			slog.Warn(fmt.Sprintf("Skipping invalid run record %q: %v", data, err))
+			continue
+		}
+		tbl.Append(&historyRow{
+			Start:    record.Start.Local().Format(time.DateTime),
+			End:      record.End.Local().Format(time.DateTime),
+			Hostname: record.Hostname,
+			ExitCode: strconv.Itoa(record.ExitCode),
+			Duration: (time.Duration(record.Duration * float64(time.Second))).Round(time.Millisecond).String(),
+		})
+	}
+	tbl.Write()
+
+	return nil
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	redis "github.com/redis/go-redis/v9"
+)
+
+// lastRunKeySuffix is appended to the lock key to get the key recording when the command last ran successfully.
+// The key expires once the minimum interval between runs has passed.
+const lastRunKeySuffix string = ".lastrun"
+
+// ranTooRecently returns true if the last successful run of the command for the lock stored at redisKey finished less
+// than the minimum interval between runs ago.
+func ranTooRecently(ctx context.Context, rdb *redis.Client, redisKey string) (bool, error) {
+	lastRun, err := rdb.Get(ctx, redisKey+lastRunKeySuffix).Int64()
+	if errors.Is(err, redis.Nil) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to get last run time: %w", err)
+	}
+
+	slog.Debug(fmt.Sprintf(
+		"Lock %s last run finished %ds ago which is within the minimum interval",
+		redisKey, time.Now().UTC().Unix()-lastRun,
+	))
+
+	return true, nil
+}
+
+// recordLastRun records that the command for the lock stored at redisKey finished successfully just now.
+// The record expires after the minimum interval between runs has passed.
+func recordLastRun(ctx context.Context, rdb *redis.Client, redisKey string, minInterval int) error {
+	lastRunKey := redisKey + lastRunKeySuffix
+	slog.Debug(fmt.Sprintf("Recording last run in %s for %ds", lastRunKey, minInterval))
+
+	now := time.Now().UTC().Unix()
+	if err := rdb.Set(ctx, lastRunKey, now, time.Duration(minInterval)*time.Second).Err(); err != nil {
+		return fmt.Errorf("failed to record last run time: %w", err)
+	}
+
+	return nil
+}
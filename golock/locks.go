@@ -0,0 +1,276 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jim-barber-he/go/texttable"
+	"github.com/jim-barber-he/go/util"
+	redis "github.com/redis/go-redis/v9"
+)
+
+// locksUsage is displayed when the `golock locks` subcommand is used incorrectly.
+const locksUsage = `Usage:
+  golock locks list            List the locks below CRONLOCK_PREFIX
+  golock locks show KEY        Show the holders and expiry of a lock
+  golock locks release KEY     Release a lock by removing it`
+
+var errLockNotFound = errors.New("lock not found")
+
+// lockHolder represents a holder of a lock.
+type lockHolder struct {
+	ID        string
+	ExpiresAt time.Time
+}
+
+// lockInfo represents the details of a lock stored in Redis.
+type lockInfo struct {
+	Key       string
+	Type      string
+	ExpiresAt time.Time
+	Holders   []lockHolder
+}
+
+// lockRow represents a row in the table output by listLocks.
+type lockRow struct {
+	Key       string `title:"KEY"`
+	Type      string `title:"TYPE"`
+	Holders   string `title:"HOLDERS"`
+	ExpiresIn string `title:"EXPIRES-IN"`
+}
+
+// TabTitleRow implements the texttab.TableFormatter interface.
+func (lr *lockRow) TabTitleRow() string {
+	return texttable.ReflectedTitleRow(lr)
+}
+
+// TabValues implements the texttab.TableFormatter interface.
+func (lr *lockRow) TabValues() string {
+	return texttable.ReflectedTabValues(lr)
+}
+
+// runLocks implements the `golock locks` subcommands used to manage the locks in Redis.
+func runLocks(args []string) int {
+	if len(args) == 0 || (args[0] != "list" && len(args) != 2) || (args[0] == "list" && len(args) != 1) {
+		fmt.Fprintln(os.Stderr, locksUsage)
+
+		return exitFailure
+	}
+
+	ctx := context.Background()
+
+	redisOpts, err := getRedisOptions()
+	if err != nil {
+		slog.Error(err.Error())
+
+		return exitFailure
+	}
+	rdb, err := redisConnect(ctx, redisOpts)
+	if err != nil {
+		slog.Error(err.Error())
+
+		return exitFailure
+	}
+	defer rdb.Close()
+
+	prefix := util.GetEnv(envLockPrefix, defLockPrefix)
+
+	switch args[0] {
+	case "list":
+		err = listLocks(ctx, rdb, prefix)
+	case "show":
+		err = showLock(ctx, rdb, lockKey(prefix, args[1]))
+	case "release":
+		err = releaseLockKey(ctx, rdb, lockKey(prefix, args[1]))
+	default:
+		fmt.Fprintln(os.Stderr, locksUsage)
+
+		return exitFailure
+	}
+	if err != nil {
+		slog.Error(err.Error())
+
+		return exitFailure
+	}
+
+	return exitSuccess
+}
+
+// auxiliaryKeys returns the keys that golock stores alongside the locks among keys, such as their run history.
+// Only keys named after one of the other keys are included, so that a lock whose own key happens to end with one of
+// the suffixes is still treated as a lock.
+func auxiliaryKeys(keys []string) map[string]bool {
+	found := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		found[key] = true
+	}
+
+	auxiliary := make(map[string]bool)
+	for _, key := range keys {
+		for _, suffix := range []string{durationsKeySuffix, fencingKeySuffix, historyKeySuffix, lastRunKeySuffix} {
+			if found[key+suffix] {
+				auxiliary[key+suffix] = true
+			}
+		}
+	}
+
+	return auxiliary
+}
+
+// lockKey returns the Redis key of a lock, adding the prefix to the key passed in if it doesn't already have it.
+func lockKey(prefix, key string) string {
+	if strings.HasPrefix(key, prefix) {
+		return key
+	}
+
+	return prefix + key
+}
+
+// describeLock returns the details of the lock stored at key.
+// Locks are stored as strings holding their expiry time and holder, or as sorted sets of holders in semaphore mode.
+func describeLock(ctx context.Context, rdb *redis.Client, key string) (lockInfo, error) {
+	info := lockInfo{Key: key}
+
+	keyType, err := rdb.Type(ctx, key).Result()
+	if err != nil {
+		return info, fmt.Errorf("failed to get type of %s: %w", key, err)
+	}
+
+	switch keyType {
+	case "none":
+		return info, fmt.Errorf("%w: %s", errLockNotFound, key)
+	case "string":
+		info.Type = "lock"
+		value, err := rdb.Get(ctx, key).Result()
+		if err != nil {
+			return info, fmt.Errorf("failed to get %s: %w", key, err)
+		}
+		held := parseLockValue(value)
+		id := "-"
+		if held.Hostname != "" {
+			id = held.holder()
+		}
+		info.Holders = []lockHolder{{ID: id, ExpiresAt: time.Unix(held.ExpiresAt, 0)}}
+	case "zset":
+		info.Type = "semaphore"
+		members, err := rdb.ZRangeByScoreWithScores(ctx, key, &redis.ZRangeBy{
+			Min: strconv.FormatInt(time.Now().Unix(), 10),
+			Max: "+inf",
+		}).Result()
+		if err != nil {
+			return info, fmt.Errorf("failed to get holders of %s: %w", key, err)
+		}
+		for _, member := range members {
+			id, _ := member.Member.(string)
+			info.Holders = append(info.Holders, lockHolder{ID: id, ExpiresAt: time.Unix(int64(member.Score), 0)})
+		}
+	default:
+		info.Type = keyType
+	}
+
+	ttl, err := rdb.TTL(ctx, key).Result()
+	if err != nil {
+		return info, fmt.Errorf("failed to get TTL of %s: %w", key, err)
+	}
+	if ttl > 0 {
+		info.ExpiresAt = time.Now().Add(ttl)
+	}
+
+	return info, nil
+}
+
+// formatExpiresIn returns how long until a time is reached, or a dash if the time is not set.
+func formatExpiresIn(expiresAt time.Time) string {
+	if expiresAt.IsZero() {
+		return "-"
+	}
+
+	return time.Until(expiresAt).Round(time.Second).String()
+}
+
+// listLocks displays the locks that have keys starting with the prefix.
+func listLocks(ctx context.Context, rdb *redis.Client, prefix string) error {
+	var tbl texttable.Table[*lockRow]
+
+	var keys []string
+	iter := rdb.Scan(ctx, 0, prefix+"*", 0).Iterator()
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+	}
+	if err := iter.Err(); err != nil {
+		return fmt.Errorf("failed to scan for locks: %w", err)
+	}
+
+	auxiliary := auxiliaryKeys(keys)
+	for _, key := range keys {
+		if auxiliary[key] {
+			continue
+		}
+
+		info, err := describeLock(ctx, rdb, key)
+		if errors.Is(err, errLockNotFound) {
+			// The lock expired since the scan found it.
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		// The history and durations lists are kept after their lock has expired, but a lock is never a list.
+		if info.Type == "list" {
+			continue
+		}
+
+		tbl.Append(&lockRow{
+			Key:       info.Key,
+			Type:      info.Type,
+			Holders:   strconv.Itoa(len(info.Holders)),
+			ExpiresIn: formatExpiresIn(info.ExpiresAt),
+		})
+	}
+
+	if len(tbl.Rows) == 0 {
+		fmt.Printf("No locks found with prefix %s\n", prefix)
+		return nil
+	}
+	tbl.Write()
+
+	return nil
+}
+
+// showLock displays the details of the lock stored at key.
+func showLock(ctx context.Context, rdb *redis.Client, key string) error {
+	info, err := describeLock(ctx, rdb, key)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Key: %s\n", info.Key)
+	fmt.Printf("Type: %s\n", info.Type)
+	fmt.Printf("ExpiresIn: %s\n", formatExpiresIn(info.ExpiresAt))
+	for _, holder := range info.Holders {
+		fmt.Printf("Holder: %s (expires in %s)\n", holder.ID, formatExpiresIn(holder.ExpiresAt))
+	}
+
+	return nil
+}
+
+// releaseLockKey releases the lock stored at key by removing it.
+func releaseLockKey(ctx context.Context, rdb *redis.Client, key string) error {
+	removed, err := rdb.Del(ctx, key).Result()
+	if err != nil {
+		return fmt.Errorf("failed to remove key %s: %w", key, err)
+	}
+	if removed == 0 {
+		return fmt.Errorf("%w: %s", errLockNotFound, key)
+	}
+
+	fmt.Printf("Lock %s released\n", key)
+
+	return nil
+}
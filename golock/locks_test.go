@@ -0,0 +1,52 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAuxiliaryKeys(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		keys     []string
+		expected map[string]bool
+	}{
+		{
+			name: "auxiliary keys of a lock",
+			keys: []string{"cronlock.backup", "cronlock.backup.history", "cronlock.backup.fencing", "cronlock.report"},
+			expected: map[string]bool{
+				"cronlock.backup.history": true,
+				"cronlock.backup.fencing": true,
+			},
+		},
+		{
+			name:     "lock ending in a suffix",
+			keys:     []string{"cronlock.rotate.history", "cronlock.db.lastrun"},
+			expected: map[string]bool{},
+		},
+		{
+			name: "lock ending in a suffix with its own auxiliary keys",
+			keys: []string{"cronlock.rotate.history", "cronlock.rotate.history.history"},
+			expected: map[string]bool{
+				"cronlock.rotate.history.history": true,
+			},
+		},
+		{
+			name:     "no keys",
+			keys:     nil,
+			expected: map[string]bool{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := auxiliaryKeys(tt.keys); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("auxiliaryKeys() failed, expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
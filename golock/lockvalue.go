@@ -0,0 +1,80 @@
+package main
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// lockValue is the value stored in an exclusive lock, describing who holds it.
+// Only the expiry time is stored by default, as an integer like cronlock does. The whole value is stored as JSON when
+// CRONLOCK_JSON_VALUE is set to yes. Both formats can be read either way.
+type lockValue struct {
+	ExpiresAt   int64     `json:"expiresAt"`
+	Hostname    string    `json:"hostname"`
+	PID         int       `json:"pid"`
+	CommandHash string    `json:"commandHash"`
+	Start       time.Time `json:"start"`
+}
+
+// newLockValue returns the lock value for this instance of golock running command, expiring at expiresAt.
+func newLockValue(command string, expiresAt int64) lockValue {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+	hash := md5.Sum([]byte(command))
+
+	return lockValue{
+		ExpiresAt:   expiresAt,
+		Hostname:    hostname,
+		PID:         os.Getpid(),
+		CommandHash: hex.EncodeToString(hash[:]),
+		Start:       time.Now().UTC(),
+	}
+}
+
+// parseLockValue parses the value of a lock in either the JSON or the cronlock format.
+// Values that can't be parsed are treated as an expired lock held by an unknown holder.
+func parseLockValue(value string) lockValue {
+	if expiresAt, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return lockValue{ExpiresAt: expiresAt}
+	}
+
+	var v lockValue
+	_ = json.Unmarshal([]byte(value), &v)
+
+	return v
+}
+
+// String returns the lock value as it is stored in Redis.
+func (v lockValue) String() string {
+	if os.Getenv(envLockJSONValue) != "yes" {
+		return strconv.FormatInt(v.ExpiresAt, 10)
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return strconv.FormatInt(v.ExpiresAt, 10)
+	}
+
+	return string(data)
+}
+
+// holder returns who holds the lock in the same form as holderID(), or "unknown" if the lock doesn't say.
+func (v lockValue) holder() string {
+	if v.Hostname == "" {
+		return "unknown"
+	}
+
+	return fmt.Sprintf("%s:%d", v.Hostname, v.PID)
+}
+
+// expiresIn returns how many seconds until the lock expires, which is negative if it has already expired.
+func (v lockValue) expiresIn() int {
+	return int(v.ExpiresAt - time.Now().UTC().Unix())
+}
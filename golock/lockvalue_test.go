@@ -0,0 +1,106 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseLockValue(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		value    string
+		expected lockValue
+	}{
+		{
+			name:     "cronlock format",
+			value:    "1700000000",
+			expected: lockValue{ExpiresAt: 1700000000},
+		},
+		{
+			name: "JSON format",
+			value: `{"expiresAt":1700000000,"hostname":"web1","pid":1234,"commandHash":"abc",` +
+				`"start":"2023-11-14T22:13:20Z"}`,
+			expected: lockValue{
+				ExpiresAt:   1700000000,
+				Hostname:    "web1",
+				PID:         1234,
+				CommandHash: "abc",
+				Start:       time.Date(2023, 11, 14, 22, 13, 20, 0, time.UTC),
+			},
+		},
+		{
+			name:     "invalid",
+			value:    "garbage",
+			expected: lockValue{},
+		},
+		{
+			name:     "empty",
+			value:    "",
+			expected: lockValue{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := parseLockValue(tt.value); got != tt.expected {
+				t.Errorf("parseLockValue() failed, expected %+v, got %+v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestLockValueRoundTrip(t *testing.T) {
+	// Not parallel since t.Setenv() can't be used in parallel tests.
+	value := newLockValue("backup.sh --full", 1700000000)
+
+	tests := []struct {
+		json     string
+		expected lockValue
+	}{
+		{json: "", expected: lockValue{ExpiresAt: 1700000000}},
+		{json: "no", expected: lockValue{ExpiresAt: 1700000000}},
+		{json: "yes", expected: value},
+	}
+
+	for _, tt := range tests {
+		t.Run("json="+tt.json, func(t *testing.T) {
+			t.Setenv(envLockJSONValue, tt.json)
+
+			got := parseLockValue(value.String())
+			// Compare the start time separately since the JSON doesn't keep the monotonic clock reading.
+			if !got.Start.Equal(tt.expected.Start) {
+				t.Errorf("parseLockValue() failed, expected start %s, got %s", tt.expected.Start, got.Start)
+			}
+			got.Start, tt.expected.Start = time.Time{}, time.Time{}
+			if got != tt.expected {
+				t.Errorf("parseLockValue() failed, expected %+v, got %+v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestLockValueHolder(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		value    lockValue
+		expected string
+	}{
+		{value: lockValue{Hostname: "web1", PID: 1234}, expected: "web1:1234"},
+		{value: lockValue{ExpiresAt: 1700000000}, expected: "unknown"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expected, func(t *testing.T) {
+			t.Parallel()
+
+			if got := tt.value.holder(); got != tt.expected {
+				t.Errorf("holder() failed, expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
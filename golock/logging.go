@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/jim-barber-he/go/util"
+)
+
+// Log formats.
+const (
+	logFormatJSON string = "json"
+	logFormatText string = "text"
+)
+
+// jsonLogging is set when log lines are being written as JSON with structured fields attached to them.
+var jsonLogging bool
+
+// setupLogging configures the default logger based on the CRONLOCK_LOG_FORMAT and CRONLOCK_VERBOSE environment
+// variables.
+// In the JSON format the host and pid are attached to every log line.
+func setupLogging() {
+	level := slog.LevelInfo
+	if os.Getenv(envLockVerbose) == "yes" {
+		level = slog.LevelDebug
+	}
+
+	if util.GetEnv(envLockLogFormat, defLockLogFormat) != logFormatJSON {
+		slog.SetLogLoggerLevel(level)
+		return
+	}
+
+	jsonLogging = true
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+	handler := slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{Level: level})
+	slog.SetDefault(slog.New(handler).With("host", hostname, "pid", os.Getpid()))
+}
+
+// addLogFields attaches the supplied key/value pairs to all further log lines when logging as JSON.
+// The text format is left alone so that its output remains the same as it has always been.
+func addLogFields(args ...any) {
+	if jsonLogging {
+		slog.SetDefault(slog.Default().With(args...))
+	}
+}
+
+// logCompletion logs the exit code and how long the command took to run.
+// When logging as JSON this is always logged so that the log pipeline has a record of every run, otherwise it is only
+// shown as a debug message.
+func logCompletion(exitCode int, duration time.Duration) {
+	level := slog.LevelDebug
+	if jsonLogging {
+		level = slog.LevelInfo
+	}
+	slog.Log(
+		context.Background(), level, "Command completed",
+		slog.Int("exit_code", exitCode), slog.Float64("duration", duration.Seconds()),
+	)
+}
@@ -14,18 +14,25 @@ import (
 	"context"
 	"crypto/md5"
 	"crypto/tls"
+	"crypto/x509"
 	"encoding/hex"
 	"errors"
 	"fmt"
+	"io"
 	"log/slog"
+	"math"
+	"net"
 	"os"
 	"os/exec"
+	"os/signal"
 	"strconv"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/jim-barber-he/go/util"
 	redis "github.com/redis/go-redis/v9"
+	flag "github.com/spf13/pflag"
 )
 
 // Default Values.
@@ -43,35 +50,113 @@ const (
 	defLockPrefix            string = "cronlock."
 	defLockReset             string = "no"
 	defLockTimeout           int    = 0
+	defLockSlots             int    = 1
+	defLockSignalGrace       int    = 10
+	defLockLogFormat         string = logFormatText
+	defLockStatsdPrefix      string = "golock"
+	defLockHistory           int    = 0
+	defLockFencing           bool   = false
+	defLockMinInterval       int    = 0
+	defLockCaptureOutput     int    = 0
+	defLockFallback          string = fallbackFail
+	defLockBackend           string = backendRedis
+	defLockConsulAddr        string = "http://localhost:8500"
+	defLockEtcdEndpoints     string = "http://localhost:2379"
+	defLockHeartbeatInterval int    = 30
+	defLockPTY               bool   = false
+	defLockSplay             int    = 0
+
+	// The multiple of the p99 duration of recent runs to hold the lock for at most. 0 disables it.
+	defLockAdaptiveRelease float64 = 0
 )
 
 // Environment Variables.
 const (
+	envLockAdaptiveRelease   = "CRONLOCK_ADAPTIVE_RELEASE"
+	envLockAuth              = "CRONLOCK_AUTH"
+	envLockBackend           = "CRONLOCK_BACKEND"
+	envLockCaptureOutput     = "CRONLOCK_CAPTURE_OUTPUT"
+	envLockConsulAddr        = "CRONLOCK_CONSUL_ADDR"
 	envLockHost              = "CRONLOCK_HOST"
+	envLockPerHost           = "CRONLOCK_PER_HOST"
 	envLockPort              = "CRONLOCK_PORT"
 	envLockDB                = "CRONLOCK_DB"
+	envLockDryRun            = "CRONLOCK_DRY_RUN"
+	envLockExitFailure       = "CRONLOCK_EXIT_FAILURE"
+	envLockExitSuccess       = "CRONLOCK_EXIT_SUCCESS"
+	envLockExitTimeout       = "CRONLOCK_EXIT_TIMEOUT"
+	envLockEtcdEndpoints     = "CRONLOCK_ETCD_ENDPOINTS"
 	envLockTLS               = "CRONLOCK_TLS"
+	envLockTLSCA             = "CRONLOCK_TLS_CA"
+	envLockTLSCert           = "CRONLOCK_TLS_CERT"
+	envLockTLSKey            = "CRONLOCK_TLS_KEY"
 	envLockTLSSkipVerify     = "CRONLOCK_TLS_SKIP_VERIFY"
 	envLockRedisTimeout      = "CRONLOCK_REDIS_TIMEOUT"
 	envLockReconnectAttempts = "CRONLOCK_RECONNECT_ATTEMPTS"
 	envLockReconnectBackoff  = "CRONLOCK_RECONNECT_BACKOFF"
+	envLockFallback          = "CRONLOCK_FALLBACK"
+	envLockFencing           = "CRONLOCK_FENCING"
 	envLockGrace             = "CRONLOCK_GRACE"
+	envLockHealthAddr        = "CRONLOCK_HEALTH_ADDR"
+	envLockHeartbeatFile     = "CRONLOCK_HEARTBEAT_FILE"
+	envLockHeartbeatInterval = "CRONLOCK_HEARTBEAT_INTERVAL"
+	envLockHistory           = "CRONLOCK_HISTORY"
+	envLockNotifyURL         = "CRONLOCK_NOTIFY_URL"
+	envLockOutputDir         = "CRONLOCK_OUTPUT_DIR"
 	envLockRelease           = "CRONLOCK_RELEASE"
 	envLockPrefix            = "CRONLOCK_PREFIX"
+	envLockPTY               = "CRONLOCK_PTY"
 	envLockKey               = "CRONLOCK_KEY"
+	envLockJSONValue         = "CRONLOCK_JSON_VALUE"
+	envLockLocalDir          = "CRONLOCK_LOCAL_DIR"
+	envLockMinInterval       = "CRONLOCK_MIN_INTERVAL"
 	envLockReset             = "CRONLOCK_RESET"
 	envLockTimeout           = "CRONLOCK_TIMEOUT"
+	envLockUsername          = "CRONLOCK_USERNAME"
 	envLockVerbose           = "CRONLOCK_VERBOSE"
+	envLockSplay             = "CRONLOCK_SPLAY"
+	envLockSlots             = "CRONLOCK_SLOTS"
+	envLockSocket            = "CRONLOCK_SOCKET"
+	envLockSignalGrace       = "CRONLOCK_SIGNAL_GRACE"
+	envLockLogFormat         = "CRONLOCK_LOG_FORMAT"
+	envLockPushgatewayURL    = "CRONLOCK_PUSHGATEWAY_URL"
+	envLockStatsdAddr        = "CRONLOCK_STATSD_ADDR"
+	envLockStatsdPrefix      = "CRONLOCK_STATSD_PREFIX"
 )
 
 // Exit codes.
 // An exit code less than 200 means a lock was acquired and is the exit code of the command that was run.
-const (
-	exitSuccess int = 200 // Success. Delete succeeded OR lock not acquired, but normal execution.
-	exitFailure int = 201 // Failure. Error encountered.
-	exitTimeout int = 202 // Failure. Lock timed out.
+// They can be changed via environment variables by setupExitCodes().
+var (
+	exitSuccess = 200 // Success. Delete succeeded OR lock not acquired, but normal execution.
+	exitFailure = 201 // Failure. Error encountered.
+	exitTimeout = 202 // Failure. Lock timed out.
 )
 
+// Commandline options.
+type options struct {
+	dryRun     bool
+	history    bool
+	lastOutput bool
+	version    bool
+}
+
+// NewNoCACertsError creates a new error for when no CA certificates could be loaded from a file.
+func NewNoCACertsError(file string) error {
+	return &util.Error{
+		Msg:   "no CA certificates found in: ",
+		Param: file,
+	}
+}
+
+// NewUnknownBackendError creates a new error for when an unknown lock backend is selected.
+func NewUnknownBackendError(backend string) error {
+	return &util.Error{
+		Msg:   "unknown lock backend: ",
+		Param: backend,
+	}
+}
+
 func NewRedisPingError(response string) error {
 	return &util.Error{
 		Msg:   "could not ping Redis: ",
@@ -79,8 +164,39 @@ func NewRedisPingError(response string) error {
 	}
 }
 
+// getEnvDuration returns the value of a timing environment variable as a duration.
+// The value can be a duration string such as "90s" or "5m", or a bare integer number of seconds as used by cronlock.
+// defaultValue is in seconds.
+func getEnvDuration(envVar string, defaultValue int) time.Duration {
+	return util.GetEnvDuration(envVar, time.Duration(defaultValue)*time.Second)
+}
+
+// getEnvSeconds returns the value of a timing environment variable like getEnvDuration() does, but as a number of
+// seconds rounded up to a whole second.
+func getEnvSeconds(envVar string, defaultValue int) int {
+	return int(math.Ceil(getEnvDuration(envVar, defaultValue).Seconds()))
+}
+
+// setupExitCodes replaces golock's own exit codes with those set in the environment variables.
+// Values that aren't valid exit codes are ignored.
+func setupExitCodes() {
+	for envVar, code := range map[string]*int{
+		envLockExitFailure: &exitFailure,
+		envLockExitSuccess: &exitSuccess,
+		envLockExitTimeout: &exitTimeout,
+	} {
+		value := util.GetEnvInt(envVar, *code)
+		if value < 0 || value > 255 {
+			slog.Warn(fmt.Sprintf("Ignoring %s since %d is not a valid exit code", envVar, value))
+			continue
+		}
+		*code = value
+	}
+}
+
 // getRedisKey returns the name of the Redis key to use for the lock.
 // If not set via the environment, then one is calculated based on the MD5 hash of the command and its arguments.
+// If envLockPerHost is "yes" then the hostname is appended to the key so that the lock only applies to this host.
 func getRedisKey(lockPrefix, command string) string {
 	redisKey := os.Getenv(envLockKey)
 	if redisKey == "" {
@@ -88,39 +204,136 @@ func getRedisKey(lockPrefix, command string) string {
 		redisKey = hex.EncodeToString(hash[:])
 	}
 
+	if os.Getenv(envLockPerHost) == "yes" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			hostname = "unknown"
+		}
+		redisKey += "." + hostname
+	}
+
 	return lockPrefix + redisKey
 }
 
 // getRedisOptions returns a redis.Options struct with the values set from the environment variables.
-func getRedisOptions() *redis.Options {
-	redisReconnectBackoff := time.Second * time.Duration(
-		util.GetEnvInt(envLockReconnectBackoff, defLockReconnectBackoff),
-	)
+func getRedisOptions() (*redis.Options, error) {
+	redisReconnectBackoff := getEnvDuration(envLockReconnectBackoff, defLockReconnectBackoff)
 	opts := &redis.Options{
-		Addr: fmt.Sprintf(
-			"%s:%d", util.GetEnv(envLockHost, defLockHost), util.GetEnvInt(envLockPort, defLockPort),
-		),
+		Addr:            redisAddrs()[0],
 		DB:              util.GetEnvInt(envLockDB, defLockDB),
-		DialTimeout:     time.Second * time.Duration(util.GetEnvInt(envLockRedisTimeout, defLockRedisTimeout)),
+		DialTimeout:     getEnvDuration(envLockRedisTimeout, defLockRedisTimeout),
 		MaxRetries:      util.GetEnvInt(envLockReconnectAttempts, defLockReconnectAttempts),
 		MaxRetryBackoff: redisReconnectBackoff,
 		MinRetryBackoff: redisReconnectBackoff,
 	}
-	if auth := os.Getenv("CRONLOCK_AUTH"); auth != "" {
+	// A Unix domain socket takes precedence over the host and port.
+	if socket := os.Getenv(envLockSocket); socket != "" {
+		opts.Network = "unix"
+		opts.Addr = socket
+	}
+	// Redis 6+ ACLs authenticate with a username as well as the password.
+	if username := os.Getenv(envLockUsername); username != "" {
+		opts.Username = username
+	}
+	if auth := os.Getenv(envLockAuth); auth != "" {
 		opts.Password = auth
 	}
 	if tlsEnabled := util.GetEnvBool(envLockTLS, defLockTLS); tlsEnabled {
-		opts.TLSConfig = &tls.Config{
-			MinVersion:         tls.VersionTLS12,
-			InsecureSkipVerify: util.GetEnvBool(envLockTLSSkipVerify, defLockTLSSkipVerify),
+		tlsConfig, err := getTLSConfig()
+		if err != nil {
+			return nil, err
+		}
+		opts.TLSConfig = tlsConfig
+	}
+
+	return opts, nil
+}
+
+// getTLSConfig returns the TLS configuration for connecting to Redis based on the environment variables.
+// A client certificate and key can be supplied for mutual TLS, and a CA bundle can be supplied to verify the server's
+// certificate against instead of the system's CA certificates.
+func getTLSConfig() (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: util.GetEnvBool(envLockTLSSkipVerify, defLockTLSSkipVerify),
+	}
+
+	certFile := os.Getenv(envLockTLSCert)
+	keyFile := os.Getenv(envLockTLSKey)
+	if certFile != "" || keyFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load TLS client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	if caFile := os.Getenv(envLockTLSCA); caFile != "" {
+		caCerts, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read TLS CA file: %w", err)
+		}
+		certPool := x509.NewCertPool()
+		if !certPool.AppendCertsFromPEM(caCerts) {
+			return nil, NewNoCACertsError(caFile)
+		}
+		tlsConfig.RootCAs = certPool
+	}
+
+	return tlsConfig, nil
+}
+
+// errRedisReplica is returned when connecting to a Redis server that is a replica, since locks can't be written to it.
+var errRedisReplica = errors.New("redis server is a replica")
+
+// errInterrupted is logged when golock is signalled before the command has started.
+var errInterrupted = errors.New("interrupted before the command was started")
+
+// redisAddrs returns the addresses of the Redis servers in CRONLOCK_HOST, which can be a comma separated list.
+// Each host can have its own port, otherwise CRONLOCK_PORT is used.
+func redisAddrs() []string {
+	port := strconv.Itoa(util.GetEnvInt(envLockPort, defLockPort))
+
+	var addrs []string
+	for _, host := range strings.Split(util.GetEnv(envLockHost, defLockHost), ",") {
+		host = strings.TrimSpace(host)
+		if _, _, err := net.SplitHostPort(host); err != nil {
+			host = net.JoinHostPort(host, port)
 		}
+		addrs = append(addrs, host)
 	}
 
-	return opts
+	return addrs
 }
 
 // redisConnect connects to a Redis server with the supplied options and returns a client.
+// Unless a Unix domain socket is used, each of the servers in CRONLOCK_HOST is tried in order until one of them that
+// isn't a replica can be connected to.
 func redisConnect(ctx context.Context, connOpts *redis.Options) (*redis.Client, error) {
+	if connOpts.Network == "unix" {
+		return redisConnectAddr(ctx, connOpts)
+	}
+
+	addrs := redisAddrs()
+	var err error
+	for i, addr := range addrs {
+		opts := *connOpts
+		opts.Addr = addr
+
+		var rdb *redis.Client
+		if rdb, err = redisConnectAddr(ctx, &opts); err == nil {
+			return rdb, nil
+		}
+		if i < len(addrs)-1 {
+			slog.Warn(err.Error())
+		}
+	}
+
+	return nil, err
+}
+
+// redisConnectAddr connects to the Redis server at the address in the supplied options and returns a client.
+func redisConnectAddr(ctx context.Context, connOpts *redis.Options) (*redis.Client, error) {
 	slog.Debug("Connecting to redis at " + connOpts.Addr)
 
 	rdb := redis.NewClient(connOpts)
@@ -128,11 +341,19 @@ func redisConnect(ctx context.Context, connOpts *redis.Options) (*redis.Client,
 	response, err := rdb.Ping(ctx).Result()
 	switch {
 	case err != nil:
-		return nil, fmt.Errorf("could not connect to Redis: %w", err)
+		rdb.Close()
+		return nil, fmt.Errorf("could not connect to Redis at %s: %w", connOpts.Addr, err)
 	case response != "PONG":
+		rdb.Close()
 		return nil, NewRedisPingError(response)
 	}
 
+	// Skip over replicas left behind by a failover. Servers that don't allow the ROLE command are assumed to be fine.
+	if role, err := rdb.Do(ctx, "ROLE").Slice(); err == nil && len(role) > 0 && role[0] == "slave" {
+		rdb.Close()
+		return nil, fmt.Errorf("could not use Redis at %s: %w", connOpts.Addr, errRedisReplica)
+	}
+
 	return rdb, nil
 }
 
@@ -159,32 +380,187 @@ func resetKey(ctx context.Context, rdb *redis.Client, redisKey string) int {
 	return 0
 }
 
-func run() int {
+// acquireLock tries to acquire an exclusive lock on redisKey that will expire at value.ExpiresAt at the latest.
+// It returns false without an error if another process holds the lock.
+func acquireLock(
+	ctx context.Context, rdb *redis.Client, redisKey string, value lockValue, lockRelease int,
+) (bool, error) {
+	slog.Debug(fmt.Sprintf("Acquiring lock on %s key", redisKey))
+	acquired, err := rdb.SetNX(ctx, redisKey, value.String(), time.Duration(lockRelease)*time.Second).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to set lock: %w", err)
+	}
+
+	if acquired {
+		slog.Debug(fmt.Sprintf("Lock %s acquired", redisKey))
+
+		return true, nil
+	}
+
+	// Handle edge cases.
+
+	current, err := rdb.Get(ctx, redisKey).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to get expiration time: %w", err)
+	}
+	held := parseLockValue(current)
+	expiresIn := held.expiresIn()
+
+	switch {
+	case expiresIn > 0:
+		slog.Debug(fmt.Sprintf(
+			"Lock %s acquired by another process %s (expires in %ds)", redisKey, held.holder(), expiresIn,
+		))
+
+		return false, nil
+	case expiresIn == 0:
+		slog.Debug(fmt.Sprintf(
+			"Lock %s acquired by another process %s but expiring now", redisKey, held.holder(),
+		))
+
+		return false, nil
+	default:
+		slog.Debug(fmt.Sprintf(
+			"Lock %s acquired by another process %s but expired %ds ago", redisKey, held.holder(), -expiresIn,
+		))
+	}
+
+	// Handle expired locks that were not cleaned up properly or not cleaned up yet because the golock that
+	// requested it is still running.
+	// Try to acquire a lock again, confirming that no other running golock beats us to it.
+	reacquire, err := rdb.GetSet(ctx, redisKey, value.String()).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to acquire lock: %w", err)
+	}
+	held = parseLockValue(reacquire)
+	if expiresIn = held.expiresIn(); expiresIn > 0 {
+		slog.Debug(fmt.Sprintf(
+			"Lock %s was just now acquired by a different process %s (expires in %ds)",
+			redisKey, held.holder(), expiresIn,
+		))
+
+		return false, nil
+	}
+
+	return true, nil
+}
+
+// checkLockHeld returns an error if the lock on redisKey is no longer held with value.
+func checkLockHeld(ctx context.Context, rdb *redis.Client, redisKey string, value lockValue) error {
+	current, err := rdb.Get(ctx, redisKey).Result()
+	if errors.Is(err, redis.Nil) {
+		return errLockLost
+	}
+	if err != nil {
+		return fmt.Errorf("failed to check lock: %w", err)
+	}
+	if held := parseLockValue(current); held.ExpiresAt != value.ExpiresAt || held.expiresIn() < 0 {
+		return errLockLost
+	}
+
+	return nil
+}
+
+// releaseLock sets the lock on redisKey to expire once the minimum grace period at value.ExpiresAt has passed.
+func releaseLock(ctx context.Context, rdb *redis.Client, redisKey string, value lockValue) {
+	// Set the value of the key to the timestamp defined by the minimum grace period.
+	// This is for the benefit of other instances of golock trying to acquire a lock and being able to say when the
+	// current one is expiring.
+	slog.Debug(fmt.Sprintf("Lock %s set minimum grace period to: %d", redisKey, value.ExpiresAt))
+	_, _ = rdb.GetSet(ctx, redisKey, value.String()).Result()
+
+	// Set the key to expire after the minimum grace period has passed.
+	slog.Debug(fmt.Sprintf("Lock %s set to expire at: %d", redisKey, value.ExpiresAt))
+	_ = rdb.ExpireAt(ctx, redisKey, time.Unix(value.ExpiresAt, 0))
+}
+
+func run(opts options, args []string) int {
 	ctx := context.Background()
 
+	wd := startWatchdog()
+	defer wd.stop()
+
+	// Command to run and its arguments represented as a string.
+	command := strings.Join(args, " ")
+
+	// The key to use in Redis.
+	redisKey := getRedisKey(util.GetEnv(envLockPrefix, defLockPrefix), command)
+	addLogFields("key", redisKey)
+	metrics := newMetricsRecorder(redisKey)
+
+	if backend := util.GetEnv(envLockBackend, defLockBackend); backend != backendRedis {
+		return runWithBackend(ctx, backend, opts, command, args, redisKey, metrics, wd)
+	}
+
+	isDryRun := opts.dryRun || os.Getenv(envLockDryRun) == "yes"
+	isReset := util.GetEnv(envLockReset, defLockReset) == "yes"
+
 	// Connect to Redis.
-	rdb, err := redisConnect(ctx, getRedisOptions())
+	redisOpts, err := getRedisOptions()
 	if err != nil {
 		slog.Error(err.Error())
 
 		return exitFailure
 	}
+	rdb, err := redisConnect(ctx, redisOpts)
+	if err != nil {
+		slog.Error(err.Error())
+
+		// The fallback policy only applies to actually running the command.
+		if opts.history || opts.lastOutput || isDryRun || isReset {
+			return exitFailure
+		}
+
+		return runWithFallback(command, args, redisKey, metrics, wd)
+	}
 	defer rdb.Close()
+	// The watchdog may be using the client to check the lock is held, so stop it before the client is closed.
+	defer wd.stop()
 
-	// Command to run and its arguments represented as a string.
-	command := strings.Join(os.Args[1:], " ")
+	if opts.history || opts.lastOutput {
+		if err := showHistory(ctx, rdb, redisKey, opts.lastOutput); err != nil {
+			slog.Error(err.Error())
 
-	// The key to use in Redis.
-	redisKey := getRedisKey(util.GetEnv(envLockPrefix, defLockPrefix), command)
+			return exitFailure
+		}
+
+		return exitSuccess
+	}
+
+	if isDryRun && isReset {
+		slog.Info(fmt.Sprintf("Dry run: lock %s would be removed", redisKey))
+
+		return exitSuccess
+	}
 
 	// If envLockReset is true, this will remove redisKey from Redis and return a 2xx code.
 	if ret := resetKey(ctx, rdb, redisKey); ret != 0 {
 		return ret
 	}
 
+	// Catch SIGINT and SIGTERM from here until the lock has been released, so that golock can't be stopped while it
+	// holds the lock. A signal received before the command starts means that it isn't run, and the lock is released.
+	// While the command runs, the signals are forwarded to it instead.
+	signalled, stopSignals := signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM)
+	defer stopSignals()
+
+	if !isDryRun {
+		sleepSplay(signalled)
+		if signalled.Err() != nil {
+			slog.Warn(errInterrupted.Error())
+
+			return exitFailure
+		}
+	}
+
+	// The number of processes that may hold the lock at the same time.
+	// More than 1 switches to semaphore mode where each holder is tracked by its own identity.
+	lockSlots := util.GetEnvInt(envLockSlots, defLockSlots)
+	holder := holderID()
+
 	// Control how long the lock is held for.
-	lockGrace := util.GetEnvInt(envLockGrace, defLockGrace)
-	lockRelease := util.GetEnvInt(envLockRelease, defLockRelease)
+	lockGrace := getEnvSeconds(envLockGrace, defLockGrace)
+	lockRelease := adaptiveRelease(ctx, rdb, redisKey, getEnvSeconds(envLockRelease, defLockRelease), lockGrace)
 
 	// Times that the lock will be completed.
 	// expireAtMax is used when the lock is acquired to set the longest time we want to keep it for.
@@ -193,101 +569,238 @@ func run() int {
 	expireAtMax := time.Now().UTC().Unix() + int64(lockRelease) + 1
 	expireAtMin := time.Now().UTC().Unix() + int64(lockGrace) + 1
 
+	// The minimum number of seconds between the end of a successful run and the start of the next one.
+	minInterval := getEnvSeconds(envLockMinInterval, defLockMinInterval)
+
+	if isDryRun {
+		return dryRun(ctx, rdb, redisKey, command, lockSlots, lockRelease, lockGrace, minInterval)
+	}
+
 	// Acquire lock.
-	slog.Debug(fmt.Sprintf("Acquiring lock on %s key", redisKey))
-	acquired, err := rdb.SetNX(ctx, redisKey, expireAtMax, time.Duration(lockRelease)*time.Second).Result()
+	notifySystemd("STATUS=Acquiring lock " + redisKey)
+	value := newLockValue(command, expireAtMax)
+	var acquired bool
+	if lockSlots > 1 {
+		acquired, err = acquireSlot(ctx, rdb, redisKey, holder, lockSlots, expireAtMax)
+	} else {
+		acquired, err = acquireLock(ctx, rdb, redisKey, value, lockRelease)
+	}
 	if err != nil {
 		slog.Error(err.Error())
 
 		return exitFailure
 	}
+	if !acquired {
+		metrics.outcome(outcomeSkipped)
+		notifySystemd(fmt.Sprintf("STATUS=Lock %s is held by another process", redisKey))
 
-	if acquired {
-		slog.Debug(fmt.Sprintf("Lock %s acquired", redisKey))
-	} else {
-		// Handle edge cases.
+		return exitSuccess
+	}
+
+	// release sets the lock to expire once the minimum grace period has passed.
+	// The watchdog is stopped first so that it doesn't report the lock as lost once it has been released.
+	release := func() {
+		wd.stop()
+		if lockSlots > 1 {
+			if err := releaseSlot(ctx, rdb, redisKey, holder, expireAtMin); err != nil {
+				slog.Error(err.Error())
+			}
+		} else {
+			released := value
+			released.ExpiresAt = expireAtMin
+			releaseLock(ctx, rdb, redisKey, released)
+		}
+	}
 
-		expiresAt, err := rdb.Get(ctx, redisKey).Result()
+	// Refuse to run the command if it last ran successfully within the minimum interval between runs.
+	if minInterval > 0 {
+		tooRecent, err := ranTooRecently(ctx, rdb, redisKey)
 		if err != nil {
-			slog.Error(fmt.Errorf("failed to get expiration time: %w", err).Error())
+			slog.Error(err.Error())
+			release()
 
 			return exitFailure
 		}
-		expiresIn, _ := strconv.Atoi(expiresAt)
-		expiresIn -= int(time.Now().UTC().Unix())
-
-		switch {
-		case expiresIn > 0:
-			slog.Debug(fmt.Sprintf(
-				"Lock %s acquired by another process (expires in %ds)", redisKey, expiresIn,
-			))
+		if tooRecent {
+			metrics.outcome(outcomeSkipped)
+			release()
 
 			return exitSuccess
-		case expiresIn == 0:
-			slog.Debug(fmt.Sprintf("Lock %s acquired by another process but expiring now", redisKey))
-
-			return exitSuccess
-		default:
-			slog.Debug(fmt.Sprintf(
-				"Lock %s acquired by another process but expired %ds ago", redisKey, -expiresIn,
-			))
 		}
+	}
+	metrics.outcome(outcomeAcquired)
 
-		// Handle expired locks that were not cleaned up properly or not cleaned up yet because the golock that
-		// requested it is still running.
-		// Try to acquire a lock again, confirming that no other running golock beats us to it.
-		reacquire, err := rdb.GetSet(ctx, redisKey, expireAtMax).Result()
-		if err != nil {
-			slog.Error(fmt.Errorf("failed to acquire lock: %w", err).Error())
+	if util.GetEnvBool(envLockFencing, defLockFencing) {
+		if err := setFencingToken(ctx, rdb, redisKey); err != nil {
+			slog.Error(err.Error())
+			release()
 
 			return exitFailure
 		}
-		expiresIn, _ = strconv.Atoi(reacquire)
-		expiresIn -= int(time.Now().UTC().Unix())
-		if expiresIn > 0 {
-			slog.Debug(fmt.Sprintf(
-				"Lock %s was just now acquired by a different process (expires in %ds)",
-				redisKey,
-				expiresIn,
-			))
+	}
 
-			return exitSuccess
+	// Don't start the command if golock was signalled while acquiring the lock or getting ready to run it.
+	if signalled.Err() != nil {
+		slog.Warn(errInterrupted.Error())
+		release()
+
+		return exitFailure
+	}
+
+	// Only keep systemd's watchdog happy while the lock is still held.
+	// The check gets its own copy of the lock value since it runs in the watchdog's goroutine.
+	held := value
+	wd.checkLock(func(ctx context.Context) error {
+		if lockSlots > 1 {
+			return checkSlotHeld(ctx, rdb, redisKey, holder)
+		}
+		return checkLockHeld(ctx, rdb, redisKey, held)
+	})
+
+	result := runCommand(command, args, redisKey, metrics, wd)
+
+	if minInterval > 0 && result.exitCode == 0 {
+		if err := recordLastRun(ctx, rdb, redisKey, minInterval); err != nil {
+			slog.Warn(err.Error())
 		}
 	}
 
-	// Run command with an optional timeout.
-	timeout := util.GetEnvInt(envLockTimeout, defLockTimeout)
-	exitCode, err := util.RunWithTimeout(timeout, os.Args[1], os.Args[2:]...)
-	if timeout > 0 && exitCode == util.ExitCodeProcessKilled {
+	if util.GetEnvFloat(envLockAdaptiveRelease, defLockAdaptiveRelease) > 0 && result.exitCode == 0 {
+		if err := recordDuration(ctx, rdb, redisKey, result.duration); err != nil {
+			slog.Warn(err.Error())
+		}
+	}
+
+	if historySize := util.GetEnvInt(envLockHistory, defLockHistory); historySize > 0 {
+		record := newRunRecord(result.start, result.duration, result.exitCode)
+		if result.output != nil {
+			record.Output = result.output.String()
+		}
+		if err := recordHistory(ctx, rdb, redisKey, record, historySize); err != nil {
+			slog.Warn(err.Error())
+		}
+	}
+
+	// Command is complete. We can set the key to expire once the minimum grace period has passed.
+	release()
+
+	return result.exitCode
+}
+
+// commandResult holds the details of a run of the command.
+type commandResult struct {
+	exitCode int
+	start    time.Time
+	duration time.Duration
+	output   *util.TailBuffer
+}
+
+// runCommand runs the command with an optional timeout, then logs, reports, and notifies on how it went.
+// SIGINT and SIGTERM are forwarded to the command so that we stay around to release the lock once it has exited.
+func runCommand(
+	command string, args []string, redisKey string, metrics *metricsRecorder, wd *watchdog,
+) commandResult {
+	var result commandResult
+
+	timeout := getEnvSeconds(envLockTimeout, defLockTimeout)
+	signalGrace := getEnvSeconds(envLockSignalGrace, defLockSignalGrace)
+
+	// Optionally keep the tail of the command's output to store with the run.
+	var outputWriter io.Writer
+	if captureSize := util.GetEnvInt(envLockCaptureOutput, defLockCaptureOutput); captureSize > 0 {
+		result.output = &util.TailBuffer{Size: captureSize}
+		outputWriter = result.output
+	}
+
+	hb := startHeartbeat(redisKey, command)
+	started := func(pid int) {
+		hb.setPID(pid)
+		wd.commandStarted(timeout, signalGrace)
+		notifySystemd(fmt.Sprintf("READY=1\nSTATUS=Running [%s] as PID %d for lock %s", command, pid, redisKey))
+	}
+
+	result.start = time.Now()
+	exitCode, err := util.RunWithTimeoutAndSignals(util.RunOptions{
+		Timeout:     timeout,
+		GracePeriod: signalGrace,
+		Output:      outputWriter,
+		Started:     started,
+		PTY:         util.GetEnvBool(envLockPTY, defLockPTY),
+		Env:         commandEnv(),
+	}, args[0], args[1:]...)
+	result.duration = time.Since(result.start)
+	hb.stop()
+	notifySystemd(fmt.Sprintf("STOPPING=1\nSTATUS=[%s] exited with code %d", command, exitCode))
+
+	timedOut := errors.Is(err, util.ErrCommandTimedOut)
+	switch {
+	case timedOut:
 		slog.Error(fmt.Sprintf("emergency: had to kill [%s] after %ds timeout", command, timeout))
 		exitCode = exitTimeout
+		metrics.outcome(outcomeTimeout)
+	case errors.Is(err, util.ErrCommandKilled):
+		slog.Error(fmt.Sprintf("emergency: had to kill [%s] after %ds signal grace period", command, signalGrace))
 	}
 	// Show any errors from trying to run the command that weren't from the command itself.
 	var exitError *exec.ExitError
-	if !errors.As(err, &exitError) {
+	if err != nil && !errors.As(err, &exitError) {
 		slog.Error(err.Error())
 	}
+	result.exitCode = exitCode
 
-	// Command is complete. We can set the key to expire once the minimum grace period has passed.
-
-	// Set the value of the key to the timestamp defined by the minimum grace period.
-	// This is for the benefit of other instances of golock trying to acquire a lock and being able to say when the
-	// current one is expiring.
-	slog.Debug(fmt.Sprintf("Lock %s set minimum grace period to: %d", redisKey, expireAtMin))
-	_, _ = rdb.GetSet(ctx, redisKey, expireAtMin).Result()
+	logCompletion(exitCode, result.duration)
+	metrics.command(exitCode, result.duration)
+	// The exit code for a timeout can be set to 0 with CRONLOCK_EXIT_TIMEOUT, but a timeout is still a failure.
+	if exitCode != 0 || timedOut {
+		notifyFailure(command, redisKey, exitCode, timedOut)
+	}
 
-	// Set the key to expire after the minimum grace period has passed.
-	slog.Debug(fmt.Sprintf("Lock %s set to expire at: %d", redisKey, expireAtMin))
-	_ = rdb.ExpireAt(ctx, redisKey, time.Unix(expireAtMin, 0))
+	if outputDir := os.Getenv(envLockOutputDir); outputDir != "" && result.output != nil {
+		if err := writeOutputFile(outputDir, redisKey, result.start, result.output.String()); err != nil {
+			slog.Warn(err.Error())
+		}
+	}
 
-	return exitCode
+	return result
 }
 
 func main() {
-	if os.Getenv(envLockVerbose) == "yes" {
-		slog.SetLogLoggerLevel(slog.LevelDebug)
+	var opts options
+
+	flag.BoolVar(&opts.dryRun, "dry-run", false, "Show whether the lock would be acquired without running the command")
+	flag.BoolVar(&opts.history, "history", false, "Show the run history of the lock for the command instead of running it")
+	// Stop parsing flags at the command so that its own flags are left alone.
+	flag.CommandLine.SetInterspersed(false)
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s [flags] COMMAND [ARGS...]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s locks list|show KEY|release KEY\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.BoolVar(
+		&opts.lastOutput, "last-output", false, "Show the captured output of the last run of the command in the history",
+	)
+	flag.BoolVar(&opts.version, "version", false, "Show the version of golock and exit")
+	flag.Parse()
+
+	// Logging is set up first so that any warnings about the exit codes use the configured format and level.
+	setupLogging()
+	setupExitCodes()
+
+	if opts.version {
+		util.DisplayVersion("golock")
+		os.Exit(0)
+	}
+
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(exitFailure)
+	}
+
+	// The locks subcommand is used to manage the locks, unless `--` was used to say it is the command to run.
+	if flag.Arg(0) == "locks" && flag.CommandLine.ArgsLenAtDash() == -1 {
+		os.Exit(runLocks(flag.Args()[1:]))
 	}
 
-	exitCode := run()
+	exitCode := run(opts, flag.Args())
 	os.Exit(exitCode)
 }
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestGetEnvSeconds(t *testing.T) {
+	// Not parallel since t.Setenv() can't be used in parallel tests.
+	const defaultValue = 30
+
+	tests := []struct {
+		value    string
+		expected int
+	}{
+		{value: "90", expected: 90},
+		{value: "0", expected: 0},
+		{value: "90s", expected: 90},
+		{value: "5m", expected: 300},
+		{value: "1500ms", expected: 2},
+		{value: "1ms", expected: 1},
+		{value: "", expected: defaultValue},
+		{value: "soon", expected: defaultValue},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			t.Setenv(envLockSplay, tt.value)
+
+			if got := getEnvSeconds(envLockSplay, defaultValue); got != tt.expected {
+				t.Errorf("getEnvSeconds() failed, expected %d, got %d", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestGetRedisKey(t *testing.T) {
+	// Not parallel since t.Setenv() can't be used in parallel tests.
+	hostname, err := os.Hostname()
+	if err != nil {
+		t.Fatalf("os.Hostname() failed: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		key      string
+		perHost  string
+		expected string
+	}{
+		{
+			name:     "hash of the command",
+			expected: "cronlock.f6741581854ed8dffffd2cff6c1edb18",
+		},
+		{
+			name:     "key from the environment",
+			key:      "backup",
+			expected: "cronlock.backup",
+		},
+		{
+			name:     "per host",
+			key:      "backup",
+			perHost:  "yes",
+			expected: "cronlock.backup." + hostname,
+		},
+		{
+			name:     "per host not enabled",
+			key:      "backup",
+			perHost:  "no",
+			expected: "cronlock.backup",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(envLockKey, tt.key)
+			t.Setenv(envLockPerHost, tt.perHost)
+
+			if got := getRedisKey(defLockPrefix, "backup.sh --full"); got != tt.expected {
+				t.Errorf("getRedisKey() failed, expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestRedisAddrs(t *testing.T) {
+	// Not parallel since t.Setenv() can't be used in parallel tests.
+	tests := []struct {
+		name     string
+		host     string
+		port     string
+		expected []string
+	}{
+		{
+			name:     "default port",
+			host:     "localhost",
+			expected: []string{"localhost:6379"},
+		},
+		{
+			name:     "single host with port",
+			host:     "redis.example.com",
+			port:     "6380",
+			expected: []string{"redis.example.com:6380"},
+		},
+		{
+			name:     "failover hosts in order",
+			host:     "redis-a, redis-b:6390 ,redis-c",
+			expected: []string{"redis-a:6379", "redis-b:6390", "redis-c:6379"},
+		},
+		{
+			name:     "IPv6 hosts",
+			host:     "::1,[fe80::1]:6390",
+			port:     "6380",
+			expected: []string{"[::1]:6380", "[fe80::1]:6390"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(envLockHost, tt.host)
+			t.Setenv(envLockPort, tt.port)
+
+			if got := redisAddrs(); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("redisAddrs() failed, expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestSetupExitCodes(t *testing.T) {
+	// Not parallel since t.Setenv() can't be used in parallel tests, and the exit codes are global.
+	tests := []struct {
+		name            string
+		failure         string
+		success         string
+		timeout         string
+		expectedFailure int
+		expectedSuccess int
+		expectedTimeout int
+	}{
+		{
+			name:            "defaults",
+			expectedFailure: 201,
+			expectedSuccess: 200,
+			expectedTimeout: 202,
+		},
+		{
+			name:            "remapped",
+			failure:         "1",
+			success:         "0",
+			timeout:         "124",
+			expectedFailure: 1,
+			expectedSuccess: 0,
+			expectedTimeout: 124,
+		},
+		{
+			name:            "invalid exit codes are ignored",
+			failure:         "256",
+			success:         "-1",
+			timeout:         "slow",
+			expectedFailure: 201,
+			expectedSuccess: 200,
+			expectedTimeout: 202,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			failure, success, timeout := exitFailure, exitSuccess, exitTimeout
+			t.Cleanup(func() {
+				exitFailure, exitSuccess, exitTimeout = failure, success, timeout
+			})
+			t.Setenv(envLockExitFailure, tt.failure)
+			t.Setenv(envLockExitSuccess, tt.success)
+			t.Setenv(envLockExitTimeout, tt.timeout)
+
+			setupExitCodes()
+
+			if exitFailure != tt.expectedFailure {
+				t.Errorf("setupExitCodes() failed, expected failure %d, got %d", tt.expectedFailure, exitFailure)
+			}
+			if exitSuccess != tt.expectedSuccess {
+				t.Errorf("setupExitCodes() failed, expected success %d, got %d", tt.expectedSuccess, exitSuccess)
+			}
+			if exitTimeout != tt.expectedTimeout {
+				t.Errorf("setupExitCodes() failed, expected timeout %d, got %d", tt.expectedTimeout, exitTimeout)
+			}
+		})
+	}
+}
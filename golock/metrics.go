@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/jim-barber-he/go/util"
+)
+
+// Outcomes of trying to run a command that are counted as metrics.
+const (
+	outcomeAcquired string = "acquired"
+	outcomeSkipped  string = "skipped"
+	outcomeTimeout  string = "timeout"
+)
+
+// metricsTimeout is how long to wait on the metrics endpoints before giving up on them.
+const metricsTimeout = 5 * time.Second
+
+// statsdNameReplacer replaces the characters that have special meaning to statsd in a metric name.
+var statsdNameReplacer = strings.NewReplacer(".", "_", ":", "_", "|", "_", "@", "_", "/", "_", " ", "_")
+
+// metricsRecorder sends metrics about the runs of golock to statsd and/or a Prometheus Pushgateway.
+// Failing to send metrics is logged, but never affects running the command.
+type metricsRecorder struct {
+	hostname       string
+	key            string
+	pushgatewayURL string
+	statsdAddr     string
+	statsdPrefix   string
+}
+
+// newMetricsRecorder returns a metricsRecorder for the lock key configured via the environment variables.
+// If neither statsd or the Pushgateway is configured, then the metricsRecorder does nothing.
+func newMetricsRecorder(key string) *metricsRecorder {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+
+	return &metricsRecorder{
+		hostname:       hostname,
+		key:            key,
+		pushgatewayURL: strings.TrimRight(os.Getenv(envLockPushgatewayURL), "/"),
+		statsdAddr:     os.Getenv(envLockStatsdAddr),
+		statsdPrefix:   util.GetEnv(envLockStatsdPrefix, defLockStatsdPrefix),
+	}
+}
+
+// outcome counts an outcome of trying to run the command.
+// For the Pushgateway, which can't count, it records the time the outcome last happened instead.
+func (m *metricsRecorder) outcome(name string) {
+	m.sendStatsd(fmt.Sprintf("%s:1|c", m.statsdName(name)))
+	m.push(map[string]float64{
+		fmt.Sprintf("golock_last_%s_timestamp_seconds", name): float64(time.Now().Unix()),
+	})
+}
+
+// command records how long the command ran for and its exit code.
+func (m *metricsRecorder) command(exitCode int, duration time.Duration) {
+	m.sendStatsd(
+		fmt.Sprintf("%s:%d|ms", m.statsdName("duration"), duration.Milliseconds()),
+		fmt.Sprintf("%s:%d|g", m.statsdName("exit_code"), exitCode),
+	)
+	m.push(map[string]float64{
+		"golock_last_duration_seconds": duration.Seconds(),
+		"golock_last_exit_code":        float64(exitCode),
+	})
+}
+
+// statsdName returns the full statsd metric name for a metric of the lock.
+func (m *metricsRecorder) statsdName(name string) string {
+	return fmt.Sprintf("%s.%s.%s", m.statsdPrefix, statsdNameReplacer.Replace(m.key), name)
+}
+
+// sendStatsd sends the supplied metric lines to statsd in a single UDP packet.
+func (m *metricsRecorder) sendStatsd(lines ...string) {
+	if m.statsdAddr == "" {
+		return
+	}
+
+	conn, err := net.DialTimeout("udp", m.statsdAddr, metricsTimeout)
+	if err != nil {
+		slog.Warn(fmt.Sprintf("Failed to connect to statsd at %s: %v", m.statsdAddr, err))
+		return
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte(strings.Join(lines, "\n"))); err != nil {
+		slog.Warn(fmt.Sprintf("Failed to send metrics to statsd at %s: %v", m.statsdAddr, err))
+	}
+}
+
+// push sends the supplied gauges to the Pushgateway, grouped by the lock key and the host.
+// A POST is used so that only the gauges with the same names are replaced in the group.
+func (m *metricsRecorder) push(gauges map[string]float64) {
+	if m.pushgatewayURL == "" {
+		return
+	}
+
+	var body bytes.Buffer
+	for name, value := range gauges {
+		fmt.Fprintf(&body, "# TYPE %s gauge\n%s %v\n", name, name, value)
+	}
+
+	// The key is base64 encoded since a CRONLOCK_KEY may contain slashes.
+	url := fmt.Sprintf(
+		"%s/metrics/job/golock/key@base64/%s/instance/%s",
+		m.pushgatewayURL, base64.URLEncoding.EncodeToString([]byte(m.key)), m.hostname,
+	)
+
+	ctx, cancel := context.WithTimeout(context.Background(), metricsTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, &body)
+	if err != nil {
+		slog.Warn(fmt.Sprintf("Failed to create Pushgateway request: %v", err))
+		return
+	}
+	req.Header.Set("Content-Type", "text/plain; version=0.0.4")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		slog.Warn(fmt.Sprintf("Failed to push metrics to %s: %v", m.pushgatewayURL, err))
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		slog.Warn(fmt.Sprintf("Failed to push metrics to %s: %s", m.pushgatewayURL, resp.Status))
+	}
+}
@@ -0,0 +1,27 @@
+package main
+
+import "testing"
+
+func TestStatsdName(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		key      string
+		expected string
+	}{
+		{key: "cronlock.backup", expected: "golock.cronlock_backup.duration"},
+		{key: "cronlock.jobs/nightly report", expected: "golock.cronlock_jobs_nightly_report.duration"},
+		{key: "cronlock.a:b|c@d", expected: "golock.cronlock_a_b_c_d.duration"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			t.Parallel()
+
+			m := &metricsRecorder{key: tt.key, statsdPrefix: defLockStatsdPrefix}
+			if got := m.statsdName("duration"); got != tt.expected {
+				t.Errorf("statsdName() failed, expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
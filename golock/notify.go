@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"time"
+)
+
+// notifyTimeout is how long to wait on the notification webhook before giving up on it.
+const notifyTimeout = 10 * time.Second
+
+// notification is the JSON payload sent to the notification webhook.
+// The Text field makes the payload suitable for a Slack incoming webhook as well.
+type notification struct {
+	Text     string `json:"text"`
+	Command  string `json:"command"`
+	Key      string `json:"key"`
+	Host     string `json:"host"`
+	ExitCode int    `json:"exitCode"`
+	TimedOut bool   `json:"timedOut"`
+}
+
+// notifyFailure sends a notification to the webhook in CRONLOCK_NOTIFY_URL about a command that was killed after
+// timing out or exited with a non-zero exit code.
+// Failing to send the notification is logged, but does not affect the exit code of golock.
+func notifyFailure(command, key string, exitCode int, timedOut bool) {
+	url := os.Getenv(envLockNotifyURL)
+	if url == "" {
+		return
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+
+	text := fmt.Sprintf("golock: [%s] on %s exited with code %d", command, hostname, exitCode)
+	if timedOut {
+		text = fmt.Sprintf("golock: [%s] on %s was killed after timing out", command, hostname)
+	}
+
+	payload, err := json.Marshal(notification{
+		Text:     text,
+		Command:  command,
+		Key:      key,
+		Host:     hostname,
+		ExitCode: exitCode,
+		TimedOut: timedOut,
+	})
+	if err != nil {
+		slog.Warn(fmt.Sprintf("Failed to marshal notification: %v", err))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), notifyTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		slog.Warn(fmt.Sprintf("Failed to create notification request: %v", err))
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	slog.Debug("Sending notification to " + url)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		slog.Warn(fmt.Sprintf("Failed to send notification: %v", err))
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		slog.Warn("Failed to send notification: " + resp.Status)
+	}
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// writeOutputFile writes the captured output of a run of the command for the lock at redisKey to a file in dir.
+// The file is named after the lock key, with any path separators replaced, and the time the run started so that
+// each run gets its own file.
+func writeOutputFile(dir, redisKey string, start time.Time, output string) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create output directory %s: %w", dir, err)
+	}
+
+	file := filepath.Join(dir, fmt.Sprintf("%s-%s.log", keyFileName(redisKey), start.UTC().Format("20060102T150405Z")))
+	slog.Debug("Writing captured output to " + file)
+
+	if err := os.WriteFile(file, []byte(output), 0o644); err != nil {
+		return fmt.Errorf("failed to write output file %s: %w", file, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWriteOutputFile(t *testing.T) {
+	t.Parallel()
+
+	dir := filepath.Join(t.TempDir(), "output")
+	start := time.Date(2023, 11, 14, 22, 13, 20, 0, time.UTC)
+
+	if err := writeOutputFile(dir, "cronlock.backup", start, "done\n"); err != nil {
+		t.Fatalf("writeOutputFile() failed: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "cronlock.backup-20231114T221320Z.log"))
+	if err != nil {
+		t.Fatalf("failed to read the output file: %v", err)
+	}
+	if string(data) != "done\n" {
+		t.Errorf("writeOutputFile() failed, expected %q, got %q", "done\n", string(data))
+	}
+}
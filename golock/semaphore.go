@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	redis "github.com/redis/go-redis/v9"
+)
+
+// acquireSlotScript atomically claims a slot in the sorted set at KEYS[1] for the holder in ARGV[1].
+// Holders whose expiry time (their score) has passed are removed first, then a slot is claimed if fewer than ARGV[2]
+// holders remain. The key itself is set to expire when the last of its holders does.
+// Returns 1 if a slot was claimed, otherwise 0.
+var acquireSlotScript = redis.NewScript(`
+local key = KEYS[1]
+local holder = ARGV[1]
+local slots = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local expireAt = tonumber(ARGV[4])
+
+redis.call("ZREMRANGEBYSCORE", key, "-inf", now)
+if redis.call("ZCARD", key) >= slots then
+	return 0
+end
+
+redis.call("ZADD", key, expireAt, holder)
+local last = redis.call("ZRANGE", key, -1, -1, "WITHSCORES")
+redis.call("EXPIREAT", key, last[2])
+
+return 1
+`)
+
+// releaseSlotScript updates the expiry time of the holder in ARGV[1] to ARGV[2] in the sorted set at KEYS[1], and then
+// sets the key to expire when the last of its holders does.
+var releaseSlotScript = redis.NewScript(`
+local key = KEYS[1]
+local holder = ARGV[1]
+local expireAt = tonumber(ARGV[2])
+
+redis.call("ZADD", key, "XX", expireAt, holder)
+local last = redis.call("ZRANGE", key, -1, -1, "WITHSCORES")
+if last[2] then
+	redis.call("EXPIREAT", key, last[2])
+end
+
+return 1
+`)
+
+// holderID returns an identifier for this instance of golock that is unique across the hosts sharing a lock.
+func holderID() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+
+	return fmt.Sprintf("%s:%d", hostname, os.Getpid())
+}
+
+// acquireSlot tries to claim one of the slots of the semaphore stored at redisKey.
+// The slot is held until expireAtMax at the latest.
+// It returns false without an error if all slots are held by other processes.
+func acquireSlot(
+	ctx context.Context, rdb *redis.Client, redisKey, holder string, slots int, expireAtMax int64,
+) (bool, error) {
+	slog.Debug(fmt.Sprintf("Acquiring one of %d slots on %s key as %s", slots, redisKey, holder))
+
+	now := time.Now().UTC().Unix()
+	acquired, err := acquireSlotScript.Run(ctx, rdb, []string{redisKey}, holder, slots, now, expireAtMax).Int()
+	if err != nil {
+		return false, fmt.Errorf("failed to acquire slot: %w", err)
+	}
+
+	if acquired == 0 {
+		slog.Debug(fmt.Sprintf("All %d slots of %s are held by other processes", slots, redisKey))
+
+		return false, nil
+	}
+
+	slog.Debug(fmt.Sprintf("Slot on %s acquired", redisKey))
+
+	return true, nil
+}
+
+// checkSlotHeld returns an error if holder no longer holds a slot of the semaphore stored at redisKey.
+func checkSlotHeld(ctx context.Context, rdb *redis.Client, redisKey, holder string) error {
+	expireAt, err := rdb.ZScore(ctx, redisKey, holder).Result()
+	if errors.Is(err, redis.Nil) {
+		return errLockLost
+	}
+	if err != nil {
+		return fmt.Errorf("failed to check slot: %w", err)
+	}
+	if int64(expireAt) < time.Now().UTC().Unix() {
+		return errLockLost
+	}
+
+	return nil
+}
+
+// releaseSlot sets the slot held by holder in the semaphore stored at redisKey to expire once the minimum grace period
+// at expireAtMin has passed.
+func releaseSlot(ctx context.Context, rdb *redis.Client, redisKey, holder string, expireAtMin int64) error {
+	slog.Debug(fmt.Sprintf("Slot on %s for %s set to expire at: %d", redisKey, holder, expireAtMin))
+
+	if err := releaseSlotScript.Run(ctx, rdb, []string{redisKey}, holder, expireAtMin).Err(); err != nil {
+		return fmt.Errorf("failed to release slot: %w", err)
+	}
+
+	return nil
+}
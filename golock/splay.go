@@ -0,0 +1,28 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"time"
+)
+
+// sleepSplay sleeps for a random amount of time of up to CRONLOCK_SPLAY seconds.
+// This spreads out the attempts to acquire the lock when cron starts the command on many hosts at the same second.
+// It returns early if ctx is cancelled.
+func sleepSplay(ctx context.Context) {
+	splay := getEnvDuration(envLockSplay, defLockSplay)
+	if splay <= 0 {
+		return
+	}
+
+	delay := rand.N(splay)
+	slog.Debug(fmt.Sprintf("Sleeping for %s before acquiring the lock", delay.Round(time.Millisecond)))
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+	case <-timer.C:
+	}
+}
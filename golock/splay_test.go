@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestSleepSplay(t *testing.T) {
+	// Not parallel since t.Setenv() can't be used in parallel tests.
+	tests := []struct {
+		splay    string
+		maxDelay time.Duration
+	}{
+		{splay: "", maxDelay: 0},
+		{splay: "0", maxDelay: 0},
+		{splay: "1", maxDelay: time.Second},
+		{splay: "500ms", maxDelay: 500 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		t.Run("splay="+tt.splay, func(t *testing.T) {
+			t.Setenv(envLockSplay, tt.splay)
+
+			start := time.Now()
+			sleepSplay(context.Background())
+			// Allow a little extra time for the scheduler.
+			if elapsed := time.Since(start); elapsed > tt.maxDelay+100*time.Millisecond {
+				t.Errorf("sleepSplay() failed, expected to sleep for at most %s, slept for %s", tt.maxDelay, elapsed)
+			}
+		})
+	}
+}
+
+func TestSleepSplayCancelled(t *testing.T) {
+	// Not parallel since t.Setenv() can't be used in parallel tests.
+	t.Setenv(envLockSplay, "1h")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	start := time.Now()
+	sleepSplay(ctx)
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Errorf("sleepSplay() failed, expected to return once cancelled, slept for %s", elapsed)
+	}
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Environment variables set by systemd for services that it expects notifications from.
+const (
+	envNotifySocket = "NOTIFY_SOCKET"
+	envWatchdogPID  = "WATCHDOG_PID"
+	envWatchdogUSec = "WATCHDOG_USEC"
+)
+
+// notifySystemd sends a notification such as "READY=1" or "STATUS=..." to systemd.
+// It does nothing when golock isn't run by systemd as a service that expects notifications.
+// Failing to send the notification is logged, but otherwise ignored.
+func notifySystemd(state string) {
+	socket := os.Getenv(envNotifySocket)
+	if socket == "" {
+		return
+	}
+	// A leading @ means the socket is in the abstract namespace.
+	if socket[0] == '@' {
+		socket = "\x00" + socket[1:]
+	}
+
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		slog.Debug(fmt.Sprintf("Failed to connect to systemd notify socket: %v", err))
+		return
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte(state)); err != nil {
+		slog.Debug(fmt.Sprintf("Failed to notify systemd: %v", err))
+	}
+}
+
+// watchdogInterval returns how often systemd expects to be sent watchdog keep-alive notifications, or 0 if the
+// watchdog isn't enabled for golock. Notifications are sent twice as often as needed as systemd recommends.
+func watchdogInterval() time.Duration {
+	usec, err := strconv.ParseInt(os.Getenv(envWatchdogUSec), 10, 64)
+	if err != nil || usec <= 0 {
+		return 0
+	}
+	if pid := os.Getenv(envWatchdogPID); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return 0
+	}
+
+	return time.Duration(usec) * time.Microsecond / 2
+}
+
+// Errors for when golock has stopped making progress.
+var (
+	errCommandOverdue = errors.New("command is still running after its timeout")
+	errLockLost       = errors.New("lock is no longer held")
+)
+
+// watchdogCheckTimeout is how long the watchdog waits for the check that the lock is still held.
+const watchdogCheckTimeout = 5 * time.Second
+
+// watchdog sends watchdog keep-alive notifications to systemd for as long as golock is making progress.
+// Once the command has started, that means it hasn't run past its timeout and the lock is confirmed to still be held,
+// so that systemd's watchdog can restart a service whose command has hung rather than just golock itself.
+type watchdog struct {
+	stopC    chan struct{}
+	doneC    chan struct{}
+	stopOnce sync.Once
+
+	mu       sync.Mutex
+	deadline time.Time                   // When the command should have exited by, if it has a timeout.
+	lockHeld func(context.Context) error // Confirms that the lock is still held while the command runs.
+}
+
+// startWatchdog starts sending watchdog keep-alive notifications to systemd until stop() is called.
+// It returns nil when the watchdog isn't enabled for golock, which is safe to call the methods of.
+func startWatchdog() *watchdog {
+	interval := watchdogInterval()
+	if interval == 0 {
+		return nil
+	}
+
+	slog.Debug(fmt.Sprintf("Sending systemd watchdog notifications every %s", interval))
+	w := &watchdog{stopC: make(chan struct{}), doneC: make(chan struct{})}
+	go func() {
+		defer close(w.doneC)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			if err := w.check(); err != nil {
+				slog.Warn(fmt.Sprintf("Not sending systemd watchdog notification: %v", err))
+			} else {
+				notifySystemd("WATCHDOG=1")
+			}
+			select {
+			case <-w.stopC:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	return w
+}
+
+// check returns an error if golock has stopped making progress.
+func (w *watchdog) check() error {
+	w.mu.Lock()
+	deadline, lockHeld := w.deadline, w.lockHeld
+	w.mu.Unlock()
+
+	if !deadline.IsZero() && time.Now().After(deadline) {
+		return errCommandOverdue
+	}
+	if lockHeld == nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), watchdogCheckTimeout)
+	defer cancel()
+
+	return lockHeld(ctx)
+}
+
+// commandStarted tells the watchdog that the command has started, and that it should have exited within timeout
+// seconds plus gracePeriod seconds to exit after being signalled. A timeout of 0 means the command has no deadline.
+func (w *watchdog) commandStarted(timeout, gracePeriod int) {
+	if w == nil || timeout <= 0 {
+		return
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.deadline = time.Now().Add(time.Duration(timeout+gracePeriod)*time.Second + watchdogCheckTimeout)
+}
+
+// checkLock sets the function used to confirm that the lock is still held while the command runs.
+func (w *watchdog) checkLock(lockHeld func(context.Context) error) {
+	if w == nil {
+		return
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.lockHeld = lockHeld
+}
+
+// stop stops sending the watchdog keep-alive notifications.
+// It waits for any check that the lock is still held to finish, so the client used for the check can be closed once
+// it returns. It is safe to call more than once.
+func (w *watchdog) stop() {
+	if w == nil {
+		return
+	}
+	w.stopOnce.Do(func() {
+		close(w.stopC)
+	})
+	<-w.doneC
+}
+
+// commandEnv returns the environment for the command, without the variables systemd set for golock.
+// This stops the command from sending its own notifications, such as READY=1 or STOPPING=1, that would conflict
+// with the ones from golock.
+func commandEnv() []string {
+	return slices.DeleteFunc(os.Environ(), func(env string) bool {
+		name, _, _ := strings.Cut(env, "=")
+		return name == envNotifySocket || name == envWatchdogPID || name == envWatchdogUSec
+	})
+}
@@ -5,6 +5,7 @@ package k8s
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"strconv"
@@ -12,6 +13,7 @@ import (
 
 	"github.com/jim-barber-he/go/util"
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
@@ -19,12 +21,48 @@ import (
 )
 
 var (
+	errGettingEvents    = errors.New("error getting events")
 	errGettingNamespace = errors.New("error getting namespace")
 	errGettingNode      = errors.New("error getting node")
+	errGettingNodeUsage = errors.New("error getting node metrics")
 	errGettingNodes     = errors.New("error getting nodes")
+	errGettingPodUsage  = errors.New("error getting pod metrics")
 	errGettingPods      = errors.New("error getting pods")
 )
 
+// metricsPath is the metrics-server API path for node and pod metrics.
+const metricsPath = "/apis/metrics.k8s.io/v1beta1"
+
+// NodeUsage holds the CPU and memory usage of a node.
+type NodeUsage struct {
+	CPU    resource.Quantity
+	Memory resource.Quantity
+}
+
+// PodUsage holds the CPU and memory usage of a pod summed across its containers.
+type PodUsage struct {
+	CPU    resource.Quantity
+	Memory resource.Quantity
+}
+
+// nodeMetricsList is the subset of the metrics.k8s.io NodeMetricsList that is needed to work out node usage.
+type nodeMetricsList struct {
+	Items []struct {
+		Metadata metav1.ObjectMeta `json:"metadata"`
+		Usage    v1.ResourceList   `json:"usage"`
+	} `json:"items"`
+}
+
+// podMetricsList is the subset of the metrics.k8s.io PodMetricsList that is needed to work out pod usage.
+type podMetricsList struct {
+	Items []struct {
+		Metadata   metav1.ObjectMeta `json:"metadata"`
+		Containers []struct {
+			Usage v1.ResourceList `json:"usage"`
+		} `json:"containers"`
+	} `json:"items"`
+}
+
 func NewContextNotFoundError(context string) error {
 	return &util.Error{
 		Msg:   "context ",
@@ -37,15 +75,16 @@ func NewContextNotFoundError(context string) error {
 // `CurrentContext`, and with the unneeded masterUrl parameter removed.
 func buildConfigFromFlags(kubeconfigPath, context string) (*rest.Config, error) {
 	return clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
-		&clientcmd.ClientConfigLoadingRules{ExplicitPath: kubeconfigPath},
+		loadingRules(kubeconfigPath),
 		&clientcmd.ConfigOverrides{
 			CurrentContext: context,
 		}).ClientConfig()
 }
 
 // Client returns a Kubernetes client.
-func Client(kubeContext string) *kubernetes.Clientset {
-	config, err := buildConfigFromFlags(KubeConfig(), kubeContext)
+// If kubeconfigPath is an empty string, then the kubeconfig files are found the same way as kubectl does.
+func Client(kubeconfigPath, kubeContext string) *kubernetes.Clientset {
+	config, err := buildConfigFromFlags(kubeconfigPath, kubeContext)
 	if err != nil {
 		panic(fmt.Errorf("failed to build config from flags: %w", err))
 	}
@@ -107,12 +146,42 @@ func isRestartableInitContainer(initContainer *v1.Container) bool {
 	return *initContainer.RestartPolicy == v1.ContainerRestartPolicyAlways
 }
 
+// IsSpotNode returns true if the node is a spot instance.
+// Both the node role used for spot instances in kOps clusters and the capacity type label set by Karpenter are
+// recognised.
+func IsSpotNode(node *v1.Node) bool {
+	return node.Labels["node-role.kubernetes.io/spot-worker"] != "" ||
+		node.Labels["karpenter.sh/capacity-type"] == "spot"
+}
+
 // KubeConfig returns the user's kube config file.
 func KubeConfig() string {
 	configAccess := clientcmd.NewDefaultPathOptions()
 	return configAccess.GetDefaultFilename()
 }
 
+// loadingRules returns the rules for finding the kubeconfig files.
+// If kubeconfigPath is an empty string, then like kubectl, the KUBECONFIG environment variable is used, which may be a
+// list of files to merge, falling back to the default of ~/.kube/config if it is not set.
+func loadingRules(kubeconfigPath string) *clientcmd.ClientConfigLoadingRules {
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	rules.ExplicitPath = kubeconfigPath
+	return rules
+}
+
+// ListEvents returns a list of Kubernetes events.
+// If namespace is an empty string then events from all namespaces are returned.
+// The field selector is applied server-side, with an empty string meaning no filtering.
+func ListEvents(client kubernetes.Interface, namespace, fieldSelector string) (*v1.EventList, error) {
+	events, err := client.CoreV1().Events(namespace).List(
+		context.Background(), metav1.ListOptions{FieldSelector: fieldSelector},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errGettingEvents, err)
+	}
+	return events, nil
+}
+
 // ListNodes returns a list of Kubernetes nodes.
 func ListNodes(client kubernetes.Interface) (*v1.NodeList, error) {
 	nodes, err := client.CoreV1().Nodes().List(context.Background(), metav1.ListOptions{})
@@ -124,11 +193,15 @@ func ListNodes(client kubernetes.Interface) (*v1.NodeList, error) {
 
 // ListPods returns a list of Kubernetes pods.
 // If namespace is an empty string then pods from all namespaces are returned.
-func ListPods(client kubernetes.Interface, namespace, labelSelector string) (*v1.PodList, error) {
+// The label and field selectors are applied server-side, with empty strings meaning no filtering.
+func ListPods(client kubernetes.Interface, namespace, labelSelector, fieldSelector string) (*v1.PodList, error) {
 	listOptions := metav1.ListOptions{}
 	if labelSelector != "" {
 		listOptions.LabelSelector = labelSelector
 	}
+	if fieldSelector != "" {
+		listOptions.FieldSelector = fieldSelector
+	}
 	pods, err := client.CoreV1().Pods(namespace).List(context.Background(), listOptions)
 	if err != nil {
 		return nil, fmt.Errorf("%w: %w", errGettingPods, err)
@@ -136,11 +209,68 @@ func ListPods(client kubernetes.Interface, namespace, labelSelector string) (*v1
 	return pods, nil
 }
 
+// ListNodeUsage returns the CPU and memory usage of nodes as reported by metrics-server, keyed by node name.
+func ListNodeUsage(client kubernetes.Interface) (map[string]NodeUsage, error) {
+	data, err := client.Discovery().RESTClient().Get().AbsPath(metricsPath + "/nodes").DoRaw(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errGettingNodeUsage, err)
+	}
+
+	return parseNodeUsage(data)
+}
+
+// ListPodUsage returns the CPU and memory usage of pods as reported by metrics-server, keyed by "namespace/name".
+// If namespace is an empty string then pods from all namespaces are returned.
+func ListPodUsage(client kubernetes.Interface, namespace, labelSelector string) (map[string]PodUsage, error) {
+	path := metricsPath + "/pods"
+	if namespace != "" {
+		path = metricsPath + "/namespaces/" + namespace + "/pods"
+	}
+	req := client.Discovery().RESTClient().Get().AbsPath(path)
+	if labelSelector != "" {
+		req = req.Param("labelSelector", labelSelector)
+	}
+	data, err := req.DoRaw(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errGettingPodUsage, err)
+	}
+
+	return parsePodUsage(data)
+}
+
+// ListPodsPaged lists Kubernetes pods a page at a time, calling fn with each page as it is received.
+// Up to limit pods are fetched per request, with 0 meaning that they are all fetched at once.
+// If namespace is an empty string then pods from all namespaces are returned.
+// The label and field selectors are applied server-side, with empty strings meaning no filtering.
+func ListPodsPaged(
+	client kubernetes.Interface, namespace, labelSelector, fieldSelector string, limit int64, fn func(*v1.PodList) error,
+) error {
+	listOptions := metav1.ListOptions{
+		LabelSelector: labelSelector,
+		FieldSelector: fieldSelector,
+		Limit:         limit,
+	}
+	for {
+		pods, err := client.CoreV1().Pods(namespace).List(context.Background(), listOptions)
+		if err != nil {
+			return fmt.Errorf("%w: %w", errGettingPods, err)
+		}
+		if err := fn(pods); err != nil {
+			return err
+		}
+		if pods.Continue == "" {
+			return nil
+		}
+		listOptions.Continue = pods.Continue
+	}
+}
+
 // Namespace returns the namespace name that is selected (or "default" if it is not set) for a context in kubeconfig.
 // If the context that is passed in is an empty string, fall back to the selected context in kubeconfig.
 // If that's not set either, then just return the "default" namespace.
-func Namespace(kubeContext string) string {
-	config, err := clientcmd.LoadFromFile(KubeConfig())
+// If kubeconfigPath is an empty string, then the kubeconfig files are found the same way as kubectl does.
+func Namespace(kubeconfigPath, kubeContext string) string {
+	config, err := loadingRules(kubeconfigPath).Load()
 	if err != nil {
 		panic(fmt.Errorf("failed to load kubeconfig: %w", err))
 	}
@@ -164,6 +294,97 @@ func Namespace(kubeContext string) string {
 	return ns
 }
 
+// parseNodeUsage converts a metrics.k8s.io NodeMetricsList into the usage of each node keyed by name.
+func parseNodeUsage(data []byte) (map[string]NodeUsage, error) {
+	var list nodeMetricsList
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("%w: %w", errGettingNodeUsage, err)
+	}
+
+	usage := make(map[string]NodeUsage, len(list.Items))
+	for _, item := range list.Items {
+		usage[item.Metadata.Name] = NodeUsage{
+			CPU:    item.Usage[v1.ResourceCPU],
+			Memory: item.Usage[v1.ResourceMemory],
+		}
+	}
+
+	return usage, nil
+}
+
+// parsePodUsage converts a metrics.k8s.io PodMetricsList into the usage of each pod keyed by "namespace/name".
+func parsePodUsage(data []byte) (map[string]PodUsage, error) {
+	var list podMetricsList
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("%w: %w", errGettingPodUsage, err)
+	}
+
+	usage := make(map[string]PodUsage, len(list.Items))
+	for _, item := range list.Items {
+		var pu PodUsage
+		for _, container := range item.Containers {
+			pu.CPU.Add(container.Usage[v1.ResourceCPU])
+			pu.Memory.Add(container.Usage[v1.ResourceMemory])
+		}
+		usage[item.Metadata.Namespace+"/"+item.Metadata.Name] = pu
+	}
+
+	return usage, nil
+}
+
+// PodRequests returns the resources requested by a pod, worked out the same way as the kubelet and scheduler do.
+// Init containers run one at a time before the other containers, so the pod needs the larger of the biggest init
+// container request and the sum of the other containers requests. Restartable init containers (sidecars) keep running
+// alongside the containers that start after them, so their requests are added to both. Any pod overhead, such as for
+// the sandbox of a runtime class, is added on top.
+func PodRequests(pod *v1.Pod) v1.ResourceList {
+	requests := v1.ResourceList{}
+	for _, container := range pod.Spec.Containers {
+		addResources(requests, container.Resources.Requests)
+	}
+
+	sidecars := v1.ResourceList{}
+	initRequests := v1.ResourceList{}
+	for i := range pod.Spec.InitContainers {
+		container := &pod.Spec.InitContainers[i]
+		containerRequests := container.Resources.Requests
+		if isRestartableInitContainer(container) {
+			addResources(requests, containerRequests)
+			addResources(sidecars, containerRequests)
+			containerRequests = sidecars
+		} else {
+			// The sidecars started before this init container are still running alongside it.
+			running := sidecars.DeepCopy()
+			addResources(running, containerRequests)
+			containerRequests = running
+		}
+		maxResources(initRequests, containerRequests)
+	}
+	maxResources(requests, initRequests)
+
+	addResources(requests, pod.Spec.Overhead)
+
+	return requests
+}
+
+// addResources adds the quantities of resources to the totals.
+func addResources(totals, resources v1.ResourceList) {
+	for name, quantity := range resources {
+		total := totals[name]
+		total.Add(quantity)
+		totals[name] = total
+	}
+}
+
+// maxResources sets each of the totals to the larger of it and the quantity of the same resource in resources.
+func maxResources(totals, resources v1.ResourceList) {
+	for name, quantity := range resources {
+		if total, ok := totals[name]; !ok || quantity.Cmp(total) > 0 {
+			totals[name] = quantity.DeepCopy()
+		}
+	}
+}
+
 // PodDetails returns details on pods as you would see in the READY, STATUS, and RESTARTS columns of kubectl output.
 // The READY would be built up via "readyContainers/totalContainers".
 // Based on: printPod() function in kubernetes/pkg/printers/internalversion/printers.go of kubernetes source code.
@@ -2,9 +2,12 @@ package k8s
 
 import (
 	"context"
+	"os"
+	"path/filepath"
 	"testing"
 
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes/fake"
 )
@@ -185,6 +188,66 @@ func TestIsRestartableInitContainer(t *testing.T) {
 	}
 }
 
+func TestIsSpotNode(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		labels map[string]string
+		result bool
+	}{
+		{labels: nil, result: false},
+		{labels: map[string]string{"node-role.kubernetes.io/node": "true"}, result: false},
+		{labels: map[string]string{"node-role.kubernetes.io/spot-worker": "true"}, result: true},
+		{labels: map[string]string{"karpenter.sh/capacity-type": "spot"}, result: true},
+		{labels: map[string]string{"karpenter.sh/capacity-type": "on-demand"}, result: false},
+	}
+
+	for _, tt := range tests {
+		t.Run("IsSpotNode", func(t *testing.T) {
+			t.Parallel()
+			node := &v1.Node{ObjectMeta: metav1.ObjectMeta{Labels: tt.labels}}
+			if result := IsSpotNode(node); result != tt.result {
+				t.Errorf("got %t, want %t", result, tt.result)
+			}
+		})
+	}
+}
+
+func TestListEvents(t *testing.T) {
+	t.Parallel()
+
+	// Create a fake client
+	client := fake.NewSimpleClientset()
+
+	// Create a fake event
+	event := &v1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test.17f0a1b2c3d4e5f6",
+			Namespace: "default",
+		},
+		InvolvedObject: v1.ObjectReference{Kind: "Node", Name: "test"},
+		Reason:         "NodeNotSchedulable",
+	}
+	_, err := client.CoreV1().Events("default").Create(context.Background(), event, metav1.CreateOptions{})
+	if err != nil {
+		t.Fatalf("error creating event: %v", err)
+	}
+
+	// List the events
+	events, err := ListEvents(client, "", "")
+	if err != nil {
+		t.Fatalf("error listing events: %v", err)
+	}
+
+	// Verify the event
+	if len(events.Items) != 1 {
+		t.Fatalf("expected 1 event, got %d", len(events.Items))
+	}
+	if events.Items[0].Reason != "NodeNotSchedulable" {
+		t.Fatalf("expected event reason to be 'NodeNotSchedulable', got '%s'", events.Items[0].Reason)
+	}
+}
+
 func TestListNodes(t *testing.T) {
 	t.Parallel()
 
@@ -236,7 +299,7 @@ func TestListPods(t *testing.T) {
 	}
 
 	// List the pods
-	pods, err := ListPods(client, "default", "")
+	pods, err := ListPods(client, "default", "", "")
 	if err != nil {
 		t.Fatalf("error listing pods: %v", err)
 	}
@@ -250,6 +313,240 @@ func TestListPods(t *testing.T) {
 	}
 }
 
+func TestParseNodeUsage(t *testing.T) {
+	t.Parallel()
+
+	data := []byte(`{
+		"kind": "NodeMetricsList",
+		"apiVersion": "metrics.k8s.io/v1beta1",
+		"items": [
+			{"metadata": {"name": "node1"}, "usage": {"cpu": "1250m", "memory": "2Gi"}}
+		]
+	}`)
+
+	usage, err := parseNodeUsage(data)
+	if err != nil {
+		t.Fatalf("error parsing node usage: %v", err)
+	}
+
+	nu, ok := usage["node1"]
+	if !ok {
+		t.Fatalf("expected usage for 'node1', got %v", usage)
+	}
+	if cpu := nu.CPU.MilliValue(); cpu != 1250 {
+		t.Fatalf("expected CPU to be 1250m, got %dm", cpu)
+	}
+	if memory := nu.Memory.Value(); memory != 2*1024*1024*1024 {
+		t.Fatalf("expected memory to be 2Gi, got %d", memory)
+	}
+
+	if _, err := parseNodeUsage([]byte("not json")); err == nil {
+		t.Fatal("expected an error parsing invalid JSON")
+	}
+}
+
+func TestParsePodUsage(t *testing.T) {
+	t.Parallel()
+
+	data := []byte(`{
+		"kind": "PodMetricsList",
+		"apiVersion": "metrics.k8s.io/v1beta1",
+		"items": [
+			{
+				"metadata": {"name": "test", "namespace": "default"},
+				"containers": [
+					{"name": "app", "usage": {"cpu": "150m", "memory": "64Mi"}},
+					{"name": "sidecar", "usage": {"cpu": "1500000n", "memory": "1Mi"}}
+				]
+			}
+		]
+	}`)
+
+	usage, err := parsePodUsage(data)
+	if err != nil {
+		t.Fatalf("error parsing pod usage: %v", err)
+	}
+
+	pu, ok := usage["default/test"]
+	if !ok {
+		t.Fatalf("expected usage for 'default/test', got %v", usage)
+	}
+	if cpu := pu.CPU.MilliValue(); cpu != 152 {
+		t.Fatalf("expected CPU to be 152m, got %dm", cpu)
+	}
+	if memory := pu.Memory.Value(); memory != 65*1024*1024 {
+		t.Fatalf("expected memory to be 65Mi, got %d", memory)
+	}
+
+	if _, err := parsePodUsage([]byte("not json")); err == nil {
+		t.Fatal("expected an error parsing invalid JSON")
+	}
+}
+
+func TestListPodsPaged(t *testing.T) {
+	t.Parallel()
+
+	// Create a fake client
+	client := fake.NewSimpleClientset()
+
+	// Create some fake pods
+	for _, name := range []string{"test1", "test2"} {
+		pod := &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: "default",
+			},
+		}
+		_, err := client.CoreV1().Pods("default").Create(context.Background(), pod, metav1.CreateOptions{})
+		if err != nil {
+			t.Fatalf("error creating pod: %v", err)
+		}
+	}
+
+	// List the pods
+	var names []string
+	err := ListPodsPaged(client, "default", "", "", 1, func(pods *v1.PodList) error {
+		for _, pod := range pods.Items {
+			names = append(names, pod.Name)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("error listing pods: %v", err)
+	}
+
+	// Verify the pods
+	if len(names) != 2 {
+		t.Fatalf("expected 2 pods, got %d", len(names))
+	}
+}
+
+func TestNamespace(t *testing.T) {
+	dir := t.TempDir()
+	writeKubeconfig := func(name, contents string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(contents), 0o600); err != nil {
+			t.Fatalf("error writing kubeconfig: %v", err)
+		}
+		return path
+	}
+	first := writeKubeconfig("first", `
+apiVersion: v1
+kind: Config
+current-context: one
+contexts:
+- name: one
+  context:
+    cluster: one
+    namespace: first-ns
+`)
+	second := writeKubeconfig("second", `
+apiVersion: v1
+kind: Config
+contexts:
+- name: two
+  context:
+    cluster: two
+    namespace: second-ns
+`)
+
+	// An explicit path only uses that file.
+	if ns := Namespace(first, ""); ns != "first-ns" {
+		t.Errorf("expected namespace to be 'first-ns', got '%s'", ns)
+	}
+
+	// KUBECONFIG can list multiple files that are merged.
+	t.Setenv("KUBECONFIG", first+string(os.PathListSeparator)+second)
+	if ns := Namespace("", "two"); ns != "second-ns" {
+		t.Errorf("expected namespace to be 'second-ns', got '%s'", ns)
+	}
+	if ns := Namespace("", ""); ns != "first-ns" {
+		t.Errorf("expected namespace to be 'first-ns', got '%s'", ns)
+	}
+}
+
+func TestPodRequests(t *testing.T) {
+	t.Parallel()
+
+	sidecar := v1.ContainerRestartPolicyAlways
+	requests := func(cpu, memory string) v1.ResourceRequirements {
+		list := v1.ResourceList{v1.ResourceCPU: resource.MustParse(cpu)}
+		if memory != "" {
+			list[v1.ResourceMemory] = resource.MustParse(memory)
+		}
+		return v1.ResourceRequirements{Requests: list}
+	}
+
+	tests := []struct {
+		name   string
+		spec   v1.PodSpec
+		cpu    int64
+		memory int64
+	}{
+		{
+			name: "init container larger than the containers",
+			spec: v1.PodSpec{
+				InitContainers: []v1.Container{{Resources: requests("2", "")}},
+				Containers: []v1.Container{
+					{Resources: requests("500m", "1Gi")},
+					{Resources: requests("250m", "512Mi")},
+				},
+			},
+			cpu:    2000,
+			memory: 1536 * 1024 * 1024,
+		},
+		{
+			name: "sidecar added to the containers",
+			spec: v1.PodSpec{
+				InitContainers: []v1.Container{{Resources: requests("250m", "128Mi"), RestartPolicy: &sidecar}},
+				Containers:     []v1.Container{{Resources: requests("500m", "1Gi")}},
+			},
+			cpu:    750,
+			memory: 1152 * 1024 * 1024,
+		},
+		{
+			name: "sidecar running alongside a later init container",
+			spec: v1.PodSpec{
+				InitContainers: []v1.Container{
+					{Resources: requests("100m", "64Mi"), RestartPolicy: &sidecar},
+					{Resources: requests("1", "64Mi")},
+				},
+				Containers: []v1.Container{{Resources: requests("500m", "1Gi")}},
+			},
+			cpu:    1100,
+			memory: 1088 * 1024 * 1024,
+		},
+		{
+			name: "overhead",
+			spec: v1.PodSpec{
+				Containers: []v1.Container{{Resources: requests("500m", "1Gi")}},
+				Overhead: v1.ResourceList{
+					v1.ResourceCPU:    resource.MustParse("100m"),
+					v1.ResourceMemory: resource.MustParse("64Mi"),
+				},
+			},
+			cpu:    600,
+			memory: 1088 * 1024 * 1024,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			requests := PodRequests(&v1.Pod{Spec: tt.spec})
+			cpu := requests[v1.ResourceCPU]
+			if cpu.MilliValue() != tt.cpu {
+				t.Errorf("got cpu %s, want %dm", cpu.String(), tt.cpu)
+			}
+			memory := requests[v1.ResourceMemory]
+			if memory.Value() != tt.memory {
+				t.Errorf("got memory %s, want %d", memory.String(), tt.memory)
+			}
+		})
+	}
+}
+
 /* TODO: Need to set up the status on the mocked pod.
 func TestPodDetails(t *testing.T) {
 	t.Parallel()
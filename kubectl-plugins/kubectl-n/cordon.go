@@ -0,0 +1,81 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/jim-barber-he/go/k8s"
+	"github.com/jim-barber-he/go/util"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// cordonEventsSelector selects the events recorded by the kubelet when a node has scheduling disabled.
+const cordonEventsSelector = "involvedObject.kind=Node,reason=NodeNotSchedulable"
+
+// cordonEvents returns the time of the most recent NodeNotSchedulable event for each node, keyed by node name.
+func cordonEvents(clientset kubernetes.Interface) (map[string]time.Time, error) {
+	events, err := k8s.ListEvents(clientset, "", cordonEventsSelector)
+	if err != nil {
+		return nil, err
+	}
+
+	times := make(map[string]time.Time)
+	for i := range events.Items {
+		event := &events.Items[i]
+		eventTime := event.LastTimestamp.Time
+		if eventTime.IsZero() {
+			eventTime = event.EventTime.Time
+		}
+		if eventTime.After(times[event.InvolvedObject.Name]) {
+			times[event.InvolvedObject.Name] = eventTime
+		}
+	}
+
+	return times, nil
+}
+
+// cordonedBy returns the field manager that most recently set spec.unschedulable on a node, and when it did so.
+// For example `kubectl cordon` and `kubectl drain` use the kubectl-cordon field manager.
+// It returns false if none of the managed fields of the node cover spec.unschedulable.
+func cordonedBy(node *v1.Node) (string, time.Time, bool) {
+	var manager string
+	var when time.Time
+
+	for _, entry := range node.ManagedFields {
+		if entry.FieldsV1 == nil || entry.Time == nil {
+			continue
+		}
+
+		var fields struct {
+			Spec map[string]json.RawMessage `json:"f:spec"`
+		}
+		if err := json.Unmarshal(entry.FieldsV1.Raw, &fields); err != nil {
+			continue
+		}
+		if _, ok := fields.Spec["f:unschedulable"]; !ok {
+			continue
+		}
+
+		if entry.Time.After(when) {
+			manager = entry.Manager
+			when = entry.Time.Time
+		}
+	}
+
+	return manager, when, manager != ""
+}
+
+// cordonWarning returns the warning message for a node that has scheduling disabled, including who disabled it and
+// when, as far as that can be worked out from the managed fields of the node and the events.
+func cordonWarning(node *v1.Node, eventTimes map[string]time.Time) string {
+	if manager, when, ok := cordonedBy(node); ok {
+		return fmt.Sprintf("Scheduling Disabled by %s %s ago", manager, util.FormatAge(when))
+	}
+	if when, ok := eventTimes[node.Name]; ok {
+		return fmt.Sprintf("Scheduling Disabled %s ago", util.FormatAge(when))
+	}
+
+	return "Scheduling Disabled"
+}
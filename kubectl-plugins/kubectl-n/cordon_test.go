@@ -0,0 +1,123 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestCordonedBy(t *testing.T) {
+	t.Parallel()
+
+	cordoned := metav1.NewTime(time.Date(2026, 10, 15, 1, 2, 3, 0, time.UTC))
+	labelled := metav1.NewTime(time.Date(2026, 10, 16, 1, 2, 3, 0, time.UTC))
+
+	node := &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			ManagedFields: []metav1.ManagedFieldsEntry{
+				{
+					Manager:  "kubelet",
+					Time:     &labelled,
+					FieldsV1: &metav1.FieldsV1{Raw: []byte(`{"f:metadata":{"f:labels":{"f:kind":{}}}}`)},
+				},
+				{
+					Manager:  "kubectl-cordon",
+					Time:     &cordoned,
+					FieldsV1: &metav1.FieldsV1{Raw: []byte(`{"f:spec":{"f:unschedulable":{}}}`)},
+				},
+			},
+		},
+	}
+
+	manager, when, ok := cordonedBy(node)
+	if !ok || manager != "kubectl-cordon" || !when.Equal(cordoned.Time) {
+		t.Errorf("got %s, %v, %t, want kubectl-cordon, %v, true", manager, when, ok, cordoned.Time)
+	}
+
+	if _, _, ok := cordonedBy(&v1.Node{}); ok {
+		t.Error("expected no manager for a node without managed fields")
+	}
+}
+
+func TestCordonWarning(t *testing.T) {
+	t.Parallel()
+
+	cordoned := metav1.NewTime(time.Now().Add(-(3*time.Hour + 12*time.Minute + 30*time.Second)))
+
+	tests := []struct {
+		name       string
+		node       *v1.Node
+		eventTimes map[string]time.Time
+		want       string
+	}{
+		{
+			name: "managed fields",
+			node: &v1.Node{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "node1",
+					ManagedFields: []metav1.ManagedFieldsEntry{
+						{
+							Manager:  "kubectl-cordon",
+							Time:     &cordoned,
+							FieldsV1: &metav1.FieldsV1{Raw: []byte(`{"f:spec":{"f:unschedulable":{}}}`)},
+						},
+					},
+				},
+			},
+			want: "Scheduling Disabled by kubectl-cordon 3h12m ago",
+		},
+		{
+			name:       "events",
+			node:       &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node1"}},
+			eventTimes: map[string]time.Time{"node1": cordoned.Time},
+			want:       "Scheduling Disabled 3h12m ago",
+		},
+		{
+			name: "unknown",
+			node: &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node1"}},
+			want: "Scheduling Disabled",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := cordonWarning(tt.node, tt.eventTimes); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCordonEvents(t *testing.T) {
+	t.Parallel()
+
+	older := metav1.NewTime(time.Date(2026, 10, 15, 1, 2, 3, 0, time.UTC))
+	newer := metav1.NewTime(time.Date(2026, 10, 16, 1, 2, 3, 0, time.UTC))
+
+	clientset := fake.NewSimpleClientset(
+		&v1.Event{
+			ObjectMeta:     metav1.ObjectMeta{Name: "node1.1", Namespace: "default"},
+			InvolvedObject: v1.ObjectReference{Kind: "Node", Name: "node1"},
+			Reason:         "NodeNotSchedulable",
+			LastTimestamp:  older,
+		},
+		&v1.Event{
+			ObjectMeta:     metav1.ObjectMeta{Name: "node1.2", Namespace: "default"},
+			InvolvedObject: v1.ObjectReference{Kind: "Node", Name: "node1"},
+			Reason:         "NodeNotSchedulable",
+			EventTime:      metav1.NewMicroTime(newer.Time),
+		},
+	)
+
+	times, err := cordonEvents(clientset)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !times["node1"].Equal(newer.Time) {
+		t.Errorf("got %v, want %v", times["node1"], newer.Time)
+	}
+}
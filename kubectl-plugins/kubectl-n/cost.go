@@ -0,0 +1,106 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+const (
+	// costUnknown is shown as the cost of nodes with no price for their region and instance type.
+	costUnknown = "unknown"
+
+	hoursPerMonth = 730
+
+	// spotFactor is the rough fraction of the on-demand price paid for a spot instance when no spot price is known.
+	spotFactor = 0.35
+)
+
+// priceTable holds the prices of instance types keyed by region and then instance type.
+type priceTable map[string]map[string]price
+
+// defaultPrices are the approximate hourly on-demand prices in USD of Linux EC2 instances.
+// Only ap-southeast-2 is bundled, and the prices are only used for estimates; pass --prices to use prices for other
+// regions or more accurate or up to date prices.
+var defaultPrices = priceTable{"ap-southeast-2": {
+	"c5.large":         {OnDemand: 0.111},
+	"c5.xlarge":        {OnDemand: 0.222},
+	"c5.2xlarge":       {OnDemand: 0.444},
+	"c6i.large":        {OnDemand: 0.111},
+	"c6i.xlarge":       {OnDemand: 0.222},
+	"c6i.2xlarge":      {OnDemand: 0.444},
+	"c6in.large":       {OnDemand: 0.1404},
+	"c6in.xlarge":      {OnDemand: 0.2808},
+	"c6in.2xlarge":     {OnDemand: 0.5616},
+	"c7i.large":        {OnDemand: 0.1166},
+	"c7i.xlarge":       {OnDemand: 0.2331},
+	"c7i.2xlarge":      {OnDemand: 0.4662},
+	"c7i-flex.large":   {OnDemand: 0.1107},
+	"c7i-flex.xlarge":  {OnDemand: 0.2214},
+	"c7i-flex.2xlarge": {OnDemand: 0.4428},
+	"m5.large":         {OnDemand: 0.12},
+	"m5.xlarge":        {OnDemand: 0.24},
+	"m5.2xlarge":       {OnDemand: 0.48},
+	"m6i.large":        {OnDemand: 0.12},
+	"m6i.xlarge":       {OnDemand: 0.24},
+	"m6i.2xlarge":      {OnDemand: 0.48},
+	"m7i.large":        {OnDemand: 0.126},
+	"m7i.xlarge":       {OnDemand: 0.252},
+	"m7i.2xlarge":      {OnDemand: 0.504},
+	"m7i-flex.large":   {OnDemand: 0.1197},
+	"m7i-flex.xlarge":  {OnDemand: 0.2394},
+	"m7i-flex.2xlarge": {OnDemand: 0.4788},
+	"r5.large":         {OnDemand: 0.151},
+	"r5.xlarge":        {OnDemand: 0.302},
+	"r5.2xlarge":       {OnDemand: 0.604},
+	"r6i.large":        {OnDemand: 0.151},
+	"r6i.xlarge":       {OnDemand: 0.302},
+	"r6i.2xlarge":      {OnDemand: 0.604},
+	"r7i.large":        {OnDemand: 0.1588},
+	"r7i.xlarge":       {OnDemand: 0.3175},
+	"r7i.2xlarge":      {OnDemand: 0.635},
+	"t3.medium":        {OnDemand: 0.0528},
+	"t3.large":         {OnDemand: 0.1056},
+	"t3.xlarge":        {OnDemand: 0.2112},
+	"t3.2xlarge":       {OnDemand: 0.4224},
+}}
+
+// price is the hourly price of an instance type.
+// If the Spot price is zero then it is estimated from the OnDemand price.
+type price struct {
+	OnDemand float64 `json:"onDemand"`
+	Spot     float64 `json:"spot,omitempty"`
+}
+
+// hourlyCost returns the hourly cost of an instance type in a region, and false if there is no price for it there.
+// estimated is true when the cost of a spot instance had to be estimated from the on-demand price.
+func hourlyCost(prices priceTable, region, instanceType string, spot bool) (cost float64, estimated, ok bool) {
+	p, ok := prices[region][instanceType]
+	if !ok {
+		return 0, false, false
+	}
+
+	if !spot {
+		return p.OnDemand, false, true
+	}
+	if p.Spot > 0 {
+		return p.Spot, false, true
+	}
+
+	return p.OnDemand * spotFactor, true, true
+}
+
+// loadPrices reads a JSON file mapping regions to the prices of the instance types in them.
+func loadPrices(path string) (priceTable, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var prices priceTable
+	if err := json.Unmarshal(data, &prices); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	return prices, nil
+}
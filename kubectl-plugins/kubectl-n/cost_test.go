@@ -0,0 +1,112 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestHourlyCost(t *testing.T) {
+	t.Parallel()
+
+	prices := priceTable{"ap-southeast-2": {
+		"m7i.xlarge": {OnDemand: 0.252},
+		"t3.xlarge":  {OnDemand: 0.2112, Spot: 0.07},
+	}}
+
+	tests := []struct {
+		name         string
+		region       string
+		instanceType string
+		spot         bool
+		want         float64
+		estimated    bool
+		ok           bool
+	}{
+		{name: "on-demand", region: "ap-southeast-2", instanceType: "m7i.xlarge", want: 0.252, ok: true},
+		{
+			name:         "estimated spot",
+			region:       "ap-southeast-2",
+			instanceType: "m7i.xlarge",
+			spot:         true,
+			want:         0.252 * spotFactor,
+			estimated:    true,
+			ok:           true,
+		},
+		{name: "known spot", region: "ap-southeast-2", instanceType: "t3.xlarge", spot: true, want: 0.07, ok: true},
+		{name: "unknown type", region: "ap-southeast-2", instanceType: "x9.huge"},
+		{name: "unknown region", region: "us-east-1", instanceType: "m7i.xlarge"},
+		{name: "no region", instanceType: "m7i.xlarge"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, estimated, ok := hourlyCost(prices, tt.region, tt.instanceType, tt.spot)
+			if got != tt.want || estimated != tt.estimated || ok != tt.ok {
+				t.Errorf("got %v, %t, %t, want %v, %t, %t", got, estimated, ok, tt.want, tt.estimated, tt.ok)
+			}
+		})
+	}
+}
+
+func TestAddCost(t *testing.T) {
+	t.Parallel()
+
+	prices := priceTable{"ap-southeast-2": {"m7i.xlarge": {OnDemand: 0.252}}}
+
+	tests := []struct {
+		name   string
+		region string
+		spot   string
+		want   string
+	}{
+		{name: "on-demand", region: "ap-southeast-2", spot: "x", want: "$0.2520"},
+		{name: "estimated spot", region: "ap-southeast-2", spot: tick, want: "~$0.0882"},
+		{name: "unknown region", region: "us-east-1", spot: "x", want: costUnknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			node := &v1.Node{
+				ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"topology.kubernetes.io/region": tt.region}},
+			}
+			row := &tableRow{Type: "m7i.xlarge", Spot: tt.spot}
+			addCost(row, node, prices)
+			if row.Cost != tt.want {
+				t.Errorf("got %s, want %s", row.Cost, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadPrices(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "prices.json")
+	data := `{"ap-southeast-2": {"m7i.xlarge": {"onDemand": 0.252, "spot": 0.09}, "t3.xlarge": {"onDemand": 0.2112}}}`
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	prices, err := loadPrices(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := priceTable{"ap-southeast-2": {
+		"m7i.xlarge": {OnDemand: 0.252, Spot: 0.09},
+		"t3.xlarge":  {OnDemand: 0.2112},
+	}}
+	if !reflect.DeepEqual(prices, want) {
+		t.Errorf("got %v, want %v", prices, want)
+	}
+
+	if _, err := loadPrices(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("expected an error for a missing file")
+	}
+}
@@ -10,17 +10,31 @@ import (
 	"cmp"
 	"fmt"
 	"log"
+	"os"
+	"regexp"
 	"slices"
 	"strings"
+	"time"
 
 	"github.com/jim-barber-he/go/k8s"
 	"github.com/jim-barber-he/go/texttable"
 	"github.com/jim-barber-he/go/util"
 	flag "github.com/spf13/pflag"
+	"golang.org/x/sync/errgroup"
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/util/version"
+	"k8s.io/client-go/kubernetes"
 )
 
-const tick = "\u2713"
+const (
+	bytesPerMiB = 1024 * 1024
+
+	// defaultChunkSize is the number of pods fetched per request when summing the pod requests for --wide.
+	defaultChunkSize = 500
+
+	tick = "\u2713"
+)
 
 var goodStatuses = map[v1.NodeConditionType]v1.ConditionStatus{
 	"ContainerRuntimeUnhealthy":   "False",
@@ -41,19 +55,61 @@ var goodStatuses = map[v1.NodeConditionType]v1.ConditionStatus{
 	"Ready":                       "True",
 }
 
+// clusterData holds what is fetched from a cluster to build the table rows.
+// The requests are keyed by node name, and along with the usage are nil unless they were asked for.
+type clusterData struct {
+	nodes         *v1.NodeList
+	requests      map[string]v1.ResourceList
+	serverVersion string
+	usage         map[string]k8s.NodeUsage
+}
+
+// listOptions holds the options that control how the nodes of a cluster are listed.
+type listOptions struct {
+	match      *regexp.Regexp
+	maxSkew    int
+	metrics    bool
+	notMatch   *regexp.Regexp
+	prices     priceTable
+	problemAge bool
+	wide       bool
+}
+
 // tableRow represents a row in the output table.
 type tableRow struct {
+	Context       string `title:"CONTEXT,omitempty"`
 	Name          string `title:"NAME"`
 	Ok            string `title:"OK"`
+	ProblemAge    string `title:"PROBLEM-AGE,omitempty"`
 	Age           string `title:"AGE"`
 	Version       string `title:"VERSION"`
 	Runtime       string `title:"RUNTIME"`
+	CPU           string `title:"CPU,omitempty"`
+	CPUPercent    string `title:"CPU%,omitempty"`
+	Memory        string `title:"MEM,omitempty"`
+	MemoryPercent string `title:"MEM%,omitempty"`
+	CPUCapacity   string `title:"CPU-CAP,omitempty"`
+	CPUAlloc      string `title:"CPU-ALLOC,omitempty"`
+	CPUReq        string `title:"CPU-REQ,omitempty"`
+	CPUReqPercent string `title:"CPU-REQ%,omitempty"`
+	MemCapacity   string `title:"MEM-CAP,omitempty"`
+	MemAlloc      string `title:"MEM-ALLOC,omitempty"`
+	MemReq        string `title:"MEM-REQ,omitempty"`
+	MemReqPercent string `title:"MEM-REQ%,omitempty"`
 	Type          string `title:"TYPE,omitempty"`
 	Spot          string `title:"SPOT,omitempty"`
+	Cost          string `title:"COST/HR,omitempty"`
 	AZ            string `title:"AZ,omitempty"`
 	InstanceID    string `title:"INSTANCE-ID,omitempty"`
 	IP            string `title:"IP-ADDRESS,omitempty"`
 	InstanceGroup string `title:"INSTANCE-GROUP,omitempty"`
+	ProviderID    string `title:"PROVIDER-ID,omitempty"`
+	PodCIDR       string `title:"POD-CIDR,omitempty"`
+
+	cordoned      bool    `title:"-"`
+	costEstimated bool    `title:"-"`
+	hourlyCost    float64 `title:"-"`
+	notReady      bool    `title:"-"`
 }
 
 // TabTitleRow implements the texttab.TableFormatter interface.
@@ -66,41 +122,100 @@ func (tr *tableRow) TabValues() string {
 	return texttable.ReflectedTabValues(tr)
 }
 
+// TabColours implements the texttab.ColourFormatter interface.
+func (tr *tableRow) TabColours() []texttable.Colour {
+	return texttable.ReflectedTabColours(tr, map[string]texttable.Colour{
+		"Ok": okColour(tr.Ok),
+	})
+}
+
 func main() {
 	kubeContext := flag.String("context", "", "The name of the kubeconfig context to use")
+	kubeContexts := flag.StringSlice(
+		"contexts", nil, "Comma separated list of kubeconfig contexts to list the nodes of, adding a CONTEXT column",
+	)
+	cost := flag.Bool(
+		"cost", false, "Show the estimated hourly cost of the nodes (the bundled prices are for ap-southeast-2 only)",
+	)
+	failOnWarning := flag.Bool(
+		"fail-on-warning", false, "Exit with a non-zero exit code if there are warnings for any of the nodes",
+	)
+	kubeConfig := flag.String("kubeconfig", "", "Path to the kubeconfig file to use")
+	maxSkew := flag.Int(
+		"max-skew", 1, "Warn about nodes with a kubelet more than this many minor versions behind the API server",
+	)
+	match := flag.String("match", "", "Only list nodes with names matching this regular expression")
+	metrics := flag.Bool("metrics", false, "Show the CPU and memory usage of the nodes from metrics-server")
+	notMatch := flag.String("not-match", "", "Don't list nodes with names matching this regular expression")
+	pricesFile := flag.String(
+		"prices", "", "JSON file of prices by region and instance type to use for --cost (implies --cost)",
+	)
+	problemsOnly := flag.Bool(
+		"problems-only", false, "Only list nodes that have bad conditions, are cordoned, or have a skewed kubelet",
+	)
+	problemAge := flag.Bool(
+		"problem-age", false, "Show how long the longest standing bad condition of each node has been active",
+	)
+	summary := flag.Bool("summary", false, "Show a summary of the node counts after the list of nodes")
+	wide := flag.Bool("wide", false, "Show the resources, pod requests, provider ID, and pod CIDRs of the nodes")
 	flag.Parse()
 
-	clientset := k8s.Client(*kubeContext)
+	if *kubeContext != "" && len(*kubeContexts) > 0 {
+		log.Fatal("The --context and --contexts options can't be used together")
+	}
+
+	opts := listOptions{maxSkew: *maxSkew, metrics: *metrics, problemAge: *problemAge, wide: *wide}
+	if *match != "" {
+		var err error
+		if opts.match, err = regexp.Compile(*match); err != nil {
+			log.Fatalf("Invalid --match regular expression: %v", err)
+		}
+	}
+	if *notMatch != "" {
+		var err error
+		if opts.notMatch, err = regexp.Compile(*notMatch); err != nil {
+			log.Fatalf("Invalid --not-match regular expression: %v", err)
+		}
+	}
+	if *cost || *pricesFile != "" {
+		opts.prices = defaultPrices
+		if *pricesFile != "" {
+			var err error
+			if opts.prices, err = loadPrices(*pricesFile); err != nil {
+				log.Fatalf("Error loading prices: %v", err)
+			}
+		}
+	}
 
-	nodes, err := k8s.ListNodes(clientset)
+	var rows []*tableRow
+	var warnings map[string][]string
+	var err error
+	if len(*kubeContexts) > 0 {
+		rows, warnings, err = listContexts(*kubeConfig, *kubeContexts, opts)
+	} else {
+		rows, warnings, err = listNodes(k8s.Client(*kubeConfig, *kubeContext), "", opts)
+	}
 	if err != nil {
 		log.Fatalf("Error listing nodes: %v", err)
 	}
-	if len(nodes.Items) == 0 {
+	if len(rows) == 0 {
 		log.Fatal("No nodes found")
 	}
-
-	var tbl texttable.Table[*tableRow]
-	warnings := make(map[string][]string)
-
-	for _, node := range nodes.Items {
-		row := createTableRow(&node)
-
-		// Keep track of any warning messages for the node and a status to reflect if there are problems.
-		status, messages := getNodeStatus(node.Status.Conditions)
-		warnings[node.Name] = messages
-		if node.Spec.Unschedulable {
-			status += " *"
-			warnings[node.Name] = append(warnings[node.Name], "Scheduling Disabled")
+	if *problemsOnly {
+		rows = slices.DeleteFunc(rows, func(row *tableRow) bool { return row.Ok == tick })
+		if len(rows) == 0 {
+			fmt.Println("No nodes with problems found")
+			return
 		}
-		row.Ok = status
-
-		tbl.Append(&row)
 	}
 
-	// Sort function to sort the rows slice by InstanceGroup, then AZ, then Name when iterating through it.
+	colour := texttable.ColourEnabled(os.Stdout)
+	tbl := texttable.Table[*tableRow]{Rows: rows, Colour: colour}
+
+	// Sort function to sort the rows slice by Context, then InstanceGroup, then AZ, then Name when iterating through it.
 	slices.SortFunc(tbl.Rows, func(a, b *tableRow) int {
 		return cmp.Or(
+			cmp.Compare(a.Context, b.Context),
 			cmp.Compare(a.InstanceGroup, b.InstanceGroup),
 			cmp.Compare(a.AZ, b.AZ),
 			cmp.Compare(a.Name, b.Name),
@@ -111,7 +226,17 @@ func main() {
 	tbl.Write()
 
 	// Display any warning messages for the nodes.
-	printWarnings(warnings)
+	printWarnings(warnings, colour)
+
+	if *summary {
+		writeSummary(os.Stdout, tbl.Rows, opts.prices != nil)
+	}
+
+	if *failOnWarning {
+		if count := warningCount(warnings); count > 0 {
+			log.Fatalf("%d node(s) have warnings", count)
+		}
+	}
 }
 
 // createTableRow creates a tableRow struct from a v1.Node struct.
@@ -125,11 +250,16 @@ func createTableRow(node *v1.Node) tableRow {
 	row.Version = node.Status.NodeInfo.KubeletVersion
 	row.Runtime = util.LastSplitItem(node.Status.NodeInfo.ContainerRuntimeVersion, "/")
 
+	row.cordoned = node.Spec.Unschedulable
+	row.notReady = !slices.ContainsFunc(node.Status.Conditions, func(condition v1.NodeCondition) bool {
+		return condition.Type == v1.NodeReady && condition.Status == v1.ConditionTrue
+	})
+
 	// Additional columns for AWS EC2 instances are from this point on.
 
 	row.Type = node.Labels["node.kubernetes.io/instance-type"]
 
-	if node.Labels["node-role.kubernetes.io/spot-worker"] != "" {
+	if k8s.IsSpotNode(node) {
 		row.Spot = tick
 	} else {
 		row.Spot = "x"
@@ -146,18 +276,129 @@ func createTableRow(node *v1.Node) tableRow {
 		row.IP = node.Annotations["alpha.kubernetes.io/provided-node-ip"]
 	}
 
-	// Handle getting a node group for both EKS and kOps.
+	// Handle getting a node group for kOps, EKS, and Karpenter.
 	row.InstanceGroup = cmp.Or(
 		node.Labels["kops.k8s.io/instancegroup"],
 		node.Labels["eks.amazonaws.com/nodegroup"],
+		node.Labels["karpenter.sh/nodepool"],
 	)
 
 	return row
 }
 
+// addCost fills in the estimated hourly cost of a row from the region of the node, its instance type, and whether it
+// is a spot instance. Spot costs estimated from the on-demand price are prefixed with a ~.
+func addCost(row *tableRow, node *v1.Node, prices priceTable) {
+	hourly, estimated, ok := hourlyCost(prices, node.Labels["topology.kubernetes.io/region"], row.Type, row.Spot == tick)
+	if !ok {
+		row.Cost = costUnknown
+		return
+	}
+
+	row.hourlyCost = hourly
+	row.Cost = fmt.Sprintf("$%.4f", hourly)
+	if estimated {
+		row.costEstimated = true
+		row.Cost = "~" + row.Cost
+	}
+}
+
+// addResources fills in the wide columns of a row with the CPU and memory capacity and allocatable of the node, along
+// with the sum of the requests of the pods on it.
+func addResources(row *tableRow, node *v1.Node, requests v1.ResourceList) {
+	row.CPUCapacity = formatCPU(node.Status.Capacity.Cpu())
+	row.CPUAlloc = formatCPU(node.Status.Allocatable.Cpu())
+	row.CPUReq = formatCPU(requests.Cpu())
+	row.CPUReqPercent = percent(requests.Cpu().MilliValue(), node.Status.Allocatable.Cpu().MilliValue())
+
+	row.MemCapacity = formatMemory(node.Status.Capacity.Memory())
+	row.MemAlloc = formatMemory(node.Status.Allocatable.Memory())
+	row.MemReq = formatMemory(requests.Memory())
+	row.MemReqPercent = percent(requests.Memory().Value(), node.Status.Allocatable.Memory().Value())
+}
+
+// addUsage fills in the CPU and memory usage columns of a row, showing the usage versus what is allocatable.
+// Nodes without metrics (for example, ones that are not ready) show "-".
+func addUsage(row *tableRow, node *v1.Node, usage map[string]k8s.NodeUsage) {
+	row.CPU, row.CPUPercent, row.Memory, row.MemoryPercent = "-", "-", "-", "-"
+
+	nu, ok := usage[node.Name]
+	if !ok {
+		return
+	}
+
+	allocatableCPU := node.Status.Allocatable.Cpu()
+	row.CPU = formatCPU(&nu.CPU) + "/" + formatCPU(allocatableCPU)
+	row.CPUPercent = percent(nu.CPU.MilliValue(), allocatableCPU.MilliValue())
+
+	allocatableMemory := node.Status.Allocatable.Memory()
+	row.Memory = formatMemory(&nu.Memory) + "/" + formatMemory(allocatableMemory)
+	row.MemoryPercent = percent(nu.Memory.Value(), allocatableMemory.Value())
+}
+
+// fetchNodes fetches the list of nodes and the API server version in parallel, along with the usage of the nodes
+// from metrics-server if --metrics was passed, and the requests of the pods on them if --wide was passed.
+func fetchNodes(clientset kubernetes.Interface, opts listOptions) (*clusterData, error) {
+	var data clusterData
+	g := new(errgroup.Group)
+
+	g.Go(func() error {
+		info, err := clientset.Discovery().ServerVersion()
+		if err != nil {
+			return fmt.Errorf("failed to get the API server version: %w", err)
+		}
+		data.serverVersion = info.GitVersion
+		return nil
+	})
+
+	g.Go(func() error {
+		var err error
+		data.nodes, err = k8s.ListNodes(clientset)
+		return err
+	})
+
+	if opts.metrics {
+		g.Go(func() error {
+			var err error
+			data.usage, err = k8s.ListNodeUsage(clientset)
+			if err != nil {
+				return fmt.Errorf("failed to get node usage (is metrics-server installed?): %w", err)
+			}
+			return nil
+		})
+	}
+
+	if opts.wide {
+		g.Go(func() error {
+			var err error
+			data.requests, err = nodeRequests(clientset)
+			return err
+		})
+	}
+
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+
+	return &data, nil
+}
+
+// formatCPU returns a CPU quantity in millicores.
+func formatCPU(q *resource.Quantity) string {
+	return fmt.Sprintf("%dm", q.MilliValue())
+}
+
+// formatMemory returns a memory quantity in mebibytes.
+func formatMemory(q *resource.Quantity) string {
+	return fmt.Sprintf("%dMi", q.Value()/bytesPerMiB)
+}
+
 // getNodeStatus looks at the conditions of a node and returns the node's status and any associated warning messages.
-func getNodeStatus(conditions []v1.NodeCondition) (string, []string) {
+// It also returns when the longest standing of the bad conditions started, which is zero if there are none or their
+// transition times aren't known.
+func getNodeStatus(conditions []v1.NodeCondition) (string, []string, time.Time) {
 	var messages []string
+	var since time.Time
 
 	status := tick
 
@@ -171,10 +412,18 @@ func getNodeStatus(conditions []v1.NodeCondition) (string, []string) {
 			continue
 		}
 		if condition.Status != expectedStatus {
-			messages = append(messages, fmt.Sprintf(
+			message := fmt.Sprintf(
 				"Node condition %s is now: %s, message: \"%s\"",
 				condition.Type, condition.Status, condition.Message,
-			))
+			)
+			// The duration makes it possible to tell a flapping condition apart from a long standing one.
+			if transition := condition.LastTransitionTime.Time; !transition.IsZero() {
+				message += fmt.Sprintf(" (for %s)", util.FormatAge(transition))
+				if since.IsZero() || transition.Before(since) {
+					since = transition
+				}
+			}
+			messages = append(messages, message)
 		}
 	}
 
@@ -182,17 +431,249 @@ func getNodeStatus(conditions []v1.NodeCondition) (string, []string) {
 		status = "x"
 	}
 
-	return status, messages
+	return status, messages, since
+}
+
+// listContexts lists the nodes of each of the kubeconfig contexts in parallel.
+// The warnings are keyed by the context and node name separated by a slash.
+func listContexts(
+	kubeConfig string, kubeContexts []string, opts listOptions,
+) ([]*tableRow, map[string][]string, error) {
+	results := make([][]*tableRow, len(kubeContexts))
+	contextWarnings := make([]map[string][]string, len(kubeContexts))
+
+	g := new(errgroup.Group)
+	for i, kubeContext := range kubeContexts {
+		g.Go(func() error {
+			var err error
+			results[i], contextWarnings[i], err = listNodes(k8s.Client(kubeConfig, kubeContext), kubeContext, opts)
+			if err != nil {
+				return fmt.Errorf("context %s: %w", kubeContext, err)
+			}
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, nil, err
+	}
+
+	var rows []*tableRow
+	warnings := make(map[string][]string)
+	for i, kubeContext := range kubeContexts {
+		rows = append(rows, results[i]...)
+		for nodeName, messages := range contextWarnings[i] {
+			warnings[kubeContext+"/"+nodeName] = messages
+		}
+	}
+
+	return rows, warnings, nil
+}
+
+// listNodes returns a table row for each of the nodes in a cluster along with any warning messages for them.
+// If kubeContext is set then it is shown in the CONTEXT column.
+func listNodes(
+	clientset kubernetes.Interface, kubeContext string, opts listOptions,
+) ([]*tableRow, map[string][]string, error) {
+	data, err := fetchNodes(clientset, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Only look up the events if they are needed to explain why nodes have scheduling disabled.
+	var eventTimes map[string]time.Time
+	if slices.ContainsFunc(data.nodes.Items, func(node v1.Node) bool { return node.Spec.Unschedulable }) {
+		if eventTimes, err = cordonEvents(clientset); err != nil {
+			log.Printf("Warning, unable to find when nodes had scheduling disabled: %v", err)
+		}
+	}
+
+	var rows []*tableRow
+	warnings := make(map[string][]string)
+
+	for _, node := range data.nodes.Items {
+		if !nameMatches(node.Name, opts) {
+			continue
+		}
+
+		row := createTableRow(&node)
+		row.Context = kubeContext
+		if data.usage != nil {
+			addUsage(&row, &node, data.usage)
+		}
+		if opts.wide {
+			addResources(&row, &node, data.requests[node.Name])
+			row.ProviderID = cmp.Or(node.Spec.ProviderID, "-")
+			row.PodCIDR = cmp.Or(podCIDRs(&node), "-")
+			// Empty values are left out of the table, so the optional columns before the wide ones need a value, or
+			// the wide values of nodes missing them would move left into the wrong columns.
+			row.Type = cmp.Or(row.Type, "-")
+			row.AZ = cmp.Or(row.AZ, "-")
+			row.InstanceID = cmp.Or(row.InstanceID, "-")
+			row.IP = cmp.Or(row.IP, "-")
+			row.InstanceGroup = cmp.Or(row.InstanceGroup, "-")
+		}
+		if opts.prices != nil {
+			addCost(&row, &node, opts.prices)
+		}
+
+		// Keep track of any warning messages for the node and a status to reflect if there are problems.
+		status, messages, since := getNodeStatus(node.Status.Conditions)
+		warnings[node.Name] = messages
+		if opts.problemAge {
+			row.ProblemAge = "-"
+			if !since.IsZero() {
+				row.ProblemAge = util.FormatAge(since)
+			}
+		}
+		if node.Spec.Unschedulable {
+			status += " *"
+			warnings[node.Name] = append(warnings[node.Name], cordonWarning(&node, eventTimes))
+		}
+		if message := skewWarning(node.Status.NodeInfo.KubeletVersion, data.serverVersion, opts.maxSkew); message != "" {
+			status += " !"
+			warnings[node.Name] = append(warnings[node.Name], message)
+		}
+		row.Ok = status
+
+		rows = append(rows, &row)
+	}
+
+	return rows, warnings, nil
+}
+
+// nameMatches returns true if a node name matches the --match regular expression and doesn't match the --not-match
+// regular expression, for those that were passed.
+func nameMatches(name string, opts listOptions) bool {
+	if opts.match != nil && !opts.match.MatchString(name) {
+		return false
+	}
+	if opts.notMatch != nil && opts.notMatch.MatchString(name) {
+		return false
+	}
+
+	return true
+}
+
+// nodeRequests returns the sum of the resource requests of the pods on each node, keyed by node name.
+// Pods that have finished are left out since they no longer hold on to their requests.
+func nodeRequests(clientset kubernetes.Interface) (map[string]v1.ResourceList, error) {
+	requests := make(map[string]v1.ResourceList)
+
+	err := k8s.ListPodsPaged(
+		clientset, "", "", "status.phase!=Succeeded,status.phase!=Failed", defaultChunkSize,
+		func(pods *v1.PodList) error {
+			for i := range pods.Items {
+				pod := &pods.Items[i]
+				if pod.Spec.NodeName == "" {
+					continue
+				}
+				total, ok := requests[pod.Spec.NodeName]
+				if !ok {
+					total = v1.ResourceList{}
+					requests[pod.Spec.NodeName] = total
+				}
+				for name, quantity := range k8s.PodRequests(pod) {
+					sum := total[name]
+					sum.Add(quantity)
+					total[name] = sum
+				}
+			}
+			return nil
+		},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pod requests: %w", err)
+	}
+
+	return requests, nil
+}
+
+// okColour returns red for nodes with bad conditions, yellow for nodes that are otherwise cordoned or have a skewed
+// kubelet version, and green for healthy nodes.
+func okColour(ok string) texttable.Colour {
+	switch {
+	case strings.HasPrefix(ok, "x"):
+		return texttable.ColourRed
+	case ok != tick:
+		return texttable.ColourYellow
+	default:
+		return texttable.ColourGreen
+	}
+}
+
+// percent returns used as a percentage of total, or "-" if the total is unknown.
+func percent(used, total int64) string {
+	if total <= 0 {
+		return "-"
+	}
+
+	return fmt.Sprintf("%d%%", used*100/total)
+}
+
+// podCIDRs returns the comma separated pod CIDRs assigned to a node.
+// Older clusters only set the single PodCIDR field, which is used when PodCIDRs isn't set.
+func podCIDRs(node *v1.Node) string {
+	if len(node.Spec.PodCIDRs) > 0 {
+		return strings.Join(node.Spec.PodCIDRs, ",")
+	}
+
+	return node.Spec.PodCIDR
 }
 
 // printWarnings displays any warning messages that were collected for the nodes.
-func printWarnings(warnings map[string][]string) {
+// If colour is set then the messages are coloured yellow.
+func printWarnings(warnings map[string][]string, colour bool) {
+	messageColour := texttable.ColourNone
+	if colour {
+		messageColour = texttable.ColourYellow
+	}
+
 	for nodeName, messages := range warnings {
 		if len(messages) > 0 {
 			fmt.Println()
 			for _, message := range messages {
-				fmt.Printf("%s: %s\n", nodeName, message)
+				fmt.Printf("%s: %s\n", nodeName, messageColour.Apply(message))
 			}
 		}
 	}
 }
+
+// skewWarning returns a warning if the kubelet version is more than maxSkew minor versions behind the API server
+// version, otherwise it returns an empty string.
+func skewWarning(kubeletVersion, serverVersion string, maxSkew int) string {
+	kubelet, err := version.ParseGeneric(kubeletVersion)
+	if err != nil {
+		return fmt.Sprintf("Unable to check kubelet version skew: %v", err)
+	}
+	server, err := version.ParseGeneric(serverVersion)
+	if err != nil {
+		return fmt.Sprintf("Unable to check kubelet version skew: %v", err)
+	}
+
+	if kubelet.Major() != server.Major() {
+		return fmt.Sprintf(
+			"Kubelet version %s has a different major version to the API server %s", kubeletVersion, serverVersion,
+		)
+	}
+
+	skew := int(server.Minor()) - int(kubelet.Minor())
+	if skew > maxSkew {
+		return fmt.Sprintf(
+			"Kubelet version %s is %d minor versions behind the API server %s", kubeletVersion, skew, serverVersion,
+		)
+	}
+
+	return ""
+}
+
+// warningCount returns the number of nodes that have warning messages.
+func warningCount(warnings map[string][]string) int {
+	var count int
+	for _, messages := range warnings {
+		if len(messages) > 0 {
+			count++
+		}
+	}
+
+	return count
+}
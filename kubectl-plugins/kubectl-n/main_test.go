@@ -2,9 +2,19 @@ package main
 
 import (
 	"reflect"
+	"regexp"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/jim-barber-he/go/k8s"
+	"github.com/jim-barber-he/go/texttable"
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/version"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	"k8s.io/client-go/kubernetes/fake"
 )
 
 func TestTabTitleRow(t *testing.T) {
@@ -168,7 +178,7 @@ func TestGetNodeStatus(t *testing.T) {
 	for _, tt := range tests {
 		t.Run("getNodeStatus", func(t *testing.T) {
 			t.Parallel()
-			status, messages := getNodeStatus(tt.conditions)
+			status, messages, _ := getNodeStatus(tt.conditions)
 			if status != tt.status {
 				t.Errorf("got %s, want %s", status, tt.status)
 			}
@@ -178,3 +188,528 @@ func TestGetNodeStatus(t *testing.T) {
 		})
 	}
 }
+
+func TestAddUsage(t *testing.T) {
+	t.Parallel()
+
+	node := &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node1"},
+		Status: v1.NodeStatus{
+			Allocatable: v1.ResourceList{
+				v1.ResourceCPU:    resource.MustParse("3920m"),
+				v1.ResourceMemory: resource.MustParse("6964Mi"),
+			},
+		},
+	}
+
+	tests := []struct {
+		name  string
+		usage map[string]k8s.NodeUsage
+		want  tableRow
+	}{
+		{
+			name: "with metrics",
+			usage: map[string]k8s.NodeUsage{
+				"node1": {CPU: resource.MustParse("850m"), Memory: resource.MustParse("5120Mi")},
+			},
+			want: tableRow{CPU: "850m/3920m", CPUPercent: "21%", Memory: "5120Mi/6964Mi", MemoryPercent: "73%"},
+		},
+		{
+			name:  "without metrics",
+			usage: map[string]k8s.NodeUsage{},
+			want:  tableRow{CPU: "-", CPUPercent: "-", Memory: "-", MemoryPercent: "-"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			var row tableRow
+			addUsage(&row, node, tt.usage)
+			if !reflect.DeepEqual(row, tt.want) {
+				t.Errorf("got %+v, want %+v", row, tt.want)
+			}
+		})
+	}
+}
+
+func TestPercent(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		used, total int64
+		want        string
+	}{
+		{used: 50, total: 200, want: "25%"},
+		{used: 300, total: 200, want: "150%"},
+		{used: 50, total: 0, want: "-"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			t.Parallel()
+			if got := percent(tt.used, tt.total); got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSkewWarning(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name           string
+		kubeletVersion string
+		serverVersion  string
+		maxSkew        int
+		want           string
+	}{
+		{
+			name:           "same version",
+			kubeletVersion: "v1.29.9",
+			serverVersion:  "v1.29.9",
+			maxSkew:        1,
+			want:           "",
+		},
+		{
+			name:           "within skew",
+			kubeletVersion: "v1.28.1-eks-43840fb",
+			serverVersion:  "v1.29.10-eks-7f9249a",
+			maxSkew:        1,
+			want:           "",
+		},
+		{
+			name:           "beyond skew",
+			kubeletVersion: "v1.27.3",
+			serverVersion:  "v1.29.9",
+			maxSkew:        1,
+			want:           "Kubelet version v1.27.3 is 2 minor versions behind the API server v1.29.9",
+		},
+		{
+			name:           "newer kubelet",
+			kubeletVersion: "v1.30.0",
+			serverVersion:  "v1.29.9",
+			maxSkew:        0,
+			want:           "",
+		},
+		{
+			name:           "unparsable version",
+			kubeletVersion: "unknown",
+			serverVersion:  "v1.29.9",
+			maxSkew:        1,
+			want:           `Unable to check kubelet version skew: could not parse "unknown" as version`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := skewWarning(tt.kubeletVersion, tt.serverVersion, tt.maxSkew); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOkColour(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		ok   string
+		want texttable.Colour
+	}{
+		{ok: tick, want: texttable.ColourGreen},
+		{ok: tick + " *", want: texttable.ColourYellow},
+		{ok: tick + " !", want: texttable.ColourYellow},
+		{ok: "x", want: texttable.ColourRed},
+		{ok: "x *", want: texttable.ColourRed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ok, func(t *testing.T) {
+			t.Parallel()
+			if got := okColour(tt.ok); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestListNodes(t *testing.T) {
+	t.Parallel()
+
+	clientset := fake.NewSimpleClientset(
+		&v1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: "node1"},
+			Status: v1.NodeStatus{
+				Conditions: []v1.NodeCondition{{Type: v1.NodeReady, Status: v1.ConditionTrue}},
+				NodeInfo:   v1.NodeSystemInfo{KubeletVersion: "v1.29.9"},
+			},
+		},
+		&v1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: "node2"},
+			Spec:       v1.NodeSpec{Unschedulable: true},
+			Status: v1.NodeStatus{
+				Conditions: []v1.NodeCondition{{Type: v1.NodeReady, Status: v1.ConditionTrue}},
+				NodeInfo:   v1.NodeSystemInfo{KubeletVersion: "v1.27.3"},
+			},
+		},
+	)
+	clientset.Discovery().(*fakediscovery.FakeDiscovery).FakedServerVersion = &version.Info{GitVersion: "v1.29.9"}
+
+	rows, warnings, err := listNodes(clientset, "prod", listOptions{maxSkew: 1})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("got %d rows, want 2", len(rows))
+	}
+
+	for _, row := range rows {
+		if row.Context != "prod" {
+			t.Errorf("got context %q, want %q", row.Context, "prod")
+		}
+	}
+	if rows[0].Ok != tick {
+		t.Errorf("got status %q for node1, want %q", rows[0].Ok, tick)
+	}
+	if want := tick + " * !"; rows[1].Ok != want {
+		t.Errorf("got status %q for node2, want %q", rows[1].Ok, want)
+	}
+
+	want := []string{
+		"Scheduling Disabled",
+		"Kubelet version v1.27.3 is 2 minor versions behind the API server v1.29.9",
+	}
+	if !reflect.DeepEqual(warnings["node2"], want) {
+		t.Errorf("got warnings %v, want %v", warnings["node2"], want)
+	}
+}
+
+func TestListNodesWide(t *testing.T) {
+	t.Parallel()
+
+	ready := v1.NodeStatus{
+		Conditions: []v1.NodeCondition{{Type: v1.NodeReady, Status: v1.ConditionTrue}},
+		NodeInfo:   v1.NodeSystemInfo{KubeletVersion: "v1.29.9", ContainerRuntimeVersion: "containerd://1.7.22"},
+	}
+	clientset := fake.NewSimpleClientset(
+		&v1.Node{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "node1",
+				Labels:      map[string]string{"kops.k8s.io/instancegroup": "nodes"},
+				Annotations: map[string]string{"alpha.kubernetes.io/provided-node-ip": "10.1.1.1"},
+			},
+			Spec:   v1.NodeSpec{ProviderID: "aws:///ap-southeast-2a/i-0123456789abcdef0", PodCIDR: "100.96.1.0/24"},
+			Status: ready,
+		},
+		&v1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: "node2"},
+			Spec:       v1.NodeSpec{ProviderID: "aws:///ap-southeast-2b/i-0fedcba9876543210"},
+			Status:     ready,
+		},
+	)
+	clientset.Discovery().(*fakediscovery.FakeDiscovery).FakedServerVersion = &version.Info{GitVersion: "v1.29.9"}
+
+	rows, _, err := listNodes(clientset, "", listOptions{maxSkew: 1, wide: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := map[string]map[string]string{
+		"node1": {
+			"INSTANCE-GROUP": "nodes",
+			"PROVIDER-ID":    "aws:///ap-southeast-2a/i-0123456789abcdef0",
+			"POD-CIDR":       "100.96.1.0/24",
+		},
+		"node2": {
+			"INSTANCE-GROUP": "-",
+			"PROVIDER-ID":    "aws:///ap-southeast-2b/i-0fedcba9876543210",
+			"POD-CIDR":       "-",
+		},
+	}
+	// The title row of the table comes from the first row, so the values of every row have to line up with it.
+	titles := strings.Split(rows[0].TabTitleRow(), "\t")
+	for _, row := range rows {
+		values := strings.Split(row.TabValues(), "\t")
+		if len(values) != len(titles) {
+			t.Fatalf("got %d values for %d titles for %s: %q", len(values), len(titles), row.Name, values)
+		}
+		for i, title := range titles {
+			if value, ok := want[row.Name][title]; ok && values[i] != value {
+				t.Errorf("got %q under %s for %s, want %q", values[i], title, row.Name, value)
+			}
+		}
+	}
+}
+
+func TestCreateTableRow(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name          string
+		labels        map[string]string
+		spot          string
+		instanceGroup string
+	}{
+		{
+			name: "kOps",
+			labels: map[string]string{
+				"kops.k8s.io/instancegroup":           "node",
+				"node-role.kubernetes.io/spot-worker": "true",
+			},
+			spot:          tick,
+			instanceGroup: "node",
+		},
+		{
+			name:          "EKS",
+			labels:        map[string]string{"eks.amazonaws.com/nodegroup": "ng-1"},
+			spot:          "x",
+			instanceGroup: "ng-1",
+		},
+		{
+			name:          "Karpenter spot",
+			labels:        map[string]string{"karpenter.sh/capacity-type": "spot", "karpenter.sh/nodepool": "default"},
+			spot:          tick,
+			instanceGroup: "default",
+		},
+		{
+			name: "Karpenter on-demand",
+			labels: map[string]string{
+				"karpenter.sh/capacity-type": "on-demand",
+				"karpenter.sh/nodepool":      "system",
+			},
+			spot:          "x",
+			instanceGroup: "system",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			node := &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "i-0123456789abcdef0", Labels: tt.labels}}
+			row := createTableRow(node)
+			if row.Spot != tt.spot {
+				t.Errorf("got spot %q, want %q", row.Spot, tt.spot)
+			}
+			if row.InstanceGroup != tt.instanceGroup {
+				t.Errorf("got instance group %q, want %q", row.InstanceGroup, tt.instanceGroup)
+			}
+		})
+	}
+}
+
+func TestAddResources(t *testing.T) {
+	t.Parallel()
+
+	node := &v1.Node{
+		Status: v1.NodeStatus{
+			Capacity: v1.ResourceList{
+				v1.ResourceCPU:    resource.MustParse("4"),
+				v1.ResourceMemory: resource.MustParse("8Gi"),
+			},
+			Allocatable: v1.ResourceList{
+				v1.ResourceCPU:    resource.MustParse("3920m"),
+				v1.ResourceMemory: resource.MustParse("6964Mi"),
+			},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		requests v1.ResourceList
+		want     tableRow
+	}{
+		{
+			name: "with pods",
+			requests: v1.ResourceList{
+				v1.ResourceCPU:    resource.MustParse("3100m"),
+				v1.ResourceMemory: resource.MustParse("4Gi"),
+			},
+			want: tableRow{
+				CPUCapacity: "4000m", CPUAlloc: "3920m", CPUReq: "3100m", CPUReqPercent: "79%",
+				MemCapacity: "8192Mi", MemAlloc: "6964Mi", MemReq: "4096Mi", MemReqPercent: "58%",
+			},
+		},
+		{
+			name:     "without pods",
+			requests: nil,
+			want: tableRow{
+				CPUCapacity: "4000m", CPUAlloc: "3920m", CPUReq: "0m", CPUReqPercent: "0%",
+				MemCapacity: "8192Mi", MemAlloc: "6964Mi", MemReq: "0Mi", MemReqPercent: "0%",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			var row tableRow
+			addResources(&row, node, tt.requests)
+			if !reflect.DeepEqual(row, tt.want) {
+				t.Errorf("got %+v, want %+v", row, tt.want)
+			}
+		})
+	}
+}
+
+func TestNodeRequests(t *testing.T) {
+	t.Parallel()
+
+	newPod := func(name, nodeName, cpu string) *v1.Pod {
+		return &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Spec: v1.PodSpec{
+				NodeName: nodeName,
+				Containers: []v1.Container{
+					{Resources: v1.ResourceRequirements{Requests: v1.ResourceList{
+						v1.ResourceCPU: resource.MustParse(cpu),
+					}}},
+				},
+			},
+		}
+	}
+
+	clientset := fake.NewSimpleClientset(
+		newPod("a", "node1", "500m"),
+		newPod("b", "node1", "250m"),
+		newPod("c", "node2", "1"),
+		newPod("pending", "", "2"),
+	)
+
+	requests, err := nodeRequests(clientset)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := map[string]int64{"node1": 750, "node2": 1000}
+	if len(requests) != len(want) {
+		t.Fatalf("got requests for %d nodes, want %d", len(requests), len(want))
+	}
+	for nodeName, cpu := range want {
+		list := requests[nodeName]
+		if got := list.Cpu().MilliValue(); got != cpu {
+			t.Errorf("got %dm for %s, want %dm", got, nodeName, cpu)
+		}
+	}
+}
+
+func TestNameMatches(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		match    string
+		notMatch string
+		result   bool
+	}{
+		{name: "ip-10-160-24-50.ap-southeast-2.compute.internal", result: true},
+		{name: "ip-10-160-24-50.ap-southeast-2.compute.internal", match: "^ip-10-160-24-", result: true},
+		{name: "ip-10-160-41-121.ap-southeast-2.compute.internal", match: "^ip-10-160-24-", result: false},
+		{name: "ip-10-160-24-50.ap-southeast-2.compute.internal", notMatch: "-50\\.", result: false},
+		{name: "ip-10-160-24-51.ap-southeast-2.compute.internal", match: "^ip-", notMatch: "-50\\.", result: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			var opts listOptions
+			if tt.match != "" {
+				opts.match = regexp.MustCompile(tt.match)
+			}
+			if tt.notMatch != "" {
+				opts.notMatch = regexp.MustCompile(tt.notMatch)
+			}
+			if result := nameMatches(tt.name, opts); result != tt.result {
+				t.Errorf("got %t, want %t", result, tt.result)
+			}
+		})
+	}
+}
+
+func TestWarningCount(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		warnings map[string][]string
+		result   int
+	}{
+		{name: "none", warnings: map[string][]string{}, result: 0},
+		{name: "healthy", warnings: map[string][]string{"node1": nil, "node2": {}}, result: 0},
+		{
+			name: "problems",
+			warnings: map[string][]string{
+				"node1": nil,
+				"node2": {"Scheduling Disabled"},
+				"node3": {"Node condition Ready is now: False, message: \"Kubelet stopped posting node status.\""},
+			},
+			result: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if result := warningCount(tt.warnings); result != tt.result {
+				t.Errorf("got %d, want %d", result, tt.result)
+			}
+		})
+	}
+}
+
+func TestGetNodeStatusSince(t *testing.T) {
+	t.Parallel()
+
+	older := metav1.NewTime(time.Now().Add(-(3*time.Hour + 12*time.Minute + 30*time.Second)))
+	newer := metav1.NewTime(time.Now().Add(-(2*time.Hour + 5*time.Minute + 30*time.Second)))
+
+	conditions := []v1.NodeCondition{
+		{Type: "Ready", Status: v1.ConditionTrue, LastTransitionTime: older},
+		{Type: "DiskPressure", Status: v1.ConditionTrue, Message: "Disk is full", LastTransitionTime: newer},
+		{Type: "PIDPressure", Status: v1.ConditionTrue, Message: "PID is full", LastTransitionTime: older},
+	}
+
+	status, messages, since := getNodeStatus(conditions)
+	if status != "x" {
+		t.Errorf("got status %s, want x", status)
+	}
+	want := []string{
+		"Node condition DiskPressure is now: True, message: \"Disk is full\" (for 2h5m)",
+		"Node condition PIDPressure is now: True, message: \"PID is full\" (for 3h12m)",
+	}
+	if !reflect.DeepEqual(messages, want) {
+		t.Errorf("got %v, want %v", messages, want)
+	}
+	if !since.Equal(older.Time) {
+		t.Errorf("got since %v, want %v", since, older.Time)
+	}
+}
+
+func TestPodCIDRs(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		spec   v1.NodeSpec
+		result string
+	}{
+		{name: "none", spec: v1.NodeSpec{}, result: ""},
+		{name: "single", spec: v1.NodeSpec{PodCIDR: "100.96.1.0/24"}, result: "100.96.1.0/24"},
+		{
+			name:   "dual stack",
+			spec:   v1.NodeSpec{PodCIDR: "100.96.1.0/24", PodCIDRs: []string{"100.96.1.0/24", "fd00:100:96:1::/64"}},
+			result: "100.96.1.0/24,fd00:100:96:1::/64",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if result := podCIDRs(&v1.Node{Spec: tt.spec}); result != tt.result {
+				t.Errorf("got %s, want %s", result, tt.result)
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+	"text/tabwriter"
+)
+
+// summaryGroup is a set of node counts keyed by some attribute of the nodes.
+type summaryGroup struct {
+	title  string
+	counts map[string]int
+}
+
+// newSummaryGroups counts the nodes by instance group, instance type, AZ, and whether they are spot instances.
+func newSummaryGroups(rows []*tableRow) []summaryGroup {
+	groups := []summaryGroup{
+		{title: "INSTANCE-GROUP", counts: make(map[string]int)},
+		{title: "TYPE", counts: make(map[string]int)},
+		{title: "AZ", counts: make(map[string]int)},
+		{title: "CAPACITY", counts: make(map[string]int)},
+	}
+
+	for _, row := range rows {
+		capacity := "on-demand"
+		if row.Spot == tick {
+			capacity = "spot"
+		}
+
+		groups[0].counts[cmp.Or(row.InstanceGroup, "<none>")]++
+		groups[1].counts[cmp.Or(row.Type, "<none>")]++
+		groups[2].counts[cmp.Or(row.AZ, "<none>")]++
+		groups[3].counts[capacity]++
+	}
+
+	return groups
+}
+
+// writeSummary writes the number of nodes in each instance group, instance type, AZ, and spot/on-demand, along with
+// how many of them are NotReady or cordoned.
+// If withCost is set then the estimated total cost of the nodes is also written.
+func writeSummary(w io.Writer, rows []*tableRow, withCost bool) {
+	var cordoned, estimated, notReady, unpriced int
+	var hourly float64
+	for _, row := range rows {
+		hourly += row.hourlyCost
+		if row.Cost == costUnknown {
+			unpriced++
+		}
+		if row.costEstimated {
+			estimated++
+		}
+		if row.cordoned {
+			cordoned++
+		}
+		if row.notReady {
+			notReady++
+		}
+	}
+
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "Nodes: %d, NotReady: %d, Cordoned: %d\n", len(rows), notReady, cordoned)
+	if withCost {
+		fmt.Fprintf(w, "Estimated cost: $%.2f/hour, $%.2f/month", hourly, hourly*hoursPerMonth)
+		var notes []string
+		if estimated > 0 {
+			notes = append(notes, fmt.Sprintf("spot price of %d node(s) estimated", estimated))
+		}
+		if unpriced > 0 {
+			notes = append(notes, fmt.Sprintf("excluding %d node(s) with no price for their region", unpriced))
+		}
+		if len(notes) > 0 {
+			fmt.Fprintf(w, " (%s)", strings.Join(notes, "; "))
+		}
+		fmt.Fprintln(w)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	for _, group := range newSummaryGroups(rows) {
+		fmt.Fprintln(tw)
+		fmt.Fprintf(tw, "%s\tNODES\n", group.title)
+		for _, key := range slices.Sorted(maps.Keys(group.counts)) {
+			fmt.Fprintf(tw, "%s\t%d\n", key, group.counts[key])
+		}
+	}
+	tw.Flush()
+}
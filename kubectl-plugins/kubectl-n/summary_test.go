@@ -0,0 +1,63 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestWriteSummary(t *testing.T) {
+	t.Parallel()
+
+	rows := []*tableRow{
+		{Name: "node1", Type: "m7i.xlarge", Spot: tick, AZ: "a", InstanceGroup: "node"},
+		{Name: "node2", Type: "m7i.xlarge", Spot: tick, AZ: "b", InstanceGroup: "node", cordoned: true},
+		{Name: "node3", Type: "t3.xlarge", Spot: "x", AZ: "a", InstanceGroup: "control-plane", notReady: true},
+		{Name: "node4", Type: "t3.xlarge", Spot: "x", AZ: "b"},
+	}
+
+	want := `
+Nodes: 4, NotReady: 1, Cordoned: 1
+
+INSTANCE-GROUP  NODES
+<none>          1
+control-plane   1
+node            2
+
+TYPE        NODES
+m7i.xlarge  2
+t3.xlarge   2
+
+AZ  NODES
+a   2
+b   2
+
+CAPACITY   NODES
+on-demand  2
+spot       2
+`
+
+	var buf bytes.Buffer
+	writeSummary(&buf, rows, false)
+	if got := buf.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestWriteSummaryCost(t *testing.T) {
+	t.Parallel()
+
+	rows := []*tableRow{
+		{Name: "node1", Cost: "$0.2520", hourlyCost: 0.252},
+		{Name: "node2", Cost: "~$0.0882", hourlyCost: 0.0882, costEstimated: true},
+		{Name: "node3", Cost: costUnknown},
+	}
+
+	var buf bytes.Buffer
+	writeSummary(&buf, rows, true)
+	want := "Estimated cost: $0.34/hour, $248.35/month " +
+		"(spot price of 1 node(s) estimated; excluding 1 node(s) with no price for their region)\n"
+	if got := buf.String(); !strings.Contains(got, want) {
+		t.Errorf("got:\n%s\nwant it to contain:\n%s", got, want)
+	}
+}
@@ -7,14 +7,19 @@ package main
 
 import (
 	"cmp"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"log"
 	"os"
+	"regexp"
 	"runtime"
 	"runtime/pprof"
 	"slices"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/jim-barber-he/go/k8s"
 	"github.com/jim-barber-he/go/texttable"
@@ -22,46 +27,323 @@ import (
 	flag "github.com/spf13/pflag"
 	"golang.org/x/sync/errgroup"
 	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/yaml"
 )
 
-const tick = "\u2713"
+const (
+	// defaultAllowStatus matches the statuses of pods that have finished successfully, which are not problems.
+	defaultAllowStatus = "^(Completed|Succeeded)$"
 
-var errNoPodsFound = errors.New("no pods found")
+	// defaultChunkSize is the default number of pods to fetch per request, which matches kubectl.
+	defaultChunkSize = 500
+
+	tick = "\u2713"
+)
+
+// Supported values for the --output option.
+const (
+	outputJSON = "json"
+	outputTSV  = "tsv"
+	outputYAML = "yaml"
+)
+
+// Supported values for the --sort-by option.
+const (
+	sortByName     = "name"
+	sortByRestarts = "restarts"
+)
+
+// Pod statuses that are coloured green or red. Other statuses are transitional and coloured yellow.
+var (
+	goodStatuses = []string{"Completed", "Running", "Succeeded"}
+	badStatuses  = []string{
+		"ContainerStatusUnknown",
+		"CrashLoopBackOff",
+		"CreateContainerConfigError",
+		"CreateContainerError",
+		"DeadlineExceeded",
+		"ErrImagePull",
+		"Error",
+		"Evicted",
+		"Failed",
+		"ImagePullBackOff",
+		"InvalidImageName",
+		"NodeLost",
+		"OOMKilled",
+		"RunContainerError",
+		"Unknown",
+	}
+)
+
+var (
+	errConflictingCapacityOptions = errors.New("--spot-only and --on-demand-only are mutually exclusive")
+	errNoPodsFound                = errors.New("no pods found")
+	errNoPodsFoundOnCapacity      = errors.New("no pods found on nodes of the requested capacity type")
+	errNoPodsMatchFilters         = errors.New("no pods found matching the filters")
+	errNodeInfoNeeded             = errors.New("--no-node-info conflicts with options that need the nodes")
+	errProblemPods                = errors.New("pods with problems found")
+)
 
 // tableRow represents a row in the output table.
+// The json tags are used for both the JSON and YAML output formats.
+// Spot is shown as a tick or an x in the table, while IsSpot is shown as a boolean in the JSON and YAML output instead,
+// which is null when the pod isn't on a known node.
+// Likewise IP and Node are shown in the table with placeholders for a missing IP and a node that is gone, while PodIP,
+// NodeName, and NodeGone hold the plain details for the JSON and YAML output. NodeGone is null when the pod has no node,
+// or the nodes weren't looked up because of --no-node-info.
 type tableRow struct {
-	Namespace string `title:"NAMESPACE,omitempty"`
-	Name      string `title:"NAME"`
-	Ready     string `title:"READY"`
-	Status    string `title:"STATUS"`
-	Restarts  string `title:"RESTARTS"`
-	Age       string `title:"AGE"`
-	IP        string `title:"IP"`
-	Node      string `title:"NODE"`
-	Spot      string `title:"SPOT"`
-	AZ        string `title:"AZ,omitempty"`
+	Namespace string `json:"namespace,omitempty" title:"NAMESPACE,omitempty"`
+	Name      string `json:"name"                title:"NAME"`
+	Ready     string `json:"ready"               title:"READY"`
+	Status    string `json:"status"              title:"STATUS"`
+	Restarts  string `json:"restarts"            title:"RESTARTS"`
+	Age       string `json:"age"                 title:"AGE"`
+	CPU       string `json:"cpu,omitempty"       title:"CPU,omitempty"`
+	Memory    string `json:"memory,omitempty"    title:"MEM,omitempty"`
+	IP        string `json:"-"                   title:"IP"`
+	PodIP     string `json:"ip"                  title:"-"`
+	Node      string `json:"-"                   title:"NODE"`
+	NodeName  string `json:"node"                title:"-"`
+	NodeGone  *bool  `json:"nodeGone"            title:"-"`
+	Spot      string `json:"-"                   title:"SPOT"`
+	IsSpot    *bool  `json:"spot"                title:"-"`
+	AZ        string `json:"az,omitempty"        title:"AZ,omitempty"`
+	Images    string `json:"images,omitempty"    title:"IMAGES,omitempty"`
+	Priority  string `json:"priority,omitempty"  title:"PRIORITY,omitempty"`
+	PVCs      string `json:"pvcs,omitempty"      title:"PVCS,omitempty"`
+	SA        string `json:"serviceAccount,omitempty" title:"SERVICEACCOUNT,omitempty"`
+
+	// Labels holds the values of the labels requested via --label-columns, which are shown as extra columns in the
+	// order of labelColumns.
+	Labels       map[string]string `json:"labels,omitempty" title:"-"`
+	labelColumns []string          `title:"-"`
+
+	// key is the "namespace/name" of the pod, used to look up its usage.
+	key string `title:"-"`
+	// unscheduled holds the pod when --why-pending was passed and the pod is waiting to be scheduled.
+	unscheduled *v1.Pod `title:"-"`
 }
 
 // TabTitleRow implements the texttab.TableFormatter interface.
+// Like kubectl, the titles of label columns are the upper-cased label names without any prefix.
 func (tr *tableRow) TabTitleRow() string {
-	return texttable.ReflectedTitleRow(tr)
+	titles := []string{texttable.ReflectedTitleRow(tr.withPlaceholders())}
+	for _, label := range tr.labelColumns {
+		titles = append(titles, strings.ToUpper(util.LastSplitItem(label, "/")))
+	}
+	return strings.Join(titles, "\t")
 }
 
 // TabValues implements the texttab.TableFormatter interface.
 func (tr *tableRow) TabValues() string {
-	return texttable.ReflectedTabValues(tr)
+	values := []string{texttable.ReflectedTabValues(tr.withPlaceholders())}
+	for _, label := range tr.labelColumns {
+		values = append(values, tr.Labels[label])
+	}
+	return strings.Join(values, "\t")
+}
+
+// TabFields implements the texttab.FieldFormatter interface so that the columns stay in place with --output tsv.
+func (tr *tableRow) TabFields() []texttable.Field {
+	fields := texttable.ReflectedTabFields(tr)
+	for _, label := range tr.labelColumns {
+		fields = append(fields, texttable.Field{
+			Title: strings.ToUpper(util.LastSplitItem(label, "/")),
+			Value: tr.Labels[label],
+		})
+	}
+	return fields
+}
+
+// TabColours implements the texttab.ColourFormatter interface.
+func (tr *tableRow) TabColours() []texttable.Colour {
+	return texttable.ReflectedTabColours(tr.withPlaceholders(), map[string]texttable.Colour{
+		"Ready":    readyColour(tr.Ready, tr.Status),
+		"Status":   statusColour(tr.Status),
+		"Restarts": restartsColour(tr.Restarts),
+	})
+}
+
+// withPlaceholders returns a copy of the row with a "-" in place of the node details that a pod doesn't have, such as
+// when it is waiting to be scheduled or its node is gone.
+// Empty values are left out of the aligned table, so without them the values of the later columns would move left into
+// the wrong columns. The columns of --output tsv are kept in place by TabFields instead.
+func (tr *tableRow) withPlaceholders() *tableRow {
+	row := *tr
+	row.Node = cmp.Or(row.Node, "-")
+	row.Spot = cmp.Or(row.Spot, "-")
+	row.AZ = cmp.Or(row.AZ, "-")
+	return &row
 }
 
 // Commandline options.
 type options struct {
 	allNamespaces bool
+	allowStatus   string
+	allowRegexp   *regexp.Regexp
+	chunkSize     int64
+	failProblems  bool
+	fieldSelector string
 	grep          string
+	grepAny       string
+	grepAnyRegexp *regexp.Regexp
+	kubeConfig    string
 	kubeContext   string
+	labelColumns  []string
 	labelSelector string
+	metrics       bool
+	minRestarts   int
 	namespace     string
+	node          string
+	noHeaders     bool
+	noNodeInfo    bool
+	onDemandOnly  bool
+	output        string
 	profileCPU    string
 	profileMemory string
+	pvc           string
+	pvcRegexp     *regexp.Regexp
+	sa            string
+	showImages    bool
+	showPriority  bool
+	showPVCs      bool
+	showSA        bool
+	sortBy        string
+	spotOnly      bool
+	stuckTerm     time.Duration
+	whyPending    bool
+}
+
+// nodesAndUsage holds the nodes that the pods run on, and the usage of the pods if --metrics was passed, which are
+// used to fill in the details of the rows.
+type nodesAndUsage struct {
+	nodes map[string]*v1.Node
+	usage map[string]k8s.PodUsage
+}
+
+// fetch fetches the nodes, and the usage of the pods if --metrics was passed, in parallel using the errgroup.
+// They are only safe to use once the errgroup has been waited on.
+// The nodes are left nil if --no-node-info was passed, and usage is left nil if it wasn't requested.
+func (nu *nodesAndUsage) fetch(g *errgroup.Group, clientset *kubernetes.Clientset, namespace string, opts options) {
+	if !opts.noNodeInfo {
+		g.Go(func() error {
+			var err error
+			nu.nodes, err = lookupNodes(clientset, opts.node)
+			return err
+		})
+	}
+
+	if opts.metrics {
+		g.Go(func() error {
+			var err error
+			nu.usage, err = k8s.ListPodUsage(clientset, namespace, opts.labelSelector)
+			if err != nil {
+				return fmt.Errorf("failed to get pod usage (is metrics-server installed?): %w", err)
+			}
+			return nil
+		})
+	}
+}
+
+// runTotals keeps what is needed once the rows have been displayed, so that the rows themselves don't need to be kept
+// when they are displayed a chunk at a time.
+type runTotals struct {
+	// The number of rows left after each stage of filtering, to say why there are no pods to show.
+	kept    int // Rows for pods that match the filters on the pods themselves.
+	matched int // Rows that also match --min-restarts and --grep-any.
+	shown   int // Rows that are also on the requested capacity type, which are the ones displayed.
+
+	// pending holds the pods that are waiting to be scheduled when --why-pending was passed.
+	pending []*v1.Pod
+	// problems describes the pods that aren't running and ready when --fail-on-problems was passed.
+	problems []string
+}
+
+// filter fills in the node and usage details of the rows, and filters out the rows that don't match the filters that
+// need them. The number of rows left after each stage are added to the totals.
+func (t *runTotals) filter(
+	rows []*tableRow, nodes map[string]*v1.Node, usage map[string]k8s.PodUsage, opts options,
+) []*tableRow {
+	t.kept += len(rows)
+
+	// If --min-restarts was passed, then filter out the pods that haven't restarted enough.
+	if opts.minRestarts > 0 {
+		rows = slices.DeleteFunc(rows, func(row *tableRow) bool {
+			return restartCount(row.Restarts) < opts.minRestarts
+		})
+	}
+
+	// Fill in the details that weren't available until all the fetches completed.
+	for _, row := range rows {
+		row.addNodeDetails(nodes)
+		row.addUsage(usage)
+	}
+
+	// If --grep-any was passed, then filter out the pods where none of the columns match.
+	if opts.grepAnyRegexp != nil {
+		rows = slices.DeleteFunc(rows, func(row *tableRow) bool {
+			return !rowMatches(row, opts.grepAnyRegexp)
+		})
+	}
+	t.matched += len(rows)
+
+	// If --spot-only or --on-demand-only were passed, then filter out the pods on the other type of node.
+	// Pods that are not on a known node can't be either, so they are filtered out too.
+	if opts.spotOnly || opts.onDemandOnly {
+		rows = slices.DeleteFunc(rows, func(row *tableRow) bool {
+			return row.IsSpot == nil || *row.IsSpot != opts.spotOnly
+		})
+	}
+
+	return rows
+}
+
+// add adds the rows that have been displayed to the totals.
+func (t *runTotals) add(rows []*tableRow, opts options) {
+	t.shown += len(rows)
+	for _, row := range rows {
+		if row.unscheduled != nil {
+			t.pending = append(t.pending, row.unscheduled)
+		}
+		if opts.failProblems && !isHealthy(row, opts.allowRegexp) {
+			t.problems = append(t.problems, fmt.Sprintf("%s (%s %s)", row.key, row.Ready, row.Status))
+		}
+	}
+}
+
+// noPodsError returns the error explaining why there are no pods to show, or nil if there are some.
+// It is based on the stage of filtering that left no rows.
+func (t *runTotals) noPodsError(opts options) error {
+	switch {
+	case t.kept == 0:
+		if opts.pvc != "" || opts.sa != "" || opts.stuckTerm > 0 {
+			return errNoPodsMatchFilters
+		}
+		return newNoMatchingPodsFoundError(opts.grep)
+	case t.matched == 0:
+		return errNoPodsMatchFilters
+	case t.shown == 0:
+		return errNoPodsFoundOnCapacity
+	}
+	return nil
+}
+
+// newInvalidOutputFormatError returns an error indicating that the --output format is not supported.
+func newInvalidOutputFormatError(format string) error {
+	return &util.Error{
+		Msg:   "invalid output format: ",
+		Param: format + " (must be one of: json, tsv, yaml)",
+	}
+}
+
+// newInvalidSortByError returns an error indicating that the --sort-by field is not supported.
+func newInvalidSortByError(field string) error {
+	return &util.Error{
+		Msg:   "invalid sort field: ",
+		Param: field + " (must be one of: name, restarts)",
+	}
 }
 
 // newNoMatchingPodsFoundError returns an error indicating that no matching pods were found.
@@ -82,12 +364,97 @@ func main() {
 		false,
 		"List the pods across all namespaces. Overrides --namespace / -n",
 	)
+	flag.BoolVar(
+		&opts.failProblems,
+		"fail-on-problems",
+		false,
+		"Exit with a non-zero exit code if any of the listed pods are not running and ready",
+	)
+	flag.StringVar(
+		&opts.fieldSelector,
+		"field-selector",
+		"",
+		"Selector (field query) to filter on, e.g. --field-selector status.phase=Running",
+	)
 	flag.StringVar(&opts.grep, "grep", "", "Limit output to pods with names containing this string")
+	flag.StringVar(
+		&opts.grepAny,
+		"grep-any",
+		"",
+		"Limit output to pods with any column matching this regular expression",
+	)
+	flag.StringVar(
+		&opts.allowStatus,
+		"allow-status",
+		defaultAllowStatus,
+		"Regular expression of pod statuses that are not problems for --fail-on-problems",
+	)
+	flag.Int64Var(
+		&opts.chunkSize,
+		"chunk-size",
+		defaultChunkSize,
+		"Return large lists in chunks rather than all at once. Pass 0 to disable",
+	)
 	flag.StringVar(&opts.kubeContext, "context", "", "The name of the kubeconfig context to use")
+	flag.StringVar(&opts.kubeConfig, "kubeconfig", "", "Path to the kubeconfig file to use")
+	flag.StringSliceVarP(
+		&opts.labelColumns,
+		"label-columns",
+		"L",
+		nil,
+		"Comma separated list of labels to show as columns. Can be repeated",
+	)
+	flag.StringVar(
+		&opts.pvc,
+		"pvc",
+		"",
+		"Limit output to pods mounting a persistent volume claim with a name matching this regular expression",
+	)
 	flag.StringVarP(&opts.labelSelector, "selector", "l", "", "Selector (label query) to filter on")
+	flag.IntVar(&opts.minRestarts, "min-restarts", 0, "Limit output to pods that have restarted at least this many times")
+	flag.BoolVar(&opts.metrics, "metrics", false, "Show the CPU and memory usage of the pods from metrics-server")
 	flag.StringVarP(&opts.namespace, "namespace", "n", "", "If present, the namespace scope for this CLI request")
+	flag.StringVar(&opts.node, "node", "", "Only list the pods running on this node")
+	flag.BoolVar(&opts.noHeaders, "no-headers", false, "Don't display the title row of the table")
+	flag.BoolVar(
+		&opts.noNodeInfo,
+		"no-node-info",
+		false,
+		"Don't look up the nodes of the pods, leaving out the spot and AZ details to reduce the load on the API server",
+	)
+	flag.BoolVar(&opts.onDemandOnly, "on-demand-only", false, "Only list pods running on on-demand nodes")
+	flag.StringVarP(&opts.output, "output", "o", "", "Output format. One of: json, tsv, yaml. Defaults to a table")
 	flag.StringVar(&opts.profileCPU, "profile-cpu", "", "Produce pprof cpu profiling output in supplied file")
 	flag.StringVar(&opts.profileMemory, "profile-mem", "", "Produce pprof memory profiling output in supplied file")
+	flag.BoolVar(&opts.showImages, "show-images", false, "Show the images used by the containers of the pods")
+	flag.BoolVar(
+		&opts.showPriority,
+		"show-priority",
+		false,
+		"Show the priority class of the pods and whether they have been preempted",
+	)
+	flag.BoolVar(&opts.showPVCs, "show-pvcs", false, "Show the persistent volume claims mounted by the pods")
+	flag.BoolVar(&opts.showSA, "show-service-account", false, "Show the service account used by the pods")
+	flag.StringVar(&opts.sa, "service-account", "", "Limit output to pods using this service account")
+	flag.StringVar(
+		&opts.sortBy,
+		"sort-by",
+		sortByName,
+		"Sort the pods by this field. One of: name, restarts. Restarts are sorted with the most first",
+	)
+	flag.BoolVar(&opts.spotOnly, "spot-only", false, "Only list pods running on spot nodes")
+	flag.DurationVar(
+		&opts.stuckTerm,
+		"stuck-terminating",
+		0,
+		"Limit output to pods that have been terminating for at least this long, e.g. 10m",
+	)
+	flag.BoolVar(
+		&opts.whyPending,
+		"why-pending",
+		false,
+		"Display a summary of why pods that are waiting to be scheduled can't be scheduled to the nodes",
+	)
 	flag.Parse()
 
 	// Have run() do the main work so that it can use defer statements,
@@ -99,6 +466,42 @@ func main() {
 
 // run is the main part of the program.
 func run(opts options) error {
+	switch opts.output {
+	case "", outputJSON, outputTSV, outputYAML:
+	default:
+		return newInvalidOutputFormatError(opts.output)
+	}
+	switch opts.sortBy {
+	case sortByName, sortByRestarts:
+	default:
+		return newInvalidSortByError(opts.sortBy)
+	}
+	if opts.spotOnly && opts.onDemandOnly {
+		return errConflictingCapacityOptions
+	}
+	if opts.noNodeInfo && (opts.spotOnly || opts.onDemandOnly || opts.whyPending) {
+		return errNodeInfoNeeded
+	}
+	if opts.pvc != "" {
+		var err error
+		if opts.pvcRegexp, err = regexp.Compile(opts.pvc); err != nil {
+			return fmt.Errorf("invalid --pvc regular expression: %w", err)
+		}
+	}
+	// An empty regular expression would match everything, so treat it as allowing nothing instead.
+	if opts.failProblems && opts.allowStatus != "" {
+		var err error
+		if opts.allowRegexp, err = regexp.Compile(opts.allowStatus); err != nil {
+			return fmt.Errorf("invalid --allow-status regular expression: %w", err)
+		}
+	}
+	if opts.grepAny != "" {
+		var err error
+		if opts.grepAnyRegexp, err = regexp.Compile(opts.grepAny); err != nil {
+			return fmt.Errorf("invalid --grep-any regular expression: %w", err)
+		}
+	}
+
 	// CPU profiling.
 	if opts.profileCPU != "" {
 		fp, err := os.Create(opts.profileCPU)
@@ -116,7 +519,7 @@ func run(opts options) error {
 		defer pprof.StopCPUProfile()
 	}
 
-	clientset := k8s.Client(opts.kubeContext)
+	clientset := k8s.Client(opts.kubeConfig, opts.kubeContext)
 
 	// Select the namespace to look at based on the command line options passed.
 	namespace, err := selectNamespace(clientset, opts)
@@ -124,25 +527,67 @@ func run(opts options) error {
 		return err
 	}
 
-	// Fetch the list of nodes and pods in parallel.
-	nodes, pods, err := fetchNodesAndPods(clientset, namespace, opts.labelSelector)
-	if err != nil {
-		return err
+	// Structured output always includes the namespace so that each entry stands on its own.
+	if opts.output == outputJSON || opts.output == outputYAML {
+		opts.allNamespaces = true
 	}
 
-	// If the --grep option was passed, then filter out the pods that don't match.
-	if opts.grep != "" {
-		filteredPods := slices.DeleteFunc(pods.Items, func(pod v1.Pod) bool {
-			return !strings.Contains(pod.Name, opts.grep)
-		})
-		if len(filteredPods) == 0 {
-			return newNoMatchingPodsFoundError(opts.grep)
+	// Fetch the nodes and the pods (and their usage if requested), then fill in and filter the rows and display them.
+	var (
+		totals runTotals
+		nodes  map[string]*v1.Node
+	)
+	if streamRows(opts) {
+		// Display the rows of each chunk of pods as it arrives.
+		if nodes, err = fetchAndDisplayChunks(clientset, namespace, opts, &totals); err != nil {
+			return err
+		}
+		if err := totals.noPodsError(opts); err != nil {
+			return err
 		}
-		pods.Items = filteredPods
+	} else {
+		rows, fetchedNodes, usage, err := fetchNodesAndPods(clientset, namespace, opts)
+		if err != nil {
+			return err
+		}
+		nodes = fetchedNodes
+		rows = totals.filter(rows, nodes, usage, opts)
+		if err := totals.noPodsError(opts); err != nil {
+			return err
+		}
+
+		sortRows(rows, opts.sortBy)
+
+		// Display the rows in the requested format.
+		if opts.output == outputJSON || opts.output == outputYAML {
+			if err := writeStructured(os.Stdout, rows, opts.output); err != nil {
+				return err
+			}
+		} else {
+			displayTable(rows, opts)
+		}
+		totals.add(rows, opts)
 	}
 
-	// Build and display the table for each pod.
-	buildAndDisplayTable(pods, nodes, opts.allNamespaces)
+	// Summarise why the pending pods can't be scheduled.
+	// This goes to stderr for structured output so that it doesn't break parsing the output.
+	if opts.whyPending {
+		summary := os.Stdout
+		if opts.output == outputJSON || opts.output == outputYAML {
+			summary = os.Stderr
+		}
+		writePendingSummary(summary, totals.pending, nodes)
+	}
+
+	// If --fail-on-problems was passed, then list the pods with problems and exit with an error if there are any.
+	if len(totals.problems) > 0 {
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "Pods with problems:")
+		for _, problem := range totals.problems {
+			fmt.Fprintln(os.Stderr, "  "+problem)
+		}
+		return fmt.Errorf("%w: %d of %d", errProblemPods, len(totals.problems), totals.shown)
+	}
 
 	// Memory profiling.
 	if opts.profileMemory != "" {
@@ -165,96 +610,383 @@ func run(opts options) error {
 	return nil
 }
 
-// buildAndDisplayTable builds the table from the pods (with some node details for the pod) and displays it.
-func buildAndDisplayTable(pods *v1.PodList, nodes map[string]*v1.Node, allNamespaces bool) {
-	var tbl texttable.Table[*tableRow]
+// chunkRows returns the rows for the pods of a chunk that match the filters that only depend on the pods themselves.
+func chunkRows(pods *v1.PodList, opts options) []*tableRow {
+	var rows []*tableRow
 	for i := range pods.Items {
-		row := createTableRow(&pods.Items[i], nodes, allNamespaces)
-		tbl.Append(&row)
+		if keepPod(&pods.Items[i], opts) {
+			rows = append(rows, createTableRow(&pods.Items[i], opts))
+		}
 	}
-
-	// Sort function to sort the rows slice by Namespace and then Name when iterating through it.
-	slices.SortFunc(tbl.Rows, func(a, b *tableRow) int {
-		return cmp.Or(
-			cmp.Compare(a.Namespace, b.Namespace),
-			cmp.Compare(a.Name, b.Name),
-		)
-	})
-
-	// Display the table.
-	tbl.Write()
+	return rows
 }
 
-// createTableRow creates a tableRow from a pod and node information.
-func createTableRow(pod *v1.Pod, nodes map[string]*v1.Node, allNamespaces bool) tableRow {
+// createTableRow creates a tableRow from a pod.
+// The node and usage details are filled in later by addNodeDetails() and addUsage() since they are fetched in parallel
+// with the pods.
+func createTableRow(pod *v1.Pod, opts options) *tableRow {
 	var row tableRow
 
 	// Get details about the containers in the pod.
 	readyContainers, totalContainers, status, restarts := k8s.PodDetails(pod)
 
 	// Build up the table contents.
-	if allNamespaces {
+	if opts.allNamespaces {
 		row.Namespace = pod.Namespace
 	}
 	row.Name = pod.Name
 	row.Ready = fmt.Sprintf("%d/%d", readyContainers, totalContainers)
 	row.Status = status
+	if since, ok := terminatingSince(pod); ok && status == "Terminating" {
+		row.Status = fmt.Sprintf("%s (%s)", status, util.FormatAge(since))
+	}
 	row.Restarts = restarts
 	row.Age = util.FormatAge(pod.CreationTimestamp.Time)
-	row.IP = pod.Status.PodIP
-	if row.IP == "" {
-		row.IP = "?"
-	}
-	node := pod.Spec.NodeName
-	if node != "" {
-		row.Node = node
-		if nodeInfo, ok := nodes[node]; ok {
-			row.Spot = spotStatus(nodeInfo)
-			row.AZ = util.LastSplitItem(nodes[node].Labels["topology.kubernetes.io/zone"], "")
-		} else {
-			row.Node += " (gone)"
+	row.PodIP = pod.Status.PodIP
+	row.IP = cmp.Or(row.PodIP, "?")
+	row.NodeName = pod.Spec.NodeName
+	row.Node = row.NodeName
+	if opts.showImages {
+		row.Images = podImages(pod)
+	}
+	if opts.showPriority {
+		row.Priority = podPriority(pod)
+	}
+	if opts.showSA {
+		row.SA = podServiceAccount(pod)
+	}
+	if opts.showPVCs {
+		row.PVCs = "-"
+		if pvcs := podPVCs(pod); len(pvcs) > 0 {
+			row.PVCs = strings.Join(pvcs, ",")
+		}
+	}
+	if len(opts.labelColumns) > 0 {
+		row.labelColumns = opts.labelColumns
+		row.Labels = make(map[string]string, len(opts.labelColumns))
+		for _, label := range opts.labelColumns {
+			if value, ok := pod.Labels[label]; ok {
+				row.Labels[label] = value
+			}
 		}
 	}
+	row.key = pod.Namespace + "/" + pod.Name
+	if opts.whyPending && isUnscheduled(pod) {
+		row.unscheduled = pendingPod(pod)
+	}
 
-	return row
+	return &row
+}
+
+// addNodeDetails fills in the details of the node that the pod of the row is running on.
+// A nil nodes map means that the nodes were not looked up, so the node details are left empty.
+func (tr *tableRow) addNodeDetails(nodes map[string]*v1.Node) {
+	if tr.NodeName == "" || nodes == nil {
+		return
+	}
+	nodeInfo, ok := nodes[tr.NodeName]
+	nodeGone := !ok
+	tr.NodeGone = &nodeGone
+	if nodeGone {
+		tr.Node = tr.NodeName + " (gone)"
+		return
+	}
+	isSpot := k8s.IsSpotNode(nodeInfo)
+	tr.IsSpot = &isSpot
+	tr.Spot = spotStatus(isSpot)
+	tr.AZ = util.LastSplitItem(nodeInfo.Labels["topology.kubernetes.io/zone"], "")
+}
+
+// addUsage fills in the CPU and memory usage of the pod of the row.
+// A nil usage map means that usage was not requested, so the CPU and MEM columns are left out.
+func (tr *tableRow) addUsage(usage map[string]k8s.PodUsage) {
+	if usage == nil {
+		return
+	}
+	// Pods that are not running (or are too new to have been scraped yet) have no metrics.
+	tr.CPU, tr.Memory = "-", "-"
+	if pu, ok := usage[tr.key]; ok {
+		tr.CPU = fmt.Sprintf("%dm", pu.CPU.MilliValue())
+		tr.Memory = fmt.Sprintf("%dMi", pu.Memory.Value()/(1024*1024))
+	}
+}
+
+// displayTable displays the rows as a table.
+func displayTable(rows []*tableRow, opts options) {
+	tbl := texttable.Table[*tableRow]{
+		Rows:      rows,
+		Colour:    texttable.ColourEnabled(os.Stdout),
+		NoHeaders: opts.noHeaders,
+		Plain:     opts.output == outputTSV,
+	}
+	tbl.Write()
+}
+
+// fetchAndDisplayChunks lists the pods in chunks of --chunk-size and displays the rows of each chunk as soon as it
+// arrives, rather than waiting for every pod to be listed first. The nodes (and usage if --metrics was passed) are
+// fetched before the pods since every chunk needs them.
+// Like kubectl does for chunked lists, each chunk is aligned into columns on its own, so the widths of the columns can
+// change from one chunk to the next. The rows are in the order that the API server lists the pods in, which is by
+// namespace and then name.
+// The totals are updated with each chunk, and the nodes are returned for the --why-pending summary.
+func fetchAndDisplayChunks(
+	clientset *kubernetes.Clientset, namespace string, opts options, totals *runTotals,
+) (map[string]*v1.Node, error) {
+	g := new(errgroup.Group)
+	var nu nodesAndUsage
+	nu.fetch(g, clientset, namespace, opts)
+	if err := g.Wait(); err != nil {
+		return nil, fmt.Errorf("failed to fetch nodes and/or pod usage: %w", err)
+	}
+
+	found := false
+	err := k8s.ListPodsPaged(
+		clientset, namespace, opts.labelSelector, podFieldSelector(opts), opts.chunkSize,
+		func(pods *v1.PodList) error {
+			found = found || len(pods.Items) > 0
+			rows := totals.filter(chunkRows(pods, opts), nu.nodes, nu.usage, opts)
+
+			// Only the first chunk with any rows shows the title row.
+			chunkOpts := opts
+			chunkOpts.noHeaders = opts.noHeaders || totals.shown > 0
+			displayTable(rows, chunkOpts)
+			totals.add(rows, opts)
+			return nil
+		},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pods: %w", err)
+	}
+	if !found {
+		return nil, errNoPodsFound
+	}
+
+	return nu.nodes, nil
 }
 
 // fetchNodesAndPods fetches the list of nodes and pods in parallel.
+// The pods are listed in chunks of --chunk-size, with each chunk converted into rows as it arrives, so that the full
+// list of pods is never held in memory at once. Pods not matching --grep are skipped at that point too.
+// The rows of all the chunks are returned together for the output formats that need every row before any can be
+// displayed, such as JSON and YAML.
+// If the --metrics option was passed, then the usage of the pods is also fetched from metrics-server, otherwise usage
+// is nil.
 func fetchNodesAndPods(
-	clientset *kubernetes.Clientset, namespace string, labelSelector string,
-) (map[string]*v1.Node, *v1.PodList, error) {
+	clientset *kubernetes.Clientset, namespace string, opts options,
+) ([]*tableRow, map[string]*v1.Node, map[string]k8s.PodUsage, error) {
 	g := new(errgroup.Group)
 
-	nodes := make(map[string]*v1.Node)
-	g.Go(func() error {
-		nodeList, err := k8s.ListNodes(clientset)
-		if err != nil {
-			return fmt.Errorf("failed to list nodes: %w", err)
-		}
-		for i, node := range nodeList.Items {
-			nodes[node.Name] = &nodeList.Items[i]
-		}
-		return nil
-	})
+	var nu nodesAndUsage
+	nu.fetch(g, clientset, namespace, opts)
 
-	pods := &v1.PodList{}
+	var rows []*tableRow
 	g.Go(func() error {
-		listPods, err := k8s.ListPods(clientset, namespace, labelSelector)
+		found := false
+		err := k8s.ListPodsPaged(
+			clientset, namespace, opts.labelSelector, podFieldSelector(opts), opts.chunkSize,
+			func(pods *v1.PodList) error {
+				found = found || len(pods.Items) > 0
+				rows = append(rows, chunkRows(pods, opts)...)
+				return nil
+			},
+		)
 		if err != nil {
 			return fmt.Errorf("failed to list pods: %w", err)
 		}
-		if len(listPods.Items) == 0 {
+		if !found {
 			return errNoPodsFound
 		}
-		*pods = *listPods
 		return nil
 	})
 
 	if err := g.Wait(); err != nil {
-		return nil, nil, fmt.Errorf("failed to fetch nodes and/or pods: %w", err)
+		return nil, nil, nil, fmt.Errorf("failed to fetch nodes and/or pods: %w", err)
+	}
+
+	return rows, nu.nodes, nu.usage, nil
+}
+
+// isHealthy returns true if the pod of a row is running with all of its containers ready, or its status is allowed.
+func isHealthy(row *tableRow, allowStatus *regexp.Regexp) bool {
+	if allowStatus != nil && allowStatus.MatchString(row.Status) {
+		return true
+	}
+	readyContainers, totalContainers, _ := strings.Cut(row.Ready, "/")
+
+	return row.Status == "Running" && readyContainers == totalContainers
+}
+
+// keepPod returns true if a pod matches the filters that only depend on the pod itself.
+func keepPod(pod *v1.Pod, opts options) bool {
+	if opts.grep != "" && !strings.Contains(pod.Name, opts.grep) {
+		return false
+	}
+	if opts.pvcRegexp != nil && !slices.ContainsFunc(podPVCs(pod), opts.pvcRegexp.MatchString) {
+		return false
+	}
+	if opts.sa != "" && podServiceAccount(pod) != opts.sa {
+		return false
+	}
+	if opts.stuckTerm > 0 {
+		since, ok := terminatingSince(pod)
+		if !ok || time.Since(since) < opts.stuckTerm {
+			return false
+		}
+	}
+
+	return true
+}
+
+// lookupNodes returns the nodes needed to fill in the node details of the rows, keyed by name.
+// If a node name is supplied, then just that node is fetched rather than listing every node in the cluster.
+// A node that no longer exists results in an empty map so that its pods are shown as being on a node that is gone.
+func lookupNodes(clientset kubernetes.Interface, name string) (map[string]*v1.Node, error) {
+	nodes := make(map[string]*v1.Node)
+
+	if name != "" {
+		node, err := k8s.GetNode(clientset, name)
+		if apierrors.IsNotFound(err) {
+			return nodes, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get node: %w", err)
+		}
+		nodes[node.Name] = node
+		return nodes, nil
+	}
+
+	nodeList, err := k8s.ListNodes(clientset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %w", err)
+	}
+	for i, node := range nodeList.Items {
+		nodes[node.Name] = &nodeList.Items[i]
+	}
+
+	return nodes, nil
+}
+
+// podFieldSelector returns the field selector to list the pods with.
+// When --node is passed, the API server is asked to only return the pods on that node.
+func podFieldSelector(opts options) string {
+	if opts.node == "" {
+		return opts.fieldSelector
+	}
+	nodeSelector := "spec.nodeName=" + opts.node
+	if opts.fieldSelector == "" {
+		return nodeSelector
+	}
+
+	return opts.fieldSelector + "," + nodeSelector
+}
+
+// podImages returns a comma separated list of the unique images used by the init and regular containers of a pod.
+func podImages(pod *v1.Pod) string {
+	var images []string
+	for _, containers := range [][]v1.Container{pod.Spec.InitContainers, pod.Spec.Containers} {
+		for _, container := range containers {
+			if !slices.Contains(images, container.Image) {
+				images = append(images, container.Image)
+			}
+		}
 	}
 
-	return nodes, pods, nil
+	return strings.Join(images, ",")
+}
+
+// podPriority returns the priority class of a pod, or "-" if it doesn't have one.
+// Pods that have been preempted by the scheduler to make room for a higher priority pod have when that happened
+// appended, while pods that are waiting for lower priority pods to be preempted so they can run have "(preempting)"
+// appended.
+func podPriority(pod *v1.Pod) string {
+	priority := pod.Spec.PriorityClassName
+	if priority == "" {
+		priority = "-"
+	}
+
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == v1.DisruptionTarget &&
+			condition.Status == v1.ConditionTrue &&
+			condition.Reason == v1.PodReasonPreemptionByScheduler {
+			return fmt.Sprintf("%s (preempted %s ago)", priority, util.FormatAge(condition.LastTransitionTime.Time))
+		}
+	}
+	if pod.Status.NominatedNodeName != "" {
+		return priority + " (preempting)"
+	}
+
+	return priority
+}
+
+// podPVCs returns the names of the persistent volume claims mounted by a pod.
+// This includes the claims created for generic ephemeral volumes, which are named after the pod and volume.
+func podPVCs(pod *v1.Pod) []string {
+	var pvcs []string
+	for _, volume := range pod.Spec.Volumes {
+		switch {
+		case volume.PersistentVolumeClaim != nil:
+			pvcs = append(pvcs, volume.PersistentVolumeClaim.ClaimName)
+		case volume.Ephemeral != nil:
+			pvcs = append(pvcs, pod.Name+"-"+volume.Name)
+		}
+	}
+
+	return pvcs
+}
+
+// podServiceAccount returns the name of the service account used by a pod.
+// Pods that don't specify one use the namespace's "default" service account.
+func podServiceAccount(pod *v1.Pod) string {
+	if pod.Spec.ServiceAccountName == "" {
+		return "default"
+	}
+
+	return pod.Spec.ServiceAccountName
+}
+
+// readyColour returns green if all the containers of a pod are ready, otherwise yellow.
+// Pods that have completed are expected to have no ready containers, so they are left uncoloured.
+func readyColour(ready, status string) texttable.Colour {
+	if status == "Completed" || status == "Succeeded" {
+		return texttable.ColourNone
+	}
+	readyContainers, totalContainers, _ := strings.Cut(ready, "/")
+	if readyContainers == totalContainers {
+		return texttable.ColourGreen
+	}
+
+	return texttable.ColourYellow
+}
+
+// restartCount returns the number of restarts from the RESTARTS column, which may also show when the last restart was.
+func restartCount(restarts string) int {
+	count, _, _ := strings.Cut(restarts, " ")
+	n, err := strconv.Atoi(count)
+	if err != nil {
+		return 0
+	}
+
+	return n
+}
+
+// restartsColour returns yellow if the containers of a pod have restarted, otherwise no colour.
+func restartsColour(restarts string) texttable.Colour {
+	if restarts == "0" {
+		return texttable.ColourNone
+	}
+
+	return texttable.ColourYellow
+}
+
+// rowMatches returns true if any of the columns of a row match the regular expression.
+// The namespace is always checked, even when it isn't displayed because --all-namespaces wasn't passed.
+func rowMatches(row *tableRow, re *regexp.Regexp) bool {
+	namespace, _, _ := strings.Cut(row.key, "/")
+	if re.MatchString(namespace) {
+		return true
+	}
+
+	return slices.ContainsFunc(strings.Split(row.TabValues(), "\t"), re.MatchString)
 }
 
 // selectNamespace returns the namespace to use based on the command line options.
@@ -271,14 +1003,93 @@ func selectNamespace(clientset *kubernetes.Clientset, opts options) (string, err
 		return opts.namespace, nil
 	}
 
-	return k8s.Namespace(opts.kubeContext), nil
+	return k8s.Namespace(opts.kubeConfig, opts.kubeContext), nil
 }
 
-// spotStatus returns a tick if the node is a spot instance, otherwise an x.
-func spotStatus(node *v1.Node) string {
-	if node.Labels["node-role.kubernetes.io/spot-worker"] != "" {
+// sortRows sorts the rows by the --sort-by field.
+// Rows are sorted by Namespace and then Name, which is also how rows with the same number of restarts are ordered.
+func sortRows(rows []*tableRow, sortBy string) {
+	slices.SortFunc(rows, func(a, b *tableRow) int {
+		if sortBy == sortByRestarts {
+			if c := cmp.Compare(restartCount(b.Restarts), restartCount(a.Restarts)); c != 0 {
+				return c
+			}
+		}
+		return cmp.Or(
+			cmp.Compare(a.Namespace, b.Namespace),
+			cmp.Compare(a.Name, b.Name),
+		)
+	})
+}
+
+// spotStatus returns a tick for the table if the node is a spot instance, otherwise an x.
+func spotStatus(isSpot bool) string {
+	if isSpot {
 		return tick
 	}
 
 	return "x"
 }
+
+// statusColour returns the colour for a pod status.
+// Statuses of failing init containers are prefixed with "Init:" and are treated the same as other containers.
+func statusColour(status string) texttable.Colour {
+	if slices.Contains(goodStatuses, status) {
+		return texttable.ColourGreen
+	}
+	status = strings.TrimPrefix(status, "Init:")
+	if slices.Contains(badStatuses, status) ||
+		strings.HasPrefix(status, "ExitCode:") ||
+		strings.HasPrefix(status, "Signal:") {
+		return texttable.ColourRed
+	}
+
+	return texttable.ColourYellow
+}
+
+// streamRows returns true if the rows can be displayed a chunk at a time as the pods are listed.
+// That is only the case for the aligned table sorted by name, which is the order that the API server lists the pods in.
+// The other output formats and sort orders need every row before any can be shown.
+func streamRows(opts options) bool {
+	return opts.output == "" && opts.sortBy == sortByName
+}
+
+// terminatingSince returns when a pod was deleted, and true if it is being deleted.
+// The deletion timestamp is when the pod will be forcibly killed, so the grace period is subtracted from it.
+func terminatingSince(pod *v1.Pod) (time.Time, bool) {
+	if pod.DeletionTimestamp == nil {
+		return time.Time{}, false
+	}
+	since := pod.DeletionTimestamp.Time
+	if pod.DeletionGracePeriodSeconds != nil {
+		since = since.Add(-time.Duration(*pod.DeletionGracePeriodSeconds) * time.Second)
+	}
+
+	return since, true
+}
+
+// writeStructured writes the rows to w as a JSON or YAML list.
+func writeStructured(w io.Writer, rows []*tableRow, format string) error {
+	var (
+		data []byte
+		err  error
+	)
+	switch format {
+	case outputJSON:
+		data, err = json.MarshalIndent(rows, "", "  ")
+		data = append(data, '\n')
+	case outputYAML:
+		data, err = yaml.Marshal(rows)
+	default:
+		return newInvalidOutputFormatError(format)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s output: %w", format, err)
+	}
+
+	if _, err := w.Write(data); err != nil {
+		return fmt.Errorf("failed to write %s output: %w", format, err)
+	}
+
+	return nil
+}
@@ -1,6 +1,22 @@
 package main
 
-import "testing"
+import (
+	"bytes"
+	"errors"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jim-barber-he/go/k8s"
+	"github.com/jim-barber-he/go/texttable"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
 
 func TestTabTitleRow(t *testing.T) {
 	t.Parallel()
@@ -11,7 +27,7 @@ func TestTabTitleRow(t *testing.T) {
 	}{
 		{
 			row:    tableRow{},
-			result: "NAME	READY	STATUS	RESTARTS	AGE	IP	NODE	SPOT",
+			result: "NAME	READY	STATUS	RESTARTS	AGE	IP	NODE	SPOT	AZ",
 		},
 		{
 			row: tableRow{
@@ -50,7 +66,7 @@ func TestTabValues(t *testing.T) {
 	}{
 		{
 			row:    tableRow{},
-			result: "",
+			result: "-	-	-",
 		},
 		{
 			row: tableRow{
@@ -93,3 +109,838 @@ func TestTabValues(t *testing.T) {
 		})
 	}
 }
+
+func TestWriteStructured(t *testing.T) {
+	t.Parallel()
+
+	isSpot, nodeFound, nodeGone := false, false, true
+	rows := []*tableRow{
+		{
+			Namespace: "default",
+			Name:      "pod1",
+			Ready:     "1/1",
+			Status:    "Running",
+			Restarts:  "0",
+			Age:       "1d",
+			IP:        "10.1.1.1",
+			PodIP:     "10.1.1.1",
+			Node:      "node1",
+			NodeName:  "node1",
+			NodeGone:  &nodeFound,
+			Spot:      "x",
+			IsSpot:    &isSpot,
+			AZ:        "a",
+		},
+		{
+			Namespace: "default",
+			Name:      "pod2",
+			Ready:     "0/1",
+			Status:    "Pending",
+			Restarts:  "0",
+			Age:       "1m",
+			IP:        "?",
+			Node:      "node2 (gone)",
+			NodeName:  "node2",
+			NodeGone:  &nodeGone,
+		},
+	}
+
+	tests := []struct {
+		format  string
+		result  string
+		wantErr bool
+	}{
+		{
+			format: outputJSON,
+			result: `[
+  {
+    "namespace": "default",
+    "name": "pod1",
+    "ready": "1/1",
+    "status": "Running",
+    "restarts": "0",
+    "age": "1d",
+    "ip": "10.1.1.1",
+    "node": "node1",
+    "nodeGone": false,
+    "spot": false,
+    "az": "a"
+  },
+  {
+    "namespace": "default",
+    "name": "pod2",
+    "ready": "0/1",
+    "status": "Pending",
+    "restarts": "0",
+    "age": "1m",
+    "ip": "",
+    "node": "node2",
+    "nodeGone": true,
+    "spot": null
+  }
+]
+`,
+		},
+		{
+			format: outputYAML,
+			result: `- age: 1d
+  az: a
+  ip: 10.1.1.1
+  name: pod1
+  namespace: default
+  node: node1
+  nodeGone: false
+  ready: 1/1
+  restarts: "0"
+  spot: false
+  status: Running
+- age: 1m
+  ip: ""
+  name: pod2
+  namespace: default
+  node: node2
+  nodeGone: true
+  ready: 0/1
+  restarts: "0"
+  spot: null
+  status: Pending
+`,
+		},
+		{
+			format:  "xml",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			t.Parallel()
+			var buf bytes.Buffer
+			err := writeStructured(&buf, rows, tt.format)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, wantErr %v", err, tt.wantErr)
+			}
+			if result := buf.String(); result != tt.result {
+				t.Errorf("got %s, want %s", result, tt.result)
+			}
+		})
+	}
+}
+
+func TestAddUsage(t *testing.T) {
+	t.Parallel()
+
+	usage := map[string]k8s.PodUsage{
+		"default/pod1": {CPU: resource.MustParse("250m"), Memory: resource.MustParse("128Mi")},
+	}
+
+	tests := []struct {
+		name   string
+		usage  map[string]k8s.PodUsage
+		cpu    string
+		memory string
+	}{
+		{name: "pod1", usage: nil, cpu: "", memory: ""},
+		{name: "pod1", usage: usage, cpu: "250m", memory: "128Mi"},
+		{name: "pod2", usage: usage, cpu: "-", memory: "-"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: tt.name, Namespace: "default"}}
+			row := createTableRow(pod, options{})
+			row.addUsage(tt.usage)
+			if row.CPU != tt.cpu || row.Memory != tt.memory {
+				t.Errorf("got %s/%s, want %s/%s", row.CPU, row.Memory, tt.cpu, tt.memory)
+			}
+		})
+	}
+}
+
+func TestAddNodeDetails(t *testing.T) {
+	t.Parallel()
+
+	nodes := map[string]*v1.Node{
+		"node1": {
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "node1",
+				Labels: map[string]string{
+					"node-role.kubernetes.io/spot-worker": "true",
+					"topology.kubernetes.io/zone":         "ap-southeast-2a",
+				},
+			},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		node     string
+		nodes    map[string]*v1.Node
+		want     tableRow
+		isSpot   string
+		nodeGone string
+	}{
+		{name: "no node", node: "", nodes: nodes, want: tableRow{}, isSpot: "<nil>", nodeGone: "<nil>"},
+		{
+			name:     "found",
+			node:     "node1",
+			nodes:    nodes,
+			want:     tableRow{Node: "node1", NodeName: "node1", Spot: tick, AZ: "a"},
+			isSpot:   "true",
+			nodeGone: "false",
+		},
+		{
+			name:     "gone",
+			node:     "node2",
+			nodes:    nodes,
+			want:     tableRow{Node: "node2 (gone)", NodeName: "node2"},
+			isSpot:   "<nil>",
+			nodeGone: "true",
+		},
+		{
+			name:     "not looked up",
+			node:     "node1",
+			nodes:    nil,
+			want:     tableRow{Node: "node1", NodeName: "node1"},
+			isSpot:   "<nil>",
+			nodeGone: "<nil>",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			row := &tableRow{Node: tt.node, NodeName: tt.node}
+			row.addNodeDetails(tt.nodes)
+			if row.Node != tt.want.Node || row.Spot != tt.want.Spot || row.AZ != tt.want.AZ {
+				t.Errorf("got %s/%s/%s, want %s/%s/%s", row.Node, row.Spot, row.AZ, tt.want.Node, tt.want.Spot, tt.want.AZ)
+			}
+			if row.NodeName != tt.want.NodeName {
+				t.Errorf("got NodeName %s, want %s", row.NodeName, tt.want.NodeName)
+			}
+			if got := formatOptionalBool(row.IsSpot); got != tt.isSpot {
+				t.Errorf("got IsSpot %s, want %s", got, tt.isSpot)
+			}
+			if got := formatOptionalBool(row.NodeGone); got != tt.nodeGone {
+				t.Errorf("got NodeGone %s, want %s", got, tt.nodeGone)
+			}
+		})
+	}
+}
+
+// formatOptionalBool formats a boolean that may be nil for comparing in the tests.
+func formatOptionalBool(b *bool) string {
+	if b == nil {
+		return "<nil>"
+	}
+	return strconv.FormatBool(*b)
+}
+
+func TestPodImages(t *testing.T) {
+	t.Parallel()
+
+	pod := &v1.Pod{
+		Spec: v1.PodSpec{
+			InitContainers: []v1.Container{{Image: "busybox:1.37"}},
+			Containers: []v1.Container{
+				{Image: "nginx:1.27"},
+				{Image: "busybox:1.37"},
+				{Image: "envoy:v1.32"},
+			},
+		},
+	}
+
+	want := "busybox:1.37,nginx:1.27,envoy:v1.32"
+	if result := podImages(pod); result != want {
+		t.Errorf("got %s, want %s", result, want)
+	}
+}
+
+func TestStatusColour(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		status string
+		result texttable.Colour
+	}{
+		{status: "Running", result: texttable.ColourGreen},
+		{status: "Completed", result: texttable.ColourGreen},
+		{status: "Pending", result: texttable.ColourYellow},
+		{status: "ContainerCreating", result: texttable.ColourYellow},
+		{status: "Init:1/2", result: texttable.ColourYellow},
+		{status: "CrashLoopBackOff", result: texttable.ColourRed},
+		{status: "Init:ImagePullBackOff", result: texttable.ColourRed},
+		{status: "Init:ExitCode:1", result: texttable.ColourRed},
+		{status: "Signal:9", result: texttable.ColourRed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.status, func(t *testing.T) {
+			t.Parallel()
+			if result := statusColour(tt.status); result != tt.result {
+				t.Errorf("got %q, want %q", result, tt.result)
+			}
+		})
+	}
+}
+
+func TestReadyColour(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		ready  string
+		status string
+		result texttable.Colour
+	}{
+		{ready: "2/2", status: "Running", result: texttable.ColourGreen},
+		{ready: "1/2", status: "Running", result: texttable.ColourYellow},
+		{ready: "0/1", status: "Completed", result: texttable.ColourNone},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ready, func(t *testing.T) {
+			t.Parallel()
+			if result := readyColour(tt.ready, tt.status); result != tt.result {
+				t.Errorf("got %q, want %q", result, tt.result)
+			}
+		})
+	}
+}
+
+func TestLabelColumns(t *testing.T) {
+	t.Parallel()
+
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "pod1",
+			Namespace: "default",
+			Labels:    map[string]string{"app.kubernetes.io/name": "web", "version": "1.2.3"},
+		},
+	}
+	opts := options{labelColumns: []string{"version", "app.kubernetes.io/name", "missing"}}
+	row := createTableRow(pod, opts)
+	row.Ready, row.Status, row.Age, row.Node, row.Spot = "1/1", "Running", "1d", "node1", "x"
+
+	wantTitle := "NAME	READY	STATUS	RESTARTS	AGE	IP	NODE	SPOT	AZ	VERSION	NAME	MISSING"
+	if result := row.TabTitleRow(); result != wantTitle {
+		t.Errorf("got %s, want %s", result, wantTitle)
+	}
+	wantValues := "pod1	1/1	Running	0	1d	?	node1	x	-	1.2.3	web	"
+	if result := row.TabValues(); result != wantValues {
+		t.Errorf("got %s, want %s", result, wantValues)
+	}
+}
+
+func TestTablePendingPod(t *testing.T) {
+	t.Parallel()
+
+	pending := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "pending", Namespace: "default"},
+		Spec: v1.PodSpec{
+			Containers:         []v1.Container{{Image: "nginx:1.27"}},
+			PriorityClassName:  "high",
+			ServiceAccountName: "web",
+		},
+		Status: v1.PodStatus{Phase: v1.PodPending},
+	}
+	gone := pending.DeepCopy()
+	gone.Name, gone.Spec.NodeName = "gone", "node2"
+	opts := options{
+		labelColumns: []string{"app"},
+		showImages:   true,
+		showPriority: true,
+		showPVCs:     true,
+		showSA:       true,
+	}
+
+	for _, pod := range []*v1.Pod{pending, gone} {
+		t.Run(pod.Name, func(t *testing.T) {
+			t.Parallel()
+			row := createTableRow(pod, opts)
+			row.addNodeDetails(map[string]*v1.Node{})
+
+			// Each value has to line up with its title, or it would be shown in the wrong column.
+			titles := strings.Split(row.TabTitleRow(), "\t")
+			values := strings.Split(row.TabValues(), "\t")
+			if len(values) != len(titles) {
+				t.Fatalf("got %d values for %d titles: %q", len(values), len(titles), values)
+			}
+			want := map[string]string{
+				"SPOT":           "-",
+				"AZ":             "-",
+				"IMAGES":         "nginx:1.27",
+				"PRIORITY":       "high",
+				"PVCS":           "-",
+				"SERVICEACCOUNT": "web",
+				"APP":            "",
+			}
+			for i, title := range titles {
+				if value, ok := want[title]; ok && values[i] != value {
+					t.Errorf("got %q under %s, want %q", values[i], title, value)
+				}
+			}
+		})
+	}
+}
+
+func TestTabFields(t *testing.T) {
+	t.Parallel()
+
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "pod1", Namespace: "default", Labels: map[string]string{"version": "1.2.3"}},
+	}
+	row := createTableRow(pod, options{labelColumns: []string{"version", "missing"}})
+	row.Ready, row.Status, row.Age = "0/1", "Pending", "1m"
+
+	want := []texttable.Field{
+		{Title: "NAMESPACE", OmitEmpty: true},
+		{Title: "NAME", Value: "pod1"},
+		{Title: "READY", Value: "0/1"},
+		{Title: "STATUS", Value: "Pending"},
+		{Title: "RESTARTS", Value: "0"},
+		{Title: "AGE", Value: "1m"},
+		{Title: "CPU", OmitEmpty: true},
+		{Title: "MEM", OmitEmpty: true},
+		{Title: "IP", Value: "?"},
+		{Title: "NODE"},
+		{Title: "SPOT"},
+		{Title: "AZ", OmitEmpty: true},
+		{Title: "IMAGES", OmitEmpty: true},
+		{Title: "PRIORITY", OmitEmpty: true},
+		{Title: "PVCS", OmitEmpty: true},
+		{Title: "SERVICEACCOUNT", OmitEmpty: true},
+		{Title: "VERSION", Value: "1.2.3"},
+		{Title: "MISSING"},
+	}
+	if result := row.TabFields(); !slices.Equal(result, want) {
+		t.Errorf("got %v, want %v", result, want)
+	}
+}
+
+func TestPodFieldSelector(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		opts   options
+		result string
+	}{
+		{opts: options{}, result: ""},
+		{opts: options{fieldSelector: "status.phase=Running"}, result: "status.phase=Running"},
+		{opts: options{node: "node1"}, result: "spec.nodeName=node1"},
+		{
+			opts:   options{fieldSelector: "status.phase=Running", node: "node1"},
+			result: "status.phase=Running,spec.nodeName=node1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.result, func(t *testing.T) {
+			t.Parallel()
+			if result := podFieldSelector(tt.opts); result != tt.result {
+				t.Errorf("got %s, want %s", result, tt.result)
+			}
+		})
+	}
+}
+
+func TestLookupNodes(t *testing.T) {
+	t.Parallel()
+
+	client := fake.NewSimpleClientset(
+		&v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node1"}},
+		&v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node2"}},
+	)
+
+	tests := []struct {
+		name  string
+		count int
+	}{
+		{name: "", count: 2},
+		{name: "node1", count: 1},
+		{name: "gone", count: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			nodes, err := lookupNodes(client, tt.name)
+			if err != nil {
+				t.Fatalf("error looking up nodes: %v", err)
+			}
+			if len(nodes) != tt.count {
+				t.Errorf("got %d nodes, want %d", len(nodes), tt.count)
+			}
+		})
+	}
+}
+
+func TestPodPriority(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		pod    v1.Pod
+		result string
+	}{
+		{name: "none", pod: v1.Pod{}, result: "-"},
+		{
+			name:   "class",
+			pod:    v1.Pod{Spec: v1.PodSpec{PriorityClassName: "low"}},
+			result: "low",
+		},
+		{
+			name: "preempting",
+			pod: v1.Pod{
+				Spec:   v1.PodSpec{PriorityClassName: "high"},
+				Status: v1.PodStatus{NominatedNodeName: "node1"},
+			},
+			result: "high (preempting)",
+		},
+		{
+			name: "preempted",
+			pod: v1.Pod{
+				Spec: v1.PodSpec{PriorityClassName: "low"},
+				Status: v1.PodStatus{
+					Conditions: []v1.PodCondition{
+						{
+							Type:               v1.DisruptionTarget,
+							Status:             v1.ConditionTrue,
+							Reason:             v1.PodReasonPreemptionByScheduler,
+							LastTransitionTime: metav1.NewTime(time.Now().Add(-5 * time.Minute)),
+						},
+					},
+				},
+			},
+			result: "low (preempted 5m0s ago)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if result := podPriority(&tt.pod); result != tt.result {
+				t.Errorf("got %s, want %s", result, tt.result)
+			}
+		})
+	}
+}
+
+func TestPodPVCs(t *testing.T) {
+	t.Parallel()
+
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "pod1"},
+		Spec: v1.PodSpec{
+			Volumes: []v1.Volume{
+				{
+					Name: "data",
+					VolumeSource: v1.VolumeSource{
+						PersistentVolumeClaim: &v1.PersistentVolumeClaimVolumeSource{ClaimName: "data-pod1"},
+					},
+				},
+				{
+					Name:         "config",
+					VolumeSource: v1.VolumeSource{ConfigMap: &v1.ConfigMapVolumeSource{}},
+				},
+				{
+					Name:         "scratch",
+					VolumeSource: v1.VolumeSource{Ephemeral: &v1.EphemeralVolumeSource{}},
+				},
+			},
+		},
+	}
+
+	want := []string{"data-pod1", "pod1-scratch"}
+	if result := podPVCs(pod); !slices.Equal(result, want) {
+		t.Errorf("got %v, want %v", result, want)
+	}
+}
+
+func TestTerminatingSince(t *testing.T) {
+	t.Parallel()
+
+	grace := int64(30)
+	deleted := metav1.NewTime(time.Now().Add(-10 * time.Minute))
+
+	tests := []struct {
+		name  string
+		pod   v1.Pod
+		since time.Time
+		ok    bool
+	}{
+		{name: "running", pod: v1.Pod{}, ok: false},
+		{
+			name:  "terminating",
+			pod:   v1.Pod{ObjectMeta: metav1.ObjectMeta{DeletionTimestamp: &deleted}},
+			since: deleted.Time,
+			ok:    true,
+		},
+		{
+			name: "grace",
+			pod: v1.Pod{
+				ObjectMeta: metav1.ObjectMeta{DeletionTimestamp: &deleted, DeletionGracePeriodSeconds: &grace},
+			},
+			since: deleted.Add(-30 * time.Second),
+			ok:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			since, ok := terminatingSince(&tt.pod)
+			if ok != tt.ok || !since.Equal(tt.since) {
+				t.Errorf("got %v/%t, want %v/%t", since, ok, tt.since, tt.ok)
+			}
+		})
+	}
+}
+
+func TestKeepPodStuckTerminating(t *testing.T) {
+	t.Parallel()
+
+	deleted := metav1.NewTime(time.Now().Add(-10 * time.Minute))
+	terminating := &v1.Pod{ObjectMeta: metav1.ObjectMeta{DeletionTimestamp: &deleted}}
+	running := &v1.Pod{}
+
+	opts := options{stuckTerm: 5 * time.Minute}
+	if !keepPod(terminating, opts) {
+		t.Error("expected pod terminating for 10m to be kept")
+	}
+	if keepPod(running, opts) {
+		t.Error("expected running pod to be filtered out")
+	}
+	opts.stuckTerm = time.Hour
+	if keepPod(terminating, opts) {
+		t.Error("expected pod terminating for 10m to be filtered out")
+	}
+}
+
+func TestPodServiceAccount(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		result string
+	}{
+		{name: "", result: "default"},
+		{name: "app", result: "app"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.result, func(t *testing.T) {
+			t.Parallel()
+			pod := &v1.Pod{Spec: v1.PodSpec{ServiceAccountName: tt.name}}
+			if result := podServiceAccount(pod); result != tt.result {
+				t.Errorf("got %s, want %s", result, tt.result)
+			}
+			if !keepPod(pod, options{sa: tt.result}) {
+				t.Errorf("expected pod using %s to be kept", tt.result)
+			}
+			if keepPod(pod, options{sa: "other"}) {
+				t.Errorf("expected pod using %s to be filtered out", tt.result)
+			}
+		})
+	}
+}
+
+func TestRestartCount(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		restarts string
+		result   int
+	}{
+		{restarts: "0", result: 0},
+		{restarts: "4 (2d6h ago)", result: 4},
+		{restarts: "", result: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.restarts, func(t *testing.T) {
+			t.Parallel()
+			if result := restartCount(tt.restarts); result != tt.result {
+				t.Errorf("got %d, want %d", result, tt.result)
+			}
+		})
+	}
+}
+
+func TestSortRows(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		sortBy string
+		result []string
+	}{
+		{sortBy: sortByName, result: []string{"a/crashing", "a/stable", "b/flaky", "b/restarted"}},
+		{sortBy: sortByRestarts, result: []string{"a/crashing", "b/flaky", "b/restarted", "a/stable"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.sortBy, func(t *testing.T) {
+			t.Parallel()
+			rows := []*tableRow{
+				{Namespace: "b", Name: "restarted", Restarts: "2 (1h ago)"},
+				{Namespace: "a", Name: "stable", Restarts: "0"},
+				{Namespace: "b", Name: "flaky", Restarts: "2 (5m ago)"},
+				{Namespace: "a", Name: "crashing", Restarts: "12 (1m ago)"},
+			}
+			sortRows(rows, tt.sortBy)
+			result := make([]string, 0, len(rows))
+			for _, row := range rows {
+				result = append(result, row.Namespace+"/"+row.Name)
+			}
+			if !slices.Equal(result, tt.result) {
+				t.Errorf("got %v, want %v", result, tt.result)
+			}
+		})
+	}
+}
+
+func TestRowMatches(t *testing.T) {
+	t.Parallel()
+
+	row := &tableRow{
+		Name:     "pod1",
+		Ready:    "1/1",
+		Status:   "Running",
+		Restarts: "0",
+		Age:      "1d",
+		IP:       "10.1.2.3",
+		Node:     "node1",
+		Spot:     "x",
+		AZ:       "b",
+		key:      "kube-system/pod1",
+	}
+
+	tests := []struct {
+		pattern string
+		result  bool
+	}{
+		{pattern: `^10\.1\.2\.3$`, result: true},
+		{pattern: `^b$`, result: true},
+		{pattern: `kube-system`, result: true},
+		{pattern: `^10\.1\.2\.4$`, result: false},
+		{pattern: `Pending`, result: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			t.Parallel()
+			if result := rowMatches(row, regexp.MustCompile(tt.pattern)); result != tt.result {
+				t.Errorf("got %t, want %t", result, tt.result)
+			}
+		})
+	}
+}
+
+func TestIsHealthy(t *testing.T) {
+	t.Parallel()
+
+	allow := regexp.MustCompile(defaultAllowStatus)
+
+	tests := []struct {
+		ready  string
+		status string
+		allow  *regexp.Regexp
+		result bool
+	}{
+		{ready: "2/2", status: "Running", allow: allow, result: true},
+		{ready: "1/2", status: "Running", allow: allow, result: false},
+		{ready: "0/1", status: "Pending", allow: allow, result: false},
+		{ready: "0/1", status: "CrashLoopBackOff", allow: allow, result: false},
+		{ready: "0/1", status: "Completed", allow: allow, result: true},
+		{ready: "0/1", status: "Completed", allow: nil, result: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.status, func(t *testing.T) {
+			t.Parallel()
+			row := &tableRow{Ready: tt.ready, Status: tt.status}
+			if result := isHealthy(row, tt.allow); result != tt.result {
+				t.Errorf("got %t, want %t", result, tt.result)
+			}
+		})
+	}
+}
+
+func TestRunTotals(t *testing.T) {
+	t.Parallel()
+
+	nodes := map[string]*v1.Node{
+		"spot": {ObjectMeta: metav1.ObjectMeta{
+			Name:   "spot",
+			Labels: map[string]string{"node-role.kubernetes.io/spot-worker": "true"},
+		}},
+		"on-demand": {ObjectMeta: metav1.ObjectMeta{Name: "on-demand"}},
+	}
+	row := func(name, node, restarts string) *tableRow {
+		return &tableRow{Name: name, NodeName: node, Node: node, Restarts: restarts, Ready: "1/1", Status: "Running"}
+	}
+
+	tests := []struct {
+		name    string
+		opts    options
+		chunks  func() [][]*tableRow
+		shown   int
+		wantErr error
+	}{
+		{
+			name: "rows in every chunk",
+			chunks: func() [][]*tableRow {
+				return [][]*tableRow{{row("a", "spot", "0")}, {row("b", "on-demand", "0")}}
+			},
+			shown: 2,
+		},
+		{
+			name: "no rows kept",
+			opts: options{sa: "missing"},
+			chunks: func() [][]*tableRow {
+				return [][]*tableRow{nil, nil}
+			},
+			wantErr: errNoPodsMatchFilters,
+		},
+		{
+			name: "not enough restarts",
+			opts: options{minRestarts: 5},
+			chunks: func() [][]*tableRow {
+				return [][]*tableRow{{row("a", "spot", "0")}, {row("b", "spot", "4 (1h ago)")}}
+			},
+			wantErr: errNoPodsMatchFilters,
+		},
+		{
+			name: "not on the capacity type",
+			opts: options{spotOnly: true},
+			chunks: func() [][]*tableRow {
+				return [][]*tableRow{{row("a", "on-demand", "0")}, {row("b", "gone", "0")}}
+			},
+			wantErr: errNoPodsFoundOnCapacity,
+		},
+		{
+			name: "on the capacity type in a later chunk",
+			opts: options{spotOnly: true},
+			chunks: func() [][]*tableRow {
+				return [][]*tableRow{{row("a", "on-demand", "0")}, {row("b", "spot", "0")}}
+			},
+			shown: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			var totals runTotals
+			for _, rows := range tt.chunks() {
+				totals.add(totals.filter(rows, nodes, nil, tt.opts), tt.opts)
+			}
+			if totals.shown != tt.shown {
+				t.Errorf("got %d rows shown, want %d", totals.shown, tt.shown)
+			}
+			if err := totals.noPodsError(tt.opts); !errors.Is(err, tt.wantErr) {
+				t.Errorf("got error %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+
+	"github.com/jim-barber-he/go/k8s"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// isUnscheduled returns true if a pod is pending because it hasn't been scheduled to a node yet.
+func isUnscheduled(pod *v1.Pod) bool {
+	return pod.Status.Phase == v1.PodPending && pod.Spec.NodeName == "" && pod.DeletionTimestamp == nil
+}
+
+// pendingPod returns a copy of just the parts of a pod needed to work out why it can't be scheduled, so that the
+// rest of the chunk of pods it came from can be freed.
+func pendingPod(pod *v1.Pod) *v1.Pod {
+	needed := v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: pod.Namespace, Name: pod.Name},
+		Spec: v1.PodSpec{
+			Affinity:       pod.Spec.Affinity,
+			Containers:     pod.Spec.Containers,
+			InitContainers: pod.Spec.InitContainers,
+			NodeSelector:   pod.Spec.NodeSelector,
+			Overhead:       pod.Spec.Overhead,
+			Tolerations:    pod.Spec.Tolerations,
+		},
+		Status: v1.PodStatus{Phase: pod.Status.Phase, Conditions: pod.Status.Conditions},
+	}
+
+	return needed.DeepCopy()
+}
+
+// nodeRejections returns the reasons that a pod can't be scheduled to a node.
+// Only the node selector, taints, and allocatable resources are checked since they can be worked out from the node
+// alone. The resources already requested by other pods on the node are not taken into account.
+func nodeRejections(pod *v1.Pod, node *v1.Node, requests v1.ResourceList) []string {
+	var reasons []string
+
+	if node.Spec.Unschedulable {
+		reasons = append(reasons, "were unschedulable")
+	}
+
+	for key, value := range pod.Spec.NodeSelector {
+		if node.Labels[key] != value {
+			reasons = append(reasons, "didn't match the node selector")
+			break
+		}
+	}
+
+	for i := range node.Spec.Taints {
+		taint := &node.Spec.Taints[i]
+		if taint.Effect == v1.TaintEffectPreferNoSchedule {
+			continue
+		}
+		tolerated := slices.ContainsFunc(pod.Spec.Tolerations, func(toleration v1.Toleration) bool {
+			return toleration.ToleratesTaint(taint)
+		})
+		if !tolerated {
+			reasons = append(reasons, fmt.Sprintf("had untolerated taint {%s}", taint.ToString()))
+		}
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(requests)) {
+		request := requests[name]
+		if request.Cmp(node.Status.Allocatable[name]) > 0 {
+			reasons = append(reasons, "had insufficient allocatable "+string(name))
+		}
+	}
+
+	return reasons
+}
+
+// pendingReasons returns a summary of why a pod can't be scheduled to any of the nodes.
+// The summary starts with the message from the scheduler if it has recorded one, followed by how many nodes were
+// rejected for each reason.
+func pendingReasons(pod *v1.Pod, nodes map[string]*v1.Node) []string {
+	var reasons []string
+
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == v1.PodScheduled && condition.Status == v1.ConditionFalse && condition.Message != "" {
+			reasons = append(reasons, "scheduler: "+condition.Message)
+		}
+	}
+
+	requests := k8s.PodRequests(pod)
+	counts := make(map[string]int)
+	for _, node := range nodes {
+		for _, reason := range nodeRejections(pod, node, requests) {
+			counts[reason]++
+		}
+	}
+
+	// List the most common reasons first.
+	rejections := slices.SortedFunc(maps.Keys(counts), func(a, b string) int {
+		return cmp.Or(cmp.Compare(counts[b], counts[a]), cmp.Compare(a, b))
+	})
+	for _, reason := range rejections {
+		reasons = append(reasons, fmt.Sprintf("%d/%d node(s) %s", counts[reason], len(nodes), reason))
+	}
+
+	if len(reasons) == 0 {
+		reasons = append(reasons, "no reason found on the nodes; check node affinity, topology spread, and pod affinity")
+	}
+
+	return reasons
+}
+
+// writePendingSummary writes the reasons that each of the pending pods can't be scheduled.
+func writePendingSummary(w io.Writer, pending []*v1.Pod, nodes map[string]*v1.Node) {
+	if len(pending) == 0 {
+		return
+	}
+
+	slices.SortFunc(pending, func(a, b *v1.Pod) int {
+		return cmp.Or(cmp.Compare(a.Namespace, b.Namespace), cmp.Compare(a.Name, b.Name))
+	})
+
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Pending pods:")
+	for _, pod := range pending {
+		fmt.Fprintf(w, "  %s/%s:\n", pod.Namespace, pod.Name)
+		for _, reason := range pendingReasons(pod, nodes) {
+			fmt.Fprintf(w, "    - %s\n", reason)
+		}
+	}
+}
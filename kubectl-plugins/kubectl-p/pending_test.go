@@ -0,0 +1,100 @@
+package main
+
+import (
+	"slices"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestPendingReasons(t *testing.T) {
+	t.Parallel()
+
+	pod := &v1.Pod{
+		Spec: v1.PodSpec{
+			NodeSelector: map[string]string{"kind": "gpu"},
+			Containers: []v1.Container{
+				{Resources: v1.ResourceRequirements{Requests: v1.ResourceList{
+					v1.ResourceCPU: resource.MustParse("4"),
+				}}},
+			},
+		},
+		Status: v1.PodStatus{
+			Phase: v1.PodPending,
+			Conditions: []v1.PodCondition{
+				{
+					Type:    v1.PodScheduled,
+					Status:  v1.ConditionFalse,
+					Message: "0/2 nodes are available",
+				},
+			},
+		},
+	}
+	nodes := map[string]*v1.Node{
+		"node1": {
+			ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"kind": "gpu"}},
+			Spec: v1.NodeSpec{
+				Taints: []v1.Taint{{Key: "dedicated", Value: "gpu", Effect: v1.TaintEffectNoSchedule}},
+			},
+			Status: v1.NodeStatus{Allocatable: v1.ResourceList{v1.ResourceCPU: resource.MustParse("8")}},
+		},
+		"node2": {
+			Spec:   v1.NodeSpec{Unschedulable: true},
+			Status: v1.NodeStatus{Allocatable: v1.ResourceList{v1.ResourceCPU: resource.MustParse("2")}},
+		},
+	}
+
+	want := []string{
+		"scheduler: 0/2 nodes are available",
+		"1/2 node(s) didn't match the node selector",
+		"1/2 node(s) had insufficient allocatable cpu",
+		"1/2 node(s) had untolerated taint {dedicated=gpu:NoSchedule}",
+		"1/2 node(s) were unschedulable",
+	}
+	if result := pendingReasons(pod, nodes); !slices.Equal(result, want) {
+		t.Errorf("got %q, want %q", result, want)
+	}
+
+	// Tolerating the taint removes it as a reason.
+	pod.Spec.Tolerations = []v1.Toleration{
+		{Key: "dedicated", Operator: v1.TolerationOpEqual, Value: "gpu", Effect: v1.TaintEffectNoSchedule},
+	}
+	if result := pendingReasons(pod, nodes); slices.Contains(result, want[3]) {
+		t.Errorf("got %q, want it to not contain %q", result, want[3])
+	}
+}
+
+func TestPendingPod(t *testing.T) {
+	t.Parallel()
+
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "pod1", Namespace: "default", Labels: map[string]string{"app": "web"}},
+		Spec: v1.PodSpec{
+			NodeSelector: map[string]string{"kind": "gpu"},
+			Tolerations:  []v1.Toleration{{Key: "dedicated", Operator: v1.TolerationOpExists}},
+			Containers:   []v1.Container{{Name: "web", Image: "nginx:1.27"}},
+			Volumes:      []v1.Volume{{Name: "data"}},
+		},
+		Status: v1.PodStatus{Phase: v1.PodPending, Conditions: []v1.PodCondition{{Type: v1.PodScheduled}}},
+	}
+
+	result := pendingPod(pod)
+	if result.Namespace != "default" || result.Name != "pod1" {
+		t.Errorf("got %s/%s, want default/pod1", result.Namespace, result.Name)
+	}
+	if result.Labels != nil || result.Spec.Volumes != nil {
+		t.Errorf("got labels %v and volumes %v, want neither to be copied", result.Labels, result.Spec.Volumes)
+	}
+	if !isUnscheduled(result) || len(result.Status.Conditions) != 1 || len(result.Spec.Tolerations) != 1 {
+		t.Errorf("got %+v, want the status and tolerations to be copied", result)
+	}
+
+	// The copy doesn't share memory with the original pod.
+	pod.Spec.NodeSelector["kind"] = "cpu"
+	pod.Spec.Containers[0].Name = "changed"
+	if result.Spec.NodeSelector["kind"] != "gpu" || result.Spec.Containers[0].Name != "web" {
+		t.Errorf("got %+v, want it to be unaffected by changes to the original pod", result.Spec)
+	}
+}
@@ -0,0 +1,362 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/MakeNowJust/heredoc/v2"
+	"github.com/jim-barber-he/go/aws"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+var browseLong = heredoc.Doc(`
+	Interactively browse the parameters in the SSM parameter store below the supplied path.
+
+	The names of all parameters below the path are loaded up front, and can then be explored like a filesystem.
+	Values are only fetched from the parameter store when a parameter is viewed or edited.
+
+	If no PATH is passed at all, then for the 'dev', 'test*', and 'prod*' environments it will start in
+	'/helm/minikube/', '/helm/test*/', or '/helm/prod*/' respectively.
+
+	In a terminal the parameters are shown as a tree on the left, with the details of the selected parameter on the
+	right. Use the arrow keys to move around the tree and open paths, '/' to search, 'e' to edit, and 'd' to delete.
+	Press '?' to see all of the keys. SecureString values are hidden until 'v' is pressed.
+
+	When the input is not a terminal, such as when commands are piped in, a command is read from each line instead.
+	Use the 'help' command to see the available commands.
+`)
+
+var browseHelp = heredoc.Doc(`
+	Commands:
+	  cd [PATH]        Change to PATH, or back to the starting path if PATH is omitted
+	  delete NAME      Delete the parameter NAME after confirmation
+	  edit NAME        Set a new value for the parameter NAME, keeping its type and encryption key
+	  exit             Leave the browser (Ctrl-D also works)
+	  find TEXT        List the parameters with TEXT in their name (case insensitive)
+	  get NAME         Show all details of the parameter NAME
+	  help             Show this help
+	  ls [PATH]        List the paths and parameters directly below PATH or the current path
+	  reload           Reload the parameter names from the parameter store
+`)
+
+// browseCmd represents the browse command.
+var browseCmd = &cobra.Command{
+	Use:   "browse [flags] ENVIRONMENT [PATH]",
+	Short: "Interactively browse the SSM parameter store below a supplied path",
+	Long:  browseLong,
+	Args:  cobra.RangeArgs(1, 2),
+	PreRunE: func(_ *cobra.Command, args []string) error {
+		return validateEnvironment(args[0])
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return doBrowse(cmd.Context(), args)
+	},
+	SilenceErrors: true,
+	ValidArgsFunction: func(
+		cmd *cobra.Command, args []string, toComplete string,
+	) ([]string, cobra.ShellCompDirective) {
+		return browseCompletionHelp(cmd, args, toComplete)
+	},
+}
+
+// browser holds the state of an interactive browse session.
+type browser struct {
+	client aws.SSMAPI
+	cwd    string
+	names  []string
+	reader lineReader
+	root   string
+}
+
+// lineReader reads a line of input after displaying a prompt.
+type lineReader interface {
+	readLine(prompt string) (string, error)
+}
+
+// pipeReader reads lines of input when stdin is not a terminal, such as when commands are piped in.
+type pipeReader struct {
+	scanner *bufio.Scanner
+}
+
+func init() {
+	rootCmd.AddCommand(browseCmd)
+}
+
+// browseCompletionHelp provides shell completion help for the browse command.
+func browseCompletionHelp(
+	cmd *cobra.Command, args []string, toComplete string,
+) ([]string, cobra.ShellCompDirective) {
+	var completionHelp []string
+	switch {
+	case len(args) == 0:
+		completionHelp = cobra.AppendActiveHelp(completionHelp, "dev, test*, or prod*")
+	case len(args) == 1:
+		return completeParameterPath(cmd, args[0], toComplete, "The path in the SSM parameter store to browse")
+	default:
+		completionHelp = cobra.AppendActiveHelp(completionHelp, "No more arguments")
+	}
+	return completionHelp, cobra.ShellCompDirectiveNoFileComp
+}
+
+// doBrowse starts an interactive session for browsing the SSM Parameter Store parameters below the specified path.
+// args[0] is the name of to AWS Profile to use when accessing the SSM parameter store.
+// args[1] is the path in the SSM parameter store to start browsing from.
+func doBrowse(ctx context.Context, args []string) error {
+	profile := getAWSProfile(args[0])
+	region := getAWSRegion(args[0])
+	cfg := aws.Login(ctx, &aws.LoginSessionDetails{Profile: profile, Region: region})
+	// Clear the cached results since they may be out of date once parameters are changed.
+	defer invalidateCache(profile, region)
+
+	var root string
+	if len(args) > 1 {
+		root = getSSMPath(args[0], args[1])
+	} else {
+		root = getSSMPath(args[0], "")
+	}
+	root = path.Clean("/" + root)
+
+	b := &browser{client: aws.SSMClient(cfg, ssmClientOptions()...), cwd: root, root: root}
+	if err := b.reload(ctx); err != nil {
+		return err
+	}
+
+	if term.IsTerminal(int(os.Stdin.Fd())) && term.IsTerminal(int(os.Stdout.Fd())) {
+		return newTUI(b).run(ctx)
+	}
+
+	fmt.Printf("Loaded %d parameters below %s. Type 'help' for a list of commands.\n", len(b.names), root)
+	b.reader = &pipeReader{scanner: bufio.NewScanner(os.Stdin)}
+	return b.run(ctx)
+}
+
+// readLine reads the next line of piped input.
+func (r *pipeReader) readLine(prompt string) (string, error) {
+	fmt.Print(prompt)
+	if !r.scanner.Scan() {
+		fmt.Println()
+		if err := r.scanner.Err(); err != nil {
+			return "", fmt.Errorf("%w: %w", errReadInput, err)
+		}
+		return "", io.EOF
+	}
+	return r.scanner.Text(), nil
+}
+
+// children returns the sorted names of the paths and parameters directly below dir.
+// Paths have a trailing slash to distinguish them from parameters.
+func (b *browser) children(dir string) []string {
+	prefix := strings.TrimSuffix(dir, "/") + "/"
+	var entries []string
+	for _, name := range b.names {
+		rest, found := strings.CutPrefix(name, prefix)
+		if !found {
+			continue
+		}
+		if i := strings.Index(rest, "/"); i >= 0 {
+			rest = rest[:i+1]
+		}
+		if !slices.Contains(entries, rest) {
+			entries = append(entries, rest)
+		}
+	}
+	slices.Sort(entries)
+	return entries
+}
+
+// delete removes a parameter from the SSM parameter store after confirmation.
+func (b *browser) delete(ctx context.Context, name string) error {
+	answer, err := b.reader.readLine(fmt.Sprintf("Delete %s? [y/N] ", name))
+	if err != nil {
+		return err
+	}
+	if answer = strings.ToLower(strings.TrimSpace(answer)); answer != "y" && answer != "yes" {
+		fmt.Println("Aborted.")
+		return nil
+	}
+
+	if err := aws.SSMDelete(ctx, b.client, name); err != nil {
+		return fmt.Errorf("%w: %w", errDeleteSSMParameter, err)
+	}
+	b.names = slices.DeleteFunc(b.names, func(n string) bool { return n == name })
+	fmt.Printf("Deleted %s\n", name)
+	return nil
+}
+
+// edit prompts for a new value for a parameter and stores it, keeping the parameter's other attributes.
+func (b *browser) edit(ctx context.Context, name string) error {
+	param, err := aws.SSMGet(ctx, b.client, name)
+	if err != nil {
+		return fmt.Errorf("%w: %w", errGetSSMParameter, err)
+	}
+	if param.Error != "" {
+		return fmt.Errorf("%w: %s", errDecryptEdit, param.Error)
+	}
+
+	fmt.Printf("Current value: %s\n", param.Value)
+	value, err := b.reader.readLine("New value (leave empty to cancel): ")
+	if err != nil {
+		return err
+	}
+	if value == "" || value == param.Value {
+		fmt.Println("Value unchanged.")
+		return nil
+	}
+
+	param.Value = value
+	version, err := aws.SSMPut(ctx, b.client, &param)
+	if err != nil {
+		return fmt.Errorf("%w: %w", errPutSSMParameter, err)
+	}
+	fmt.Printf("Parameter %s updated to version %d\n", name, version)
+	return nil
+}
+
+// execute runs a single browser command.
+// It returns true when the browser should exit.
+func (b *browser) execute(ctx context.Context, line string) (bool, error) {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return false, nil
+	}
+	command, args := fields[0], fields[1:]
+
+	var target string
+	if len(args) > 0 {
+		target = b.resolve(args[0])
+	}
+	needsTarget := func() error {
+		if target == "" {
+			return newBrowseArgRequiredError(command)
+		}
+		return nil
+	}
+
+	switch command {
+	case "cd":
+		if target == "" {
+			target = b.root
+		}
+		if !b.isDir(target) {
+			return false, newBrowsePathNotFoundError(target)
+		}
+		b.cwd = target
+	case "delete", "rm":
+		if err := needsTarget(); err != nil {
+			return false, err
+		}
+		return false, b.delete(ctx, target)
+	case "edit":
+		if err := needsTarget(); err != nil {
+			return false, err
+		}
+		return false, b.edit(ctx, target)
+	case "exit", "quit":
+		return true, nil
+	case "find":
+		if len(args) == 0 {
+			return false, newBrowseArgRequiredError(command)
+		}
+		b.find(strings.Join(args, " "))
+	case "get", "view":
+		if err := needsTarget(); err != nil {
+			return false, err
+		}
+		param, err := aws.SSMGetFull(ctx, b.client, target)
+		if err != nil {
+			return false, fmt.Errorf("%w: %w", errGetSSMParameter, err)
+		}
+		param.Print()
+	case "help", "?":
+		fmt.Print(browseHelp)
+	case "ls":
+		if target == "" {
+			target = b.cwd
+		}
+		for _, entry := range b.children(target) {
+			fmt.Println(entry)
+		}
+	case "reload":
+		if err := b.reload(ctx); err != nil {
+			return false, err
+		}
+		fmt.Printf("Loaded %d parameters below %s\n", len(b.names), b.root)
+	default:
+		return false, newBrowseUnknownCommandError(command)
+	}
+
+	return false, nil
+}
+
+// find displays the parameters that contain the text in their name, ignoring case.
+func (b *browser) find(text string) {
+	text = strings.ToLower(text)
+	count := 0
+	for _, name := range b.names {
+		if strings.Contains(strings.ToLower(name), text) {
+			fmt.Println(name)
+			count++
+		}
+	}
+	fmt.Printf("%d parameters found\n", count)
+}
+
+// isDir returns true if the path has parameters below it.
+func (b *browser) isDir(dir string) bool {
+	if dir == b.root {
+		return true
+	}
+	prefix := strings.TrimSuffix(dir, "/") + "/"
+	return slices.ContainsFunc(b.names, func(name string) bool { return strings.HasPrefix(name, prefix) })
+}
+
+// reload fetches the names of all the parameters below the root path.
+func (b *browser) reload(ctx context.Context) error {
+	names, err := aws.SSMListNames(ctx, b.client, b.root, true)
+	if err != nil {
+		return fmt.Errorf("%w: %w", errListSSMParameters, err)
+	}
+	slices.Sort(names)
+	b.names = names
+	return nil
+}
+
+// resolve turns a path that may be relative to the current path into an absolute one.
+func (b *browser) resolve(p string) string {
+	if p == "" {
+		return b.cwd
+	}
+	if strings.HasPrefix(p, "/") {
+		return path.Clean(p)
+	}
+	return path.Join(b.cwd, p)
+}
+
+// run reads and executes commands until the user exits.
+func (b *browser) run(ctx context.Context) error {
+	for {
+		line, err := b.reader.readLine(b.cwd + "> ")
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		done, err := b.execute(ctx, line)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+		}
+		if done {
+			return nil
+		}
+	}
+}
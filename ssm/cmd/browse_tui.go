@@ -0,0 +1,714 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/MakeNowJust/heredoc/v2"
+	"github.com/jim-barber-he/go/aws"
+	"golang.org/x/term"
+)
+
+// Escape sequences used to draw the terminal user interface.
+const (
+	ansiAltScreen  = "\x1b[?1049h"
+	ansiClearLine  = "\x1b[K"
+	ansiHideCursor = "\x1b[?25l"
+	ansiHome       = "\x1b[H"
+	ansiMainScreen = "\x1b[?1049l"
+	ansiReset      = "\x1b[0m"
+	ansiReverse    = "\x1b[7m"
+	ansiShowCursor = "\x1b[?25h"
+)
+
+// Names of the keys read from the terminal that aren't printable characters.
+const (
+	keyBackspace = "backspace"
+	keyCtrlC     = "ctrl-c"
+	keyCtrlU     = "ctrl-u"
+	keyDown      = "down"
+	keyEnd       = "end"
+	keyEnter     = "enter"
+	keyEscape    = "escape"
+	keyHome      = "home"
+	keyLeft      = "left"
+	keyPageDown  = "pgdn"
+	keyPageUp    = "pgup"
+	keyRight     = "right"
+	keyUp        = "up"
+)
+
+// tuiFetchDelay is how long the cursor has to rest on a parameter before its details are fetched.
+// This stops scrolling through the tree from making an API call for every parameter passed over.
+const tuiFetchDelay = 250 * time.Millisecond
+
+// tuiMaskedValue replaces the value of a SecureString parameter until values are revealed.
+const tuiMaskedValue = "******** (press v to reveal)"
+
+// Fallback dimensions of the terminal for when its size can't be determined.
+const (
+	tuiDefaultHeight = 24
+	tuiDefaultWidth  = 80
+)
+
+const tuiKeysHelp = "↑/↓ move  →/← open/close  / search  e edit  d delete  v reveal  r reload  ? help  q quit"
+
+var tuiHelp = heredoc.Doc(`
+	Keys:
+	  ↑ ↓ k j          Move up or down
+	  PgUp PgDn        Move up or down a page
+	  Home End g G     Move to the top or bottom
+	  → l              Expand a path, or refresh the details of a parameter
+	  ← h              Collapse a path, or move to its parent
+	  Enter            Expand or collapse a path, or refresh the details of a parameter
+	  /                Only show parameters with the typed text in their name (Esc clears it)
+	  e                Set a new value for the parameter, keeping its type and encryption key
+	  d                Delete the parameter after confirmation
+	  v                Reveal or hide the values of SecureString parameters
+	  r                Reload the parameter names from the parameter store
+	  ?                Show or hide this help
+	  q Ctrl-C         Leave the browser
+`)
+
+// escapeKeys maps the escape sequences sent by terminals to the names of the keys they represent.
+var escapeKeys = map[string]string{
+	"\x1b[A":  keyUp,
+	"\x1bOA":  keyUp,
+	"\x1b[B":  keyDown,
+	"\x1bOB":  keyDown,
+	"\x1b[C":  keyRight,
+	"\x1bOC":  keyRight,
+	"\x1b[D":  keyLeft,
+	"\x1bOD":  keyLeft,
+	"\x1b[H":  keyHome,
+	"\x1bOH":  keyHome,
+	"\x1b[1~": keyHome,
+	"\x1b[7~": keyHome,
+	"\x1b[F":  keyEnd,
+	"\x1bOF":  keyEnd,
+	"\x1b[4~": keyEnd,
+	"\x1b[8~": keyEnd,
+	"\x1b[5~": keyPageUp,
+	"\x1b[6~": keyPageDown,
+}
+
+// tuiMode determines what the keys typed into the browser are used for.
+type tuiMode int
+
+const (
+	tuiModeBrowse tuiMode = iota
+	tuiModeDelete
+	tuiModeEdit
+	tuiModeSearch
+)
+
+// tui is a terminal user interface for the browser.
+// The parameters are shown as a tree on the left, with the details of the selected one on the right.
+type tui struct {
+	*browser
+	cursor   int
+	details  map[string]tuiDetail
+	entries  []tuiEntry
+	expanded map[string]bool
+	height   int
+	input    []rune
+	mode     tuiMode
+	offset   int
+	quit     bool
+	reveal   bool
+	search   string
+	showHelp bool
+	status   string
+	width    int
+}
+
+// tuiDetail holds the result of fetching the details of a parameter.
+type tuiDetail struct {
+	err   error
+	param aws.SSMParameter
+}
+
+// tuiEntry is a line of the tree in the left pane.
+type tuiEntry struct {
+	depth int
+	isDir bool
+	label string
+	path  string
+}
+
+// newTUI returns a terminal user interface for the browser with the top level of the tree showing.
+func newTUI(b *browser) *tui {
+	t := &tui{
+		browser:  b,
+		details:  make(map[string]tuiDetail),
+		expanded: make(map[string]bool),
+		height:   tuiDefaultHeight,
+		width:    tuiDefaultWidth,
+	}
+	t.rebuild()
+	return t
+}
+
+// parseKeys splits the bytes read from the terminal into the keys that were pressed.
+// Printable characters are returned as themselves, and other keys by their name.
+func parseKeys(buf []byte) []string {
+	var keys []string
+	s := string(buf)
+	for s != "" {
+		switch {
+		case s[0] == '\x1b':
+			key, size := parseEscape(s)
+			if key != "" {
+				keys = append(keys, key)
+			}
+			s = s[size:]
+			continue
+		case s[0] == '\r' || s[0] == '\n':
+			keys = append(keys, keyEnter)
+		case s[0] == '\x7f' || s[0] == '\b':
+			keys = append(keys, keyBackspace)
+		case s[0] == '\x03':
+			keys = append(keys, keyCtrlC)
+		case s[0] == '\x15':
+			keys = append(keys, keyCtrlU)
+		case s[0] < ' ':
+			// Ignore other control characters.
+		default:
+			r, size := utf8.DecodeRuneInString(s)
+			keys = append(keys, string(r))
+			s = s[size:]
+			continue
+		}
+		s = s[1:]
+	}
+	return keys
+}
+
+// parseEscape returns the name of the key for the escape sequence at the start of s, and the length of the sequence.
+// Unknown sequences are skipped by returning an empty key name.
+func parseEscape(s string) (string, int) {
+	for seq, key := range escapeKeys {
+		if strings.HasPrefix(s, seq) {
+			return key, len(seq)
+		}
+	}
+	if len(s) < 3 || (s[1] != '[' && s[1] != 'O') {
+		return keyEscape, 1
+	}
+	// Skip to the final byte of the unknown control sequence.
+	for i := 2; i < len(s); i++ {
+		if s[i] >= '@' && s[i] <= '~' {
+			return "", i + 1
+		}
+	}
+	return "", len(s)
+}
+
+// readKeys sends the keys read from r to the keys channel until reading fails.
+func readKeys(r io.Reader, keys chan<- []string) {
+	defer close(keys)
+	buf := make([]byte, 256)
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			keys <- parseKeys(buf[:n])
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// addEntries adds the paths and parameters below dir to the tree, along with those below any expanded paths.
+func (t *tui) addEntries(dir string, depth int) {
+	for _, child := range t.children(dir) {
+		entry := tuiEntry{depth: depth, label: child, path: strings.TrimSuffix(dir, "/") + "/" + child}
+		if strings.HasSuffix(child, "/") {
+			entry.isDir = true
+			entry.path = strings.TrimSuffix(entry.path, "/")
+		}
+		t.entries = append(t.entries, entry)
+		if entry.isDir && t.expanded[entry.path] {
+			t.addEntries(entry.path, depth+1)
+		}
+	}
+}
+
+// bottomLine returns what to show on the last line of the screen, which depends on what the browser is doing.
+func (t *tui) bottomLine() string {
+	switch t.mode {
+	case tuiModeBrowse:
+	case tuiModeDelete:
+		return fmt.Sprintf("Delete %s? [y/N] ", t.selected().path)
+	case tuiModeEdit:
+		// Show the end of the value being typed if it is too long to fit.
+		prompt := "New value (Enter saves, Esc cancels): "
+		input := []rune(string(t.input) + "_")
+		if space := t.width - utf8.RuneCountInString(prompt); len(input) > space && space > 0 {
+			input = input[len(input)-space:]
+		}
+		return prompt + string(input)
+	case tuiModeSearch:
+		return "/" + string(t.input) + "_"
+	}
+	if t.status != "" {
+		return t.status
+	}
+	return tuiKeysHelp
+}
+
+// collapse closes the selected path, or moves to the parent of the selected entry.
+func (t *tui) collapse() {
+	entry := t.selected()
+	if entry == nil {
+		return
+	}
+	if entry.isDir && t.expanded[entry.path] {
+		delete(t.expanded, entry.path)
+		t.rebuild()
+		return
+	}
+	for i := t.cursor - 1; i >= 0; i-- {
+		if t.entries[i].depth < entry.depth {
+			t.cursor = i
+			return
+		}
+	}
+}
+
+// detailLines returns the lines shown in the right pane for the selected entry.
+func (t *tui) detailLines() []string {
+	if t.showHelp {
+		return strings.Split(strings.TrimSuffix(tuiHelp, "\n"), "\n")
+	}
+
+	entry := t.selected()
+	switch {
+	case entry == nil && t.search != "":
+		return []string{fmt.Sprintf("No parameters match %q.", t.search)}
+	case entry == nil:
+		return []string{"No parameters found."}
+	case entry.isDir:
+		prefix := entry.path + "/"
+		count := 0
+		for _, name := range t.names {
+			if strings.HasPrefix(name, prefix) {
+				count++
+			}
+		}
+		return []string{"Path: " + prefix, fmt.Sprintf("Parameters: %d", count)}
+	}
+
+	detail, found := t.details[entry.path]
+	switch {
+	case !found:
+		return []string{"Loading " + entry.path + "..."}
+	case detail.err != nil:
+		return []string{"Error: " + detail.err.Error()}
+	}
+
+	param := detail.param
+	if param.Type == typeSecureString && param.Value != "" && !t.reveal {
+		param.Value = tuiMaskedValue
+	}
+	var buf bytes.Buffer
+	param.Fprint(&buf)
+	return strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+}
+
+// fetch gets the details of a parameter to display in the right pane.
+func (t *tui) fetch(ctx context.Context, name string) {
+	param, err := aws.SSMGetFull(ctx, t.client, name)
+	if err != nil {
+		err = fmt.Errorf("%w: %w", errGetSSMParameter, err)
+	}
+	t.details[name] = tuiDetail{err: err, param: param}
+}
+
+// handleBrowseKey acts on a key pressed while moving around the tree.
+func (t *tui) handleBrowseKey(ctx context.Context, key string) {
+	entry := t.selected()
+	switch key {
+	case keyUp, "k":
+		t.move(-1)
+	case keyDown, "j":
+		t.move(1)
+	case keyPageUp:
+		t.move(-t.paneHeight())
+	case keyPageDown:
+		t.move(t.paneHeight())
+	case keyHome, "g":
+		t.move(-len(t.entries))
+	case keyEnd, "G":
+		t.move(len(t.entries))
+	case keyRight, "l", keyEnter:
+		switch {
+		case entry == nil:
+		case !entry.isDir:
+			t.fetch(ctx, entry.path)
+		case t.expanded[entry.path] && key == keyEnter:
+			delete(t.expanded, entry.path)
+			t.rebuild()
+		case t.expanded[entry.path]:
+			t.move(1)
+		default:
+			t.expanded[entry.path] = true
+			t.rebuild()
+		}
+	case keyLeft, "h":
+		t.collapse()
+	case "/":
+		t.mode = tuiModeSearch
+		t.input = []rune(t.search)
+	case keyEscape:
+		t.setSearch("")
+	case "e":
+		t.startEdit(ctx)
+	case "d":
+		if entry != nil && !entry.isDir {
+			t.mode = tuiModeDelete
+		}
+	case "v":
+		t.reveal = !t.reveal
+	case "r":
+		if err := t.reload(ctx); err != nil {
+			t.status = "Error: " + err.Error()
+			return
+		}
+		clear(t.details)
+		t.rebuild()
+		t.status = fmt.Sprintf("Loaded %d parameters below %s", len(t.names), t.root)
+	case "?":
+		t.showHelp = !t.showHelp
+	case "q", keyCtrlC:
+		t.quit = true
+	}
+}
+
+// handleDeleteKey deletes the selected parameter if the deletion was confirmed.
+func (t *tui) handleDeleteKey(ctx context.Context, key string) {
+	t.mode = tuiModeBrowse
+	entry := t.selected()
+	if key != "y" && key != "Y" {
+		t.status = "Aborted."
+		return
+	}
+
+	if err := aws.SSMDelete(ctx, t.client, entry.path); err != nil {
+		t.status = fmt.Sprintf("Error: %v: %v", errDeleteSSMParameter, err)
+		return
+	}
+	t.names = slices.DeleteFunc(t.names, func(n string) bool { return n == entry.path })
+	delete(t.details, entry.path)
+	t.status = "Deleted " + entry.path
+	t.rebuild()
+}
+
+// handleInputKey edits the text typed into the bottom line while searching or editing a value.
+// It returns whether the input is finished with, and whether it was accepted by pressing enter rather than escape.
+func (t *tui) handleInputKey(key string) (bool, bool) {
+	switch key {
+	case keyEnter:
+		return true, true
+	case keyEscape, keyCtrlC:
+		return true, false
+	case keyBackspace:
+		if len(t.input) > 0 {
+			t.input = t.input[:len(t.input)-1]
+		}
+	case keyCtrlU:
+		t.input = nil
+	default:
+		if utf8.RuneCountInString(key) == 1 {
+			t.input = append(t.input, []rune(key)...)
+		}
+	}
+	return false, false
+}
+
+// handleKey acts on a key pressed in the browser.
+func (t *tui) handleKey(ctx context.Context, key string) {
+	t.status = ""
+	switch t.mode {
+	case tuiModeBrowse:
+		t.handleBrowseKey(ctx, key)
+	case tuiModeDelete:
+		t.handleDeleteKey(ctx, key)
+	case tuiModeEdit:
+		if done, accepted := t.handleInputKey(key); done {
+			t.mode = tuiModeBrowse
+			if accepted {
+				t.save(ctx)
+			} else {
+				t.status = "Value unchanged."
+			}
+		}
+	case tuiModeSearch:
+		done, accepted := t.handleInputKey(key)
+		if done && !accepted {
+			t.setSearch("")
+		} else {
+			t.setSearch(string(t.input))
+		}
+		if done {
+			t.mode = tuiModeBrowse
+		}
+	}
+}
+
+// move moves the cursor by delta lines, keeping it within the tree.
+func (t *tui) move(delta int) {
+	t.cursor = max(min(t.cursor+delta, len(t.entries)-1), 0)
+}
+
+// paneHeight returns the number of lines available to the panes between the title and bottom lines.
+func (t *tui) paneHeight() int {
+	return max(t.height-2, 1)
+}
+
+// pendingFetch returns the name of the selected parameter if its details haven't been fetched yet.
+func (t *tui) pendingFetch() string {
+	entry := t.selected()
+	if t.mode != tuiModeBrowse || entry == nil || entry.isDir {
+		return ""
+	}
+	if _, found := t.details[entry.path]; found {
+		return ""
+	}
+	return entry.path
+}
+
+// rebuild regenerates the lines of the tree, keeping the same entry selected if it is still there.
+func (t *tui) rebuild() {
+	var selectedPath string
+	if entry := t.selected(); entry != nil {
+		selectedPath = entry.path
+	}
+
+	t.entries = nil
+	if t.search == "" {
+		t.addEntries(t.root, 0)
+	} else {
+		text := strings.ToLower(t.search)
+		prefix := strings.TrimSuffix(t.root, "/") + "/"
+		for _, name := range t.names {
+			if strings.Contains(strings.ToLower(name), text) {
+				t.entries = append(t.entries, tuiEntry{label: strings.TrimPrefix(name, prefix), path: name})
+			}
+		}
+	}
+
+	if i := slices.IndexFunc(t.entries, func(e tuiEntry) bool { return e.path == selectedPath }); i >= 0 {
+		t.cursor = i
+	}
+	t.move(0)
+}
+
+// render returns the escape sequences and text that draw the whole screen.
+func (t *tui) render() string {
+	paneHeight := t.paneHeight()
+	leftWidth := max(t.width*2/5, 1)
+	rightWidth := max(t.width-leftWidth-1, 0)
+
+	// Scroll the tree so that the cursor is visible.
+	if t.cursor < t.offset {
+		t.offset = t.cursor
+	}
+	if t.cursor >= t.offset+paneHeight {
+		t.offset = t.cursor - paneHeight + 1
+	}
+	t.offset = max(min(t.offset, len(t.entries)-paneHeight), 0)
+
+	var right []string
+	for _, line := range t.detailLines() {
+		right = append(right, wrapRunes(line, rightWidth)...)
+	}
+
+	lines := make([]string, 0, t.height)
+	title := fmt.Sprintf(" ssm browse: %s (%d parameters)", t.root, len(t.names))
+	if t.search != "" {
+		title += fmt.Sprintf(", %d matching %q", len(t.entries), t.search)
+	}
+	lines = append(lines, ansiReverse+fitWidth(title, t.width)+ansiReset)
+
+	for i := range paneHeight {
+		var left, detail string
+		if i+t.offset < len(t.entries) {
+			left = t.entries[i+t.offset].treeLabel(t.expanded)
+		}
+		left = fitWidth(left, leftWidth)
+		if i+t.offset == t.cursor && len(t.entries) > 0 {
+			left = ansiReverse + left + ansiReset
+		}
+		if i < len(right) {
+			detail = right[i]
+		}
+		lines = append(lines, left+"│"+fitWidth(detail, rightWidth))
+	}
+
+	lines = append(lines, fitWidth(t.bottomLine(), t.width))
+	return ansiHome + strings.Join(lines, ansiClearLine+"\r\n") + ansiClearLine
+}
+
+// run draws the browser and acts on the keys pressed until the user quits.
+func (t *tui) run(ctx context.Context) error {
+	fd := int(os.Stdin.Fd())
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		return fmt.Errorf("%w: %w", errReadInput, err)
+	}
+	defer func() { _ = term.Restore(fd, state) }()
+
+	fmt.Print(ansiAltScreen + ansiHideCursor)
+	defer fmt.Print(ansiShowCursor + ansiMainScreen)
+
+	keys := make(chan []string)
+	go readKeys(os.Stdin, keys)
+
+	resize := make(chan os.Signal, 1)
+	signal.Notify(resize, syscall.SIGWINCH)
+	defer signal.Stop(resize)
+
+	for !t.quit {
+		if width, height, err := term.GetSize(int(os.Stdout.Fd())); err == nil {
+			t.width, t.height = width, height
+		}
+		fmt.Print(t.render())
+
+		var fetch <-chan time.Time
+		if t.pendingFetch() != "" {
+			fetch = time.After(tuiFetchDelay)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case pressed, ok := <-keys:
+			if !ok {
+				return nil
+			}
+			for _, key := range pressed {
+				t.handleKey(ctx, key)
+			}
+		case <-fetch:
+			t.fetch(ctx, t.pendingFetch())
+		case <-resize:
+		}
+	}
+	return nil
+}
+
+// save stores the value typed in for the selected parameter, keeping the parameter's other attributes.
+func (t *tui) save(ctx context.Context) {
+	name := t.selected().path
+	param := t.details[name].param
+	value := string(t.input)
+	if value == "" || value == param.Value {
+		t.status = "Value unchanged."
+		return
+	}
+
+	param.Value = value
+	version, err := aws.SSMPut(ctx, t.client, &param)
+	if err != nil {
+		t.status = fmt.Sprintf("Error: %v: %v", errPutSSMParameter, err)
+		return
+	}
+	// Fetch the details again to pick up the new version and modification time.
+	delete(t.details, name)
+	t.status = fmt.Sprintf("Parameter %s updated to version %d", name, version)
+}
+
+// selected returns the entry under the cursor, or nil if the tree is empty.
+func (t *tui) selected() *tuiEntry {
+	if t.cursor < 0 || t.cursor >= len(t.entries) {
+		return nil
+	}
+	return &t.entries[t.cursor]
+}
+
+// setSearch filters the tree to the parameters with the text in their name, or shows the whole tree if it is empty.
+func (t *tui) setSearch(text string) {
+	if text == t.search {
+		return
+	}
+	t.search = text
+	t.rebuild()
+}
+
+// startEdit prompts for a new value for the selected parameter, starting with its current value.
+func (t *tui) startEdit(ctx context.Context) {
+	entry := t.selected()
+	if entry == nil || entry.isDir {
+		return
+	}
+	if _, found := t.details[entry.path]; !found {
+		t.fetch(ctx, entry.path)
+	}
+
+	detail := t.details[entry.path]
+	switch {
+	case detail.err != nil:
+		t.status = "Error: " + detail.err.Error()
+	case detail.param.Error != "":
+		t.status = fmt.Sprintf("Error: %v: %s", errDecryptEdit, detail.param.Error)
+	default:
+		t.mode = tuiModeEdit
+		t.input = []rune(detail.param.Value)
+	}
+}
+
+// treeLabel returns the text of the entry as it appears in the tree, indented by its depth.
+func (e *tuiEntry) treeLabel(expanded map[string]bool) string {
+	marker := "  "
+	switch {
+	case e.isDir && expanded[e.path]:
+		marker = "▾ "
+	case e.isDir:
+		marker = "▸ "
+	}
+	return " " + strings.Repeat("  ", e.depth) + marker + e.label
+}
+
+// fitWidth truncates or pads a string so that it is exactly width characters wide.
+// Control characters such as tabs are replaced by spaces so that they can't disturb the layout.
+func fitWidth(s string, width int) string {
+	runes := []rune(strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return ' '
+		}
+		return r
+	}, s))
+	if len(runes) > width {
+		if width == 0 {
+			return ""
+		}
+		return string(runes[:width-1]) + "…"
+	}
+	return string(runes) + strings.Repeat(" ", width-len(runes))
+}
+
+// wrapRunes splits a line into pieces of at most width characters.
+// Values such as ARNs have no spaces to wrap at, so the line is split wherever it reaches the width.
+func wrapRunes(line string, width int) []string {
+	runes := []rune(line)
+	if width <= 0 || len(runes) <= width {
+		return []string{line}
+	}
+	var lines []string
+	for len(runes) > width {
+		lines = append(lines, string(runes[:width]))
+		runes = runes[width:]
+	}
+	return append(lines, string(runes))
+}
@@ -0,0 +1,146 @@
+package cmd
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jim-barber-he/go/aws"
+)
+
+// Kinds of results kept in the cache.
+const (
+	cacheCompletion = "completion"
+	cacheGet        = "get"
+	cacheList       = "list"
+)
+
+// cacheDir returns the directory that caches the results fetched using an AWS profile and region.
+// Each combination has its own directory so that all of its results can be invalidated at once.
+func cacheDir(profile, region string) string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "ssm", cacheHash(profile, region))
+}
+
+// cacheFile returns the path of the file that caches a kind of result for an AWS profile and region.
+// The key is everything that affects the result, such as the path and command line options, so that results that
+// differ are cached separately.
+func cacheFile(profile, region, kind string, key ...string) string {
+	return filepath.Join(cacheDir(profile, region), kind+"-"+cacheHash(key...)+".json")
+}
+
+// cacheHash returns a hash of the parts that is safe to use in a file name.
+func cacheHash(parts ...string) string {
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	return hex.EncodeToString(sum[:])
+}
+
+// invalidateCache removes all of the cached results for an AWS profile and region.
+// It is called after parameters are changed so that the cache doesn't return stale results.
+func invalidateCache(profile, region string) {
+	// Errors are ignored since there is nothing more that can be done about them.
+	_ = os.RemoveAll(cacheDir(profile, region))
+}
+
+// readCache decodes a cached result into v, returning false if there is no cached result younger than the ttl.
+func readCache(file string, ttl time.Duration, v any) bool {
+	info, err := os.Stat(file)
+	if err != nil || time.Since(info.ModTime()) >= ttl {
+		return false
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return false
+	}
+	return json.Unmarshal(data, v) == nil
+}
+
+// readResultCache decodes a cached list or get result into v, returning false if --cache wasn't used or there is no
+// cached result younger than it.
+func readResultCache(file string, v any) bool {
+	return rootOpts.cache > 0 && readCache(file, rootOpts.cache, v)
+}
+
+// writeCache stores a result in the cache.
+// The files are only readable by the user since results can include the names and details of parameters.
+// Failing to cache a result only makes the next lookup slower, so errors are ignored.
+func writeCache(file string, v any) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0o700); err != nil {
+		return
+	}
+	_ = os.WriteFile(file, data, 0o600)
+}
+
+// writeResultCache stores a list or get result in the cache if --cache was used.
+func writeResultCache(file string, v any) {
+	if rootOpts.cache > 0 {
+		writeCache(file, v)
+	}
+}
+
+// withoutSecureStrings returns a copy of the parameters with the values of SecureString parameters removed, so that
+// decrypted values are never written to the cache.
+func withoutSecureStrings(params []aws.SSMParameter) []aws.SSMParameter {
+	params = slices.Clone(params)
+	for i := range params {
+		if params[i].Type == typeSecureString {
+			params[i].Value = ""
+		}
+	}
+	return params
+}
+
+// restoreSecureStrings fetches the values of the SecureString parameters read from the cache, since only their details
+// are cached. Parameters that failed to decrypt when they were cached are left alone.
+// It returns errCacheStale if a parameter has been deleted or has a new version since it was cached, in which case the
+// cached results shouldn't be used.
+func restoreSecureStrings(ctx context.Context, profile, region string, params []aws.SSMParameter) error {
+	var names []string
+	for _, p := range params {
+		if p.Type == typeSecureString && p.Error == "" {
+			names = append(names, p.Name+p.Selector)
+		}
+	}
+	if len(names) == 0 {
+		return nil
+	}
+
+	cfg := aws.Login(ctx, &aws.LoginSessionDetails{Profile: profile, Region: region})
+	fetched, invalid, err := aws.SSMGetParameters(ctx, aws.SSMClient(cfg, ssmClientOptions()...), names, true)
+	if err != nil {
+		return err
+	}
+	if len(invalid) > 0 {
+		return errCacheStale
+	}
+
+	current := make(map[string]aws.SSMParameter, len(fetched))
+	for _, p := range fetched {
+		current[p.Name+p.Selector] = p
+	}
+	for i, p := range params {
+		if p.Type != typeSecureString || p.Error != "" {
+			continue
+		}
+		fresh, ok := current[p.Name+p.Selector]
+		if !ok || fresh.Version != p.Version {
+			return errCacheStale
+		}
+		params[i].Value = fresh.Value
+		params[i].Error = fresh.Error
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jim-barber-he/go/aws"
+	"github.com/spf13/cobra"
+)
+
+const (
+	// completionCacheTTL is how long the parameter names fetched for shell completion are reused for.
+	// It keeps completing successive path segments fast, while still picking up new parameters reasonably quickly.
+	completionCacheTTL = 2 * time.Minute
+
+	// completionTimeout limits how long shell completion waits for the SSM parameter store so the shell never hangs.
+	completionTimeout = 5 * time.Second
+)
+
+// completeParameterPath returns shell completions for the next segment of an SSM parameter path, along with the
+// active help message.
+// Relative paths are completed relative to the path for the environment, the same way getSSMPath resolves them.
+// Completion never prompts for an AWS SSO login, so only the help is returned if there is no valid session.
+// Spaces aren't added after a completion that ends in a slash so that the next segment of the path can be typed.
+func completeParameterPath(
+	cmd *cobra.Command, environment, toComplete, help string,
+) ([]string, cobra.ShellCompDirective) {
+	completions := cobra.AppendActiveHelp(nil, help)
+	directive := cobra.ShellCompDirectiveNoFileComp
+
+	// The directory part of what has been typed so far, including its trailing slash.
+	dir := toComplete[:strings.LastIndex(toComplete, "/")+1]
+
+	listPath := "/"
+	if dir != "/" {
+		listPath = getSSMPath(environment, strings.TrimSuffix(dir, "/"))
+	}
+
+	ctx := cmd.Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ctx, cancel := context.WithTimeout(ctx, completionTimeout)
+	defer cancel()
+
+	names, err := completionNames(ctx, environment, listPath)
+	if err != nil {
+		return completions, directive
+	}
+
+	prefix := strings.TrimSuffix(listPath, "/") + "/"
+	var paths []string
+	for _, name := range names {
+		rest, found := strings.CutPrefix(name, prefix)
+		if !found {
+			continue
+		}
+		if i := strings.Index(rest, "/"); i >= 0 {
+			rest = rest[:i+1]
+		}
+		completion := dir + rest
+		if strings.HasPrefix(completion, toComplete) && !slices.Contains(paths, completion) {
+			paths = append(paths, completion)
+		}
+	}
+	slices.Sort(paths)
+
+	if slices.ContainsFunc(paths, func(p string) bool { return strings.HasSuffix(p, "/") }) {
+		directive |= cobra.ShellCompDirectiveNoSpace
+	}
+	return append(completions, paths...), directive
+}
+
+// completionNames returns the names of all the parameters below a path, using an on-disk cache when it is fresh.
+func completionNames(ctx context.Context, environment, path string) ([]string, error) {
+	profile := getAWSProfile(environment)
+	region := getAWSRegion(environment)
+	file := cacheFile(profile, region, cacheCompletion, path)
+
+	var names []string
+	// Reuse the names for longer if a longer --cache was asked for.
+	if readCache(file, max(completionCacheTTL, rootOpts.cache), &names) {
+		return names, nil
+	}
+
+	cfg, err := aws.LoadConfig(ctx, &aws.LoginSessionDetails{Profile: profile, Region: region})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errListSSMParameters, err)
+	}
+	names, err = aws.SSMListNames(ctx, aws.SSMClient(cfg, ssmClientOptions()...), path, true)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errListSSMParameters, err)
+	}
+	writeCache(file, names)
+
+	return names, nil
+}
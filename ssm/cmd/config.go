@@ -0,0 +1,127 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"sigs.k8s.io/yaml"
+)
+
+// envPlaceholder is replaced by the name of the environment in the path of an environment in the config file.
+const envPlaceholder = "{env}"
+
+// userConfig is the contents of the optional config file that maps environments to AWS profiles, regions, and paths
+// in the SSM parameter store.
+type userConfig struct {
+	Environments []environmentConfig `json:"environments"`
+}
+
+// environmentConfig is the configuration of an environment in the config file.
+// The Name can end with a '*' to match all environments starting with the rest of the name.
+// Any of the other fields that are empty fall back to the built-in rules for the environment.
+type environmentConfig struct {
+	Name    string `json:"name"`
+	Path    string `json:"path,omitempty"`
+	Profile string `json:"profile,omitempty"`
+	Region  string `json:"region,omitempty"`
+}
+
+// loadUserConfig reads the config file the first time it is called, and returns the same result after that.
+// A missing config file is not an error, and results in an empty config so that the built-in rules are used.
+var loadUserConfig = sync.OnceValues(func() (*userConfig, error) {
+	// Without a config directory there is no config file to read.
+	file, found := configFilePath()
+	if !found {
+		return &userConfig{}, nil
+	}
+
+	return readUserConfig(file)
+})
+
+// readUserConfig reads and validates the config file.
+// A missing config file is not an error, and results in an empty config.
+func readUserConfig(file string) (*userConfig, error) {
+	data, err := os.ReadFile(file)
+	if errors.Is(err, fs.ErrNotExist) {
+		return &userConfig{}, nil
+	}
+	if err != nil {
+		return &userConfig{}, fmt.Errorf("%w: %w", errReadFile, err)
+	}
+
+	var cfg userConfig
+	if err := yaml.UnmarshalStrict(data, &cfg); err != nil {
+		return &userConfig{}, fmt.Errorf("%w: %w", newInvalidConfigError(file), err)
+	}
+	for _, env := range cfg.Environments {
+		if env.Name == "" {
+			return &userConfig{}, fmt.Errorf("%w: %w", newInvalidConfigError(file), errConfigNameRequired)
+		}
+		if env.Path != "" && !strings.HasPrefix(env.Path, "/") {
+			return &userConfig{}, fmt.Errorf("%w: %w", newInvalidConfigError(file), newConfigPathError(env.Name))
+		}
+	}
+
+	return &cfg, nil
+}
+
+// configFilePath returns the location of the config file, or false if there is no config directory to look in.
+// It is `ssm/config.yaml` in the user's config directory (e.g. ~/.config on Linux), unless overridden by the
+// SSM_CONFIG environment variable.
+func configFilePath() (string, bool) {
+	if file := os.Getenv("SSM_CONFIG"); file != "" {
+		return file, true
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", false
+	}
+	return filepath.Join(dir, "ssm", "config.yaml"), true
+}
+
+// getEnvironmentConfig returns the configuration of the first environment in the config file that matches.
+// If none match, or the config file couldn't be loaded, then an empty configuration is returned so that the built-in
+// rules are used. Errors loading the config file are reported when the command starts.
+func getEnvironmentConfig(environment string) environmentConfig {
+	cfg, err := loadUserConfig()
+	if err != nil {
+		return environmentConfig{}
+	}
+
+	return cfg.environment(environment)
+}
+
+// environment returns the configuration of the first environment in the config that matches, or an empty
+// configuration if none match.
+func (cfg *userConfig) environment(environment string) environmentConfig {
+	for _, env := range cfg.Environments {
+		if prefix, found := strings.CutSuffix(env.Name, "*"); found && strings.HasPrefix(environment, prefix) {
+			return env
+		}
+		if env.Name == environment {
+			return env
+		}
+	}
+	return environmentConfig{}
+}
+
+// getConfigPath returns the base path in the SSM parameter store for an environment from the config file,
+// or an empty string if it isn't configured.
+func getConfigPath(environment string) string {
+	path := getEnvironmentConfig(environment).Path
+	if path == "" {
+		return ""
+	}
+	return strings.TrimSuffix(strings.ReplaceAll(path, envPlaceholder, environment), "/")
+}
+
+// validateUserConfig checks that the config file, if there is one, can be loaded.
+func validateUserConfig() error {
+	_, err := loadUserConfig()
+	return err
+}
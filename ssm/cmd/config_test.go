@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestReadUserConfig(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		data     string
+		expected *userConfig
+		err      error
+		wantErr  bool
+	}{
+		{
+			name: "valid",
+			data: `environments:
+  - name: dev
+    path: /helm/minikube
+  - name: prod*
+    path: /app/{env}
+    profile: production
+    region: us-east-1
+`,
+			expected: &userConfig{Environments: []environmentConfig{
+				{Name: "dev", Path: "/helm/minikube"},
+				{Name: "prod*", Path: "/app/{env}", Profile: "production", Region: "us-east-1"},
+			}},
+		},
+		{name: "empty", data: "", expected: &userConfig{}},
+		{name: "unknown field", data: "environments:\n  - name: dev\n    paths: /helm\n", wantErr: true},
+		{name: "not yaml", data: "environments: [", wantErr: true},
+		{
+			name:    "missing name",
+			data:    "environments:\n  - path: /helm\n",
+			err:     errConfigNameRequired,
+			wantErr: true,
+		},
+		{name: "relative path", data: "environments:\n  - name: dev\n    path: helm\n", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			file := filepath.Join(t.TempDir(), "config.yaml")
+			if err := os.WriteFile(file, []byte(tt.data), 0o600); err != nil {
+				t.Fatalf("failed to write the config file: %v", err)
+			}
+
+			cfg, err := readUserConfig(file)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("readUserConfig() failed, expected error %t, got %v", tt.wantErr, err)
+			}
+			if tt.err != nil && !errors.Is(err, tt.err) {
+				t.Errorf("readUserConfig() failed, expected %v, got %v", tt.err, err)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(cfg, tt.expected) {
+				t.Errorf("readUserConfig() failed, expected %+v, got %+v", tt.expected, cfg)
+			}
+		})
+	}
+}
+
+func TestReadUserConfigMissing(t *testing.T) {
+	t.Parallel()
+
+	cfg, err := readUserConfig(filepath.Join(t.TempDir(), "config.yaml"))
+	if err != nil {
+		t.Fatalf("readUserConfig() failed, expected a missing file not to be an error, got %v", err)
+	}
+	if len(cfg.Environments) != 0 {
+		t.Errorf("readUserConfig() failed, expected an empty config, got %+v", cfg)
+	}
+}
+
+func TestUserConfigEnvironment(t *testing.T) {
+	t.Parallel()
+
+	cfg := &userConfig{Environments: []environmentConfig{
+		{Name: "dev", Path: "/helm/minikube"},
+		{Name: "prod1", Profile: "first"},
+		{Name: "prod*", Profile: "production"},
+	}}
+
+	tests := []struct {
+		environment string
+		expected    environmentConfig
+	}{
+		{environment: "dev", expected: environmentConfig{Name: "dev", Path: "/helm/minikube"}},
+		{environment: "prod1", expected: environmentConfig{Name: "prod1", Profile: "first"}},
+		{environment: "prod2", expected: environmentConfig{Name: "prod*", Profile: "production"}},
+		{environment: "test1", expected: environmentConfig{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.environment, func(t *testing.T) {
+			t.Parallel()
+
+			if got := cfg.environment(tt.environment); got != tt.expected {
+				t.Errorf("environment() failed, expected %+v, got %+v", tt.expected, got)
+			}
+		})
+	}
+}
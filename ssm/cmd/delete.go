@@ -2,62 +2,199 @@ package cmd
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
 
 	"github.com/MakeNowJust/heredoc/v2"
+	"github.com/aws/aws-sdk-go-v2/service/ssm/types"
 	"github.com/jim-barber-he/go/aws"
+	"github.com/jim-barber-he/go/util"
 	"github.com/spf13/cobra"
 )
 
+// Commandline options.
+type deleteOptions struct {
+	dryRun    bool
+	recursive bool
+	yes       bool
+}
+
 var deleteLong = heredoc.Doc(`
 	Delete a parameter from the SSM parameter store.
 
-	There is no confirmation, and once deleted you cannot recover.
+	The type, version, and last modification details of the parameter are shown, and you are asked to confirm
+	before it is deleted, since once deleted it cannot be recovered.
+	Pass the --yes flag to skip the confirmation, such as when running from a script.
+
+	If the --recursive flag is used then PARAMETER is treated as a path, and all parameters below it are deleted.
+	The parameters to be deleted are listed along with how many there are before asking for confirmation.
+
+	The --dry-run flag checks that the parameter exists and reports what would be deleted without deleting it.
+	With --recursive it lists the parameters that would be deleted.
 `)
 
-// deleteCmd represents the delete command.
-var deleteCmd = &cobra.Command{
-	Use:   "delete [flags] ENVIRONMENT PARAMETER",
-	Short: "Delete a parameter from the SSM parameter store",
-	Long:  deleteLong,
-	Args:  cobra.ExactArgs(2),
-	PreRunE: func(_ *cobra.Command, args []string) error {
-		return validateEnvironment(args[0])
-	},
-	RunE: func(cmd *cobra.Command, args []string) error {
-		return doDelete(cmd.Context(), args)
-	},
-	SilenceErrors: true,
-	ValidArgsFunction: func(_ *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
-		return deleteCompletionHelp(args)
-	},
-}
+var (
+	// deleteCmd represents the delete command.
+	deleteCmd = &cobra.Command{
+		Use:   "delete [flags] ENVIRONMENT PARAMETER",
+		Short: "Delete a parameter from the SSM parameter store",
+		Long:  deleteLong,
+		Args:  cobra.ExactArgs(2),
+		PreRunE: func(_ *cobra.Command, args []string) error {
+			return validateEnvironment(args[0])
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return doDelete(cmd.Context(), args)
+		},
+		SilenceErrors: true,
+		ValidArgsFunction: func(
+			cmd *cobra.Command, args []string, toComplete string,
+		) ([]string, cobra.ShellCompDirective) {
+			return deleteCompletionHelp(cmd, args, toComplete)
+		},
+	}
+
+	deleteOpts deleteOptions
+)
 
 func init() {
 	rootCmd.AddCommand(deleteCmd)
+
+	deleteCmd.Flags().BoolVar(
+		&deleteOpts.dryRun, "dry-run", false, "Report what would be deleted without deleting it",
+	)
+	deleteCmd.Flags().BoolVarP(
+		&deleteOpts.recursive, "recursive", "r", false, "Delete all parameters below the parameter store path",
+	)
+	deleteCmd.Flags().BoolVarP(&deleteOpts.yes, "yes", "y", false, "Delete without asking for confirmation")
 }
 
 // deleteCompletionHelp provides shell completion help for the delete command.
-func deleteCompletionHelp(args []string) ([]string, cobra.ShellCompDirective) {
+func deleteCompletionHelp(
+	cmd *cobra.Command, args []string, toComplete string,
+) ([]string, cobra.ShellCompDirective) {
 	var completionHelp []string
 	switch {
 	case len(args) == 0:
 		completionHelp = cobra.AppendActiveHelp(completionHelp, "dev, test*, or prod*")
 	case len(args) == 1:
-		completionHelp = cobra.AppendActiveHelp(completionHelp, "The path of the SSM parameter")
+		return completeParameterPath(cmd, args[0], toComplete, "The path of the SSM parameter")
 	default:
 		completionHelp = cobra.AppendActiveHelp(completionHelp, "No more arguments")
 	}
 	return completionHelp, cobra.ShellCompDirectiveNoFileComp
 }
 
+// confirmDelete asks the user to confirm deleting parameters, unless --yes was passed.
+func confirmDelete(prompt string) (bool, error) {
+	if deleteOpts.yes {
+		return true, nil
+	}
+	confirmed, err := util.Confirm(os.Stdin, os.Stdout, prompt)
+	if err != nil {
+		return false, fmt.Errorf("%w: %w", errConfirmDelete, err)
+	}
+	if !confirmed {
+		fmt.Println("Aborted.")
+	}
+	return confirmed, nil
+}
+
 // doDelete deletes a parameter from the SSM parameter store.
 // args[0] is the name of to AWS Profile to use when accessing the SSM parameter store.
 // args[1] is the path of the SSM parameter to delete.
 func doDelete(ctx context.Context, args []string) error {
 	profile := getAWSProfile(args[0])
-	cfg := aws.Login(ctx, &aws.LoginSessionDetails{Profile: profile, Region: rootOpts.region})
-	ssmClient := aws.SSMClient(cfg)
+	region := getAWSRegion(args[0])
+	cfg := aws.Login(ctx, &aws.LoginSessionDetails{Profile: profile, Region: region})
+	ssmClient := aws.SSMClient(cfg, ssmClientOptions()...)
+	// Clear the cached results since they may be out of date once parameters are changed.
+	defer invalidateCache(profile, region)
 
 	param := getSSMPath(args[0], args[1])
-	return aws.SSMDelete(ctx, ssmClient, param)
+	if deleteOpts.recursive {
+		return doDeleteRecursive(ctx, ssmClient, param)
+	}
+	return doDeleteSingle(ctx, ssmClient, param)
+}
+
+// doDeleteRecursive deletes all the parameters below a path in the SSM parameter store after the user confirms it.
+func doDeleteRecursive(ctx context.Context, ssmClient aws.SSMAPI, path string) error {
+	names, err := aws.SSMListNames(ctx, ssmClient, path, true)
+	if err != nil {
+		return fmt.Errorf("%w: %w", errListSSMParameters, err)
+	}
+	if len(names) == 0 {
+		fmt.Printf("No parameters found below %s\n", path)
+		return nil
+	}
+
+	slices.Sort(names)
+	for _, name := range names {
+		fmt.Println(name)
+	}
+	fmt.Println()
+
+	if deleteOpts.dryRun {
+		fmt.Printf("Would delete %d parameters below %s\n", len(names), path)
+		return nil
+	}
+
+	confirmed, err := confirmDelete(fmt.Sprintf("Delete these %d parameters below %s?", len(names), path))
+	if err != nil || !confirmed {
+		return err
+	}
+
+	invalid, err := aws.SSMDeleteParameters(ctx, ssmClient, names)
+	if err != nil {
+		return fmt.Errorf("%w: %w", errDeleteSSMParameters, err)
+	}
+	for _, name := range invalid {
+		fmt.Printf("Parameter %s was not found.\n", name)
+	}
+	fmt.Printf("Deleted %d parameters below %s\n", len(names)-len(invalid), path)
+
+	return nil
+}
+
+// doDeleteSingle shows the details of a parameter and deletes it from the SSM parameter store after the user
+// confirms it.
+func doDeleteSingle(ctx context.Context, ssmClient aws.SSMAPI, param string) error {
+	p, err := aws.SSMGet(ctx, ssmClient, param)
+	if err != nil {
+		var notFound *types.ParameterNotFound
+		if errors.As(err, &notFound) {
+			fmt.Printf("Parameter %s is not found.\n", param)
+			return nil
+		}
+		return fmt.Errorf("%w: %w", errGetSSMParameter, err)
+	}
+
+	fmt.Printf("Name: %s\n", p.Name)
+	fmt.Printf("Type: %s\n", p.Type)
+	fmt.Printf("Version: %d\n", p.Version)
+	fmt.Printf("LastModifiedDate: %s\n", p.LastModifiedDate)
+	if p.LastModifiedUser != "" {
+		fmt.Printf("LastModifiedUser: %s\n", p.LastModifiedUser)
+	}
+	fmt.Println()
+
+	if deleteOpts.dryRun {
+		fmt.Printf("Would delete parameter %s\n", p.Name)
+		return nil
+	}
+
+	confirmed, err := confirmDelete(fmt.Sprintf("Delete parameter %s?", p.Name))
+	if err != nil || !confirmed {
+		return err
+	}
+
+	if err := aws.SSMDelete(ctx, ssmClient, p.Name); err != nil {
+		return fmt.Errorf("%w: %w", errDeleteSSMParameter, err)
+	}
+	fmt.Printf("Deleted parameter %s\n", p.Name)
+
+	return nil
 }
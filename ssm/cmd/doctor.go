@@ -0,0 +1,139 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/MakeNowJust/heredoc/v2"
+	"github.com/aws/aws-sdk-go-v2/service/ssm/types"
+	"github.com/jim-barber-he/go/aws"
+	"github.com/spf13/cobra"
+)
+
+// doctorProbeParameter is the name of the parameter below the base path used to check permissions.
+// It doesn't need to exist, and is never created.
+const doctorProbeParameter = "ssm-doctor-permission-check"
+
+var doctorLong = heredoc.Doc(`
+	Check that everything needed to use an environment is set up, and report the results as a checklist.
+
+	The checks are:
+	- The AWS profile and region that the environment resolves to, and that the AWS config for them can be loaded.
+	- Whether the AWS SSO session is valid. If it isn't, then the usual AWS SSO login is started so that any problems
+	  with it are shown, and the session is checked again afterwards.
+	- Whether the SSM parameters below the base path of the environment can be described, retrieved, and stored.
+
+	Nothing is changed by the checks. Storing is checked with a value that AWS always rejects after checking the
+	permissions.
+
+	The exit code is non-zero if any of the checks failed.
+`)
+
+// doctorCmd represents the doctor command.
+var doctorCmd = &cobra.Command{
+	Use:   "doctor [flags] ENVIRONMENT",
+	Short: "Check the AWS profile, login, and permissions needed to use an environment",
+	Long:  doctorLong,
+	Args:  cobra.ExactArgs(1),
+	PreRunE: func(_ *cobra.Command, args []string) error {
+		return validateEnvironment(args[0])
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return doDoctor(cmd.Context(), args)
+	},
+	SilenceErrors: true,
+	ValidArgsFunction: func(
+		cmd *cobra.Command, args []string, toComplete string,
+	) ([]string, cobra.ShellCompDirective) {
+		return doctorCompletionHelp(cmd, args, toComplete)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(doctorCmd)
+}
+
+// doctorCompletionHelp provides shell completion help for the doctor command.
+func doctorCompletionHelp(
+	_ *cobra.Command, args []string, _ string,
+) ([]string, cobra.ShellCompDirective) {
+	var completionHelp []string
+	if len(args) == 0 {
+		completionHelp = cobra.AppendActiveHelp(completionHelp, "dev, test*, or prod*")
+	} else {
+		completionHelp = cobra.AppendActiveHelp(completionHelp, "No more arguments")
+	}
+	return completionHelp, cobra.ShellCompDirectiveNoFileComp
+}
+
+// doctorChecklist reports the results of the checks and counts how many failed.
+type doctorChecklist struct {
+	failed int
+}
+
+// report displays the result of a check.
+// A nil error means the check passed, and the detail is shown alongside it.
+func (c *doctorChecklist) report(check, detail string, err error) {
+	if err != nil {
+		c.failed++
+		fmt.Printf("[FAIL] %s: %v\n", check, err)
+		return
+	}
+	if detail == "" {
+		fmt.Printf("[ OK ] %s\n", check)
+		return
+	}
+	fmt.Printf("[ OK ] %s: %s\n", check, detail)
+}
+
+// doDoctor checks that the AWS profile, login, and permissions needed to use an environment are set up.
+// args[0] is the name of the environment to check.
+func doDoctor(ctx context.Context, args []string) error {
+	var checklist doctorChecklist
+
+	profile := getAWSProfile(args[0])
+	region := getAWSRegion(args[0])
+	checklist.report("AWS profile", profile, nil)
+
+	cfg, err := aws.LoadConfig(ctx, &aws.LoginSessionDetails{Profile: profile, Region: region})
+	checklist.report("AWS config", "", err)
+	if err != nil {
+		return newDoctorFailedError(checklist.failed)
+	}
+	checklist.report("AWS region", cfg.Region, nil)
+
+	identity, err := aws.CallerIdentity(ctx, cfg)
+	if err != nil {
+		fmt.Printf("The AWS SSO session isn't valid, so starting an AWS SSO login: %v\n", err)
+		cfg = aws.Login(ctx, &aws.LoginSessionDetails{Profile: profile, Region: region})
+		identity, err = aws.CallerIdentity(ctx, cfg)
+	}
+	checklist.report("AWS SSO session", identity, err)
+	if err != nil {
+		return newDoctorFailedError(checklist.failed)
+	}
+
+	ssmClient := aws.SSMClient(cfg, ssmClientOptions()...)
+	path := getSSMPath(args[0], "")
+	probe := strings.TrimSuffix(path, "/") + "/" + doctorProbeParameter
+
+	_, err = aws.SSMListMetadata(ctx, ssmClient, path, false)
+	checklist.report("Describe parameters below "+path, "", err)
+
+	_, err = aws.SSMGet(ctx, ssmClient, probe)
+	var notFound *types.ParameterNotFound
+	if errors.As(err, &notFound) {
+		err = nil
+	}
+	checklist.report("Get parameters below "+path, "", err)
+
+	err = aws.SSMCheckPutAccess(ctx, ssmClient, probe)
+	checklist.report("Put parameters below "+path, "", err)
+
+	if checklist.failed > 0 {
+		return newDoctorFailedError(checklist.failed)
+	}
+	return nil
+}
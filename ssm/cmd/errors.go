@@ -2,19 +2,96 @@ package cmd
 
 import (
 	"errors"
+	"fmt"
+	"strconv"
+	"strings"
 
 	"github.com/jim-barber-he/go/util"
 )
 
 var (
-	errGetSSMParameter   = errors.New("failed to get SSM parameter")
-	errPutSSMParameter   = errors.New("failed to put SSM parameter")
-	errListSSMParameters = errors.New("failed to list SSM parameters")
-	errReadFile          = errors.New("failed to read file")
-	errValueRequired     = errors.New("VALUE is required when --file is not used")
-	errValueWithFile     = errors.New("VALUE should not be provided when --file is used")
+	errCacheStale          = errors.New("a parameter has changed since it was cached")
+	errConfirmDelete       = errors.New("failed to confirm delete")
+	errConfigNameRequired  = errors.New("every environment must have a name")
+	errDecryptEdit         = errors.New("can't edit a parameter that failed to decrypt")
+	errDecryptGet          = errors.New("failed to decrypt the parameter")
+	errDecryptPolicy       = errors.New("can't change the policies of a parameter that failed to decrypt")
+	errDecryptReencrypt    = errors.New("can't re-encrypt a parameter that failed to decrypt")
+	errDecryptSource       = errors.New("can't copy a parameter that failed to decrypt")
+	errDeleteSSMParameter  = errors.New("failed to delete SSM parameter")
+	errDeleteSSMParameters = errors.New("failed to delete SSM parameters")
+	errExpirationRequired  = errors.New("an ExpirationNotification policy requires an Expiration policy")
+	errGenerateValue       = errors.New("failed to generate a random value")
+	errGetSecret           = errors.New("failed to get secret")
+	errGetSSMParameter     = errors.New("failed to get SSM parameter")
+	errMarshalJSON         = errors.New("failed to marshal parameters to JSON")
+	errMarshalYAML         = errors.New("failed to marshal parameters to YAML")
+	errMoveSSMParameter    = errors.New("copied SSM parameter but failed to delete the original")
+	errParamRequired       = errors.New("PARAMETER is required when --params-from-file is not used")
+	errParamsWithFile      = errors.New("PARAMETER should not be provided when --params-from-file is used")
+	errPolicyFileWithFlags = errors.New("--file can't be combined with the other policy options")
+	errPolicyJSON          = errors.New("failed to parse the policies as a JSON array")
+	errPolicyRequired      = errors.New("at least one policy is required")
+	errPutSecret           = errors.New("failed to put secret")
+	errPutSSMParameter     = errors.New("failed to put SSM parameter")
+	errKeyIDRequired       = errors.New("--new-key-id is required")
+	errLabelSSMParameter   = errors.New("failed to label SSM parameter")
+	errListSecrets         = errors.New("failed to list secrets")
+	errListSSMParameters   = errors.New("failed to list SSM parameters")
+	errQuietMultiple       = errors.New("--quiet only gets a single parameter, without --full, --json, or --regions")
+	errReadFile            = errors.New("failed to read file")
+	errReadInput           = errors.New("failed to read input")
+	errStringListNewline   = errors.New("a StringList value may not contain newlines")
+	errValueRequired       = errors.New("VALUE is required when --file is not used")
+	errValueWithFile       = errors.New("VALUE should not be provided when --file is used")
+	errWriteCSV            = errors.New("failed to write CSV")
+	errWriteFile           = errors.New("failed to write file")
 )
 
+// ExitError is an error that sets the exit code of the program.
+// Err is nil when the exit code is all that needs to be reported.
+type ExitError struct {
+	Code int
+	Err  error
+}
+
+// Error implements the Error interface.
+func (e *ExitError) Error() string {
+	if e.Err == nil {
+		return "exit status " + strconv.Itoa(e.Code)
+	}
+	return e.Err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (e *ExitError) Unwrap() error {
+	return e.Err
+}
+
+// newBrowseArgRequiredError creates a new error for when a browse command is missing its argument.
+func newBrowseArgRequiredError(command string) error {
+	return &util.Error{
+		Msg:   "an argument is required for the command: ",
+		Param: command,
+	}
+}
+
+// newBrowsePathNotFoundError creates a new error for when a browse command is given a path with no parameters.
+func newBrowsePathNotFoundError(path string) error {
+	return &util.Error{
+		Msg:   "no parameters found below path: ",
+		Param: path,
+	}
+}
+
+// newBrowseUnknownCommandError creates a new error for when an unknown browse command is entered.
+func newBrowseUnknownCommandError(command string) error {
+	return &util.Error{
+		Msg:   "unknown command (type 'help' for a list of commands): ",
+		Param: command,
+	}
+}
+
 // newBriefAndFullError creates a new error for when the --brief and --full options are both specified.
 func newBriefAndFullError(usage string) error {
 	return &util.Error{
@@ -23,6 +100,71 @@ func newBriefAndFullError(usage string) error {
 	}
 }
 
+// newBriefAndOutputError creates a new error for when the --brief and --output options are both specified.
+func newBriefAndOutputError(usage string) error {
+	return &util.Error{
+		Msg:   "it does not make sense to specify both --brief and --output\n",
+		Param: usage,
+	}
+}
+
+// newConfigPathError creates a new error for when an environment in the config file has a path that isn't absolute.
+func newConfigPathError(env string) error {
+	return &util.Error{
+		Msg:   "the path must start with a slash (/) for environment: ",
+		Param: env,
+	}
+}
+
+// newDecodeBase64Error creates a new error for when the value of a parameter isn't valid base64.
+func newDecodeBase64Error(param string) error {
+	return &util.Error{
+		Msg:   "failed to base64 decode the value of parameter: ",
+		Param: param,
+	}
+}
+
+// newExpectVersionNotFoundError creates a new error for when --expect-version is used for a parameter that doesn't
+// exist.
+func newExpectVersionNotFoundError(param string) error {
+	return &util.Error{
+		Msg:   "can't check the expected version of a parameter that doesn't exist: ",
+		Param: param,
+	}
+}
+
+// newDoctorFailedError creates a new error for when some of the doctor command's checks failed.
+func newDoctorFailedError(failed int) error {
+	return &util.Error{
+		Msg:   "checks failed: ",
+		Param: strconv.Itoa(failed),
+	}
+}
+
+// newInvalidCharsetError creates a new error for when an unknown charset is specified.
+func newInvalidCharsetError(charset string) error {
+	return &util.Error{
+		Msg:   "invalid charset (must be alnum, alpha, hex, numeric, or symbols): ",
+		Param: charset,
+	}
+}
+
+// newInvalidConcurrencyError creates a new error for when a concurrency less than one is specified.
+func newInvalidConcurrencyError(concurrency int) error {
+	return &util.Error{
+		Msg:   "the concurrency must be at least 1: ",
+		Param: strconv.Itoa(concurrency),
+	}
+}
+
+// newInvalidConfigError creates a new error for when the config file is invalid.
+func newInvalidConfigError(file string) error {
+	return &util.Error{
+		Msg:   "invalid config file: ",
+		Param: file,
+	}
+}
+
 // newInvalidEnvError creates a new error for when an invalid environment is specified.
 func newInvalidEnvError(env string) error {
 	return &util.Error{
@@ -30,3 +172,147 @@ func newInvalidEnvError(env string) error {
 		Param: env,
 	}
 }
+
+// newInvalidIntervalError creates a new error for when an interval that isn't positive is specified.
+func newInvalidIntervalError(interval string) error {
+	return &util.Error{
+		Msg:   "the interval must be greater than zero: ",
+		Param: interval,
+	}
+}
+
+// newInvalidLengthError creates a new error for when a length that can't be stored in a parameter is specified.
+func newInvalidLengthError(length int) error {
+	return &util.Error{
+		Msg:   "the length must be between 1 and " + strconv.Itoa(tierStandardMaxBytes) + ": ",
+		Param: strconv.Itoa(length),
+	}
+}
+
+// newInvalidLabelsError creates a new error for when labels don't meet the requirements for a label.
+func newInvalidLabelsError(labels []string) error {
+	return &util.Error{
+		Msg:   "invalid labels: ",
+		Param: strings.Join(labels, ", "),
+	}
+}
+
+// newInvalidOutputError creates a new error for when an invalid output format is specified.
+func newInvalidOutputError(output string) error {
+	return &util.Error{
+		Msg:   "invalid output format (must be table, csv, json, jsonl, or yaml): ",
+		Param: output,
+	}
+}
+
+// newInvalidPolicyError creates a new error for when a parameter policy isn't valid.
+func newInvalidPolicyError(policyType, reason string) error {
+	return &util.Error{
+		Msg:   "invalid parameter policy: ",
+		Param: policyType + ": " + reason,
+	}
+}
+
+// newInvalidTierError creates a new error for when an invalid parameter tier is specified.
+func newInvalidTierError(tier string) error {
+	return &util.Error{
+		Msg:   "invalid parameter tier (must be Standard or Advanced): ",
+		Param: tier,
+	}
+}
+
+// newInvalidTypeError creates a new error for when an invalid parameter type is specified.
+func newInvalidTypeError(paramType string) error {
+	return &util.Error{
+		Msg:   "invalid parameter type (must be String, StringList, or SecureString): ",
+		Param: paramType,
+	}
+}
+
+// newInvalidVersionError creates a new error for when a negative parameter version is specified.
+func newInvalidVersionError(version int64) error {
+	return &util.Error{
+		Msg:   "the version must be greater than zero: ",
+		Param: strconv.FormatInt(version, 10),
+	}
+}
+
+// newParameterExistsError creates a new error for when a parameter that shouldn't exist already does.
+func newParameterExistsError(param string) error {
+	return &util.Error{
+		Msg:   "parameter already exists: ",
+		Param: param,
+	}
+}
+
+// newReencryptFailedError creates a new error for when some of the parameters failed to be re-encrypted.
+func newReencryptFailedError(failed int) error {
+	return &util.Error{
+		Msg:   "failed to re-encrypt parameters: ",
+		Param: strconv.Itoa(failed),
+	}
+}
+
+// newRegionError creates a new error identifying the region that an operation failed in.
+func newRegionError(region string) error {
+	return &util.Error{
+		Msg:   "in region ",
+		Param: region,
+	}
+}
+
+// newRelativeWithoutBriefError creates a new error for when the --relative option is used without --brief.
+func newRelativeWithoutBriefError(usage string) error {
+	return &util.Error{
+		Msg:   "--relative can only be used with --brief\n",
+		Param: usage,
+	}
+}
+
+// newRenderMissingError creates a new error for when parameters referenced by a template can't be read.
+func newRenderMissingError(params []string) error {
+	return &util.Error{
+		Msg:   "parameters not found or failed to decrypt: ",
+		Param: strings.Join(params, ", "),
+	}
+}
+
+// newSecretsManagerCopyError creates a new error for when some parameters or secrets failed to be copied.
+func newSecretsManagerCopyError(failed int) error {
+	return &util.Error{
+		Msg:   "failed to copy: ",
+		Param: strconv.Itoa(failed),
+	}
+}
+
+// newSecureAndTypeError creates a new error for when --secure is used with a --type other than SecureString.
+func newSecureAndTypeError(usage string) error {
+	return &util.Error{
+		Msg:   "--secure can only be combined with --type SecureString\n",
+		Param: usage,
+	}
+}
+
+// newTreeAndFormatError creates a new error for when --tree is combined with options that change the output format.
+func newTreeAndFormatError(usage string) error {
+	return &util.Error{
+		Msg:   "--tree can't be combined with --brief, --full, or --output\n",
+		Param: usage,
+	}
+}
+
+// newValueTooLargeError creates a new error for when a value is too large to store in any parameter tier.
+func newValueTooLargeError(size int) error {
+	return &util.Error{
+		Msg:   "the value is too large to store in the parameter store: ",
+		Param: fmt.Sprintf("%d bytes exceeds the limit of %d bytes", size, tierAdvancedMaxBytes),
+	}
+}
+
+// newVersionMismatchError creates a new error for when a parameter isn't at the version that was expected.
+func newVersionMismatchError(param string, expected, actual int64) error {
+	return &util.Error{
+		Msg:   "the parameter has been changed since the expected version: ",
+		Param: fmt.Sprintf("%s is at version %d instead of %d", param, actual, expected),
+	}
+}
@@ -0,0 +1,185 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/MakeNowJust/heredoc/v2"
+	"github.com/aws/aws-sdk-go-v2/service/ssm/types"
+	"github.com/jim-barber-he/go/aws"
+	"github.com/jim-barber-he/go/util"
+	"github.com/spf13/cobra"
+)
+
+// Characters that generated values can be made from.
+const (
+	charsetDigits = "0123456789"
+	charsetLower  = "abcdefghijklmnopqrstuvwxyz"
+	charsetUpper  = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	// charsetPunctuation only has characters that don't need quoting in shells, URLs, or config files.
+	charsetPunctuation = "-_.~+=@%^"
+)
+
+// generateCharsets maps the names accepted by --charset to the characters they pick from.
+var generateCharsets = map[string]string{
+	"alnum":   charsetLower + charsetUpper + charsetDigits,
+	"alpha":   charsetLower + charsetUpper,
+	"hex":     charsetDigits + "abcdef",
+	"numeric": charsetDigits,
+	"symbols": charsetLower + charsetUpper + charsetDigits + charsetPunctuation,
+}
+
+// Commandline options.
+type generateOptions struct {
+	charset   string
+	keyID     string
+	length    int
+	overwrite bool
+	show      bool
+}
+
+var generateLong = heredoc.Doc(`
+	Generate a cryptographically random value and store it as a SecureString in the SSM parameter store.
+
+	The value is --length characters long, picked from one of the following sets of characters via --charset:
+	'alnum' lower and upper case letters and digits (the default).
+	'alpha' lower and upper case letters.
+	'hex' lower case hexadecimal digits.
+	'numeric' digits.
+	'symbols' the same as 'alnum' plus the punctuation characters -_.~+=@%^ which don't need quoting.
+
+	The value is never shown unless --show is passed, so that secrets can be created without them appearing in the
+	terminal or logs.
+
+	An existing parameter is only replaced if --overwrite is passed.
+
+	By default it will use the alias/parameter_store_key KMS key to encrypt the value, but you can supply a key via
+	--key-id.
+	The key is checked to exist and be enabled before anything is stored.
+`)
+
+var (
+	// generateCmd represents the generate command.
+	generateCmd = &cobra.Command{
+		Use:   "generate [flags] ENVIRONMENT PARAMETER",
+		Short: "Store a random value as a SecureString in the SSM parameter store",
+		Long:  generateLong,
+		Args:  cobra.ExactArgs(2),
+		PreRunE: func(_ *cobra.Command, args []string) error {
+			if err := validateGenerateOptions(); err != nil {
+				return err
+			}
+			return validateEnvironment(args[0])
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return doGenerate(cmd.Context(), args)
+		},
+		SilenceErrors: true,
+		ValidArgsFunction: func(
+			cmd *cobra.Command, args []string, toComplete string,
+		) ([]string, cobra.ShellCompDirective) {
+			return generateCompletionHelp(cmd, args, toComplete)
+		},
+	}
+
+	generateOpts generateOptions
+)
+
+func init() {
+	rootCmd.AddCommand(generateCmd)
+
+	generateCmd.Flags().StringVarP(
+		&generateOpts.charset, "charset", "c", "alnum",
+		"The characters to use: "+strings.Join(slices.Sorted(maps.Keys(generateCharsets)), ", "),
+	)
+	generateCmd.Flags().StringVar(
+		&generateOpts.keyID, "key-id", defaultKeyID, "The ID of the KMS key to encrypt the value",
+	)
+	generateCmd.Flags().IntVarP(&generateOpts.length, "length", "l", 32, "The number of characters to generate")
+	generateCmd.Flags().BoolVar(&generateOpts.overwrite, "overwrite", false, "Replace the parameter if it exists")
+	generateCmd.Flags().BoolVar(&generateOpts.show, "show", false, "Show the generated value")
+}
+
+// generateCompletionHelp provides shell completion help for the generate command.
+func generateCompletionHelp(
+	cmd *cobra.Command, args []string, toComplete string,
+) ([]string, cobra.ShellCompDirective) {
+	var completionHelp []string
+	switch {
+	case len(args) == 0:
+		completionHelp = cobra.AppendActiveHelp(completionHelp, "dev, test*, or prod*")
+	case len(args) == 1:
+		return completeParameterPath(cmd, args[0], toComplete, "The path of the SSM parameter")
+	default:
+		completionHelp = cobra.AppendActiveHelp(completionHelp, "No more arguments")
+	}
+	return completionHelp, cobra.ShellCompDirectiveNoFileComp
+}
+
+// doGenerate stores a random value as a SecureString in the SSM parameter store.
+// args[0] is the name of to AWS Profile to use when accessing the SSM parameter store.
+// args[1] is the path of the SSM parameter to store the value in.
+func doGenerate(ctx context.Context, args []string) error {
+	value, err := util.RandomString(generateOpts.length, generateCharsets[generateOpts.charset])
+	if err != nil {
+		return fmt.Errorf("%w: %w", errGenerateValue, err)
+	}
+
+	profile := getAWSProfile(args[0])
+	region := getAWSRegion(args[0])
+	cfg := aws.Login(ctx, &aws.LoginSessionDetails{Profile: profile, Region: region})
+	ssmClient := aws.SSMClient(cfg, ssmClientOptions()...)
+	// Clear the cached results since they may be out of date once parameters are changed.
+	defer invalidateCache(profile, region)
+
+	param := getSSMPath(args[0], args[1])
+	ssmParam := aws.SSMParameter{
+		KeyID: generateOpts.keyID,
+		Name:  param,
+		Type:  typeSecureString,
+		Value: value,
+	}
+
+	existing, err := aws.SSMGet(ctx, ssmClient, param)
+	if err == nil {
+		if !generateOpts.overwrite {
+			return newParameterExistsError(param)
+		}
+		setPutTier(&ssmParam, existing)
+	} else {
+		var notFound *types.ParameterNotFound
+		if !errors.As(err, &notFound) {
+			return fmt.Errorf("%w: %w", errGetSSMParameter, err)
+		}
+	}
+
+	if err := checkKeyID(ctx, aws.KMSClient(cfg), ssmParam.KeyID); err != nil {
+		return err
+	}
+
+	version, err := aws.SSMPut(ctx, ssmClient, &ssmParam)
+	if err != nil {
+		return fmt.Errorf("%w: %w", errPutSSMParameter, err)
+	}
+	if generateOpts.show {
+		fmt.Printf("Setting %s = %s\n", param, value)
+	}
+	fmt.Printf("Parameter %s updated to version %d\n", param, version)
+
+	return nil
+}
+
+// validateGenerateOptions validates the generate command options.
+func validateGenerateOptions() error {
+	if _, ok := generateCharsets[generateOpts.charset]; !ok {
+		return newInvalidCharsetError(generateOpts.charset)
+	}
+	if generateOpts.length < 1 || generateOpts.length > tierStandardMaxBytes {
+		return newInvalidLengthError(generateOpts.length)
+	}
+	return nil
+}
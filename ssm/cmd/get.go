@@ -1,9 +1,17 @@
 package cmd
 
 import (
+	"bufio"
+	"cmp"
 	"context"
+	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
 
 	"github.com/MakeNowJust/heredoc/v2"
 	"github.com/aws/aws-sdk-go-v2/service/ssm/types"
@@ -13,32 +21,68 @@ import (
 
 // Commandline options.
 type getOptions struct {
-	full bool
+	decodeBase64   bool
+	full           bool
+	json           bool
+	paramsFromFile string
+	quiet          bool
+	regions        []string
 }
 
+// Exit codes used by --quiet so that scripts can tell a missing parameter apart from a failure.
+const (
+	exitFailure  = 2
+	exitNotFound = 1
+)
+
 var getLong = heredoc.Doc(`
 	Retrieve a parameter from the AWS SSM parameter store for a given environment.
 
 	By default it will retrieve just the parameter's value.
-	Passing the --full flag will show all sorts of details about the parameter including its value.
+	Passing the --full flag will show all sorts of details about the parameter including its value and tags.
+
+	Multiple parameters can be retrieved at once by passing more than one PARAMETER, or by passing --params-from-file
+	with a file that has one parameter per line. Blank lines and lines starting with a # in the file are ignored.
+	The parameters are fetched in batches, and each is shown with its name, value, and type, or all of its details
+	if --full is used.
+
+	A version or label of a parameter can be retrieved by adding it to the end of the PARAMETER after a colon, such
+	as PARAMETER:3 for version 3, or PARAMETER:current for the version labelled 'current'.
+
+	Passing the --json flag will output the parameters as a JSON array instead.
+
+	The --decode-base64 flag decodes values that were stored as base64, such as binary certificates, before they are
+	shown. When getting the value of a single parameter, the decoded value is written exactly as it is, without a
+	trailing newline, so that it can be redirected to a file.
+
+	The --quiet flag is for use in scripts. It writes only the value of a single parameter without a trailing newline,
+	and nothing else. The exit code is 0 if the parameter was found, 1 if it wasn't found, and 2 for any other error.
+
+	The --regions flag takes a comma separated list of regions to get the parameters from instead of the one set by
+	--region. Each parameter is shown once per region along with the region it came from.
 `)
 
 var (
 	// getCmd represents the get command.
 	getCmd = &cobra.Command{
-		Use:   "get [flags] ENVIRONMENT PARAMETER",
+		Use:   "get [flags] ENVIRONMENT PARAMETER [PARAMETER...]\n  ssm get [flags] ENVIRONMENT --params-from-file FILE",
 		Short: "Retrieve a parameter from the AWS SSM parameter store",
 		Long:  getLong,
-		Args:  cobra.ExactArgs(2),
+		Args:  cobra.MinimumNArgs(1),
 		PreRunE: func(_ *cobra.Command, args []string) error {
-			return validateEnvironment(args[0])
+			if err := validateGetOptions(args); err != nil {
+				return quietError(err)
+			}
+			return quietError(validateEnvironment(args[0]))
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return doGet(cmd.Context(), args)
+			return quietError(doGet(cmd.Context(), args))
 		},
 		SilenceErrors: true,
-		ValidArgsFunction: func(_ *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
-			return getCompletionHelp(args)
+		ValidArgsFunction: func(
+			cmd *cobra.Command, args []string, toComplete string,
+		) ([]string, cobra.ShellCompDirective) {
+			return getCompletionHelp(cmd, args, toComplete)
 		},
 	}
 
@@ -48,42 +92,88 @@ var (
 func init() {
 	rootCmd.AddCommand(getCmd)
 
+	getCmd.Flags().BoolVar(
+		&getOpts.decodeBase64, "decode-base64", false, "Decode the base64 encoded values before showing them",
+	)
 	getCmd.Flags().BoolVarP(&getOpts.full, "full", "f", false, "Show all details for the parameter")
+	getCmd.Flags().BoolVarP(&getOpts.json, "json", "j", false, "Output the parameters as a JSON array")
+	getCmd.Flags().StringVarP(
+		&getOpts.paramsFromFile, "params-from-file", "p", "", "Read the parameters to get from a file, one per line",
+	)
+	getCmd.Flags().BoolVarP(
+		&getOpts.quiet, "quiet", "q", false, "Only write the value, and exit with 1 if not found or 2 on errors",
+	)
+	getCmd.Flags().StringSliceVar(
+		&getOpts.regions, "regions", nil, "Comma separated list of regions to get the parameters from",
+	)
 }
 
 // getCompletionHelp provides shell completion help for the delete command.
-func getCompletionHelp(args []string) ([]string, cobra.ShellCompDirective) {
+func getCompletionHelp(
+	cmd *cobra.Command, args []string, toComplete string,
+) ([]string, cobra.ShellCompDirective) {
 	var completionHelp []string
 	switch {
 	case len(args) == 0:
 		completionHelp = cobra.AppendActiveHelp(completionHelp, "dev, test*, or prod*")
-	case len(args) == 1:
-		completionHelp = cobra.AppendActiveHelp(completionHelp, "The path of the SSM parameter")
-	default:
+	case getOpts.paramsFromFile != "":
 		completionHelp = cobra.AppendActiveHelp(completionHelp, "No more arguments")
+	default:
+		return completeParameterPath(cmd, args[0], toComplete, "The path of the SSM parameter")
 	}
 	return completionHelp, cobra.ShellCompDirectiveNoFileComp
 }
 
-// doGet fetches a parameter from the SSM parameter store.
+// doGet fetches one or more parameters from the SSM parameter store.
 // args[0] is the name of to AWS Profile to use when accessing the SSM parameter store.
-// args[1] is the path of the SSM parameter to get.
+// args[1:] are the paths of the SSM parameters to get, but only if --params-from-file is not used.
 func doGet(ctx context.Context, args []string) error {
+	names, err := getParameterNames(args)
+	if err != nil {
+		return err
+	}
+
+	if len(names) > 1 || getOpts.json || getOpts.paramsFromFile != "" || len(getOpts.regions) > 0 {
+		return getMultiple(ctx, args[0], names)
+	}
+
 	profile := getAWSProfile(args[0])
-	cfg := aws.Login(ctx, &aws.LoginSessionDetails{Profile: profile, Region: rootOpts.region})
-	ssmClient := aws.SSMClient(cfg)
+	region := getAWSRegion(args[0])
 
 	param := getSSMPath(args[0], args[1])
-	p, err := aws.SSMGet(ctx, ssmClient, param)
+	p, err := getParameter(ctx, profile, region, param)
 	if err != nil {
 		var notFound *types.ParameterNotFound
 		if errors.As(err, &notFound) {
+			if getOpts.quiet {
+				return &ExitError{Code: exitNotFound}
+			}
 			fmt.Printf("Parameter %s is not found.", args[1])
 			return nil
 		}
 		return fmt.Errorf("%w: %w", errGetSSMParameter, err)
 	}
 
+	if getOpts.decodeBase64 {
+		if err := decodeParameterValue(&p); err != nil {
+			return err
+		}
+		if !getOpts.full && !getOpts.quiet {
+			// Write the decoded value as is, since adding a newline would corrupt binary values.
+			_, err := os.Stdout.WriteString(p.Value)
+			return err
+		}
+	}
+
+	if getOpts.quiet {
+		if p.Error != "" {
+			return fmt.Errorf("%w: %s", errDecryptGet, p.Error)
+		}
+		// Write the value as is so that scripts don't need to strip a newline from it.
+		_, err := os.Stdout.WriteString(p.Value)
+		return err
+	}
+
 	if getOpts.full {
 		p.Print()
 	} else {
@@ -92,3 +182,249 @@ func doGet(ctx context.Context, args []string) error {
 
 	return nil
 }
+
+// decodeParameterValue replaces the value of a parameter with its base64 decoded value.
+// Parameters that failed to decrypt are left alone since they have no value to decode.
+func decodeParameterValue(param *aws.SSMParameter) error {
+	if param.Error != "" {
+		return nil
+	}
+	value, err := base64.StdEncoding.DecodeString(param.Value)
+	if err != nil {
+		return fmt.Errorf("%w: %w", newDecodeBase64Error(param.Name), err)
+	}
+	param.Value = string(value)
+	return nil
+}
+
+// describeParameters adds the encryption key ID, last modified user, and tags to each of the parameters for --full.
+// This performs AWS API lookups per parameter, so doesn't scale as well as fetching the parameters does.
+func describeParameters(ctx context.Context, ssmClient aws.SSMAPI, params []aws.SSMParameter) error {
+	for i := range params {
+		p := &params[i]
+		var err error
+		p.KeyID, p.LastModifiedUser, err = aws.SSMDescribeParameter(ctx, ssmClient, p.Name)
+		if err != nil {
+			return err
+		}
+		if p.Tags, err = aws.SSMListTags(ctx, ssmClient, p.Name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// displayGetParameters displays multiple SSM parameters as either text blocks or a JSON array.
+func displayGetParameters(params []aws.SSMParameter) error {
+	if getOpts.json {
+		// Output an empty array rather than null when none of the parameters were found.
+		if params == nil {
+			params = []aws.SSMParameter{}
+		}
+		data, err := json.MarshalIndent(params, "", "  ")
+		if err != nil {
+			return fmt.Errorf("%w: %w", errMarshalJSON, err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	for i, param := range params {
+		if i > 0 {
+			fmt.Println()
+		}
+		if getOpts.full {
+			param.Print()
+			continue
+		}
+		fmt.Printf("Name: %s%s\n", param.Name, param.Selector)
+		if param.Region != "" {
+			fmt.Printf("Region: %s\n", param.Region)
+		}
+		fmt.Printf("Value: %s\n", param.Value)
+		fmt.Printf("Type: %s\n", param.Type)
+		if param.Error != "" {
+			fmt.Printf("Error: %s\n", param.Error)
+		}
+	}
+	return nil
+}
+
+// getMultiple fetches a batch of parameters from the SSM parameter store in each region and displays them.
+// Parameters that are not found are reported on stderr so that they don't interfere with JSON output.
+func getMultiple(ctx context.Context, environment string, names []string) error {
+	profile := getAWSProfile(environment)
+
+	var params []aws.SSMParameter
+	var notFound []string
+	for _, region := range getRegions(environment, getOpts.regions) {
+		regionParams, invalid, err := getParameters(ctx, profile, region, names)
+		if err != nil {
+			return fmt.Errorf("%w: %w: %w", errGetSSMParameter, newRegionError(region), err)
+		}
+		if getOpts.decodeBase64 {
+			for i := range regionParams {
+				if err := decodeParameterValue(&regionParams[i]); err != nil {
+					return err
+				}
+			}
+		}
+		// Only show the region when multiple regions were asked for.
+		if len(getOpts.regions) > 0 {
+			for i := range regionParams {
+				regionParams[i].Region = region
+			}
+		}
+		params = append(params, regionParams...)
+		for _, name := range invalid {
+			if len(getOpts.regions) > 0 {
+				name = fmt.Sprintf("%s in region %s", name, region)
+			}
+			notFound = append(notFound, name)
+		}
+	}
+
+	// Keep the same parameter from each region together so that they can be compared.
+	if len(getOpts.regions) > 0 {
+		slices.SortStableFunc(params, func(a, b aws.SSMParameter) int {
+			return cmp.Compare(slices.Index(names, a.Name+a.Selector), slices.Index(names, b.Name+b.Selector))
+		})
+	}
+
+	if err := displayGetParameters(params); err != nil {
+		return err
+	}
+	for _, name := range notFound {
+		fmt.Fprintf(os.Stderr, "Parameter %s is not found.\n", name)
+	}
+	return nil
+}
+
+// getParameter fetches a parameter from a region, using the cached result when --cache is used.
+func getParameter(ctx context.Context, profile, region, name string) (aws.SSMParameter, error) {
+	file := cacheFile(profile, region, cacheGet, strconv.FormatBool(getOpts.full), name)
+	var p aws.SSMParameter
+	if readResultCache(file, &p) {
+		cached := []aws.SSMParameter{p}
+		if err := restoreSecureStrings(ctx, profile, region, cached); err == nil {
+			return cached[0], nil
+		}
+	}
+
+	cfg := aws.Login(ctx, &aws.LoginSessionDetails{Profile: profile, Region: region})
+	ssmClient := aws.SSMClient(cfg, ssmClientOptions()...)
+	var err error
+	if getOpts.full {
+		p, err = aws.SSMGetFull(ctx, ssmClient, name)
+	} else {
+		p, err = aws.SSMGet(ctx, ssmClient, name)
+	}
+	if err != nil {
+		return aws.SSMParameter{}, err
+	}
+	writeResultCache(file, withoutSecureStrings([]aws.SSMParameter{p})[0])
+
+	return p, nil
+}
+
+// getParameters fetches a batch of parameters from a region, using the cached results when --cache is used.
+// The names of the parameters that weren't found are returned separately.
+func getParameters(ctx context.Context, profile, region string, names []string) ([]aws.SSMParameter, []string, error) {
+	file := cacheFile(profile, region, cacheGet, append([]string{strconv.FormatBool(getOpts.full)}, names...)...)
+	var result struct {
+		Params  []aws.SSMParameter
+		Invalid []string
+	}
+	if readResultCache(file, &result) {
+		if err := restoreSecureStrings(ctx, profile, region, result.Params); err == nil {
+			return result.Params, result.Invalid, nil
+		}
+	}
+
+	cfg := aws.Login(ctx, &aws.LoginSessionDetails{Profile: profile, Region: region})
+	ssmClient := aws.SSMClient(cfg, ssmClientOptions()...)
+	var err error
+	result.Params, result.Invalid, err = aws.SSMGetParameters(ctx, ssmClient, names, true)
+	if err != nil {
+		return nil, nil, err
+	}
+	if getOpts.full {
+		if err := describeParameters(ctx, ssmClient, result.Params); err != nil {
+			return nil, nil, err
+		}
+	}
+	cached := result
+	cached.Params = withoutSecureStrings(result.Params)
+	writeResultCache(file, cached)
+
+	return result.Params, result.Invalid, nil
+}
+
+// getParameterNames returns the unique fully qualified names of the parameters to get, either from the command line
+// arguments or from the file passed via --params-from-file.
+func getParameterNames(args []string) ([]string, error) {
+	params := args[1:]
+	if getOpts.paramsFromFile != "" {
+		if len(params) > 0 {
+			return nil, errParamsWithFile
+		}
+		var err error
+		if params, err = readParamsFile(getOpts.paramsFromFile); err != nil {
+			return nil, err
+		}
+	}
+	if len(params) == 0 {
+		return nil, errParamRequired
+	}
+
+	var names []string
+	for _, param := range params {
+		name := getSSMPath(args[0], param)
+		if !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
+
+// quietError sets the exit code of an error to the one used for failures when --quiet is used.
+// Errors that already have an exit code, such as a parameter not being found, are returned as is.
+func quietError(err error) error {
+	var exitErr *ExitError
+	if err == nil || !getOpts.quiet || errors.As(err, &exitErr) {
+		return err
+	}
+	return &ExitError{Code: exitFailure, Err: err}
+}
+
+// readParamsFile reads parameter names from a file, one per line, ignoring blank lines and comments.
+func readParamsFile(file string) ([]string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errReadFile, err)
+	}
+	defer f.Close()
+
+	var params []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		params = append(params, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %w", errReadFile, err)
+	}
+	return params, nil
+}
+
+// validateGetOptions validates the get command options.
+func validateGetOptions(args []string) error {
+	if getOpts.quiet && (len(args) != 2 || getOpts.full || getOpts.json || getOpts.paramsFromFile != "" ||
+		len(getOpts.regions) > 0) {
+		return errQuietMultiple
+	}
+	return nil
+}
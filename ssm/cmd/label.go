@@ -0,0 +1,108 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/MakeNowJust/heredoc/v2"
+	"github.com/jim-barber-he/go/aws"
+	"github.com/spf13/cobra"
+)
+
+// Commandline options.
+type labelOptions struct {
+	version int64
+}
+
+var labelLong = heredoc.Doc(`
+	Attach one or more labels to a version of a parameter in the SSM parameter store.
+
+	By default the labels are attached to the latest version of the parameter, or to the version passed via
+	--version. A label can only be attached to one version of a parameter at a time, so a label that is already
+	attached to another version is moved.
+
+	Labels are case-sensitive, may contain letters, numbers, periods, hyphens, and underscores, and can't start with
+	a number, 'aws', or 'ssm'.
+
+	The value of a labelled version can then be retrieved via the get command with PARAMETER:LABEL.
+`)
+
+var (
+	// labelCmd represents the label command.
+	labelCmd = &cobra.Command{
+		Use:   "label [flags] ENVIRONMENT PARAMETER LABEL [LABEL...]",
+		Short: "Attach labels to a version of a parameter in the SSM parameter store",
+		Long:  labelLong,
+		Args:  cobra.MinimumNArgs(3),
+		PreRunE: func(_ *cobra.Command, args []string) error {
+			return validateEnvironment(args[0])
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return doLabel(cmd.Context(), args)
+		},
+		SilenceErrors: true,
+		ValidArgsFunction: func(
+			cmd *cobra.Command, args []string, toComplete string,
+		) ([]string, cobra.ShellCompDirective) {
+			return labelCompletionHelp(cmd, args, toComplete)
+		},
+	}
+
+	labelOpts labelOptions
+)
+
+func init() {
+	rootCmd.AddCommand(labelCmd)
+
+	labelCmd.Flags().Int64VarP(
+		&labelOpts.version, "version", "V", 0, "The version of the parameter to label (default the latest version)",
+	)
+}
+
+// labelCompletionHelp provides shell completion help for the label command.
+func labelCompletionHelp(
+	cmd *cobra.Command, args []string, toComplete string,
+) ([]string, cobra.ShellCompDirective) {
+	var completionHelp []string
+	switch {
+	case len(args) == 0:
+		completionHelp = cobra.AppendActiveHelp(completionHelp, "dev, test*, or prod*")
+	case len(args) == 1:
+		return completeParameterPath(cmd, args[0], toComplete, "The path of the SSM parameter")
+	default:
+		completionHelp = cobra.AppendActiveHelp(completionHelp, "The label to attach")
+	}
+	return completionHelp, cobra.ShellCompDirectiveNoFileComp
+}
+
+// doLabel attaches labels to a version of a parameter in the SSM parameter store.
+// args[0] is the name of to AWS Profile to use when accessing the SSM parameter store.
+// args[1] is the path of the SSM parameter to label.
+// args[2:] are the labels to attach.
+func doLabel(ctx context.Context, args []string) error {
+	if labelOpts.version < 0 {
+		return newInvalidVersionError(labelOpts.version)
+	}
+
+	profile := getAWSProfile(args[0])
+	region := getAWSRegion(args[0])
+	cfg := aws.Login(ctx, &aws.LoginSessionDetails{Profile: profile, Region: region})
+	ssmClient := aws.SSMClient(cfg, ssmClientOptions()...)
+	// Clear the cached results since they may be out of date once parameters are changed.
+	defer invalidateCache(profile, region)
+
+	param := getSSMPath(args[0], args[1])
+	labels := args[2:]
+
+	version, invalid, err := aws.SSMLabel(ctx, ssmClient, param, labelOpts.version, labels)
+	if err != nil {
+		return fmt.Errorf("%w: %w", errLabelSSMParameter, err)
+	}
+	if len(invalid) > 0 {
+		return newInvalidLabelsError(invalid)
+	}
+	fmt.Printf("Labelled version %d of %s with %s\n", version, param, strings.Join(labels, ", "))
+
+	return nil
+}
@@ -3,21 +3,68 @@ package cmd
 import (
 	"cmp"
 	"context"
+	"encoding/csv"
+	"encoding/json"
 	"fmt"
+	"io"
+	"maps"
+	"os"
 	"slices"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/MakeNowJust/heredoc/v2"
-	"github.com/aws/aws-sdk-go-v2/service/ssm"
 	"github.com/jim-barber-he/go/aws"
+	"github.com/jim-barber-he/go/texttable"
 	"github.com/spf13/cobra"
+	"sigs.k8s.io/yaml"
+)
+
+// Output formats for the list command.
+const (
+	outputCSV   = "csv"
+	outputJSON  = "json"
+	outputJSONL = "jsonl"
+	outputTable = "table"
+	outputYAML  = "yaml"
 )
 
 // Commandline options.
 type listOptions struct {
 	brief       bool
 	full        bool
+	output      string
+	paramType   string
 	recursive   bool
+	regions     []string
+	relative    bool
 	safeDecrypt bool
+	tier        string
+	tree        bool
+}
+
+// listTreeNode is a level of the parameter hierarchy displayed by --tree.
+// A node can be both a parameter and a folder, since a parameter can have the same name as a path to others.
+type listTreeNode struct {
+	children map[string]*listTreeNode
+	count    int
+	regions  []string
+	isParam  bool
+}
+
+// listTableRow is a row of the table displayed by `--output table`.
+// The Region column is only shown when --regions is used, and the KeyID and ModifiedBy columns are only shown when
+// --full is used.
+type listTableRow struct {
+	Name       string `title:"NAME"`
+	Region     string `title:"REGION,omitempty"`
+	Type       string `title:"TYPE"`
+	Version    string `title:"VERSION"`
+	Modified   string `title:"MODIFIED"`
+	ModifiedBy string `title:"MODIFIED-BY,omitempty"`
+	KeyID      string `title:"KEY-ID,omitempty"`
+	Value      string `title:"VALUE"`
 }
 
 var listLong = heredoc.Doc(`
@@ -29,9 +76,35 @@ var listLong = heredoc.Doc(`
 
 	If the --full flag is specified, then more details about each parameter will be shown.
 
+	The --output flag selects another output format:
+	'table' shows a column for each of the name, type, version, modified date, and value of the parameters.
+	'csv' outputs the same columns as comma separated values suitable for importing into a spreadsheet.
+	'yaml' outputs all the fields of the parameters as a YAML list.
+	'json' outputs all the fields of the parameters as a JSON array, suitable for jq.
+	'jsonl' outputs all the fields of each parameter as a JSON object on its own line (JSON Lines), suitable for
+	streaming into other tools.
+	The --full flag adds the last modified user and encryption key ID to each of these.
+
+	The --regions flag takes a comma separated list of regions to list the parameters from instead of the one set by
+	--region. The region of each parameter is shown, and the same parameter from each region is listed together so
+	that differences between the regions are easy to spot.
+
 	If no PATH is passed at all, then for the 'dev', 'test*', and 'prod*' environments it will look in
 	'/helm/minikube/', '/helm/test*/', or '/helm/prod*/' respectively.
 
+	The --relative flag can be used with --brief to remove the path being listed from the start of the parameter
+	names, such as showing 'DB_PASSWORD = x' instead of '/helm/prod/app/DB_PASSWORD = x', so that the output can be
+	used as an env file.
+
+	The --type and --tier flags only list the parameters of the given type (String, StringList, or SecureString) or
+	tier (Standard or Advanced). For example '--type String' finds plaintext values that may need to be SecureStrings.
+	Filtering by tier needs an extra AWS API call per page of parameters to look up their tiers.
+
+	The --tree flag shows the names of all the parameters below the path as an indented tree, where each folder is
+	followed by the number of parameters below it. It always lists recursively, and doesn't fetch the values, so it
+	works even for SecureStrings that can't be decrypted. When used with --regions, the regions that each parameter
+	exists in are shown after its name.
+
 	The --safe-decrypt flag is slower, but can handle if you have SecureStrings in your SSM parameter store that
 	can't be decrypted due to their KMS key being inaccessible or deleted.
 `)
@@ -54,8 +127,10 @@ var (
 			return doList(cmd.Context(), args)
 		},
 		SilenceErrors: true,
-		ValidArgsFunction: func(_ *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
-			return listCompletionHelp(args)
+		ValidArgsFunction: func(
+			cmd *cobra.Command, args []string, toComplete string,
+		) ([]string, cobra.ShellCompDirective) {
+			return listCompletionHelp(cmd, args, toComplete)
 		},
 	}
 
@@ -67,20 +142,34 @@ func init() {
 
 	listCmd.Flags().BoolVarP(&listOpts.brief, "brief", "b", false, "Show parameter = value output")
 	listCmd.Flags().BoolVarP(&listOpts.full, "full", "f", false, "Show additional details for each parameter")
+	listCmd.Flags().StringVarP(&listOpts.output, "output", "o", "", "Output format: table, csv, json, jsonl, or yaml")
 	listCmd.Flags().BoolVarP(
 		&listOpts.recursive, "recursive", "r", false, "Recursively list parameters below the parameter store path",
 	)
+	listCmd.Flags().BoolVar(
+		&listOpts.relative, "relative", false, "Remove the path being listed from the names in --brief output",
+	)
+	listCmd.Flags().StringSliceVar(
+		&listOpts.regions, "regions", nil, "Comma separated list of regions to list the parameters from",
+	)
 	listCmd.Flags().BoolVarP(&listOpts.safeDecrypt, "safe-decrypt", "s", false, "Slower decrypt that can handle errors")
+	listCmd.Flags().StringVar(&listOpts.tier, "tier", "", "Only list parameters in this tier: Standard or Advanced")
+	listCmd.Flags().BoolVarP(&listOpts.tree, "tree", "t", false, "Show the parameter names as a tree")
+	listCmd.Flags().StringVar(
+		&listOpts.paramType, "type", "", "Only list parameters of this type: String, StringList, or SecureString",
+	)
 }
 
 // listCompletionHelp provides shell completion help for the delete command.
-func listCompletionHelp(args []string) ([]string, cobra.ShellCompDirective) {
+func listCompletionHelp(
+	cmd *cobra.Command, args []string, toComplete string,
+) ([]string, cobra.ShellCompDirective) {
 	var completionHelp []string
 	switch {
 	case len(args) == 0:
 		completionHelp = cobra.AppendActiveHelp(completionHelp, "dev, test*, or prod*")
 	case len(args) == 1:
-		completionHelp = cobra.AppendActiveHelp(completionHelp, "The path in the SSM parameter store to list")
+		return completeParameterPath(cmd, args[0], toComplete, "The path in the SSM parameter store to list")
 	default:
 		completionHelp = cobra.AppendActiveHelp(completionHelp, "No more arguments")
 	}
@@ -92,6 +181,24 @@ func validateListOptions(cmd *cobra.Command) error {
 	if listOpts.brief && listOpts.full {
 		return newBriefAndFullError(cmd.UsageString())
 	}
+	if listOpts.output != "" && listOpts.brief {
+		return newBriefAndOutputError(cmd.UsageString())
+	}
+	if listOpts.relative && !listOpts.brief {
+		return newRelativeWithoutBriefError(cmd.UsageString())
+	}
+	if listOpts.tree && (listOpts.brief || listOpts.full || listOpts.output != "") {
+		return newTreeAndFormatError(cmd.UsageString())
+	}
+	if !slices.Contains([]string{"", outputCSV, outputJSON, outputJSONL, outputTable, outputYAML}, listOpts.output) {
+		return newInvalidOutputError(listOpts.output)
+	}
+	if !slices.Contains([]string{"", typeSecureString, typeString, typeStringList}, listOpts.paramType) {
+		return newInvalidTypeError(listOpts.paramType)
+	}
+	if !slices.Contains([]string{"", tierAdvanced, tierStandard}, listOpts.tier) {
+		return newInvalidTierError(listOpts.tier)
+	}
 	return nil
 }
 
@@ -100,8 +207,6 @@ func validateListOptions(cmd *cobra.Command) error {
 // args[1] is the path of the SSM parameter to list.
 func doList(ctx context.Context, args []string) error {
 	profile := getAWSProfile(args[0])
-	cfg := aws.Login(ctx, &aws.LoginSessionDetails{Profile: profile, Region: rootOpts.region})
-	ssmClient := aws.SSMClient(cfg)
 
 	var path string
 	if len(args) > 1 {
@@ -110,32 +215,158 @@ func doList(ctx context.Context, args []string) error {
 		path = getSSMPath(args[0], "")
 	}
 
-	params, err := listParameters(ctx, ssmClient, path)
-	if err != nil {
-		return fmt.Errorf("%w: %w", errListSSMParameters, err)
+	if listOpts.tree {
+		return doListTree(ctx, args[0], path)
 	}
 
-	displayListParameters(params)
-
-	return nil
-}
+	var params []aws.SSMParameter
+	for _, region := range getRegions(args[0], listOpts.regions) {
+		regionParams, err := listRegion(ctx, profile, region, path)
+		if err != nil {
+			return fmt.Errorf("%w: %w: %w", errListSSMParameters, newRegionError(region), err)
+		}
+		// Only show the region when multiple regions were asked for.
+		if len(listOpts.regions) > 0 {
+			for i := range regionParams {
+				regionParams[i].Region = region
+			}
+		}
+		params = append(params, regionParams...)
+	}
 
-// displayListParameters displays the list of SSM parameters formatted according to the command line flags.
-func displayListParameters(params []aws.SSMParameter) {
 	// Sort function to sort the parameters by Name when iterating through them.
-	slices.SortFunc(params, func(a, b aws.SSMParameter) int {
+	// The same parameter from multiple regions are kept in the order of the regions so that they can be compared.
+	slices.SortStableFunc(params, func(a, b aws.SSMParameter) int {
 		return cmp.Compare(a.Name, b.Name)
 	})
 
+	switch listOpts.output {
+	case outputCSV:
+		return writeListCSV(params)
+	case outputJSON:
+		return writeListJSON(params)
+	case outputJSONL:
+		return writeListJSONL(params)
+	case outputTable:
+		writeListTable(params)
+	case outputYAML:
+		return writeListYAML(params)
+	default:
+		displayListParameters(params, path)
+	}
+
+	return nil
+}
+
+// doListTree displays the names of all the parameters below the path, in each of the regions, as a tree.
+func doListTree(ctx context.Context, environment, path string) error {
+	profile := getAWSProfile(environment)
+
+	root := &listTreeNode{}
+	prefix := strings.TrimSuffix(path, "/") + "/"
+	for _, region := range getRegions(environment, listOpts.regions) {
+		cfg := aws.Login(ctx, &aws.LoginSessionDetails{Profile: profile, Region: region})
+		ssmClient := aws.SSMClient(cfg, ssmClientOptions()...)
+
+		params, err := aws.SSMListMetadata(ctx, ssmClient, path, true)
+		if err != nil {
+			return fmt.Errorf("%w: %w: %w", errListSSMParameters, newRegionError(region), err)
+		}
+		// Only show the regions when multiple regions were asked for.
+		if len(listOpts.regions) == 0 {
+			region = ""
+		}
+		for _, p := range params {
+			if listFilterMatch(&p) {
+				root.add(strings.Split(strings.TrimPrefix(p.Name, prefix), "/"), region)
+			}
+		}
+	}
+
+	fmt.Printf("%s (%d)\n", prefix, root.count)
+	root.write(os.Stdout, "  ")
+
+	return nil
+}
+
+// add adds a parameter to the tree below the node, where parts are the segments of its name relative to the node.
+// It returns true if the parameter wasn't already in the tree, such as from another region.
+func (n *listTreeNode) add(parts []string, region string) bool {
+	if n.children == nil {
+		n.children = make(map[string]*listTreeNode)
+	}
+	child, found := n.children[parts[0]]
+	if !found {
+		child = &listTreeNode{}
+		n.children[parts[0]] = child
+	}
+
+	var added bool
+	if len(parts) == 1 {
+		added = !child.isParam
+		child.isParam = true
+		if region != "" {
+			child.regions = append(child.regions, region)
+		}
+	} else {
+		added = child.add(parts[1:], region)
+	}
+	if added {
+		n.count++
+	}
+	return added
+}
+
+// write writes the children of the node to w sorted by name, with each level indented further than the last.
+func (n *listTreeNode) write(w io.Writer, indent string) {
+	for _, name := range slices.Sorted(maps.Keys(n.children)) {
+		child := n.children[name]
+		if child.isParam {
+			if len(child.regions) > 0 {
+				fmt.Fprintf(w, "%s%s [%s]\n", indent, name, strings.Join(child.regions, ", "))
+			} else {
+				fmt.Fprintf(w, "%s%s\n", indent, name)
+			}
+		}
+		if len(child.children) > 0 {
+			fmt.Fprintf(w, "%s%s/ (%d)\n", indent, name, child.count)
+			child.write(w, indent+"  ")
+		}
+	}
+}
+
+// TabTitleRow implements the texttable.TableFormatter interface.
+func (tr *listTableRow) TabTitleRow() string {
+	return texttable.ReflectedTitleRow(tr)
+}
+
+// TabValues implements the texttable.TableFormatter interface.
+func (tr *listTableRow) TabValues() string {
+	return texttable.ReflectedTabValues(tr)
+}
+
+// displayListParameters displays the sorted list of SSM parameters formatted according to the command line flags.
+// The path being listed is removed from the start of the names in brief output when --relative is used.
+func displayListParameters(params []aws.SSMParameter, path string) {
+	prefix := strings.TrimSuffix(path, "/") + "/"
 	numParams := len(params) - 1
 	for i, param := range params {
+		name := param.Name
+		if listOpts.relative {
+			name = strings.TrimPrefix(name, prefix)
+		}
 		switch {
+		case listOpts.brief && param.Region != "":
+			fmt.Printf("[%s] %s = %s\n", param.Region, name, param.Value)
 		case listOpts.brief:
-			fmt.Printf("%s = %s\n", param.Name, param.Value)
+			fmt.Printf("%s = %s\n", name, param.Value)
 		case listOpts.full:
 			param.Print()
 		default:
 			fmt.Printf("Name: %s\n", param.Name)
+			if param.Region != "" {
+				fmt.Printf("Region: %s\n", param.Region)
+			}
 			fmt.Printf("Value: %s\n", param.Value)
 			fmt.Printf("Type: %s\n", param.Type)
 			if param.Error != "" {
@@ -148,10 +379,182 @@ func displayListParameters(params []aws.SSMParameter) {
 	}
 }
 
+// filterListParameters removes the parameters that don't match the --type and --tier flags.
+// Since the tiers of the parameters aren't returned when listing their values, they are looked up separately when
+// filtering by tier.
+func filterListParameters(
+	ctx context.Context, ssmClient aws.SSMAPI, path string, params []aws.SSMParameter,
+) ([]aws.SSMParameter, error) {
+	if listOpts.tier != "" {
+		metadata, err := aws.SSMListMetadata(ctx, ssmClient, path, listOpts.recursive)
+		if err != nil {
+			return nil, err
+		}
+		tiers := make(map[string]string, len(metadata))
+		for _, p := range metadata {
+			tiers[p.Name] = p.Tier
+		}
+		for i := range params {
+			params[i].Tier = tiers[params[i].Name]
+		}
+	}
+
+	return slices.DeleteFunc(params, func(p aws.SSMParameter) bool {
+		return !listFilterMatch(&p)
+	}), nil
+}
+
+// listFilterMatch returns true if the parameter matches the --type and --tier flags.
+func listFilterMatch(param *aws.SSMParameter) bool {
+	if listOpts.paramType != "" && param.Type != listOpts.paramType {
+		return false
+	}
+	if listOpts.tier != "" && param.Tier != listOpts.tier {
+		return false
+	}
+	return true
+}
+
+// listRegion fetches the parameters below the path in a region that match the filters, using cached results when
+// --cache is used.
+func listRegion(ctx context.Context, profile, region, path string) ([]aws.SSMParameter, error) {
+	file := cacheFile(
+		profile, region, cacheList, path, strconv.FormatBool(listOpts.recursive), strconv.FormatBool(listOpts.full),
+		strconv.FormatBool(listOpts.safeDecrypt), listOpts.paramType, listOpts.tier,
+	)
+	var params []aws.SSMParameter
+	if readResultCache(file, &params) {
+		if err := restoreSecureStrings(ctx, profile, region, params); err == nil {
+			return params, nil
+		}
+	}
+
+	cfg := aws.Login(ctx, &aws.LoginSessionDetails{Profile: profile, Region: region})
+	ssmClient := aws.SSMClient(cfg, ssmClientOptions()...)
+
+	params, err := listParameters(ctx, ssmClient, path)
+	if err != nil {
+		return nil, err
+	}
+	params, err = filterListParameters(ctx, ssmClient, path, params)
+	if err != nil {
+		return nil, err
+	}
+	writeResultCache(file, withoutSecureStrings(params))
+
+	return params, nil
+}
+
 // listParameters fetches the SSM parameters handling how decryption is performed based on the safeDecrypt flag.
-func listParameters(ctx context.Context, ssmClient *ssm.Client, path string) ([]aws.SSMParameter, error) {
+func listParameters(ctx context.Context, ssmClient aws.SSMAPI, path string) ([]aws.SSMParameter, error) {
 	if listOpts.safeDecrypt {
 		return aws.SSMListSafeDecrypt(ctx, ssmClient, path, listOpts.recursive, listOpts.full)
 	}
 	return aws.SSMList(ctx, ssmClient, path, listOpts.recursive, listOpts.full)
 }
+
+// listModified returns the last modified date of a parameter in the format used by the table and CSV output.
+func listModified(param *aws.SSMParameter) string {
+	return param.LastModifiedDate.Local().Format(time.DateTime)
+}
+
+// writeListCSV writes the parameters as CSV with a header row.
+func writeListCSV(params []aws.SSMParameter) error {
+	header := []string{"name", "type", "version", "modified", "value"}
+	if listOpts.full {
+		header = []string{"name", "type", "version", "modified", "modified_by", "key_id", "value"}
+	}
+	if len(listOpts.regions) > 0 {
+		header = slices.Insert(header, 1, "region")
+	}
+
+	w := csv.NewWriter(os.Stdout)
+	if err := w.Write(header); err != nil {
+		return fmt.Errorf("%w: %w", errWriteCSV, err)
+	}
+	for i := range params {
+		p := &params[i]
+		record := []string{p.Name, p.Type, strconv.FormatInt(p.Version, 10), listModified(p), p.Value}
+		if listOpts.full {
+			record = []string{
+				p.Name, p.Type, strconv.FormatInt(p.Version, 10), listModified(p), p.LastModifiedUser, p.KeyID, p.Value,
+			}
+		}
+		if len(listOpts.regions) > 0 {
+			record = slices.Insert(record, 1, p.Region)
+		}
+		if err := w.Write(record); err != nil {
+			return fmt.Errorf("%w: %w", errWriteCSV, err)
+		}
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return fmt.Errorf("%w: %w", errWriteCSV, err)
+	}
+	return nil
+}
+
+// writeListJSON writes the parameters as a JSON array.
+func writeListJSON(params []aws.SSMParameter) error {
+	// Output an empty array rather than null when there are no parameters.
+	if params == nil {
+		params = []aws.SSMParameter{}
+	}
+	data, err := json.MarshalIndent(params, "", "  ")
+	if err != nil {
+		return fmt.Errorf("%w: %w", errMarshalJSON, err)
+	}
+	fmt.Println(string(data))
+	return nil
+}
+
+// writeListJSONL writes each parameter as a JSON object on its own line.
+func writeListJSONL(params []aws.SSMParameter) error {
+	enc := json.NewEncoder(os.Stdout)
+	for _, p := range params {
+		if err := enc.Encode(p); err != nil {
+			return fmt.Errorf("%w: %w", errMarshalJSON, err)
+		}
+	}
+	return nil
+}
+
+// writeListTable writes the parameters as a table.
+// Since empty cells would misalign the table they are shown as a '-', and values have any tabs and newlines escaped
+// so that each parameter stays on a single line.
+func writeListTable(params []aws.SSMParameter) {
+	escaper := strings.NewReplacer("\t", `\t`, "\n", `\n`, "\r", `\r`)
+
+	tbl := texttable.Table[*listTableRow]{}
+	for i := range params {
+		p := &params[i]
+		row := listTableRow{
+			Name:     p.Name,
+			Region:   p.Region,
+			Type:     p.Type,
+			Version:  strconv.FormatInt(p.Version, 10),
+			Modified: listModified(p),
+			Value:    cmp.Or(escaper.Replace(p.Value), "-"),
+		}
+		if listOpts.full {
+			row.ModifiedBy = cmp.Or(p.LastModifiedUser, "-")
+			row.KeyID = cmp.Or(p.KeyID, "-")
+		}
+		tbl.Append(&row)
+	}
+	tbl.Write()
+}
+
+// writeListYAML writes the parameters as a YAML list.
+func writeListYAML(params []aws.SSMParameter) error {
+	// Output an empty list rather than null when there are no parameters.
+	if params == nil {
+		params = []aws.SSMParameter{}
+	}
+	data, err := yaml.Marshal(params)
+	if err != nil {
+		return fmt.Errorf("%w: %w", errMarshalYAML, err)
+	}
+	fmt.Print(string(data))
+	return nil
+}
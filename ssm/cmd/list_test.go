@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestListTree(t *testing.T) {
+	t.Parallel()
+
+	type param struct {
+		name   string
+		region string
+	}
+
+	tests := []struct {
+		name     string
+		params   []param
+		count    int
+		expected string
+	}{
+		{
+			name:     "empty",
+			params:   nil,
+			count:    0,
+			expected: "",
+		},
+		{
+			name:   "nested",
+			params: []param{{name: "api/db/host"}, {name: "api/db/port"}, {name: "web/url"}, {name: "api/key"}},
+			count:  4,
+			expected: `  api/ (3)
+    db/ (2)
+      host
+      port
+    key
+  web/ (1)
+    url
+`,
+		},
+		{
+			name:   "parameter that is also a path",
+			params: []param{{name: "api"}, {name: "api/key"}},
+			count:  2,
+			expected: `  api
+  api/ (1)
+    key
+`,
+		},
+		{
+			name: "the same parameter in several regions",
+			params: []param{
+				{name: "api/key", region: "ap-southeast-2"},
+				{name: "api/url", region: "ap-southeast-2"},
+				{name: "api/key", region: "us-east-1"},
+			},
+			count: 2,
+			expected: `  api/ (2)
+    key [ap-southeast-2, us-east-1]
+    url [ap-southeast-2]
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			root := &listTreeNode{}
+			for _, p := range tt.params {
+				root.add(strings.Split(p.name, "/"), p.region)
+			}
+			if root.count != tt.count {
+				t.Errorf("add() failed, expected a count of %d, got %d", tt.count, root.count)
+			}
+
+			var buf bytes.Buffer
+			root.write(&buf, "  ")
+			if buf.String() != tt.expected {
+				t.Errorf("write() failed, expected:\n%s\ngot:\n%s", tt.expected, buf.String())
+			}
+		})
+	}
+}
+
+func TestListTreeAdd(t *testing.T) {
+	t.Parallel()
+
+	root := &listTreeNode{}
+	if !root.add([]string{"api", "key"}, "ap-southeast-2") {
+		t.Error("add() failed, expected a new parameter to be added")
+	}
+	if root.add([]string{"api", "key"}, "us-east-1") {
+		t.Error("add() failed, expected the parameter from another region not to be added again")
+	}
+}
@@ -0,0 +1,156 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/MakeNowJust/heredoc/v2"
+	"github.com/aws/aws-sdk-go-v2/service/ssm/types"
+	"github.com/jim-barber-he/go/aws"
+	"github.com/spf13/cobra"
+)
+
+// Commandline options.
+type moveOptions struct {
+	keepSource   bool
+	targetRegion string
+}
+
+var moveLong = heredoc.Doc(`
+	Move (rename) a parameter in the SSM parameter store to a new path.
+
+	The parameter is copied to the new path, preserving its type, encryption key, description, and tier, and then
+	the original parameter is deleted.
+	Passing the --keep-source flag will just copy the parameter, leaving the original in place.
+
+	The new path must not already exist.
+	Parameters that can't be decrypted can't be moved since their value can't be read.
+
+	The --target-region flag moves or copies the parameter to another region, in which case NEW_PARAMETER can be the
+	same as OLD_PARAMETER. Since KMS keys belong to a single region, a SecureString encrypted with a key alias uses
+	the key with the same alias in the target region, while one encrypted with a specific key ID uses the
+	'` + defaultKeyID + `' key instead.
+`)
+
+var (
+	// moveCmd represents the move command.
+	moveCmd = &cobra.Command{
+		Use:   "move [flags] ENVIRONMENT OLD_PARAMETER NEW_PARAMETER",
+		Short: "Move or rename a parameter in the SSM parameter store",
+		Long:  moveLong,
+		Args:  cobra.ExactArgs(3),
+		PreRunE: func(_ *cobra.Command, args []string) error {
+			return validateEnvironment(args[0])
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return doMove(cmd.Context(), args)
+		},
+		SilenceErrors: true,
+		ValidArgsFunction: func(
+			cmd *cobra.Command, args []string, toComplete string,
+		) ([]string, cobra.ShellCompDirective) {
+			return moveCompletionHelp(cmd, args, toComplete)
+		},
+	}
+
+	moveOpts moveOptions
+)
+
+func init() {
+	rootCmd.AddCommand(moveCmd)
+
+	moveCmd.Flags().BoolVarP(
+		&moveOpts.keepSource, "keep-source", "k", false, "Copy the parameter instead of moving it",
+	)
+	moveCmd.Flags().StringVar(
+		&moveOpts.targetRegion, "target-region", "", "The region to move the parameter to (default the --region)",
+	)
+}
+
+// moveCompletionHelp provides shell completion help for the move command.
+func moveCompletionHelp(
+	cmd *cobra.Command, args []string, toComplete string,
+) ([]string, cobra.ShellCompDirective) {
+	var completionHelp []string
+	switch {
+	case len(args) == 0:
+		completionHelp = cobra.AppendActiveHelp(completionHelp, "dev, test*, or prod*")
+	case len(args) == 1:
+		return completeParameterPath(cmd, args[0], toComplete, "The path of the SSM parameter to move")
+	case len(args) == 2:
+		return completeParameterPath(cmd, args[0], toComplete, "The new path of the SSM parameter")
+	default:
+		completionHelp = cobra.AppendActiveHelp(completionHelp, "No more arguments")
+	}
+	return completionHelp, cobra.ShellCompDirectiveNoFileComp
+}
+
+// doMove copies a parameter to a new path in the SSM parameter store and then deletes the original.
+// args[0] is the name of to AWS Profile to use when accessing the SSM parameter store.
+// args[1] is the path of the SSM parameter to move.
+// args[2] is the path to move the SSM parameter to.
+func doMove(ctx context.Context, args []string) error {
+	profile := getAWSProfile(args[0])
+	region := getAWSRegion(args[0])
+	cfg := aws.Login(ctx, &aws.LoginSessionDetails{Profile: profile, Region: region})
+	ssmClient := aws.SSMClient(cfg, ssmClientOptions()...)
+	// Clear the cached results since they may be out of date once parameters are changed.
+	defer invalidateCache(profile, region)
+
+	// The destination client is the same as the source one unless moving to another region.
+	targetClient := ssmClient
+	crossRegion := moveOpts.targetRegion != "" && moveOpts.targetRegion != region
+	if crossRegion {
+		targetCfg := aws.Login(ctx, &aws.LoginSessionDetails{Profile: profile, Region: moveOpts.targetRegion})
+		targetClient = aws.SSMClient(targetCfg, ssmClientOptions()...)
+		defer invalidateCache(profile, moveOpts.targetRegion)
+	}
+
+	source := getSSMPath(args[0], args[1])
+	destination := getSSMPath(args[0], args[2])
+
+	p, err := aws.SSMGet(ctx, ssmClient, source)
+	if err != nil {
+		return fmt.Errorf("%w: %w", errGetSSMParameter, err)
+	}
+	if p.Error != "" {
+		return fmt.Errorf("%w: %s", errDecryptSource, p.Error)
+	}
+
+	// Refuse to overwrite an existing parameter.
+	_, err = aws.SSMGet(ctx, targetClient, destination)
+	if err == nil {
+		return newParameterExistsError(destination)
+	}
+	var notFound *types.ParameterNotFound
+	if !errors.As(err, &notFound) {
+		return fmt.Errorf("%w: %w", errGetSSMParameter, err)
+	}
+
+	p.Name = destination
+	if crossRegion && p.KeyID != "" && !strings.HasPrefix(p.KeyID, "alias/") {
+		p.KeyID = defaultKeyID
+	}
+	version, err := aws.SSMPut(ctx, targetClient, &p)
+	if err != nil {
+		return fmt.Errorf("%w: %w", errPutSSMParameter, err)
+	}
+
+	if crossRegion {
+		destination = fmt.Sprintf("%s in region %s", destination, moveOpts.targetRegion)
+	}
+
+	if moveOpts.keepSource {
+		fmt.Printf("Parameter %s copied to %s version %d\n", source, destination, version)
+		return nil
+	}
+
+	if err := aws.SSMDelete(ctx, ssmClient, source); err != nil {
+		return fmt.Errorf("%w: %w", errMoveSSMParameter, err)
+	}
+	fmt.Printf("Parameter %s moved to %s version %d\n", source, destination, version)
+
+	return nil
+}
@@ -0,0 +1,532 @@
+package cmd
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/MakeNowJust/heredoc/v2"
+	"github.com/jim-barber-he/go/aws"
+	"github.com/spf13/cobra"
+)
+
+// Parameter policy types.
+const (
+	policyExpiration             = "Expiration"
+	policyExpirationNotification = "ExpirationNotification"
+	policyNoChangeNotification   = "NoChangeNotification"
+)
+
+// policyVersion is the only version of the parameter policy syntax.
+const policyVersion = "1.0"
+
+// policyPeriod matches the periods used by the notification policies, such as `15d` or `12h`.
+var policyPeriod = regexp.MustCompile(`^([1-9][0-9]*)([dh])$`)
+
+// Commandline options.
+type policyRemoveOptions struct {
+	policyType string
+}
+
+// Commandline options.
+type policySetOptions struct {
+	expiration             string
+	expirationNotification string
+	file                   string
+	noChangeNotification   string
+}
+
+// parameterPolicy is the JSON of a parameter policy.
+type parameterPolicy struct {
+	Type       string            `json:"Type"`
+	Version    string            `json:"Version"`
+	Attributes map[string]string `json:"Attributes"`
+}
+
+var policyLong = heredoc.Doc(`
+	Manage the policies attached to a parameter in the SSM parameter store.
+
+	The policy types are:
+	'Expiration' deletes the parameter at a given time.
+	'ExpirationNotification' sends an Amazon EventBridge event a number of days or hours before the parameter expires.
+	'NoChangeNotification' sends an Amazon EventBridge event if the parameter hasn't changed for a number of days or
+	hours.
+
+	A parameter can only have one policy of each type.
+	Policies are only available to parameters in the Advanced tier, which incurs charges.
+`)
+
+var policyGetLong = heredoc.Doc(`
+	Show the policies attached to a parameter in the SSM parameter store, along with their status.
+`)
+
+var policyRemoveLong = heredoc.Doc(`
+	Remove policies from a parameter in the SSM parameter store.
+
+	By default all of the policies are removed. Passing --type only removes the policy of that type.
+
+	This stores a new version of the parameter with the same value, and the parameter stays in the Advanced tier
+	since parameters can't be moved back to the Standard tier.
+`)
+
+var policySetLong = heredoc.Doc(`
+	Attach policies to a parameter in the SSM parameter store.
+
+	--expiration takes the time that the parameter is deleted, either as a date such as 2027-01-31 (midnight UTC)
+	or an RFC 3339 timestamp such as 2027-01-31T09:00:00+08:00.
+	--expiration-notification takes how long before the expiration to be notified, such as 15d for 15 days or 12h
+	for 12 hours. It requires the parameter to also have an Expiration policy.
+	--no-change-notification takes how long the parameter can go unchanged before being notified, such as 90d.
+
+	Alternatively --file takes a file containing a JSON array of policies in the format used by AWS, which are
+	validated before they are attached.
+
+	The policies replace any existing policies of the same type, while policies of other types are kept.
+	This stores a new version of the parameter with the same value, and moves it to the Advanced tier if needed.
+`)
+
+var (
+	// policyCmd represents the policy command.
+	policyCmd = &cobra.Command{
+		Use:   "policy",
+		Short: "Manage the policies attached to a parameter in the SSM parameter store",
+		Long:  policyLong,
+	}
+
+	// policyGetCmd represents the policy get command.
+	policyGetCmd = &cobra.Command{
+		Use:   "get [flags] ENVIRONMENT PARAMETER",
+		Short: "Show the policies attached to a parameter",
+		Long:  policyGetLong,
+		Args:  cobra.ExactArgs(2),
+		PreRunE: func(_ *cobra.Command, args []string) error {
+			return validateEnvironment(args[0])
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return doPolicyGet(cmd.Context(), args)
+		},
+		SilenceErrors: true,
+		ValidArgsFunction: func(
+			cmd *cobra.Command, args []string, toComplete string,
+		) ([]string, cobra.ShellCompDirective) {
+			return policyCompletionHelp(cmd, args, toComplete)
+		},
+	}
+
+	// policyRemoveCmd represents the policy remove command.
+	policyRemoveCmd = &cobra.Command{
+		Use:   "remove [flags] ENVIRONMENT PARAMETER",
+		Short: "Remove policies from a parameter",
+		Long:  policyRemoveLong,
+		Args:  cobra.ExactArgs(2),
+		PreRunE: func(_ *cobra.Command, args []string) error {
+			if err := validatePolicyRemoveOptions(); err != nil {
+				return err
+			}
+			return validateEnvironment(args[0])
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return doPolicyRemove(cmd.Context(), args)
+		},
+		SilenceErrors: true,
+		ValidArgsFunction: func(
+			cmd *cobra.Command, args []string, toComplete string,
+		) ([]string, cobra.ShellCompDirective) {
+			return policyCompletionHelp(cmd, args, toComplete)
+		},
+	}
+
+	// policySetCmd represents the policy set command.
+	policySetCmd = &cobra.Command{
+		Use:   "set [flags] ENVIRONMENT PARAMETER",
+		Short: "Attach policies to a parameter",
+		Long:  policySetLong,
+		Args:  cobra.ExactArgs(2),
+		PreRunE: func(_ *cobra.Command, args []string) error {
+			return validateEnvironment(args[0])
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return doPolicySet(cmd.Context(), args)
+		},
+		SilenceErrors: true,
+		ValidArgsFunction: func(
+			cmd *cobra.Command, args []string, toComplete string,
+		) ([]string, cobra.ShellCompDirective) {
+			return policyCompletionHelp(cmd, args, toComplete)
+		},
+	}
+
+	policyRemoveOpts policyRemoveOptions
+	policySetOpts    policySetOptions
+)
+
+func init() {
+	rootCmd.AddCommand(policyCmd)
+	policyCmd.AddCommand(policyGetCmd)
+	policyCmd.AddCommand(policyRemoveCmd)
+	policyCmd.AddCommand(policySetCmd)
+
+	policyRemoveCmd.Flags().StringVarP(
+		&policyRemoveOpts.policyType, "type", "t", "", "Only remove the policy of this type (default all policies)",
+	)
+
+	policySetCmd.Flags().StringVar(
+		&policySetOpts.expiration, "expiration", "", "When the parameter expires, as a date or RFC 3339 timestamp",
+	)
+	policySetCmd.Flags().StringVar(
+		&policySetOpts.expirationNotification, "expiration-notification", "",
+		"How long before expiring to notify, such as 15d or 12h",
+	)
+	policySetCmd.Flags().StringVarP(
+		&policySetOpts.file, "file", "f", "", "Read a JSON array of policies from a file",
+	)
+	policySetCmd.Flags().StringVar(
+		&policySetOpts.noChangeNotification, "no-change-notification", "",
+		"How long without a change before notifying, such as 90d",
+	)
+}
+
+// policyCompletionHelp provides shell completion help for the policy commands.
+func policyCompletionHelp(
+	cmd *cobra.Command, args []string, toComplete string,
+) ([]string, cobra.ShellCompDirective) {
+	var completionHelp []string
+	switch {
+	case len(args) == 0:
+		completionHelp = cobra.AppendActiveHelp(completionHelp, "dev, test*, or prod*")
+	case len(args) == 1:
+		return completeParameterPath(cmd, args[0], toComplete, "The path of the SSM parameter")
+	default:
+		completionHelp = cobra.AppendActiveHelp(completionHelp, "No more arguments")
+	}
+	return completionHelp, cobra.ShellCompDirectiveNoFileComp
+}
+
+// checkExpirationRequired returns an error if the policies have an ExpirationNotification policy without the Expiration
+// policy that it notifies about.
+func checkExpirationRequired(policies []aws.SSMPolicy) error {
+	hasType := func(policyType string) bool {
+		return slices.ContainsFunc(policies, func(policy aws.SSMPolicy) bool {
+			return policy.Type == policyType
+		})
+	}
+	if hasType(policyExpirationNotification) && !hasType(policyExpiration) {
+		return errExpirationRequired
+	}
+	return nil
+}
+
+// doPolicyGet displays the policies attached to a parameter.
+// args[0] is the name of to AWS Profile to use when accessing the SSM parameter store.
+// args[1] is the path of the SSM parameter.
+func doPolicyGet(ctx context.Context, args []string) error {
+	profile := getAWSProfile(args[0])
+	cfg := aws.Login(ctx, &aws.LoginSessionDetails{Profile: profile, Region: getAWSRegion(args[0])})
+	ssmClient := aws.SSMClient(cfg, ssmClientOptions()...)
+
+	param := getSSMPath(args[0], args[1])
+	p, err := aws.SSMGet(ctx, ssmClient, param)
+	if err != nil {
+		return fmt.Errorf("%w: %w", errGetSSMParameter, err)
+	}
+	if len(p.Policies) == 0 {
+		fmt.Printf("Parameter %s has no policies.\n", param)
+		return nil
+	}
+
+	for i, policy := range p.Policies {
+		if i > 0 {
+			fmt.Println()
+		}
+		fmt.Printf("Type: %s\n", policy.Type)
+		fmt.Printf("Status: %s\n", policy.Status)
+		var text bytes.Buffer
+		if err := json.Indent(&text, []byte(policy.Text), "", "  "); err != nil {
+			fmt.Printf("Policy: %s\n", policy.Text)
+			continue
+		}
+		fmt.Printf("Policy: %s\n", text.String())
+	}
+	return nil
+}
+
+// doPolicyRemove removes policies from a parameter.
+// args[0] is the name of to AWS Profile to use when accessing the SSM parameter store.
+// args[1] is the path of the SSM parameter.
+func doPolicyRemove(ctx context.Context, args []string) error {
+	profile := getAWSProfile(args[0])
+	region := getAWSRegion(args[0])
+	cfg := aws.Login(ctx, &aws.LoginSessionDetails{Profile: profile, Region: region})
+	ssmClient := aws.SSMClient(cfg, ssmClientOptions()...)
+	// Clear the cached results since they may be out of date once parameters are changed.
+	defer invalidateCache(profile, region)
+
+	param := getSSMPath(args[0], args[1])
+	p, err := getPolicyParameter(ctx, ssmClient, param)
+	if err != nil {
+		return err
+	}
+
+	policies := keptPolicies(p.Policies, policyRemoveOpts.policyType)
+	if len(policies) == len(p.Policies) {
+		fmt.Printf("Parameter %s has no policies to remove.\n", param)
+		return nil
+	}
+	if err := checkExpirationRequired(policies); err != nil {
+		return err
+	}
+	p.Policies = policies
+
+	return putPolicies(ctx, ssmClient, &p)
+}
+
+// doPolicySet attaches policies to a parameter.
+// args[0] is the name of to AWS Profile to use when accessing the SSM parameter store.
+// args[1] is the path of the SSM parameter.
+func doPolicySet(ctx context.Context, args []string) error {
+	// Validate the policies before logging in so that invalid policies fail early.
+	newPolicies, err := getSetPolicies()
+	if err != nil {
+		return err
+	}
+
+	profile := getAWSProfile(args[0])
+	region := getAWSRegion(args[0])
+	cfg := aws.Login(ctx, &aws.LoginSessionDetails{Profile: profile, Region: region})
+	ssmClient := aws.SSMClient(cfg, ssmClientOptions()...)
+	// Clear the cached results since they may be out of date once parameters are changed.
+	defer invalidateCache(profile, region)
+
+	param := getSSMPath(args[0], args[1])
+	p, err := getPolicyParameter(ctx, ssmClient, param)
+	if err != nil {
+		return err
+	}
+
+	// Keep the existing policies of the types that aren't being set.
+	var policies []aws.SSMPolicy
+	for _, policy := range p.Policies {
+		if !slices.ContainsFunc(newPolicies, func(newPolicy parameterPolicy) bool {
+			return newPolicy.Type == policy.Type
+		}) {
+			policies = append(policies, policy)
+		}
+	}
+	for _, newPolicy := range newPolicies {
+		text, err := json.Marshal(newPolicy)
+		if err != nil {
+			return fmt.Errorf("%w: %w", errMarshalJSON, err)
+		}
+		policies = append(policies, aws.SSMPolicy{Text: string(text), Type: newPolicy.Type})
+	}
+	if err := checkExpirationRequired(policies); err != nil {
+		return err
+	}
+	slices.SortFunc(policies, func(a, b aws.SSMPolicy) int {
+		return cmp.Compare(a.Type, b.Type)
+	})
+	p.Policies = policies
+
+	if p.Tier != tierAdvanced {
+		p.Tier = tierAdvanced
+		fmt.Fprintln(
+			os.Stderr, "Notice: policies need the Advanced tier, so the parameter will be moved to it, which incurs charges.",
+		)
+	}
+
+	return putPolicies(ctx, ssmClient, &p)
+}
+
+// getPolicyParameter fetches the parameter that will have its policies changed.
+func getPolicyParameter(ctx context.Context, ssmClient aws.SSMAPI, param string) (aws.SSMParameter, error) {
+	p, err := aws.SSMGet(ctx, ssmClient, param)
+	if err != nil {
+		return aws.SSMParameter{}, fmt.Errorf("%w: %w", errGetSSMParameter, err)
+	}
+	// The value has to be stored again to change the policies, so it has to be readable.
+	if p.Error != "" {
+		return aws.SSMParameter{}, fmt.Errorf("%w: %s", errDecryptPolicy, p.Error)
+	}
+	return p, nil
+}
+
+// getSetPolicies returns the validated policies to set from either the command line options or the file.
+func getSetPolicies() ([]parameterPolicy, error) {
+	opts := policySetOpts
+	hasFlags := opts.expiration != "" || opts.expirationNotification != "" || opts.noChangeNotification != ""
+
+	if opts.file != "" {
+		if hasFlags {
+			return nil, errPolicyFileWithFlags
+		}
+		data, err := os.ReadFile(opts.file)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", errReadFile, err)
+		}
+		return parsePolicies(data)
+	}
+	if !hasFlags {
+		return nil, errPolicyRequired
+	}
+
+	var policies []parameterPolicy
+	if opts.expiration != "" {
+		timestamp, err := parseExpiration(opts.expiration)
+		if err != nil {
+			return nil, err
+		}
+		policies = append(policies, parameterPolicy{
+			Type:       policyExpiration,
+			Version:    policyVersion,
+			Attributes: map[string]string{"Timestamp": timestamp},
+		})
+	}
+	if opts.expirationNotification != "" {
+		policy, err := newNotificationPolicy(policyExpirationNotification, "Before", opts.expirationNotification)
+		if err != nil {
+			return nil, err
+		}
+		policies = append(policies, policy)
+	}
+	if opts.noChangeNotification != "" {
+		policy, err := newNotificationPolicy(policyNoChangeNotification, "After", opts.noChangeNotification)
+		if err != nil {
+			return nil, err
+		}
+		policies = append(policies, policy)
+	}
+	return policies, nil
+}
+
+// keptPolicies returns the policies that are kept when removing the policies of a type, or all of them if the type is
+// empty.
+// The list is empty rather than nil if none are kept so that storing it removes all of the policies.
+func keptPolicies(policies []aws.SSMPolicy, removeType string) []aws.SSMPolicy {
+	kept := []aws.SSMPolicy{}
+	for _, policy := range policies {
+		if removeType != "" && policy.Type != removeType {
+			kept = append(kept, policy)
+		}
+	}
+	return kept
+}
+
+// newNotificationPolicy returns a notification policy for a period such as `15d`, where the attribute is the name
+// of the attribute that holds the number of days or hours.
+func newNotificationPolicy(policyType, attribute, period string) (parameterPolicy, error) {
+	match := policyPeriod.FindStringSubmatch(period)
+	if match == nil {
+		return parameterPolicy{}, newInvalidPolicyError(policyType, "the period must be like 15d or 12h: "+period)
+	}
+	unit := "Days"
+	if match[2] == "h" {
+		unit = "Hours"
+	}
+	return parameterPolicy{
+		Type:       policyType,
+		Version:    policyVersion,
+		Attributes: map[string]string{attribute: match[1], "Unit": unit},
+	}, nil
+}
+
+// parseExpiration converts a date or RFC 3339 timestamp into the timestamp format used by Expiration policies.
+func parseExpiration(expiration string) (string, error) {
+	t, err := time.Parse(time.RFC3339, expiration)
+	if err != nil {
+		t, err = time.Parse(time.DateOnly, expiration)
+	}
+	if err != nil {
+		return "", newInvalidPolicyError(policyExpiration, "invalid date or timestamp: "+expiration)
+	}
+	return t.UTC().Format("2006-01-02T15:04:05.000Z"), nil
+}
+
+// parsePolicies parses and validates a JSON array of policies.
+func parsePolicies(data []byte) ([]parameterPolicy, error) {
+	var policies []parameterPolicy
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&policies); err != nil {
+		return nil, fmt.Errorf("%w: %w", errPolicyJSON, err)
+	}
+	if len(policies) == 0 {
+		return nil, errPolicyRequired
+	}
+
+	var types []string
+	for _, policy := range policies {
+		if err := validatePolicy(policy); err != nil {
+			return nil, err
+		}
+		if slices.Contains(types, policy.Type) {
+			return nil, newInvalidPolicyError(policy.Type, "only one policy of each type is allowed")
+		}
+		types = append(types, policy.Type)
+	}
+	return policies, nil
+}
+
+// putPolicies stores the parameter again with its new policies.
+func putPolicies(ctx context.Context, ssmClient aws.SSMAPI, p *aws.SSMParameter) error {
+	version, err := aws.SSMPut(ctx, ssmClient, p)
+	if err != nil {
+		return fmt.Errorf("%w: %w", errPutSSMParameter, err)
+	}
+	fmt.Printf("Parameter %s updated to version %d with %d policies\n", p.Name, version, len(p.Policies))
+	return nil
+}
+
+// validatePolicy checks that a policy has a known type and the attributes that the type needs.
+func validatePolicy(policy parameterPolicy) error {
+	if policy.Version != policyVersion {
+		return newInvalidPolicyError(policy.Type, "the Version must be "+policyVersion)
+	}
+
+	var attributes []string
+	switch policy.Type {
+	case policyExpiration:
+		attributes = []string{"Timestamp"}
+		if _, err := time.Parse(time.RFC3339, policy.Attributes["Timestamp"]); err != nil {
+			return newInvalidPolicyError(policy.Type, "the Timestamp must be an RFC 3339 timestamp")
+		}
+	case policyExpirationNotification, policyNoChangeNotification:
+		period := "After"
+		if policy.Type == policyExpirationNotification {
+			period = "Before"
+		}
+		attributes = []string{period, "Unit"}
+		if n, err := strconv.Atoi(policy.Attributes[period]); err != nil || n < 1 {
+			return newInvalidPolicyError(policy.Type, "the "+period+" must be a positive whole number")
+		}
+		if unit := policy.Attributes["Unit"]; unit != "Days" && unit != "Hours" {
+			return newInvalidPolicyError(policy.Type, "the Unit must be Days or Hours")
+		}
+	default:
+		return newInvalidPolicyError(policy.Type, "unknown policy type")
+	}
+
+	for name := range policy.Attributes {
+		if !slices.Contains(attributes, name) {
+			return newInvalidPolicyError(policy.Type, "unknown attribute "+name)
+		}
+	}
+	return nil
+}
+
+// validatePolicyRemoveOptions validates the policy remove command options.
+func validatePolicyRemoveOptions() error {
+	if !slices.Contains(
+		[]string{"", policyExpiration, policyExpirationNotification, policyNoChangeNotification},
+		policyRemoveOpts.policyType,
+	) {
+		return newInvalidPolicyError(policyRemoveOpts.policyType, "unknown policy type")
+	}
+	return nil
+}
@@ -0,0 +1,233 @@
+package cmd
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/jim-barber-he/go/aws"
+)
+
+func TestValidatePolicy(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		policy  parameterPolicy
+		wantErr bool
+	}{
+		{
+			name: "expiration",
+			policy: parameterPolicy{
+				Type:       policyExpiration,
+				Version:    policyVersion,
+				Attributes: map[string]string{"Timestamp": "2027-01-31T00:00:00.000Z"},
+			},
+		},
+		{
+			name: "expiration notification",
+			policy: parameterPolicy{
+				Type:       policyExpirationNotification,
+				Version:    policyVersion,
+				Attributes: map[string]string{"Before": "15", "Unit": "Days"},
+			},
+		},
+		{
+			name: "no change notification",
+			policy: parameterPolicy{
+				Type:       policyNoChangeNotification,
+				Version:    policyVersion,
+				Attributes: map[string]string{"After": "12", "Unit": "Hours"},
+			},
+		},
+		{
+			name: "wrong version",
+			policy: parameterPolicy{
+				Type:       policyExpiration,
+				Version:    "2.0",
+				Attributes: map[string]string{"Timestamp": "2027-01-31T00:00:00.000Z"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid timestamp",
+			policy: parameterPolicy{
+				Type:       policyExpiration,
+				Version:    policyVersion,
+				Attributes: map[string]string{"Timestamp": "2027-01-31"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "zero period",
+			policy: parameterPolicy{
+				Type:       policyNoChangeNotification,
+				Version:    policyVersion,
+				Attributes: map[string]string{"After": "0", "Unit": "Days"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "wrong period attribute",
+			policy: parameterPolicy{
+				Type:       policyExpirationNotification,
+				Version:    policyVersion,
+				Attributes: map[string]string{"After": "15", "Unit": "Days"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid unit",
+			policy: parameterPolicy{
+				Type:       policyNoChangeNotification,
+				Version:    policyVersion,
+				Attributes: map[string]string{"After": "3", "Unit": "Weeks"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "unknown attribute",
+			policy: parameterPolicy{
+				Type:       policyExpiration,
+				Version:    policyVersion,
+				Attributes: map[string]string{"Timestamp": "2027-01-31T00:00:00.000Z", "Zone": "UTC"},
+			},
+			wantErr: true,
+		},
+		{
+			name:    "unknown type",
+			policy:  parameterPolicy{Type: "Rotation", Version: policyVersion},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if err := validatePolicy(tt.policy); (err != nil) != tt.wantErr {
+				t.Errorf("validatePolicy() failed, expected error %t, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestParsePolicies(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		data     string
+		expected []string
+		err      error
+		wantErr  bool
+	}{
+		{
+			name: "valid",
+			data: `[
+				{"Type": "Expiration", "Version": "1.0", "Attributes": {"Timestamp": "2027-01-31T00:00:00.000Z"}},
+				{"Type": "ExpirationNotification", "Version": "1.0", "Attributes": {"Before": "15", "Unit": "Days"}}
+			]`,
+			expected: []string{policyExpiration, policyExpirationNotification},
+		},
+		{name: "not json", data: `Expiration`, err: errPolicyJSON, wantErr: true},
+		{name: "not an array", data: `{"Type": "Expiration"}`, err: errPolicyJSON, wantErr: true},
+		{
+			name:    "unknown field",
+			data:    `[{"Type": "Expiration", "Version": "1.0", "Attributes": {}, "Enabled": true}]`,
+			err:     errPolicyJSON,
+			wantErr: true,
+		},
+		{name: "empty", data: `[]`, err: errPolicyRequired, wantErr: true},
+		{
+			name:    "invalid policy",
+			data:    `[{"Type": "Expiration", "Version": "1.0", "Attributes": {"Timestamp": "soon"}}]`,
+			wantErr: true,
+		},
+		{
+			name: "duplicate type",
+			data: `[
+				{"Type": "NoChangeNotification", "Version": "1.0", "Attributes": {"After": "30", "Unit": "Days"}},
+				{"Type": "NoChangeNotification", "Version": "1.0", "Attributes": {"After": "60", "Unit": "Days"}}
+			]`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			policies, err := parsePolicies([]byte(tt.data))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parsePolicies() failed, expected error %t, got %v", tt.wantErr, err)
+			}
+			if tt.err != nil && !errors.Is(err, tt.err) {
+				t.Errorf("parsePolicies() failed, expected %v, got %v", tt.err, err)
+			}
+			var types []string
+			for _, policy := range policies {
+				types = append(types, policy.Type)
+			}
+			if !slices.Equal(types, tt.expected) {
+				t.Errorf("parsePolicies() failed, expected %v, got %v", tt.expected, types)
+			}
+		})
+	}
+}
+
+func TestPolicyRemove(t *testing.T) {
+	t.Parallel()
+
+	all := []aws.SSMPolicy{
+		{Type: policyExpiration},
+		{Type: policyExpirationNotification},
+		{Type: policyNoChangeNotification},
+	}
+
+	tests := []struct {
+		name       string
+		removeType string
+		expected   []string
+		err        error
+	}{
+		{name: "all", removeType: "", expected: []string{}},
+		{
+			name:       "no change notification",
+			removeType: policyNoChangeNotification,
+			expected:   []string{policyExpiration, policyExpirationNotification},
+		},
+		{
+			name:       "expiration notification",
+			removeType: policyExpirationNotification,
+			expected:   []string{policyExpiration, policyNoChangeNotification},
+		},
+		{
+			name:       "expiration",
+			removeType: policyExpiration,
+			expected:   []string{policyExpirationNotification, policyNoChangeNotification},
+			err:        errExpirationRequired,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			kept := keptPolicies(all, tt.removeType)
+			if kept == nil {
+				t.Fatal("keptPolicies() failed, expected an empty rather than nil list")
+			}
+			types := []string{}
+			for _, policy := range kept {
+				types = append(types, policy.Type)
+			}
+			if !slices.Equal(types, tt.expected) {
+				t.Errorf("keptPolicies() failed, expected %v, got %v", tt.expected, types)
+			}
+			if err := checkExpirationRequired(kept); !errors.Is(err, tt.err) {
+				t.Errorf("checkExpirationRequired() failed, expected %v, got %v", tt.err, err)
+			}
+		})
+	}
+}
@@ -2,21 +2,49 @@ package cmd
 
 import (
 	"context"
+	"encoding/base64"
+	"errors"
 	"fmt"
 	"os"
+	"slices"
+	"strings"
 
 	"github.com/MakeNowJust/heredoc/v2"
-	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	kmstypes "github.com/aws/aws-sdk-go-v2/service/kms/types"
+	"github.com/aws/aws-sdk-go-v2/service/ssm/types"
+	"github.com/aws/smithy-go"
 	"github.com/jim-barber-he/go/aws"
 	"github.com/spf13/cobra"
 )
 
+// defaultKeyID is the KMS key used to encrypt SecureStrings when one isn't specified.
+const defaultKeyID = "alias/parameter_store_key"
+
+// Parameter types that can be stored.
+const (
+	typeSecureString = "SecureString"
+	typeString       = "String"
+	typeStringList   = "StringList"
+)
+
+// Parameter tiers, and the maximum size of the value that each can store.
+const (
+	tierAdvanced         = "Advanced"
+	tierAdvancedMaxBytes = 8192
+	tierStandard         = "Standard"
+	tierStandardMaxBytes = 4096
+)
+
 // Commandline options.
 type putOptions struct {
-	file    string
-	keyID   string
-	secure  bool
-	verbose bool
+	dryRun        bool
+	encodeBase64  bool
+	expectVersion int64
+	file          string
+	keyID         string
+	paramType     string
+	secure        bool
+	verbose       bool
 }
 
 var putLong = heredoc.Doc(`
@@ -24,11 +52,28 @@ var putLong = heredoc.Doc(`
 
 	The value to be stored can be passed directly on the command line or read from a file via the --file flag.
 
-	The value will be encrypted if --secure is passed.
+	The --type flag sets the type of the parameter to one of String (the default), StringList, or SecureString.
+	A StringList value is a comma separated list of items, and may not contain newlines.
+	When the value of a StringList is read from a file, a single trailing newline is removed.
+
+	The --encode-base64 flag stores the value base64 encoded, which allows binary files such as certificates to be
+	stored via --file. The value is encoded exactly as it is, so no trailing newline is removed.
+
+	The value will be encrypted if --secure is passed, which is the same as --type SecureString.
 	By default it will use the alias/parameter_store_key KMS key to encrypt the value, but you can supply a key via
 	--key-id.
+	The key is checked to exist and be enabled before anything is stored.
 
 	If the --verbose flag is shown, the value stored will be shown.
+
+	Values larger than the 4KB limit of the Standard tier are automatically stored using the Advanced tier, which
+	can hold up to 8KB but incurs charges. A parameter that already uses the Advanced tier stays in it.
+
+	The --expect-version flag aborts without storing anything unless the parameter is currently at that version,
+	so that two people updating the same parameter at once don't silently overwrite each other's changes.
+
+	The --dry-run flag performs all the validation and checks whether the value has changed, then reports whether
+	the parameter would be created or updated without storing anything.
 `)
 
 var (
@@ -38,15 +83,20 @@ var (
 		Short: "Store a parameter and its value in the AWS SSM parameter store",
 		Long:  putLong,
 		Args:  cobra.RangeArgs(2, 3),
-		PreRunE: func(_ *cobra.Command, args []string) error {
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if err := validatePutOptions(cmd); err != nil {
+				return err
+			}
 			return validateEnvironment(args[0])
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return doPut(cmd.Context(), args)
 		},
 		SilenceErrors: true,
-		ValidArgsFunction: func(_ *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
-			return putCompletionHelp(args)
+		ValidArgsFunction: func(
+			cmd *cobra.Command, args []string, toComplete string,
+		) ([]string, cobra.ShellCompDirective) {
+			return putCompletionHelp(cmd, args, toComplete)
 		},
 	}
 
@@ -56,22 +106,32 @@ var (
 func init() {
 	rootCmd.AddCommand(putCmd)
 
+	putCmd.Flags().BoolVar(&putOpts.dryRun, "dry-run", false, "Report what would be stored without storing it")
+	putCmd.Flags().BoolVar(&putOpts.encodeBase64, "encode-base64", false, "Base64 encode the value before storing it")
+	putCmd.Flags().Int64Var(
+		&putOpts.expectVersion, "expect-version", 0, "Only store the value if the parameter is currently at this version",
+	)
 	putCmd.Flags().StringVarP(&putOpts.file, "file", "f", "", "Get the value from the file contents")
 	putCmd.Flags().StringVar(
-		&putOpts.keyID, "key-id", "alias/parameter_store_key", "The ID of the KMS key to encrypt SecureStrings",
+		&putOpts.keyID, "key-id", defaultKeyID, "The ID of the KMS key to encrypt SecureStrings",
 	)
 	putCmd.Flags().BoolVar(&putOpts.secure, "secure", false, "Store the value as a SecureString")
+	putCmd.Flags().StringVarP(
+		&putOpts.paramType, "type", "t", "", "The parameter type: String, StringList, or SecureString (default String)",
+	)
 	putCmd.Flags().BoolVarP(&putOpts.verbose, "verbose", "v", false, "Show the value set for the parameter")
 }
 
 // putCompletionHelp provides shell completion help for the put command.
-func putCompletionHelp(args []string) ([]string, cobra.ShellCompDirective) {
+func putCompletionHelp(
+	cmd *cobra.Command, args []string, toComplete string,
+) ([]string, cobra.ShellCompDirective) {
 	var completionHelp []string
 	switch {
 	case len(args) == 0:
 		completionHelp = cobra.AppendActiveHelp(completionHelp, "dev, test*, or prod*")
 	case len(args) == 1:
-		completionHelp = cobra.AppendActiveHelp(completionHelp, "The path of the SSM parameter")
+		return completeParameterPath(cmd, args[0], toComplete, "The path of the SSM parameter")
 	case len(args) == 2:
 		if putOpts.file != "" {
 			completionHelp = cobra.AppendActiveHelp(completionHelp, "No more arguments")
@@ -89,25 +149,50 @@ func putCompletionHelp(args []string) ([]string, cobra.ShellCompDirective) {
 // args[1] is the path of the SSM parameter to put.
 // args[2] is the value to put, but is only valid to use if --file is not used.
 func doPut(ctx context.Context, args []string) error {
+	// Validate the value before logging in so that invalid values fail early.
+	value, err := getPutValue(args)
+	if err != nil {
+		return err
+	}
+
 	profile := getAWSProfile(args[0])
-	cfg := aws.Login(ctx, &aws.LoginSessionDetails{Profile: profile, Region: rootOpts.region})
-	ssmClient := aws.SSMClient(cfg)
+	region := getAWSRegion(args[0])
+	cfg := aws.Login(ctx, &aws.LoginSessionDetails{Profile: profile, Region: region})
+	ssmClient := aws.SSMClient(cfg, ssmClientOptions()...)
+	// Clear the cached results since they may be out of date once parameters are changed.
+	defer invalidateCache(profile, region)
 
 	param := getSSMPath(args[0], args[1])
 
-	value, err := getPutValue(args)
+	ssmParam := createPutSSMParameter(param, value)
+
+	existing, found, err := getExistingParameter(ctx, ssmClient, param)
 	if err != nil {
 		return err
 	}
-
-	ssmParam := createPutSSMParameter(param, value)
+	if err := checkExpectedVersion(param, existing, found); err != nil {
+		return err
+	}
 
 	// Return if the parameter is already set to the same value and type.
-	if unchanged, err := isPutValueUnchanged(ctx, ssmClient, param, ssmParam); err == nil && unchanged {
+	if found && existing.Value == ssmParam.Value && existing.Type == ssmParam.Type {
 		fmt.Println("Value unchanged.")
 		return nil
 	}
 
+	setPutTier(&ssmParam, existing)
+
+	if ssmParam.Type == typeSecureString {
+		if err := checkKeyID(ctx, aws.KMSClient(cfg), ssmParam.KeyID); err != nil {
+			return err
+		}
+	}
+
+	if putOpts.dryRun {
+		reportPutDryRun(ssmParam, existing, found)
+		return nil
+	}
+
 	version, err := aws.SSMPut(ctx, ssmClient, &ssmParam)
 	if err != nil {
 		return fmt.Errorf("%w: %w", errPutSSMParameter, err)
@@ -120,21 +205,76 @@ func doPut(ctx context.Context, args []string) error {
 	return nil
 }
 
+// checkExpectedVersion returns an error if --expect-version was used and the parameter isn't at that version.
+func checkExpectedVersion(param string, existing aws.SSMParameter, found bool) error {
+	if putOpts.expectVersion == 0 {
+		return nil
+	}
+	if !found {
+		return newExpectVersionNotFoundError(param)
+	}
+	if existing.Version != putOpts.expectVersion {
+		return newVersionMismatchError(param, putOpts.expectVersion, existing.Version)
+	}
+	return nil
+}
+
+// checkKeyID makes sure that a KMS key for encrypting SecureStrings exists and is enabled before anything is stored
+// with it, so that a mistyped key or alias fails fast with a clear error instead of a generic error from SSM.
+// When the key isn't found, the aliases that do exist are shown to help spot the mistake.
+// If the caller isn't allowed to describe the key, then SSM is left to decide whether the key can be used.
+func checkKeyID(ctx context.Context, kmsClient aws.KMSAPI, keyID string) error {
+	_, err := aws.KMSResolveKey(ctx, kmsClient, keyID)
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) && apiErr.ErrorCode() == "AccessDeniedException" {
+		return nil
+	}
+
+	var notFound *kmstypes.NotFoundException
+	if errors.As(err, &notFound) {
+		if aliases, listErr := aws.KMSListAliases(ctx, kmsClient); listErr == nil {
+			var names []string
+			for _, alias := range aliases {
+				// Skip the keys managed by AWS since they can't be chosen for SecureStrings apart from aws/ssm.
+				if !strings.HasPrefix(alias.Name, "alias/aws/") || alias.Name == "alias/aws/ssm" {
+					names = append(names, alias.Name)
+				}
+			}
+			fmt.Fprintf(os.Stderr, "Available KMS aliases: %s\n", strings.Join(names, ", "))
+		}
+	}
+	return err
+}
+
 // createPutSSMParameter creates an SSMParameter struct based on the provided values.
 func createPutSSMParameter(name, value string) aws.SSMParameter {
 	ssmParam := aws.SSMParameter{
 		Name:  name,
+		Type:  putType(),
 		Value: value,
 	}
-	if putOpts.secure {
+	if ssmParam.Type == typeSecureString {
 		ssmParam.KeyID = putOpts.keyID
-		ssmParam.Type = "SecureString"
-	} else {
-		ssmParam.Type = "String"
 	}
 	return ssmParam
 }
 
+// getExistingParameter fetches the current state of the parameter, returning false if it doesn't exist yet.
+// Any other failure, such as being denied access, is returned as an error rather than being treated as a new parameter.
+func getExistingParameter(
+	ctx context.Context, ssmClient aws.SSMAPI, param string,
+) (aws.SSMParameter, bool, error) {
+	p, err := aws.SSMGet(ctx, ssmClient, param)
+	if err != nil {
+		var notFound *types.ParameterNotFound
+		if errors.As(err, &notFound) {
+			return aws.SSMParameter{}, false, nil
+		}
+		return aws.SSMParameter{}, false, fmt.Errorf("%w: %w", errGetSSMParameter, err)
+	}
+	return p, true, nil
+}
+
 // getPutValue retrieves the value to put into the SSM parameter store.
 func getPutValue(args []string) (string, error) {
 	if putOpts.file != "" {
@@ -145,21 +285,99 @@ func getPutValue(args []string) (string, error) {
 		if err != nil {
 			return "", fmt.Errorf("%w: %w", errReadFile, err)
 		}
-		return string(bytes), nil
+		if putOpts.encodeBase64 {
+			value := base64.StdEncoding.EncodeToString(bytes)
+			return value, validatePutValue(value)
+		}
+		value := string(bytes)
+		if putType() == typeStringList {
+			value = strings.TrimSuffix(value, "\n")
+		}
+		return value, validatePutValue(value)
 	}
 	if len(args) == 2 {
 		return "", errValueRequired
 	}
-	return args[2], nil
+	value := args[2]
+	if putOpts.encodeBase64 {
+		value = base64.StdEncoding.EncodeToString([]byte(value))
+	}
+	return value, validatePutValue(value)
 }
 
-// isPutValueUnchanged checks if the parameter is already set to the same value and type.
-func isPutValueUnchanged(
-	ctx context.Context, ssmClient *ssm.Client, param string, ssmParam aws.SSMParameter,
-) (bool, error) {
-	p, err := aws.SSMGet(ctx, ssmClient, param)
-	if err != nil {
-		return false, fmt.Errorf("%w: %w", errGetSSMParameter, err)
+// putType returns the type of the parameter to store based on the --secure and --type flags.
+func putType() string {
+	if putOpts.secure {
+		return typeSecureString
+	}
+	if putOpts.paramType == "" {
+		return typeString
+	}
+	return putOpts.paramType
+}
+
+// reportPutDryRun displays what a put would have done to the parameter.
+func reportPutDryRun(ssmParam, existing aws.SSMParameter, found bool) {
+	if putOpts.verbose {
+		fmt.Printf("Would set %s = %s\n", ssmParam.Name, ssmParam.Value)
+	}
+	if !found {
+		fmt.Printf("Parameter %s would be created as a %s\n", ssmParam.Name, ssmParam.Type)
+		return
+	}
+	if existing.Tier != tierAdvanced && ssmParam.Tier == tierAdvanced {
+		fmt.Printf("Parameter %s would be moved to the Advanced tier\n", ssmParam.Name)
+	}
+	if existing.Type != ssmParam.Type {
+		fmt.Printf(
+			"Parameter %s would be updated from version %d and changed from a %s to a %s\n",
+			ssmParam.Name, existing.Version, existing.Type, ssmParam.Type,
+		)
+		return
+	}
+	fmt.Printf("Parameter %s would be updated from version %d\n", ssmParam.Name, existing.Version)
+}
+
+// setPutTier uses the Advanced tier for values that are too large for the Standard tier, displaying a notice since
+// the Advanced tier incurs charges.
+// An existing parameter in the Advanced tier is kept there since parameters can't be moved back to the Standard tier.
+func setPutTier(ssmParam *aws.SSMParameter, existing aws.SSMParameter) {
+	if existing.Tier == tierAdvanced {
+		ssmParam.Tier = tierAdvanced
+		return
+	}
+	if len(ssmParam.Value) > tierStandardMaxBytes {
+		ssmParam.Tier = tierAdvanced
+		fmt.Fprintf(
+			os.Stderr,
+			"Notice: the value is %d bytes which exceeds the %d byte limit of the Standard tier, so the Advanced tier "+
+				"will be used, which incurs charges.\n",
+			len(ssmParam.Value), tierStandardMaxBytes,
+		)
+	}
+}
+
+// validatePutOptions validates the put command options.
+func validatePutOptions(cmd *cobra.Command) error {
+	if !slices.Contains([]string{"", typeSecureString, typeString, typeStringList}, putOpts.paramType) {
+		return newInvalidTypeError(putOpts.paramType)
+	}
+	if cmd.Flags().Changed("expect-version") && putOpts.expectVersion < 1 {
+		return newInvalidVersionError(putOpts.expectVersion)
+	}
+	if putOpts.secure && putOpts.paramType != "" && putOpts.paramType != typeSecureString {
+		return newSecureAndTypeError(cmd.UsageString())
+	}
+	return nil
+}
+
+// validatePutValue validates the value to put against the rules for the parameter type.
+func validatePutValue(value string) error {
+	if putType() == typeStringList && strings.ContainsAny(value, "\r\n") {
+		return errStringListNewline
 	}
-	return p.Value == ssmParam.Value && p.Type == ssmParam.Type, nil
+	if len(value) > tierAdvancedMaxBytes {
+		return newValueTooLargeError(len(value))
+	}
+	return nil
 }
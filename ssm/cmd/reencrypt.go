@@ -0,0 +1,218 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"sync/atomic"
+
+	"github.com/MakeNowJust/heredoc/v2"
+	"github.com/jim-barber-he/go/aws"
+	"github.com/spf13/cobra"
+	"golang.org/x/sync/errgroup"
+)
+
+// Commandline options.
+type reencryptOptions struct {
+	concurrency int
+	dryRun      bool
+	newKeyID    string
+}
+
+var reencryptLong = heredoc.Doc(`
+	Re-encrypt all of the SecureString parameters below a path in the SSM parameter store with a new KMS key.
+
+	Each SecureString below the path, at any depth, is decrypted and stored again using the KMS key passed via
+	--new-key-id, preserving its value, description, and tier. This creates a new version of each parameter.
+	Parameters that are already encrypted with the new key are skipped.
+	The new key is checked to exist and be enabled before any parameters are changed.
+
+	Up to --concurrency parameters are re-encrypted at a time, and a line is shown as each one completes.
+	A parameter that fails to be re-encrypted, such as one that can't be decrypted, doesn't stop the others.
+
+	The --dry-run flag lists the parameters that would be re-encrypted along with their current key without changing
+	anything.
+
+	If no PATH is passed at all, then for the 'dev', 'test*', and 'prod*' environments it will look in
+	'/helm/minikube/', '/helm/test*/', or '/helm/prod*/' respectively.
+`)
+
+var (
+	// reencryptCmd represents the reencrypt command.
+	reencryptCmd = &cobra.Command{
+		Use:   "reencrypt [flags] ENVIRONMENT [PATH] --new-key-id KEY_ID",
+		Short: "Re-encrypt the SecureString parameters below a path with a new KMS key",
+		Long:  reencryptLong,
+		Args:  cobra.RangeArgs(1, 2),
+		PreRunE: func(_ *cobra.Command, args []string) error {
+			if err := validateReencryptOptions(); err != nil {
+				return err
+			}
+			return validateEnvironment(args[0])
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return doReencrypt(cmd.Context(), args)
+		},
+		SilenceErrors: true,
+		ValidArgsFunction: func(
+			cmd *cobra.Command, args []string, toComplete string,
+		) ([]string, cobra.ShellCompDirective) {
+			return reencryptCompletionHelp(cmd, args, toComplete)
+		},
+	}
+
+	reencryptOpts reencryptOptions
+)
+
+func init() {
+	rootCmd.AddCommand(reencryptCmd)
+
+	reencryptCmd.Flags().IntVarP(
+		&reencryptOpts.concurrency, "concurrency", "c", 4, "How many parameters to re-encrypt at a time",
+	)
+	reencryptCmd.Flags().BoolVar(
+		&reencryptOpts.dryRun, "dry-run", false, "Report what would be re-encrypted without changing anything",
+	)
+	reencryptCmd.Flags().StringVar(
+		&reencryptOpts.newKeyID, "new-key-id", "", "The ID or alias of the KMS key to re-encrypt the parameters with",
+	)
+}
+
+// reencryptCompletionHelp provides shell completion help for the reencrypt command.
+func reencryptCompletionHelp(
+	cmd *cobra.Command, args []string, toComplete string,
+) ([]string, cobra.ShellCompDirective) {
+	var completionHelp []string
+	switch {
+	case len(args) == 0:
+		completionHelp = cobra.AppendActiveHelp(completionHelp, "dev, test*, or prod*")
+	case len(args) == 1:
+		return completeParameterPath(
+			cmd, args[0], toComplete, "The path in the SSM parameter store of the parameters to re-encrypt",
+		)
+	default:
+		completionHelp = cobra.AppendActiveHelp(completionHelp, "No more arguments")
+	}
+	return completionHelp, cobra.ShellCompDirectiveNoFileComp
+}
+
+// doReencrypt re-encrypts the SecureString parameters below a path in the SSM parameter store with a new KMS key.
+// args[0] is the name of to AWS Profile to use when accessing the SSM parameter store.
+// args[1] is the path in the SSM parameter store of the parameters to re-encrypt.
+func doReencrypt(ctx context.Context, args []string) error {
+	profile := getAWSProfile(args[0])
+	region := getAWSRegion(args[0])
+	cfg := aws.Login(ctx, &aws.LoginSessionDetails{Profile: profile, Region: region})
+	ssmClient := aws.SSMClient(cfg, ssmClientOptions()...)
+	// Clear the cached results since they may be out of date once parameters are changed.
+	defer invalidateCache(profile, region)
+
+	if err := checkKeyID(ctx, aws.KMSClient(cfg), reencryptOpts.newKeyID); err != nil {
+		return err
+	}
+
+	var path string
+	if len(args) > 1 {
+		path = getSSMPath(args[0], args[1])
+	} else {
+		path = getSSMPath(args[0], "")
+	}
+
+	params, err := aws.SSMListMetadata(ctx, ssmClient, path, true)
+	if err != nil {
+		return fmt.Errorf("%w: %w", errListSSMParameters, err)
+	}
+
+	var names []string
+	var skipped int
+	for _, p := range params {
+		if p.Type != typeSecureString {
+			continue
+		}
+		if p.KeyID == reencryptOpts.newKeyID {
+			skipped++
+			continue
+		}
+		if reencryptOpts.dryRun {
+			fmt.Printf("Would re-encrypt %s (currently using %s)\n", p.Name, p.KeyID)
+		}
+		names = append(names, p.Name)
+	}
+	slices.Sort(names)
+
+	if reencryptOpts.dryRun {
+		fmt.Printf(
+			"Would re-encrypt %d parameters below %s with %s (%d already use it)\n",
+			len(names), path, reencryptOpts.newKeyID, skipped,
+		)
+		return nil
+	}
+
+	failed := reencryptParameters(ctx, ssmClient, names)
+	fmt.Printf(
+		"Re-encrypted %d parameters below %s with %s (%d already used it)\n",
+		len(names)-failed, path, reencryptOpts.newKeyID, skipped,
+	)
+	if failed > 0 {
+		return newReencryptFailedError(failed)
+	}
+
+	return nil
+}
+
+// reencryptParameter stores a parameter again using the new KMS key, returning its new version.
+func reencryptParameter(ctx context.Context, ssmClient aws.SSMAPI, name string) (int64, error) {
+	p, err := aws.SSMGet(ctx, ssmClient, name)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %w", errGetSSMParameter, err)
+	}
+	if p.Error != "" {
+		return 0, errDecryptReencrypt
+	}
+
+	p.KeyID = reencryptOpts.newKeyID
+	version, err := aws.SSMPut(ctx, ssmClient, &p)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %w", errPutSSMParameter, err)
+	}
+	return version, nil
+}
+
+// reencryptParameters re-encrypts the named parameters, limiting how many are done at a time, and displays the
+// progress as each one completes.
+// It returns how many of the parameters failed to be re-encrypted.
+func reencryptParameters(ctx context.Context, ssmClient aws.SSMAPI, names []string) int {
+	var done, failed atomic.Int64
+
+	g := new(errgroup.Group)
+	g.SetLimit(reencryptOpts.concurrency)
+	for _, name := range names {
+		g.Go(func() error {
+			version, err := reencryptParameter(ctx, ssmClient, name)
+			n := done.Add(1)
+			if err != nil {
+				failed.Add(1)
+				fmt.Fprintf(os.Stderr, "[%d/%d] Failed to re-encrypt %s: %v\n", n, len(names), name, err)
+				return nil
+			}
+			fmt.Printf("[%d/%d] Re-encrypted %s (version %d)\n", n, len(names), name, version)
+			return nil
+		})
+	}
+	// The goroutines never return an error since a failure shouldn't stop the other parameters being re-encrypted.
+	_ = g.Wait()
+
+	return int(failed.Load())
+}
+
+// validateReencryptOptions validates the reencrypt command options.
+func validateReencryptOptions() error {
+	if reencryptOpts.newKeyID == "" {
+		return errKeyIDRequired
+	}
+	if reencryptOpts.concurrency < 1 {
+		return newInvalidConcurrencyError(reencryptOpts.concurrency)
+	}
+	return nil
+}
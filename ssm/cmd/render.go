@@ -0,0 +1,173 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+
+	"github.com/MakeNowJust/heredoc/v2"
+	"github.com/jim-barber-he/go/aws"
+	"github.com/spf13/cobra"
+)
+
+// renderReference matches a reference to a parameter in a template, such as `{{ssm:/helm/dev/api/host}}`.
+// Spaces are allowed inside the braces, and the first submatch is the path of the parameter.
+var renderReference = regexp.MustCompile(`\{\{\s*ssm:([^}\s]+)\s*\}\}`)
+
+// Commandline options.
+type renderOptions struct {
+	output string
+}
+
+var renderLong = heredoc.Doc(`
+	Render a template file by replacing the references to parameters in it with their values from the SSM parameter
+	store, so that application config files can be generated directly from the parameter store.
+
+	A reference is written as {{ssm:PATH}}, such as {{ssm:/helm/prod1/api/db_host}}. Spaces are allowed inside the
+	braces. Relative paths are looked for below the path for the environment, the same as for the get command.
+	A path can end with a selector for a label or version, such as {{ssm:api/db_host:current}} or
+	{{ssm:api/db_host:3}}.
+	Anything else in the file is left untouched, including other uses of {{ and }}, so templates used by other tools
+	can also contain references.
+
+	All of the parameters are fetched before anything is written, and an error is returned listing any that don't
+	exist or couldn't be decrypted.
+
+	The result is written to stdout, or to the file passed via --output, which is created readable only by its owner
+	since it may contain secrets.
+`)
+
+var (
+	// renderCmd represents the render command.
+	renderCmd = &cobra.Command{
+		Use:   "render [flags] ENVIRONMENT TEMPLATE_FILE",
+		Short: "Replace references to parameters in a file with their values",
+		Long:  renderLong,
+		Args:  cobra.ExactArgs(2),
+		PreRunE: func(_ *cobra.Command, args []string) error {
+			return validateEnvironment(args[0])
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return doRender(cmd.Context(), args)
+		},
+		SilenceErrors: true,
+		ValidArgsFunction: func(
+			cmd *cobra.Command, args []string, toComplete string,
+		) ([]string, cobra.ShellCompDirective) {
+			return renderCompletionHelp(cmd, args, toComplete)
+		},
+	}
+
+	renderOpts renderOptions
+)
+
+func init() {
+	rootCmd.AddCommand(renderCmd)
+
+	renderCmd.Flags().StringVarP(
+		&renderOpts.output, "output", "o", "", "Write the result to a file instead of stdout",
+	)
+}
+
+// renderCompletionHelp provides shell completion help for the render command.
+func renderCompletionHelp(
+	_ *cobra.Command, args []string, _ string,
+) ([]string, cobra.ShellCompDirective) {
+	var completionHelp []string
+	switch {
+	case len(args) == 0:
+		completionHelp = cobra.AppendActiveHelp(completionHelp, "dev, test*, or prod*")
+	case len(args) == 1:
+		completionHelp = cobra.AppendActiveHelp(completionHelp, "The template file to render")
+		return completionHelp, cobra.ShellCompDirectiveDefault
+	default:
+		completionHelp = cobra.AppendActiveHelp(completionHelp, "No more arguments")
+	}
+	return completionHelp, cobra.ShellCompDirectiveNoFileComp
+}
+
+// doRender replaces the references to parameters in a template file with their values.
+// args[0] is the name of to AWS Profile to use when accessing the SSM parameter store.
+// args[1] is the template file to render.
+func doRender(ctx context.Context, args []string) error {
+	data, err := os.ReadFile(args[1])
+	if err != nil {
+		return fmt.Errorf("%w: %w", errReadFile, err)
+	}
+	template := string(data)
+	references, names := renderReferences(args[0], template)
+
+	values := make(map[string]string)
+	if len(names) > 0 {
+		profile := getAWSProfile(args[0])
+		cfg := aws.Login(ctx, &aws.LoginSessionDetails{Profile: profile, Region: getAWSRegion(args[0])})
+		ssmClient := aws.SSMClient(cfg, ssmClientOptions()...)
+
+		params, invalid, err := aws.SSMGetParameters(ctx, ssmClient, names, true)
+		if err != nil {
+			return fmt.Errorf("%w: %w", errGetSSMParameter, err)
+		}
+		for _, p := range params {
+			if p.Error != "" {
+				invalid = append(invalid, p.Name)
+				continue
+			}
+			// Key the values the same way as SSMGetParameters, so that references with a selector find their value.
+			values[p.Name+p.Selector] = p.Value
+		}
+		if len(invalid) > 0 {
+			return newRenderMissingError(invalid)
+		}
+	}
+
+	result, err := renderTemplate(template, references, values)
+	if err != nil {
+		return err
+	}
+
+	if renderOpts.output == "" {
+		fmt.Print(result)
+		return nil
+	}
+	if err := os.WriteFile(renderOpts.output, []byte(result), 0o600); err != nil {
+		return fmt.Errorf("%w: %w", errWriteFile, err)
+	}
+	return nil
+}
+
+// renderReferences maps each reference as written in a template to the fully qualified name of its parameter,
+// including any selector for a version or label, and returns the names of the parameters to fetch.
+func renderReferences(environment, template string) (map[string]string, []string) {
+	references := make(map[string]string)
+	var names []string
+	for _, match := range renderReference.FindAllStringSubmatch(template, -1) {
+		name := getSSMPath(environment, match[1])
+		references[match[1]] = name
+		if !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	return references, names
+}
+
+// renderTemplate replaces the references in a template with the values of their parameters, which are keyed by the
+// names returned by renderReferences.
+// An error listing the parameters is returned if any of the references don't have a value, rather than quietly
+// replacing them with nothing.
+func renderTemplate(template string, references, values map[string]string) (string, error) {
+	var missing []string
+	result := renderReference.ReplaceAllStringFunc(template, func(reference string) string {
+		name := references[renderReference.FindStringSubmatch(reference)[1]]
+		value, ok := values[name]
+		if !ok && !slices.Contains(missing, name) {
+			missing = append(missing, name)
+		}
+		return value
+	})
+	if len(missing) > 0 {
+		return "", newRenderMissingError(missing)
+	}
+	return result, nil
+}
@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestRenderTemplate(t *testing.T) {
+	t.Parallel()
+
+	values := map[string]string{
+		"/helm/prod1/api/host":         "db.example.com",
+		"/helm/prod1/api/port:current": "5432",
+		"/helm/prod1/api/user:3":       "app",
+	}
+
+	tests := []struct {
+		name     string
+		template string
+		names    []string
+		expected string
+		wantErr  bool
+	}{
+		{
+			name:     "no references",
+			template: "host: {{ .Values.host }}\n",
+			expected: "host: {{ .Values.host }}\n",
+		},
+		{
+			name:     "plain",
+			template: "host: {{ssm:/helm/prod1/api/host}}\n",
+			names:    []string{"/helm/prod1/api/host"},
+			expected: "host: db.example.com\n",
+		},
+		{
+			name:     "label and version selectors",
+			template: "port: {{ ssm:/helm/prod1/api/port:current }}\nuser: {{ssm:/helm/prod1/api/user:3}}\n",
+			names:    []string{"/helm/prod1/api/port:current", "/helm/prod1/api/user:3"},
+			expected: "port: 5432\nuser: app\n",
+		},
+		{
+			name:     "repeated",
+			template: "{{ssm:/helm/prod1/api/host}} {{ssm:/helm/prod1/api/host}}",
+			names:    []string{"/helm/prod1/api/host"},
+			expected: "db.example.com db.example.com",
+		},
+		{
+			name:     "unresolved",
+			template: "port: {{ssm:/helm/prod1/api/port:previous}}\n",
+			names:    []string{"/helm/prod1/api/port:previous"},
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			references, names := renderReferences("prod1", tt.template)
+			if !slices.Equal(names, tt.names) {
+				t.Errorf("renderReferences() failed, expected %v, got %v", tt.names, names)
+			}
+			result, err := renderTemplate(tt.template, references, values)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("renderTemplate() failed, expected error %t, got %v", tt.wantErr, err)
+			}
+			if result != tt.expected {
+				t.Errorf("renderTemplate() failed, expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+}
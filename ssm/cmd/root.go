@@ -9,14 +9,18 @@ import (
 	"os"
 	"regexp"
 	"strings"
+	"time"
 
 	"github.com/MakeNowJust/heredoc/v2"
+	"github.com/jim-barber-he/go/aws"
 	"github.com/jim-barber-he/go/util"
 	"github.com/spf13/cobra"
 )
 
 // Commandline options.
 type rootOptions struct {
+	cache   time.Duration
+	maxRPS  float64
 	profile string
 	region  string
 }
@@ -26,7 +30,9 @@ var rootLong = heredoc.Doc(`
 
 	The tool is somewhat tailored to the environment at my workplace.
 
-	Each of the 'delete', 'get', 'list', and 'put' commands accepts an environment name as the first argument.
+	Each of the 'browse', 'delete', 'demote-from-secretsmanager', 'doctor', 'generate', 'get', 'label', 'list', 'move',
+	'policy', 'promote-to-secretsmanager', 'put', 'reencrypt', 'render', and 'watch' commands accepts an environment
+	name as the first argument.
 	This is one of 'dev', 'test*', or 'prod*'.
 	The command maps these to the 'hetest', 'hetest', or 'heaws' AWS profile respectively.
 
@@ -36,6 +42,35 @@ var rootLong = heredoc.Doc(`
 	The 'minikube' in the path is a legacy path for the development environments at my work place.
 	The '/helm/' prefix for all of them is a strange naming convention where the name of the product using these
 	parameters was used for the initial path.
+
+	These rules can be replaced by defining environments in a config file at 'ssm/config.yaml' in your user config
+	directory (such as ~/.config/ssm/config.yaml), or the file named by the SSM_CONFIG environment variable.
+	Each environment can set the AWS profile, AWS region, and SSM parameter store path to use, and environments
+	that aren't in the file keep using the rules above. For example:
+
+	  environments:
+	    - name: dev
+	      profile: hetest
+	      path: /helm/minikube
+	    - name: prod*
+	      profile: heaws
+	      region: ap-southeast-2
+	      path: /helm/{env}
+
+	A name ending in '*' matches all environments starting with the rest of the name, and '{env}' in a path is
+	replaced with the environment name.
+
+	AWS API calls that are throttled are retried with an exponential backoff. For large runs, such as listing
+	hundreds of parameters with --full, the --max-rps flag can also be used to limit the rate of the calls so that
+	they aren't throttled in the first place. The number of attempts and the retry mode can be changed with the
+	AWS_MAX_ATTEMPTS and AWS_RETRY_MODE environment variables, such as 'AWS_RETRY_MODE=adaptive' to also slow down
+	the calls on the client side once throttling is seen.
+
+	The --cache flag keeps the results of the list and get commands in your user cache directory (such as
+	~/.cache/ssm/) and reuses them for the given duration, such as '--cache 5m'. This avoids repeated AWS API calls
+	and SSO logins during an interactive session. The cache is cleared whenever this tool changes parameters, but
+	changes made elsewhere aren't seen until the cached results expire. The values of SecureString parameters are
+	never cached. They are fetched again each time, and the cached results aren't used if any of them have changed.
 `)
 
 // rootCmd represents the base command when called without any subcommands.
@@ -44,8 +79,9 @@ var (
 		Use:   "ssm",
 		Short: "Manipulate SSM parameter store entries",
 		Long:  rootLong,
-		PersistentPreRun: func(cmd *cobra.Command, _ []string) {
+		PersistentPreRunE: func(cmd *cobra.Command, _ []string) error {
 			cmd.SilenceUsage = true
+			return validateUserConfig()
 		},
 	}
 
@@ -81,18 +117,28 @@ func init() {
 	)
 	rootCmd.SetUsageTemplate(usageTemplate)
 
+	rootCmd.PersistentFlags().DurationVar(
+		&rootOpts.cache, "cache", 0, "Reuse list and get results fetched within this long, such as 5m (default disabled)",
+	)
+	rootCmd.PersistentFlags().Float64Var(
+		&rootOpts.maxRPS, "max-rps", 0, "Maximum AWS API requests per second to make (default unlimited)",
+	)
 	rootCmd.PersistentFlags().StringVar(&rootOpts.profile, "profile", "", "AWS profile to use")
 	rootCmd.PersistentFlags().StringVar(&rootOpts.region, "region", defaultRegion, "AWS region to use")
 }
 
 // getAWSProfile takes an environment name and returns an AWS Profile based on what is used at my workplace.
-// Note that if --profile was passed, then that will take precedence.
+// Note that if --profile was passed, then that will take precedence, followed by the profile in the config file.
 func getAWSProfile(environment string) string {
 	// The --profile command line option takes precedence.
 	if rootOpts.profile != "" {
 		return rootOpts.profile
 	}
 
+	if profile := getEnvironmentConfig(environment).Profile; profile != "" {
+		return profile
+	}
+
 	// Determine the AWS profile based on the environment.
 	switch {
 	case environment == "dev":
@@ -119,15 +165,50 @@ func getDefaultRegion() string {
 	}
 }
 
+// getAWSRegion takes an environment name and returns the AWS region to use for it.
+// If --region was passed then that takes precedence, followed by the region in the config file, and then the default
+// region from the environment variables.
+func getAWSRegion(environment string) string {
+	if rootCmd.PersistentFlags().Changed("region") {
+		return rootOpts.region
+	}
+	if region := getEnvironmentConfig(environment).Region; region != "" {
+		return region
+	}
+	return rootOpts.region
+}
+
+// getRegions returns the regions passed via a command's --regions flag, or just the region for the environment if
+// there were none.
+func getRegions(environment string, regions []string) []string {
+	if len(regions) == 0 {
+		return []string{getAWSRegion(environment)}
+	}
+	return regions
+}
+
 // getSSMPath takes an environment name and a path to a location in the SSM parameter store
 // and then returns a potentially modified SSM parameter store path.
-// The results of these are based on rules used at my workplace.
+// The results of these are based on rules used at my workplace, unless the config file sets the path instead.
 func getSSMPath(environment, path string) string {
+	// A selector for a version or label of a parameter, such as `:current`, is kept as is since labels are
+	// case-sensitive.
+	if name, selector, found := strings.Cut(path, ":"); found {
+		return getSSMPath(environment, name) + ":" + selector
+	}
+
 	// Return fully qualified paths unmodified.
 	if strings.HasPrefix(path, "/") {
 		return path
 	}
 
+	if base := getConfigPath(environment); base != "" {
+		if path == "" {
+			return base
+		}
+		return fmt.Sprintf("%s/%s", base, strings.ToLower(path))
+	}
+
 	// dev parameters at my workplace are under the /helm/minikube/ SSM parameter store path.
 	if environment == "dev" {
 		environment = "minikube"
@@ -155,6 +236,11 @@ func getTerminalWidth() int {
 	return cols - 1
 }
 
+// ssmClientOptions returns the options for creating SSM clients based on the global command line options.
+func ssmClientOptions() []aws.SSMClientOption {
+	return []aws.SSMClientOption{aws.WithMaxRPS(rootOpts.maxRPS)}
+}
+
 // validateEnvironment checks that the environment name has valid syntax.
 // It uses the same rules as an AWS profile name.
 func validateEnvironment(environment string) error {
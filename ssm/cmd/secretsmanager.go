@@ -0,0 +1,351 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/MakeNowJust/heredoc/v2"
+	smtypes "github.com/aws/aws-sdk-go-v2/service/secretsmanager/types"
+	"github.com/aws/aws-sdk-go-v2/service/ssm/types"
+	"github.com/jim-barber-he/go/aws"
+	"github.com/spf13/cobra"
+)
+
+// Commandline options.
+type secretsManagerOptions struct {
+	dryRun    bool
+	keyID     string
+	overwrite bool
+	recursive bool
+}
+
+var demoteLong = heredoc.Doc(`
+	Copy a secret from AWS Secrets Manager into the SSM parameter store, the reverse of promote-to-secretsmanager.
+
+	PARAMETER is the name of the parameter to store the secret in, and the secret copied is named after it without
+	the leading slash, the same way that promote-to-secretsmanager names secrets. The value and description of the
+	secret are stored in a SecureString parameter encrypted with the KMS key passed via --key-id.
+
+	Passing the --recursive flag copies every secret whose name is below the path of PARAMETER instead.
+	Existing parameters are left alone unless --overwrite is passed, in which case a new version of the parameter is
+	stored. Secrets holding binary data rather than a string can't be stored in a parameter, so they are reported
+	and skipped.
+
+	The --dry-run flag lists the secrets that would be copied without changing anything.
+`)
+
+var promoteLong = heredoc.Doc(`
+	Copy a parameter from the SSM parameter store into an AWS Secrets Manager secret, to help migrate between the two.
+
+	The secret is named after the parameter without its leading slash, so '/helm/minikube/api/db_password' is copied
+	to the 'helm/minikube/api/db_password' secret. The value and description of the parameter are copied, and the
+	secret is encrypted with the KMS key passed via --key-id, or the Secrets Manager default key if it isn't passed.
+	The tags and policies of the parameter aren't copied.
+
+	Passing the --recursive flag copies every parameter below the path PARAMETER, at any depth, instead.
+	Existing secrets are left alone unless --overwrite is passed, in which case a new version of the secret is stored.
+	Parameters that can't be decrypted are reported and skipped.
+
+	The --dry-run flag lists the parameters that would be copied without changing anything.
+`)
+
+var (
+	// demoteCmd represents the demote-from-secretsmanager command.
+	demoteCmd = &cobra.Command{
+		Use:   "demote-from-secretsmanager [flags] ENVIRONMENT PARAMETER",
+		Short: "Copy secrets from AWS Secrets Manager into the SSM parameter store",
+		Long:  demoteLong,
+		Args:  cobra.ExactArgs(2),
+		PreRunE: func(_ *cobra.Command, args []string) error {
+			return validateEnvironment(args[0])
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return doDemote(cmd.Context(), args)
+		},
+		SilenceErrors: true,
+		ValidArgsFunction: func(
+			cmd *cobra.Command, args []string, toComplete string,
+		) ([]string, cobra.ShellCompDirective) {
+			return secretsManagerCompletionHelp(cmd, args, toComplete)
+		},
+	}
+
+	// promoteCmd represents the promote-to-secretsmanager command.
+	promoteCmd = &cobra.Command{
+		Use:   "promote-to-secretsmanager [flags] ENVIRONMENT PARAMETER",
+		Short: "Copy parameters from the SSM parameter store into AWS Secrets Manager",
+		Long:  promoteLong,
+		Args:  cobra.ExactArgs(2),
+		PreRunE: func(_ *cobra.Command, args []string) error {
+			return validateEnvironment(args[0])
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return doPromote(cmd.Context(), args)
+		},
+		SilenceErrors: true,
+		ValidArgsFunction: func(
+			cmd *cobra.Command, args []string, toComplete string,
+		) ([]string, cobra.ShellCompDirective) {
+			return secretsManagerCompletionHelp(cmd, args, toComplete)
+		},
+	}
+
+	demoteOpts  secretsManagerOptions
+	promoteOpts secretsManagerOptions
+)
+
+func init() {
+	rootCmd.AddCommand(demoteCmd)
+	rootCmd.AddCommand(promoteCmd)
+
+	demoteCmd.Flags().BoolVar(
+		&demoteOpts.dryRun, "dry-run", false, "Report what would be copied without changing anything",
+	)
+	demoteCmd.Flags().StringVar(
+		&demoteOpts.keyID, "key-id", defaultKeyID, "The ID or alias of the KMS key to encrypt the parameters with",
+	)
+	demoteCmd.Flags().BoolVar(
+		&demoteOpts.overwrite, "overwrite", false, "Store a new version of parameters that already exist",
+	)
+	demoteCmd.Flags().BoolVarP(
+		&demoteOpts.recursive, "recursive", "r", false, "Copy all of the secrets below the path of PARAMETER",
+	)
+
+	promoteCmd.Flags().BoolVar(
+		&promoteOpts.dryRun, "dry-run", false, "Report what would be copied without changing anything",
+	)
+	promoteCmd.Flags().StringVar(
+		&promoteOpts.keyID, "key-id", "", "The ID or alias of the KMS key to encrypt new secrets with",
+	)
+	promoteCmd.Flags().BoolVar(
+		&promoteOpts.overwrite, "overwrite", false, "Store a new version of secrets that already exist",
+	)
+	promoteCmd.Flags().BoolVarP(
+		&promoteOpts.recursive, "recursive", "r", false, "Copy all of the parameters below the path PARAMETER",
+	)
+}
+
+// secretsManagerCompletionHelp provides shell completion help for the promote and demote commands.
+func secretsManagerCompletionHelp(
+	cmd *cobra.Command, args []string, toComplete string,
+) ([]string, cobra.ShellCompDirective) {
+	var completionHelp []string
+	switch {
+	case len(args) == 0:
+		completionHelp = cobra.AppendActiveHelp(completionHelp, "dev, test*, or prod*")
+	case len(args) == 1:
+		return completeParameterPath(cmd, args[0], toComplete, "The path of the SSM parameter")
+	default:
+		completionHelp = cobra.AppendActiveHelp(completionHelp, "No more arguments")
+	}
+	return completionHelp, cobra.ShellCompDirectiveNoFileComp
+}
+
+// doDemote copies secrets from AWS Secrets Manager into the SSM parameter store.
+// args[0] is the name of to AWS Profile to use when accessing the SSM parameter store and Secrets Manager.
+// args[1] is the path of the SSM parameter to copy the secret to, or the path to copy the secrets below.
+func doDemote(ctx context.Context, args []string) error {
+	profile := getAWSProfile(args[0])
+	region := getAWSRegion(args[0])
+	cfg := aws.Login(ctx, &aws.LoginSessionDetails{Profile: profile, Region: region})
+	ssmClient := aws.SSMClient(cfg, ssmClientOptions()...)
+	smClient := aws.SecretsManagerClient(cfg)
+	// Clear the cached results since they may be out of date once parameters are changed.
+	defer invalidateCache(profile, region)
+
+	if err := checkKeyID(ctx, aws.KMSClient(cfg), demoteOpts.keyID); err != nil {
+		return err
+	}
+
+	secretNames := []string{secretName(getSSMPath(args[0], args[1]))}
+	if demoteOpts.recursive {
+		secrets, err := aws.SecretsManagerList(ctx, smClient, strings.TrimSuffix(secretNames[0], "/")+"/")
+		if err != nil {
+			return fmt.Errorf("%w: %w", errListSecrets, err)
+		}
+		secretNames = secretNames[:0]
+		for _, s := range secrets {
+			secretNames = append(secretNames, s.Name)
+		}
+	}
+
+	var copied, failed, skipped int
+	for _, name := range secretNames {
+		done, err := demoteSecret(ctx, smClient, ssmClient, name)
+		switch {
+		case err != nil:
+			failed++
+			fmt.Fprintf(os.Stderr, "Failed to copy secret %s: %v\n", name, err)
+		case done:
+			copied++
+		default:
+			skipped++
+		}
+	}
+
+	if demoteOpts.recursive || demoteOpts.dryRun {
+		verb := "Copied"
+		if demoteOpts.dryRun {
+			verb = "Would copy"
+		}
+		fmt.Printf("%s %d secrets (%d skipped, %d failed)\n", verb, copied, skipped, failed)
+	}
+	if failed > 0 {
+		return newSecretsManagerCopyError(failed)
+	}
+
+	return nil
+}
+
+// doPromote copies parameters from the SSM parameter store into AWS Secrets Manager.
+// args[0] is the name of to AWS Profile to use when accessing the SSM parameter store and Secrets Manager.
+// args[1] is the path of the SSM parameter to copy, or the path to copy the parameters below.
+func doPromote(ctx context.Context, args []string) error {
+	profile := getAWSProfile(args[0])
+	region := getAWSRegion(args[0])
+	cfg := aws.Login(ctx, &aws.LoginSessionDetails{Profile: profile, Region: region})
+	ssmClient := aws.SSMClient(cfg, ssmClientOptions()...)
+	smClient := aws.SecretsManagerClient(cfg)
+
+	if promoteOpts.keyID != "" {
+		if err := checkKeyID(ctx, aws.KMSClient(cfg), promoteOpts.keyID); err != nil {
+			return err
+		}
+	}
+
+	names := []string{getSSMPath(args[0], args[1])}
+	if promoteOpts.recursive {
+		var err error
+		names, err = aws.SSMListNames(ctx, ssmClient, names[0], true)
+		if err != nil {
+			return fmt.Errorf("%w: %w", errListSSMParameters, err)
+		}
+	}
+
+	var copied, failed, skipped int
+	for _, name := range names {
+		done, err := promoteParameter(ctx, ssmClient, smClient, name)
+		switch {
+		case err != nil:
+			failed++
+			fmt.Fprintf(os.Stderr, "Failed to copy parameter %s: %v\n", name, err)
+		case done:
+			copied++
+		default:
+			skipped++
+		}
+	}
+
+	if promoteOpts.recursive || promoteOpts.dryRun {
+		verb := "Copied"
+		if promoteOpts.dryRun {
+			verb = "Would copy"
+		}
+		fmt.Printf("%s %d parameters (%d skipped, %d failed)\n", verb, copied, skipped, failed)
+	}
+	if failed > 0 {
+		return newSecretsManagerCopyError(failed)
+	}
+
+	return nil
+}
+
+// demoteSecret copies a secret into the SSM parameter store.
+// It returns false if the parameter was skipped because it already exists.
+func demoteSecret(
+	ctx context.Context, smClient aws.SecretsManagerAPI, ssmClient aws.SSMAPI, name string,
+) (bool, error) {
+	secret, err := aws.SecretsManagerGet(ctx, smClient, name)
+	if err != nil {
+		return false, fmt.Errorf("%w: %w", errGetSecret, err)
+	}
+
+	param := parameterName(name)
+	existing, err := aws.SSMGet(ctx, ssmClient, param)
+	if err == nil && !demoteOpts.overwrite {
+		fmt.Printf("Parameter %s already exists, skipped\n", param)
+		return false, nil
+	}
+	var notFound *types.ParameterNotFound
+	if err != nil && !errors.As(err, &notFound) {
+		return false, fmt.Errorf("%w: %w", errGetSSMParameter, err)
+	}
+
+	if demoteOpts.dryRun {
+		fmt.Printf("Would copy secret %s to parameter %s\n", name, param)
+		return true, nil
+	}
+
+	ssmParam := aws.SSMParameter{
+		Description: secret.Description,
+		KeyID:       demoteOpts.keyID,
+		Name:        param,
+		Type:        typeSecureString,
+		Value:       secret.Value,
+	}
+	setPutTier(&ssmParam, existing)
+	version, err := aws.SSMPut(ctx, ssmClient, &ssmParam)
+	if err != nil {
+		return false, fmt.Errorf("%w: %w", errPutSSMParameter, err)
+	}
+	fmt.Printf("Secret %s copied to parameter %s version %d\n", name, param, version)
+
+	return true, nil
+}
+
+// promoteParameter copies a parameter into AWS Secrets Manager.
+// It returns false if the secret was skipped because it already exists.
+func promoteParameter(
+	ctx context.Context, ssmClient aws.SSMAPI, smClient aws.SecretsManagerAPI, name string,
+) (bool, error) {
+	p, err := aws.SSMGet(ctx, ssmClient, name)
+	if err != nil {
+		return false, fmt.Errorf("%w: %w", errGetSSMParameter, err)
+	}
+	if p.Error != "" {
+		return false, fmt.Errorf("%w: %s", errDecryptSource, p.Error)
+	}
+
+	secret := secretName(p.Name)
+	_, err = aws.SecretsManagerGet(ctx, smClient, secret)
+	if err == nil && !promoteOpts.overwrite {
+		fmt.Printf("Secret %s already exists, skipped\n", secret)
+		return false, nil
+	}
+	var notFound *smtypes.ResourceNotFoundException
+	if err != nil && !errors.As(err, &notFound) {
+		return false, fmt.Errorf("%w: %w", errGetSecret, err)
+	}
+
+	if promoteOpts.dryRun {
+		fmt.Printf("Would copy parameter %s to secret %s\n", name, secret)
+		return true, nil
+	}
+
+	version, err := aws.SecretsManagerPut(ctx, smClient, &aws.SecretsManagerSecret{
+		Description: p.Description,
+		KeyID:       promoteOpts.keyID,
+		Name:        secret,
+		Value:       p.Value,
+	})
+	if err != nil {
+		return false, fmt.Errorf("%w: %w", errPutSecret, err)
+	}
+	fmt.Printf("Parameter %s copied to secret %s version %s\n", name, secret, version)
+
+	return true, nil
+}
+
+// parameterName returns the name of the SSM parameter that a secret is copied to.
+func parameterName(secretName string) string {
+	return "/" + strings.TrimPrefix(secretName, "/")
+}
+
+// secretName returns the name of the secret that an SSM parameter is copied to.
+// The leading slash is dropped since secret names are conventionally relative.
+func secretName(parameterName string) string {
+	return strings.TrimPrefix(parameterName, "/")
+}
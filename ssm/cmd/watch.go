@@ -0,0 +1,198 @@
+package cmd
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"time"
+
+	"github.com/MakeNowJust/heredoc/v2"
+	"github.com/jim-barber-he/go/aws"
+	"github.com/spf13/cobra"
+)
+
+// Change types reported by the watch command.
+const (
+	changeCreated = "CREATED"
+	changeDeleted = "DELETED"
+	changeUpdated = "UPDATED"
+)
+
+// Commandline options.
+type watchOptions struct {
+	interval  time.Duration
+	recursive bool
+}
+
+// parameterChange describes a change to a parameter seen between two polls of the SSM parameter store.
+type parameterChange struct {
+	change string
+	param  aws.SSMParameter
+}
+
+var watchLong = heredoc.Doc(`
+	Watch the parameters in the SSM parameter store below the supplied path and print a feed of the changes made.
+
+	The path is polled at the interval set by --interval, and each parameter that has been created, updated, or
+	deleted since the last poll is shown along with its version and the user that last modified it.
+	Changes made and then reverted between two polls are not seen.
+
+	By default it will only watch the parameters directly below the supplied path.
+	If the --recursive flag is used then it will also watch all parameters in the paths below the specified path.
+
+	If no PATH is passed at all, then for the 'dev', 'test*', and 'prod*' environments it will look in
+	'/helm/minikube/', '/helm/test*/', or '/helm/prod*/' respectively.
+
+	Press Ctrl-C to stop watching.
+`)
+
+var (
+	// watchCmd represents the watch command.
+	watchCmd = &cobra.Command{
+		Use:   "watch [flags] ENVIRONMENT [PATH]",
+		Short: "Print a feed of the changes made to parameters below a supplied path",
+		Long:  watchLong,
+		Args:  cobra.RangeArgs(1, 2),
+		PreRunE: func(_ *cobra.Command, args []string) error {
+			return validateEnvironment(args[0])
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return doWatch(cmd.Context(), args)
+		},
+		SilenceErrors: true,
+		ValidArgsFunction: func(
+			cmd *cobra.Command, args []string, toComplete string,
+		) ([]string, cobra.ShellCompDirective) {
+			return watchCompletionHelp(cmd, args, toComplete)
+		},
+	}
+
+	watchOpts watchOptions
+)
+
+func init() {
+	rootCmd.AddCommand(watchCmd)
+
+	watchCmd.Flags().DurationVarP(&watchOpts.interval, "interval", "i", 30*time.Second, "How often to poll for changes")
+	watchCmd.Flags().BoolVarP(
+		&watchOpts.recursive, "recursive", "r", false, "Watch all parameters below the parameter store path",
+	)
+}
+
+// watchCompletionHelp provides shell completion help for the watch command.
+func watchCompletionHelp(
+	cmd *cobra.Command, args []string, toComplete string,
+) ([]string, cobra.ShellCompDirective) {
+	var completionHelp []string
+	switch {
+	case len(args) == 0:
+		completionHelp = cobra.AppendActiveHelp(completionHelp, "dev, test*, or prod*")
+	case len(args) == 1:
+		return completeParameterPath(cmd, args[0], toComplete, "The path in the SSM parameter store to watch")
+	default:
+		completionHelp = cobra.AppendActiveHelp(completionHelp, "No more arguments")
+	}
+	return completionHelp, cobra.ShellCompDirectiveNoFileComp
+}
+
+// doWatch polls the SSM Parameter Store parameters below the specified path and prints the changes made to them.
+// args[0] is the name of to AWS Profile to use when accessing the SSM parameter store.
+// args[1] is the path in the SSM parameter store to watch.
+func doWatch(ctx context.Context, args []string) error {
+	if watchOpts.interval <= 0 {
+		return newInvalidIntervalError(watchOpts.interval.String())
+	}
+
+	profile := getAWSProfile(args[0])
+	cfg := aws.Login(ctx, &aws.LoginSessionDetails{Profile: profile, Region: getAWSRegion(args[0])})
+	// Watching can go on for longer than the AWS SSO token lasts.
+	aws.KeepSessionAlive(ctx, cfg)
+	ssmClient := aws.SSMClient(cfg, ssmClientOptions()...)
+
+	var path string
+	if len(args) > 1 {
+		path = getSSMPath(args[0], args[1])
+	} else {
+		path = getSSMPath(args[0], "")
+	}
+
+	previous, err := watchSnapshot(ctx, ssmClient, path)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Watching %d parameters below %s every %s\n", len(previous), path, watchOpts.interval)
+
+	ticker := time.NewTicker(watchOpts.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		current, err := watchSnapshot(ctx, ssmClient, path)
+		if err != nil {
+			// Keep watching since the error may be transient, such as being throttled.
+			log.Printf("Error: %v", err)
+			continue
+		}
+		for _, c := range parameterChanges(previous, current, time.Now()) {
+			printParameterChange(c)
+		}
+		previous = current
+	}
+}
+
+// parameterChanges compares two snapshots of the parameters and returns what changed between them sorted by name.
+// Deleted parameters have their last modified date set to `now` since the time of the deletion isn't known.
+func parameterChanges(previous, current map[string]aws.SSMParameter, now time.Time) []parameterChange {
+	var changes []parameterChange
+	for name, param := range current {
+		old, found := previous[name]
+		switch {
+		case !found:
+			changes = append(changes, parameterChange{change: changeCreated, param: param})
+		case param.Version != old.Version:
+			changes = append(changes, parameterChange{change: changeUpdated, param: param})
+		}
+	}
+	for name, param := range previous {
+		if _, found := current[name]; !found {
+			param.LastModifiedDate = now
+			param.LastModifiedUser = ""
+			changes = append(changes, parameterChange{change: changeDeleted, param: param})
+		}
+	}
+
+	slices.SortFunc(changes, func(a, b parameterChange) int {
+		return cmp.Compare(a.param.Name, b.param.Name)
+	})
+
+	return changes
+}
+
+// printParameterChange displays a single change to a parameter.
+func printParameterChange(c parameterChange) {
+	user := cmp.Or(c.param.LastModifiedUser, "-")
+	fmt.Printf(
+		"%s  %-7s  %s  v%d  %s\n",
+		c.param.LastModifiedDate.Local().Format(time.DateTime), c.change, c.param.Name, c.param.Version, user,
+	)
+}
+
+// watchSnapshot returns the current metadata of the parameters below the path, keyed by the parameter name.
+func watchSnapshot(ctx context.Context, ssmClient aws.SSMAPI, path string) (map[string]aws.SSMParameter, error) {
+	params, err := aws.SSMListMetadata(ctx, ssmClient, path, watchOpts.recursive)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errListSSMParameters, err)
+	}
+
+	snapshot := make(map[string]aws.SSMParameter, len(params))
+	for _, p := range params {
+		snapshot[p.Name] = p
+	}
+	return snapshot, nil
+}
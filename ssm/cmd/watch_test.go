@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/jim-barber-he/go/aws"
+)
+
+func TestParameterChanges(t *testing.T) {
+	t.Parallel()
+
+	modified := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	now := time.Date(2026, 3, 1, 9, 5, 0, 0, time.UTC)
+	param := func(name string, version int64) aws.SSMParameter {
+		return aws.SSMParameter{Name: name, Version: version, LastModifiedDate: modified, LastModifiedUser: "alice"}
+	}
+	snapshot := func(params ...aws.SSMParameter) map[string]aws.SSMParameter {
+		result := make(map[string]aws.SSMParameter, len(params))
+		for _, p := range params {
+			result[p.Name] = p
+		}
+		return result
+	}
+
+	tests := []struct {
+		name     string
+		previous map[string]aws.SSMParameter
+		current  map[string]aws.SSMParameter
+		expected []string
+	}{
+		{
+			name:     "unchanged",
+			previous: snapshot(param("/a", 1), param("/b", 2)),
+			current:  snapshot(param("/a", 1), param("/b", 2)),
+			expected: nil,
+		},
+		{
+			name:     "added",
+			previous: snapshot(param("/a", 1)),
+			current:  snapshot(param("/a", 1), param("/b", 1)),
+			expected: []string{changeCreated + " /b v1"},
+		},
+		{
+			name:     "removed",
+			previous: snapshot(param("/a", 1), param("/b", 3)),
+			current:  snapshot(param("/a", 1)),
+			expected: []string{changeDeleted + " /b v3"},
+		},
+		{
+			name:     "version changed",
+			previous: snapshot(param("/a", 1)),
+			current:  snapshot(param("/a", 2)),
+			expected: []string{changeUpdated + " /a v2"},
+		},
+		{
+			name:     "all at once sorted by name",
+			previous: snapshot(param("/c", 1), param("/b", 4), param("/d", 1)),
+			current:  snapshot(param("/a", 1), param("/b", 5), param("/d", 1)),
+			expected: []string{changeCreated + " /a v1", changeUpdated + " /b v5", changeDeleted + " /c v1"},
+		},
+		{
+			name:     "first poll",
+			previous: nil,
+			current:  snapshot(param("/a", 1)),
+			expected: []string{changeCreated + " /a v1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var got []string
+			for _, c := range parameterChanges(tt.previous, tt.current, now) {
+				got = append(got, c.change+" "+c.param.Name+" v"+strconv.FormatInt(c.param.Version, 10))
+
+				// Deletions aren't timestamped by the parameter store, so they are shown as happening at the poll.
+				wantDate, wantUser := modified, "alice"
+				if c.change == changeDeleted {
+					wantDate, wantUser = now, ""
+				}
+				if !c.param.LastModifiedDate.Equal(wantDate) || c.param.LastModifiedUser != wantUser {
+					t.Errorf(
+						"parameterChanges() failed for %s, expected %s by %q, got %s by %q",
+						c.param.Name, wantDate, wantUser, c.param.LastModifiedDate, c.param.LastModifiedUser,
+					)
+				}
+			}
+			if !slices.Equal(got, tt.expected) {
+				t.Errorf("parameterChanges() failed, expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
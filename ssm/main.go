@@ -5,7 +5,9 @@ package main
 
 import (
 	"context"
+	"errors"
 	"log"
+	"os"
 
 	"github.com/jim-barber-he/go/ssm/cmd"
 )
@@ -16,6 +18,13 @@ func main() {
 
 	ctx := context.Background()
 	if err := cmd.Execute(ctx); err != nil {
+		var exitErr *cmd.ExitError
+		if errors.As(err, &exitErr) {
+			if exitErr.Err != nil {
+				log.Printf("Error executing command: %v", exitErr.Err)
+			}
+			os.Exit(exitErr.Code)
+		}
 		log.Fatalf("Error executing command: %v", err)
 	}
 }
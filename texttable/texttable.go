@@ -4,11 +4,15 @@ Package texttable provides functions for handling outputting a text based table.
 package texttable
 
 import (
+	"bytes"
 	"fmt"
+	"io"
 	"os"
 	"reflect"
 	"strings"
 	"text/tabwriter"
+
+	"golang.org/x/term"
 )
 
 const (
@@ -19,6 +23,31 @@ const (
 	tableTabWidth = 8
 )
 
+// Colour is an ANSI escape sequence used to colour the value of a table cell.
+type Colour string
+
+// Colours that can be applied to table cells.
+const (
+	ColourNone   Colour = ""
+	ColourGreen  Colour = "\033[32m"
+	ColourRed    Colour = "\033[31m"
+	ColourYellow Colour = "\033[33m"
+
+	colourReset = "\033[0m"
+)
+
+// plainNone is shown in a Plain table in place of an empty value of a row implementing the FieldFormatter interface.
+const plainNone = "<none>"
+
+// Apply returns the string wrapped in the colour, or the string unchanged for ColourNone.
+func (c Colour) Apply(s string) string {
+	if c == ColourNone {
+		return s
+	}
+
+	return string(c) + s + colourReset
+}
+
 // TableFormatter interface that a table row struct needs to implement for the table.Write() method to use it.
 // Both of these methods need to return a string containing tab separated row values for the tabwriter module to use.
 type TableFormatter interface {
@@ -26,9 +55,37 @@ type TableFormatter interface {
 	TabValues() string
 }
 
+// ColourFormatter is an optional interface that a table row struct can implement to colour its cells.
+// The TabColours method needs to return a colour for each of the values returned by the TabValues method.
+type ColourFormatter interface {
+	TabColours() []Colour
+}
+
+// FieldFormatter is an optional interface that a table row struct can implement to keep its columns in place in a
+// Plain table. The TabFields method needs to return every column that the row can have, including the empty ones.
+type FieldFormatter interface {
+	TabFields() []Field
+}
+
+// Field is a column of a row as returned by the TabFields method of the FieldFormatter interface.
+// A column with OmitEmpty set is left out of a Plain table when none of its rows have a value for it.
+type Field struct {
+	Title     string
+	Value     string
+	OmitEmpty bool
+}
+
 // Table is a generic struct for representing a table with a slice of rows.
+// If Colour is set, then the cells of rows that implement the ColourFormatter interface are coloured.
+// If NoHeaders is set, then the title row is not displayed.
+// If Plain is set, then the values are separated by a single tab instead of being aligned into columns, and are never
+// coloured. This suits processing the output with tools like awk and cut. For rows that implement the FieldFormatter
+// interface, empty values are shown as <none> so that each column is always in the same place.
 type Table[R TableFormatter] struct {
-	Rows []R
+	Rows      []R
+	Colour    bool
+	NoHeaders bool
+	Plain     bool
 }
 
 // Append adds a new row to existing rows in a table.
@@ -38,23 +95,185 @@ func (t *Table[R]) Append(r R) {
 
 // Write displays the table to stdout.
 func (t *Table[R]) Write() {
+	t.write(os.Stdout)
+}
+
+// write displays the table to the supplied writer.
+// The colours are applied after the tabwriter has aligned the columns since it would otherwise count the escape
+// sequences as part of the width of the cells.
+func (t *Table[R]) write(w io.Writer) {
 	if len(t.Rows) == 0 {
 		return
 	}
 
-	tw := tabwriter.NewWriter(os.Stdout, tableMinWidth, tableTabWidth, tablePadding, tablePadChar, tableFlags)
-	fmt.Fprintln(tw, t.Rows[0].TabTitleRow())
+	if t.Plain {
+		t.writePlain(w)
+		return
+	}
+
+	var buf bytes.Buffer
+	out := w
+	if t.Colour {
+		out = &buf
+	}
+
+	tw := tabwriter.NewWriter(out, tableMinWidth, tableTabWidth, tablePadding, tablePadChar, tableFlags)
+	if !t.NoHeaders {
+		fmt.Fprintln(tw, t.Rows[0].TabTitleRow())
+	}
 	for _, row := range t.Rows {
 		fmt.Fprintln(tw, row.TabValues())
 	}
 	tw.Flush()
+
+	if !t.Colour {
+		return
+	}
+
+	lines := strings.SplitAfter(buf.String(), "\n")
+	if !t.NoHeaders {
+		fmt.Fprint(w, lines[0])
+		lines = lines[1:]
+	}
+	for i, row := range t.Rows {
+		fmt.Fprint(w, colourLine(lines[i], row))
+	}
 }
 
-// ReflectedTabValues outputs the field values of a struct separated by tabs. Empty fields are ignored.
+// writePlain displays the table to the supplied writer with the values separated by a single tab.
+// For rows that implement the FieldFormatter interface, the columns are chosen from all of the rows rather than just the
+// first, and empty values are replaced with plainNone.
+func (t *Table[R]) writePlain(w io.Writer) {
+	if _, ok := any(t.Rows[0]).(FieldFormatter); !ok {
+		if !t.NoHeaders {
+			fmt.Fprintln(w, t.Rows[0].TabTitleRow())
+		}
+		for _, row := range t.Rows {
+			fmt.Fprintln(w, row.TabValues())
+		}
+		return
+	}
+
+	rows := make([][]Field, len(t.Rows))
+	var show []bool
+	for i, row := range t.Rows {
+		rows[i] = any(row).(FieldFormatter).TabFields()
+		for j, field := range rows[i] {
+			if j == len(show) {
+				show = append(show, false)
+			}
+			show[j] = show[j] || !field.OmitEmpty || field.Value != ""
+		}
+	}
+
+	if !t.NoHeaders {
+		var titles []string
+		for j, field := range rows[0] {
+			if show[j] {
+				titles = append(titles, field.Title)
+			}
+		}
+		fmt.Fprintln(w, strings.Join(titles, "\t"))
+	}
+	for _, fields := range rows {
+		var values []string
+		for j, field := range fields {
+			if !show[j] {
+				continue
+			}
+			if field.Value == "" {
+				field.Value = plainNone
+			}
+			values = append(values, field.Value)
+		}
+		fmt.Fprintln(w, strings.Join(values, "\t"))
+	}
+}
+
+// colourLine wraps the values within an aligned line of the table with the colours of the row.
+// Each value is located by searching from the end of the previous one, since only padding separates them.
+func colourLine(line string, row TableFormatter) string {
+	cf, ok := row.(ColourFormatter)
+	if !ok {
+		return line
+	}
+	colours := cf.TabColours()
+
+	var sb strings.Builder
+	pos := 0
+	for i, value := range strings.Split(row.TabValues(), "\t") {
+		idx := strings.Index(line[pos:], value)
+		if value == "" || idx < 0 {
+			break
+		}
+		start := pos + idx
+		sb.WriteString(line[pos:start])
+		if i < len(colours) {
+			sb.WriteString(colours[i].Apply(value))
+		} else {
+			sb.WriteString(value)
+		}
+		pos = start + len(value)
+	}
+	sb.WriteString(line[pos:])
+
+	return sb.String()
+}
+
+// ColourEnabled returns true if colour should be used when writing to the supplied file.
+// Colour is disabled when the NO_COLOR environment variable is set (see https://no-color.org/) or the file is not a
+// terminal.
+func ColourEnabled(f *os.File) bool {
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+
+	return term.IsTerminal(int(f.Fd()))
+}
+
+// ReflectedTabColours returns the colours for the non-empty fields of a struct, in the same order as the values
+// returned by ReflectedTabValues.
+// The colours are looked up by field name, with fields not in the map being left uncoloured.
+func ReflectedTabColours[R any](row *R, colours map[string]Colour) []Colour {
+	var result []Colour
+	v := reflect.ValueOf(*row)
+	for i, sf := range reflect.VisibleFields(v.Type()) {
+		if !skipField(sf) && strings.TrimSpace(v.Field(i).String()) != "" {
+			result = append(result, colours[sf.Name])
+		}
+	}
+	return result
+}
+
+// ReflectedTabFields returns the columns of a struct for the FieldFormatter interface, including those with empty
+// values. The titles and whether they are omitted when empty come from the struct tag called 'title'.
+// Fields with a title tag of '-' are ignored.
+func ReflectedTabFields[R any](row *R) []Field {
+	var fields []Field
+	v := reflect.ValueOf(*row)
+	for i, sf := range reflect.VisibleFields(v.Type()) {
+		if skipField(sf) {
+			continue
+		}
+		title, option, _ := strings.Cut(sf.Tag.Get("title"), ",")
+		fields = append(fields, Field{
+			Title:     title,
+			Value:     strings.TrimSpace(v.Field(i).String()),
+			OmitEmpty: option == "omitempty",
+		})
+	}
+	return fields
+}
+
+// ReflectedTabValues outputs the field values of a struct separated by tabs.
+// Empty fields and fields with a title tag of '-' are ignored.
 func ReflectedTabValues[R any](row *R) string {
 	var s []string
 	v := reflect.ValueOf(*row)
-	for i := range v.NumField() {
+	for i, sf := range reflect.VisibleFields(v.Type()) {
+		if skipField(sf) {
+			continue
+		}
 		if str := strings.TrimSpace(v.Field(i).String()); str != "" {
 			s = append(s, str)
 		}
@@ -65,12 +284,16 @@ func ReflectedTabValues[R any](row *R) string {
 // ReflectedTitleRow returns a new struct based on the passed in struct with the field values populated via the struct
 // tag called 'title'.
 // If the field value of the passed in struct is unset and the title tag is set to 'omitempty' then do not include it.
+// Fields with a title tag of '-' are not included either.
 func ReflectedTitleRow[R any](row *R) string {
 	var result R
 	resultElem := reflect.ValueOf(&result).Elem()
 
 	v := reflect.ValueOf(*row)
 	for i, sf := range reflect.VisibleFields(v.Type()) {
+		if skipField(sf) {
+			continue
+		}
 		titleArray := strings.Split(sf.Tag.Get("title"), ",")
 		if len(titleArray) > 1 && titleArray[1] == "omitempty" && v.Field(i).String() == "" {
 			continue
@@ -79,3 +302,9 @@ func ReflectedTitleRow[R any](row *R) string {
 	}
 	return ReflectedTabValues(&result)
 }
+
+// skipField returns true if a struct field has a title tag of '-' meaning it is not a column of the table.
+// Such fields can hold data that a row uses to build extra columns itself.
+func skipField(sf reflect.StructField) bool {
+	return sf.Tag.Get("title") == "-"
+}
@@ -1,13 +1,16 @@
 package texttable
 
 import (
+	"bytes"
 	"fmt"
+	"slices"
 	"testing"
 )
 
 type Row struct {
 	Name  string `title:"NAME"`
 	Value string `title:"VALUE"`
+	Extra []int  `title:"-"`
 }
 
 // Implement the texttab.TableFormatter interface.
@@ -20,6 +23,54 @@ func (tr *Row) TabValues() string {
 	return ReflectedTabValues(tr)
 }
 
+type ColourRow struct {
+	Name   string `title:"NAME"`
+	Note   string `title:"NOTE,omitempty"`
+	Status string `title:"STATUS"`
+}
+
+// Implement the texttab.TableFormatter interface.
+func (tr *ColourRow) TabTitleRow() string {
+	return ReflectedTitleRow(tr)
+}
+
+// Implement the texttab.TableFormatter interface.
+func (tr *ColourRow) TabValues() string {
+	return ReflectedTabValues(tr)
+}
+
+// Implement the texttab.ColourFormatter interface.
+func (tr *ColourRow) TabColours() []Colour {
+	colour := ColourGreen
+	if tr.Status != "ok" {
+		colour = ColourRed
+	}
+	return ReflectedTabColours(tr, map[string]Colour{"Status": colour})
+}
+
+type FieldRow struct {
+	Name   string `title:"NAME"`
+	Note   string `title:"NOTE,omitempty"`
+	IP     string `title:"IP"`
+	Status string `title:"STATUS"`
+	Extra  []int  `title:"-"`
+}
+
+// Implement the texttab.TableFormatter interface.
+func (tr *FieldRow) TabTitleRow() string {
+	return ReflectedTitleRow(tr)
+}
+
+// Implement the texttab.TableFormatter interface.
+func (tr *FieldRow) TabValues() string {
+	return ReflectedTabValues(tr)
+}
+
+// Implement the texttab.FieldFormatter interface.
+func (tr *FieldRow) TabFields() []Field {
+	return ReflectedTabFields(tr)
+}
+
 func TestReflectedTitleRow(t *testing.T) {
 	t.Parallel()
 
@@ -40,7 +91,7 @@ func TestReflectedTabValues(t *testing.T) {
 	t.Run("ReflectedTabValues", func(t *testing.T) {
 		t.Parallel()
 
-		row := &Row{Name: "a", Value: "b"}
+		row := &Row{Name: "a", Value: "b", Extra: []int{1}}
 		expected := "a\tb"
 		if row.TabValues() != expected {
 			t.Errorf("TabValues() failed, expected %s, got %s", expected, row.TabValues())
@@ -77,3 +128,185 @@ func TestAppend(t *testing.T) {
 		}
 	})
 }
+
+func TestColourApply(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Apply", func(t *testing.T) {
+		t.Parallel()
+
+		if got := ColourNone.Apply("a"); got != "a" {
+			t.Errorf("Apply() failed, expected %q, got %q", "a", got)
+		}
+		if got, expected := ColourRed.Apply("a"), "\033[31ma\033[0m"; got != expected {
+			t.Errorf("Apply() failed, expected %q, got %q", expected, got)
+		}
+	})
+}
+
+func TestReflectedTabColours(t *testing.T) {
+	t.Parallel()
+
+	t.Run("ReflectedTabColours", func(t *testing.T) {
+		t.Parallel()
+
+		row := &ColourRow{Name: "a", Status: "ok"}
+		colours := row.TabColours()
+		expected := []Colour{ColourNone, ColourGreen}
+		if fmt.Sprint(colours) != fmt.Sprint(expected) {
+			t.Errorf("TabColours() failed, expected %q, got %q", expected, colours)
+		}
+	})
+}
+
+func TestWriteColour(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		colour   bool
+		expected string
+	}{
+		{
+			colour:   false,
+			expected: "NAME  STATUS\na     ok\nlong  failed\n",
+		},
+		{
+			colour:   true,
+			expected: "NAME  STATUS\na     \033[32mok\033[0m\nlong  \033[31mfailed\033[0m\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run("WriteColour", func(t *testing.T) {
+			t.Parallel()
+
+			tbl := Table[*ColourRow]{
+				Rows: []*ColourRow{
+					{Name: "a", Status: "ok"},
+					{Name: "long", Status: "failed"},
+				},
+				Colour: tt.colour,
+			}
+			var buf bytes.Buffer
+			tbl.write(&buf)
+			if buf.String() != tt.expected {
+				t.Errorf("write() failed, expected %q, got %q", tt.expected, buf.String())
+			}
+		})
+	}
+}
+
+func TestWriteOptions(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		table    Table[*ColourRow]
+		expected string
+	}{
+		{
+			name:     "NoHeaders",
+			table:    Table[*ColourRow]{NoHeaders: true},
+			expected: "a     ok\nlong  failed\n",
+		},
+		{
+			name:     "NoHeadersColour",
+			table:    Table[*ColourRow]{NoHeaders: true, Colour: true},
+			expected: "a     \033[32mok\033[0m\nlong  \033[31mfailed\033[0m\n",
+		},
+		{
+			name:     "Plain",
+			table:    Table[*ColourRow]{Plain: true, Colour: true},
+			expected: "NAME\tSTATUS\na\tok\nlong\tfailed\n",
+		},
+		{
+			name:     "PlainNoHeaders",
+			table:    Table[*ColourRow]{Plain: true, NoHeaders: true},
+			expected: "a\tok\nlong\tfailed\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			tbl := tt.table
+			tbl.Rows = []*ColourRow{
+				{Name: "a", Status: "ok"},
+				{Name: "long", Status: "failed"},
+			}
+			var buf bytes.Buffer
+			tbl.write(&buf)
+			if buf.String() != tt.expected {
+				t.Errorf("write() failed, expected %q, got %q", tt.expected, buf.String())
+			}
+		})
+	}
+}
+
+func TestReflectedTabFields(t *testing.T) {
+	t.Parallel()
+
+	row := &FieldRow{Name: "a", Status: " ok "}
+	expected := []Field{
+		{Title: "NAME", Value: "a"},
+		{Title: "NOTE", OmitEmpty: true},
+		{Title: "IP"},
+		{Title: "STATUS", Value: "ok"},
+	}
+	if result := row.TabFields(); !slices.Equal(result, expected) {
+		t.Errorf("ReflectedTabFields() failed, expected %v, got %v", expected, result)
+	}
+}
+
+func TestWritePlainFields(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		table    Table[*FieldRow]
+		rows     []*FieldRow
+		expected string
+	}{
+		{
+			name:  "EmptyValues",
+			table: Table[*FieldRow]{Plain: true},
+			rows: []*FieldRow{
+				{Name: "a", IP: "10.0.0.1", Status: "ok"},
+				{Name: "b", Status: "pending"},
+			},
+			expected: "NAME\tIP\tSTATUS\na\t10.0.0.1\tok\nb\t<none>\tpending\n",
+		},
+		{
+			name:  "OmitEmptyFromLaterRow",
+			table: Table[*FieldRow]{Plain: true},
+			rows: []*FieldRow{
+				{Name: "a", IP: "10.0.0.1", Status: "ok"},
+				{Name: "b", Note: "slow", IP: "10.0.0.2", Status: "ok"},
+			},
+			expected: "NAME\tNOTE\tIP\tSTATUS\na\t<none>\t10.0.0.1\tok\nb\tslow\t10.0.0.2\tok\n",
+		},
+		{
+			name:  "NoHeaders",
+			table: Table[*FieldRow]{Plain: true, NoHeaders: true},
+			rows: []*FieldRow{
+				{Name: "a", Status: "ok"},
+			},
+			expected: "a\t<none>\tok\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			tbl := tt.table
+			tbl.Rows = tt.rows
+			var buf bytes.Buffer
+			tbl.write(&buf)
+			if buf.String() != tt.expected {
+				t.Errorf("write() failed, expected %q, got %q", tt.expected, buf.String())
+			}
+		})
+	}
+}
@@ -17,6 +17,14 @@ func (e *Error) Error() string {
 }
 
 var (
-	errCommandTimedOut = errors.New("command timed out")
-	errTerminalSize    = errors.New("failed to get terminal size")
+	// ErrCommandKilled is returned by RunWithTimeoutAndSignals() if it killed the process for not exiting within the
+	// grace period after a signal was forwarded to it.
+	ErrCommandKilled = errors.New("command killed after not exiting within its signal grace period")
+	// ErrCommandTimedOut is returned if the process was killed for exceeding its timeout.
+	ErrCommandTimedOut = errors.New("command timed out")
+
+	errEmptyCharset     = errors.New("the charset to pick random characters from is empty")
+	errRandom           = errors.New("failed to generate random data")
+	errReadConfirmation = errors.New("failed to read confirmation")
+	errTerminalSize     = errors.New("failed to get terminal size")
 )
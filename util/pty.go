@@ -0,0 +1,91 @@
+package util
+
+import (
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/creack/pty"
+	"golang.org/x/term"
+)
+
+// ptyDrainTimeout is how long to wait for the remaining output of a command run in a pseudo-terminal after it exits.
+// Background processes left running by the command keep the terminal open, so this can't wait forever.
+const ptyDrainTimeout = time.Second
+
+// eot is the character that tells a terminal the end of its input has been reached. (Ctrl-D)
+const eot = 4
+
+// startWithPTY starts the process in a new session attached to a pseudo-terminal.
+// This process' stdin is copied to the terminal, and the terminal's output is copied to stdout.
+// If this process' stdin is a terminal, it is put into raw mode so that keystrokes are passed on as is, and size
+// changes are passed on to the command's terminal.
+// Returns a function that waits for the process to exit and then cleans up the pseudo-terminal.
+func startWithPTY(process *exec.Cmd, stdout io.Writer) (func() error, error) {
+	ptmx, err := pty.Start(process)
+	if err != nil {
+		return nil, err
+	}
+
+	stdinFd := int(os.Stdin.Fd())
+	isTerminal := term.IsTerminal(stdinFd)
+
+	var restore func()
+	winch := make(chan os.Signal, 1)
+	if isTerminal {
+		if err := pty.InheritSize(os.Stdin, ptmx); err != nil {
+			log.Println("Failed to set terminal size:", err)
+		}
+		signal.Notify(winch, syscall.SIGWINCH)
+		go func() {
+			for range winch {
+				if err := pty.InheritSize(os.Stdin, ptmx); err != nil {
+					log.Println("Failed to set terminal size:", err)
+				}
+			}
+		}()
+
+		if state, err := term.MakeRaw(stdinFd); err == nil {
+			restore = func() { _ = term.Restore(stdinFd, state) }
+		}
+	}
+
+	go func() {
+		_, _ = io.Copy(ptmx, os.Stdin)
+		// Pass on the end of piped input, since the terminal doesn't know about it otherwise.
+		if !isTerminal {
+			_, _ = ptmx.Write([]byte{eot})
+		}
+	}()
+
+	copied := make(chan struct{})
+	go func() {
+		// This ends with an error once the terminal is closed, which is expected.
+		_, _ = io.Copy(stdout, ptmx)
+		close(copied)
+	}()
+
+	wait := func() error {
+		err := process.Wait()
+
+		select {
+		case <-copied:
+		case <-time.After(ptyDrainTimeout):
+		}
+		_ = ptmx.Close()
+
+		signal.Stop(winch)
+		close(winch)
+		if restore != nil {
+			restore()
+		}
+
+		return err
+	}
+
+	return wait, nil
+}
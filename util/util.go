@@ -4,14 +4,21 @@ Package util provides various utility functions.
 package util
 
 import (
+	"bufio"
 	"context"
+	"crypto/rand"
 	"errors"
 	"fmt"
+	"io"
 	"log"
+	"math/big"
 	"os"
 	"os/exec"
+	"os/signal"
+	"runtime/debug"
 	"strconv"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 
@@ -30,6 +37,59 @@ const (
 	tabStopWidth = 8
 )
 
+// Confirm writes a yes/no prompt to out and reads the answer from in, returning true if it was 'y' or 'yes'.
+// Any other answer, including an empty one or reaching the end of the input, is treated as no.
+func Confirm(in io.Reader, out io.Writer, prompt string) (bool, error) {
+	fmt.Fprintf(out, "%s [y/N] ", prompt)
+
+	answer, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return false, fmt.Errorf("%w: %w", errReadConfirmation, err)
+	}
+	if errors.Is(err, io.EOF) && answer == "" {
+		// Move off the prompt line since the user never pressed enter.
+		fmt.Fprintln(out)
+	}
+
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true, nil
+	default:
+		return false, nil
+	}
+}
+
+// DisplayVersion prints the version of the named program along with the commit and date that it was built from.
+// These details come from the build information that the Go toolchain embeds in the binary.
+func DisplayVersion(name string) {
+	version, commit, date := "unknown", "unknown", "unknown"
+	modified := false
+
+	if info, ok := debug.ReadBuildInfo(); ok {
+		version = info.Main.Version
+		for _, setting := range info.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				commit = setting.Value
+			case "vcs.time":
+				date = setting.Value
+			case "vcs.modified":
+				modified = setting.Value == "true"
+			}
+		}
+	}
+
+	fmt.Println(formatVersion(name, version, commit, date, modified))
+}
+
+// formatVersion returns the version details of a program as a single line.
+func formatVersion(name, version, commit, date string, modified bool) string {
+	if modified {
+		commit += "-dirty"
+	}
+	return fmt.Sprintf("%s version %s, commit %s, built %s", name, version, commit, date)
+}
+
 // FormatAge returns the age in a human readable format of the first 2 non-zero time units from weeks to seconds,
 // or just the seconds if no higher time unit was above 0.
 // This differs from duration.String() in that it also handles weeks and days.
@@ -117,6 +177,32 @@ func GetEnvInt(envVar string, defaultValue int) int {
 	return defaultValue
 }
 
+// GetEnvDuration returns the value of an environment variable as a duration.
+// The value can be a duration string such as "90s" or "2h30m", or a bare integer number of seconds.
+// If the value is not set, then the supplied default value will be returned instead.
+func GetEnvDuration(envVar string, defaultValue time.Duration) time.Duration {
+	if val, exists := os.LookupEnv(envVar); exists {
+		if ret, err := strconv.Atoi(val); err == nil {
+			return time.Duration(ret) * time.Second
+		}
+		if ret, err := time.ParseDuration(val); err == nil {
+			return ret
+		}
+	}
+	return defaultValue
+}
+
+// GetEnvFloat returns the value of an environment variable as a float.
+// If the value is not set, then the supplied default value will be returned instead.
+func GetEnvFloat(envVar string, defaultValue float64) float64 {
+	if val, exists := os.LookupEnv(envVar); exists {
+		if ret, err := strconv.ParseFloat(val, 64); err == nil {
+			return ret
+		}
+	}
+	return defaultValue
+}
+
 // LastSplitItem splits a string into a slice based on a split character and returns the last item.
 func LastSplitItem(str, splitChar string) string {
 	result := strings.Split(str, splitChar)
@@ -126,6 +212,26 @@ func LastSplitItem(str, splitChar string) string {
 	return ""
 }
 
+// RandomString returns a cryptographically random string of length characters picked from the charset.
+// Each character in the charset is equally likely to be picked, so it is suitable for generating secrets.
+func RandomString(length int, charset string) (string, error) {
+	chars := []rune(charset)
+	if len(chars) == 0 {
+		return "", errEmptyCharset
+	}
+
+	maxIndex := big.NewInt(int64(len(chars)))
+	result := make([]rune, length)
+	for i := range result {
+		n, err := rand.Int(rand.Reader, maxIndex)
+		if err != nil {
+			return "", fmt.Errorf("%w: %w", errRandom, err)
+		}
+		result[i] = chars[n.Int64()]
+	}
+	return string(result), nil
+}
+
 // RunWithTimeout executes a command with a timeout.
 // If the timeout is set to 0 then there is no timeout.
 // Returns an integer suitable for use as an exit code, and an error.
@@ -147,7 +253,7 @@ func RunWithTimeout(timeout int, command string, args ...string) (int, error) {
 		if err := syscall.Kill(-process.Process.Pid, syscall.SIGKILL); err != nil {
 			log.Println("Failed to kill process:", err)
 		}
-		return ExitCodeProcessKilled, errCommandTimedOut
+		return ExitCodeProcessKilled, ErrCommandTimedOut
 	}
 	if err != nil {
 		var exitError *exec.ExitError
@@ -160,6 +266,155 @@ func RunWithTimeout(timeout int, command string, args ...string) (int, error) {
 	return 0, nil
 }
 
+// RunOptions holds the options for RunWithTimeoutAndSignals().
+type RunOptions struct {
+	// Timeout is how many seconds the command can run for before it is killed. 0 means there is no timeout.
+	Timeout int
+	// GracePeriod is how many seconds the command has to exit after a signal has been forwarded to it before it is
+	// killed. 0 means there is no limit on how long the command can take to exit.
+	GracePeriod int
+	// Output, if not nil, has the command's stdout and stderr written to it as well.
+	Output io.Writer
+	// Started, if not nil, is called with the PID of the command once it has started.
+	Started func(pid int)
+	// PTY runs the command attached to a pseudo-terminal, for commands that behave differently without one or that
+	// need to read from the terminal.
+	// The command's stdout and stderr are combined and written to stdout when it is set.
+	PTY bool
+	// Env, if not nil, is the environment of the command instead of the environment of this process.
+	Env []string
+}
+
+// RunWithTimeoutAndSignals executes a command with a timeout like RunWithTimeout() does, but SIGINT and SIGTERM
+// received by this process are forwarded to the command's process group instead of terminating this process.
+// Returns an integer suitable for use as an exit code, and an error.
+func RunWithTimeoutAndSignals(opts RunOptions, command string, args ...string) (int, error) {
+	process := exec.Command(command, args...)
+	process.Env = opts.Env
+	stdout, stderr := io.Writer(os.Stdout), io.Writer(os.Stderr)
+	if opts.Output != nil {
+		stdout = io.MultiWriter(os.Stdout, opts.Output)
+		stderr = io.MultiWriter(os.Stderr, opts.Output)
+	}
+
+	// Start listening for signals before the command starts so none are missed.
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	// A command in a pseudo-terminal is the leader of its own session, and hence its own process group too.
+	wait := process.Wait
+	var err error
+	if opts.PTY {
+		wait, err = startWithPTY(process, stdout)
+	} else {
+		// The command is put in a process group of its own so that signals can be forwarded to it, and only the
+		// foreground process group can read from a terminal without being stopped by SIGTTIN.
+		// So stdin is only passed through when it is a pipe or file. The PTY option suits interactive commands.
+		if !term.IsTerminal(int(os.Stdin.Fd())) {
+			process.Stdin = os.Stdin
+		}
+		process.Stdout = stdout
+		process.Stderr = stderr
+		process.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+		err = process.Start()
+	}
+	if err != nil {
+		return 1, fmt.Errorf("process run error: %w", err)
+	}
+	pgid := -process.Process.Pid
+	if opts.Started != nil {
+		opts.Started(process.Process.Pid)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- wait()
+	}()
+
+	// A nil channel blocks forever, so these only fire once they have been set.
+	var timeoutC, graceC <-chan time.Time
+	if opts.Timeout > 0 {
+		timer := time.NewTimer(time.Duration(opts.Timeout) * time.Second)
+		defer timer.Stop()
+		timeoutC = timer.C
+	}
+
+	for {
+		select {
+		case err := <-done:
+			return exitCodeFromError(err)
+		case sig := <-signals:
+			log.Printf("Forwarding %s to process group %d", sig, -pgid)
+			if err := syscall.Kill(pgid, sig.(syscall.Signal)); err != nil {
+				log.Println("Failed to forward signal:", err)
+			}
+			if graceC == nil && opts.GracePeriod > 0 {
+				graceC = time.After(time.Duration(opts.GracePeriod) * time.Second)
+			}
+		case <-graceC:
+			if err := syscall.Kill(pgid, syscall.SIGKILL); err != nil {
+				log.Println("Failed to kill process:", err)
+			}
+			<-done
+			return ExitCodeProcessKilled, ErrCommandKilled
+		case <-timeoutC:
+			if err := syscall.Kill(pgid, syscall.SIGKILL); err != nil {
+				log.Println("Failed to kill process:", err)
+			}
+			<-done
+			return ExitCodeProcessKilled, ErrCommandTimedOut
+		}
+	}
+}
+
+// exitCodeFromError converts the error returned from running a command into an integer suitable for use as an exit
+// code, and an error.
+// A command that was terminated by a signal gets an exit code of 128 plus the signal number like a shell would use.
+func exitCodeFromError(err error) (int, error) {
+	if err == nil {
+		return 0, nil
+	}
+
+	var exitError *exec.ExitError
+	if !errors.As(err, &exitError) {
+		return 1, fmt.Errorf("process run error: %w", err)
+	}
+	if status, ok := exitError.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+		return 128 + int(status.Signal()), fmt.Errorf("process exited with error: %w", exitError)
+	}
+	return exitError.ExitCode(), fmt.Errorf("process exited with error: %w", exitError)
+}
+
+// TailBuffer is an io.Writer that only keeps the last Size bytes written to it.
+// It is safe to write to from multiple goroutines.
+type TailBuffer struct {
+	Size int
+
+	mu  sync.Mutex
+	buf []byte
+}
+
+// Write implements the io.Writer interface.
+func (t *TailBuffer) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.buf = append(t.buf, p...)
+	if len(t.buf) > t.Size {
+		t.buf = t.buf[len(t.buf)-t.Size:]
+	}
+	return len(p), nil
+}
+
+// String returns the bytes kept by the TailBuffer as a string.
+func (t *TailBuffer) String() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return string(t.buf)
+}
+
 // TerminalSize tries to return the character dimensions of the terminal.
 // It works through all the standard file descriptors until they are exhausted.
 // That's because if a descriptor is being redirected, the call to term.GetSize() will fail.
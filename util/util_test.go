@@ -1,10 +1,66 @@
 package util
 
 import (
+	"bytes"
+	"errors"
+	"strings"
 	"testing"
 	"time"
 )
 
+func TestConfirm(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		input    string
+		expected bool
+	}{
+		{name: "y", input: "y\n", expected: true},
+		{name: "yes", input: "yes\n", expected: true},
+		{name: "uppercase", input: " YES \n", expected: true},
+		{name: "no", input: "n\n", expected: false},
+		{name: "empty", input: "\n", expected: false},
+		{name: "other", input: "yep\n", expected: false},
+		{name: "no newline", input: "y", expected: true},
+		{name: "end of input", input: "", expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var out bytes.Buffer
+			got, err := Confirm(strings.NewReader(tt.input), &out, "Continue?")
+			if err != nil {
+				t.Fatalf("Confirm() returned an unexpected error: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("Confirm() failed, expected %v, got %v", tt.expected, got)
+			}
+			if !strings.HasPrefix(out.String(), "Continue? [y/N] ") {
+				t.Errorf("Confirm() wrote an unexpected prompt: %q", out.String())
+			}
+		})
+	}
+}
+
+func TestConfirmReadError(t *testing.T) {
+	t.Parallel()
+
+	_, err := Confirm(errReader{}, &bytes.Buffer{}, "Continue?")
+	if !errors.Is(err, errReadConfirmation) {
+		t.Errorf("Confirm() failed, expected %v, got %v", errReadConfirmation, err)
+	}
+}
+
+// errReader is an io.Reader that always fails.
+type errReader struct{}
+
+func (errReader) Read([]byte) (int, error) {
+	return 0, errors.New("read failed")
+}
+
 func TestFormatAge(t *testing.T) {
 	t.Parallel()
 
@@ -45,6 +101,74 @@ func TestFormatAge(t *testing.T) {
 	}
 }
 
+func TestFormatVersion(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		version  string
+		commit   string
+		date     string
+		modified bool
+		expected string
+	}{
+		{
+			version:  "(devel)",
+			commit:   "0123abc",
+			date:     "2024-12-20T01:02:03Z",
+			modified: false,
+			expected: "prog version (devel), commit 0123abc, built 2024-12-20T01:02:03Z",
+		},
+		{
+			version:  "v1.2.3",
+			commit:   "0123abc",
+			date:     "2024-12-20T01:02:03Z",
+			modified: true,
+			expected: "prog version v1.2.3, commit 0123abc-dirty, built 2024-12-20T01:02:03Z",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run("formatVersion", func(t *testing.T) {
+			t.Parallel()
+
+			result := formatVersion("prog", tt.version, tt.commit, tt.date, tt.modified)
+			if result != tt.expected {
+				t.Errorf("formatVersion() failed, expected %s, got %s", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestGetEnvDuration(t *testing.T) {
+	// Not parallel since t.Setenv() can't be used in parallel tests.
+	const envVar = "UTIL_TEST_DURATION"
+	defaultValue := 30 * time.Second
+
+	tests := []struct {
+		value    string
+		expected time.Duration
+	}{
+		{value: "90", expected: 90 * time.Second},
+		{value: "0", expected: 0},
+		{value: "90s", expected: 90 * time.Second},
+		{value: "5m", expected: 5 * time.Minute},
+		{value: "2h30m", expected: 2*time.Hour + 30*time.Minute},
+		{value: "500ms", expected: 500 * time.Millisecond},
+		{value: "", expected: defaultValue},
+		{value: "soon", expected: defaultValue},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			t.Setenv(envVar, tt.value)
+
+			if got := GetEnvDuration(envVar, defaultValue); got != tt.expected {
+				t.Errorf("GetEnvDuration() failed, expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
 func TestLastSplitItem(t *testing.T) {
 	t.Parallel()
 
@@ -137,6 +261,96 @@ func TestLineVisualWidth(t *testing.T) {
 	}
 }
 
+func TestRandomString(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		length  int
+		charset string
+	}{
+		{name: "alphanumeric", length: 32, charset: "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"},
+		{name: "single character", length: 5, charset: "x"},
+		{name: "multi-byte characters", length: 16, charset: "äöü"},
+		{name: "empty", length: 0, charset: "abc"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := RandomString(tt.length, tt.charset)
+			if err != nil {
+				t.Fatalf("RandomString() returned an unexpected error: %v", err)
+			}
+			if n := len([]rune(got)); n != tt.length {
+				t.Errorf("RandomString() failed, expected %d characters, got %d", tt.length, n)
+			}
+			for _, c := range got {
+				if !strings.ContainsRune(tt.charset, c) {
+					t.Errorf("RandomString() returned a character not in the charset: %q", c)
+				}
+			}
+		})
+	}
+}
+
+func TestRandomStringEmptyCharset(t *testing.T) {
+	t.Parallel()
+
+	_, err := RandomString(8, "")
+	if !errors.Is(err, errEmptyCharset) {
+		t.Errorf("RandomString() failed, expected %v, got %v", errEmptyCharset, err)
+	}
+}
+
+func TestTailBuffer(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		size     int
+		writes   []string
+		expected string
+	}{
+		{
+			size:     10,
+			writes:   []string{"hello"},
+			expected: "hello",
+		},
+		{
+			size:     10,
+			writes:   []string{"hello", " ", "world"},
+			expected: "ello world",
+		},
+		{
+			size:     5,
+			writes:   []string{"abcdefghij"},
+			expected: "fghij",
+		},
+		{
+			size:     0,
+			writes:   []string{"abc"},
+			expected: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run("TailBuffer", func(t *testing.T) {
+			t.Parallel()
+
+			tail := &TailBuffer{Size: tt.size}
+			for _, w := range tt.writes {
+				if n, err := tail.Write([]byte(w)); err != nil || n != len(w) {
+					t.Fatalf("Write() failed, wrote %d of %d bytes: %v", n, len(w), err)
+				}
+			}
+			if tail.String() != tt.expected {
+				t.Errorf("TailBuffer failed, expected %q, got %q", tt.expected, tail.String())
+			}
+		})
+	}
+}
+
 func TestWrapLine(t *testing.T) {
 	t.Parallel()
 